@@ -2,6 +2,10 @@ package cmd
 
 import (
 	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -24,11 +28,39 @@ var rootCmd = &cobra.Command{
 var legalHoldData string
 var outputPath string
 var legalHoldSecret string
+var publicKeyPath string
+var encryptionKey string
+
+// Exit codes of the processor.
+const (
+	exitOK = 0
+	// exitError means the legal hold data could not be read or processed.
+	exitError = 1
+	// exitVerificationFailed means the legal hold data was read, but failed verification.
+	exitVerificationFailed = 2
+)
+
+// Names of the verification report files written to the output path.
+const (
+	verificationReportJSONPath = "verification.json"
+	verificationReportTextPath = "verification.txt"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify a Mattermost Legal Hold",
+	Long:  `Verifies the authenticity of the data exported by the Mattermost Legal Hold plugin without processing it`,
+	Run:   Verify,
+}
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&legalHoldData, "legal-hold-data", "", "Path to the legal hold data file")
 	rootCmd.PersistentFlags().StringVar(&outputPath, "output-path", "", "Path where the output files will be written")
 	rootCmd.PersistentFlags().StringVar(&legalHoldSecret, "legal-hold-secret", "", "Secret to verify the legal hold data")
+	rootCmd.PersistentFlags().StringVar(&publicKeyPath, "public-key", "", "Path to the PEM-encoded public key to verify the signed legal hold manifests")
+	rootCmd.PersistentFlags().StringVar(&encryptionKey, "encryption-key", "", "Base64-encoded encryption key configured in the plugin, to decrypt encrypted legal holds")
+
+	rootCmd.AddCommand(verifyCmd)
 }
 
 func Execute() {
@@ -85,28 +117,28 @@ func Process(cmd *cobra.Command, _ []string) {
 	}
 	fmt.Println()
 
+	if err = DecryptLegalHolds(legalHolds); err != nil {
+		fmt.Printf("Error while decrypting legal holds: %v\n", err)
+		os.RemoveAll(tempPath)
+		os.Exit(exitError)
+	}
+
 	// Verify the legal hold data
-	if legalHoldSecret != "" {
-		fmt.Println("Secret key was provided, verifying legal holds...")
-		var errorsOnVerify bool
+	report, err := VerifyLegalHolds(tempPath, legalHolds)
+	if err != nil {
+		fmt.Printf("Error while verifying legal holds: %v\n", err)
+		os.Exit(exitError)
+	}
 
-		for _, hold := range legalHolds {
-			fmt.Printf("- Verifying Legal Hold (%s): ", hold.Name)
-			err := parse.ParseHashes(tempPath, hold.Path, legalHoldSecret)
-			if err != nil {
-				fmt.Printf("[Error] %v\n", err)
-				errorsOnVerify = true
-				continue
-			} else {
-				fmt.Println("Verified")
-			}
-		}
-		fmt.Println()
+	if err = writeVerificationReport(report, outputPath); err != nil {
+		fmt.Printf("Error while writing the verification report: %v\n", err)
+		os.Exit(exitError)
+	}
 
-		if errorsOnVerify {
-			fmt.Println("Failed to verify the authenticity of the legal holds. Exiting.")
-			os.Exit(1)
-		}
+	if !report.Verified {
+		fmt.Println("Failed to verify the authenticity of the legal holds. Exiting.")
+		os.RemoveAll(tempPath)
+		os.Exit(exitVerificationFailed)
 	}
 
 	// Process Each Legal Hold.
@@ -119,6 +151,188 @@ func Process(cmd *cobra.Command, _ []string) {
 	}
 }
 
+// Verify extracts the legal hold data to a temporary directory and verifies it, writing the
+// verification report to the output path, if provided. It exits with exitVerificationFailed if
+// any legal hold fails verification.
+func Verify(cmd *cobra.Command, _ []string) {
+	if legalHoldData == "" {
+		fmt.Println("Error: --legal-hold-data flag is required")
+		fmt.Println("")
+		_ = cmd.Help()
+		os.Exit(exitError)
+	}
+
+	tempPath, err := os.MkdirTemp("", "legalhold-verify-")
+	if err != nil {
+		fmt.Printf("Error while creating temporary directory: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	exitCode := verify(tempPath)
+	os.RemoveAll(tempPath)
+	os.Exit(exitCode)
+}
+
+func verify(tempPath string) int {
+	if err := ExtractZip(legalHoldData, tempPath); err != nil {
+		fmt.Printf("Error while extracting: %v\n", err)
+		return exitError
+	}
+
+	legalHolds, err := parse.ListLegalHolds(tempPath)
+	if err != nil {
+		fmt.Printf("Error while listing legal holds: %v\n", err)
+		return exitError
+	}
+
+	if err = DecryptLegalHolds(legalHolds); err != nil {
+		fmt.Printf("Error while decrypting legal holds: %v\n", err)
+		return exitError
+	}
+
+	report, err := VerifyLegalHolds(tempPath, legalHolds)
+	if err != nil {
+		fmt.Printf("Error while verifying legal holds: %v\n", err)
+		return exitError
+	}
+
+	if err = writeVerificationReport(report, outputPath); err != nil {
+		fmt.Printf("Error while writing the verification report: %v\n", err)
+		return exitError
+	}
+
+	if !report.Verified {
+		return exitVerificationFailed
+	}
+	return exitOK
+}
+
+// DecryptLegalHolds decrypts the encrypted legal holds in place with the encryption key, failing
+// if a legal hold is encrypted but no key was provided.
+func DecryptLegalHolds(legalHolds []model.LegalHold) error {
+	var masterKey []byte
+	if encryptionKey != "" {
+		var err error
+		if masterKey, err = model.ParseEncryptionKey(encryptionKey); err != nil {
+			return err
+		}
+	}
+
+	for _, hold := range legalHolds {
+		decrypted, err := parse.DecryptLegalHold(hold.Path, masterKey)
+		if err != nil {
+			return fmt.Errorf("legal hold %s: %w", hold.Name, err)
+		}
+		if decrypted > 0 {
+			fmt.Printf("- Decrypted %d files of Legal Hold (%s)\n", decrypted, hold.Name)
+		}
+	}
+
+	return nil
+}
+
+// VerifyLegalHolds verifies the legal holds extracted to tempPath against their ledgers, and
+// against the secret and the public key, whichever were provided. Every check is made on every
+// legal hold, so that the report lists every problem found. It only returns an error if the
+// public key cannot be read.
+func VerifyLegalHolds(tempPath string, legalHolds []model.LegalHold) (model.VerificationReport, error) {
+	var publicKey ed25519.PublicKey
+	if publicKeyPath != "" {
+		publicKeyData, err := os.ReadFile(publicKeyPath)
+		if err != nil {
+			return model.VerificationReport{}, fmt.Errorf("error reading public key: %w", err)
+		}
+
+		publicKey, err = model.ParsePublicKey(publicKeyData)
+		if err != nil {
+			return model.VerificationReport{}, err
+		}
+	}
+
+	var verifications []model.LegalHoldVerification
+	for _, hold := range legalHolds {
+		fmt.Printf("- Verifying Legal Hold (%s)...\n", hold.Name)
+
+		verification := model.LegalHoldVerification{
+			LegalHoldID:   hold.ID,
+			LegalHoldName: hold.Name,
+		}
+
+		if legalHoldSecret != "" {
+			check, err := parse.VerifyHashes(tempPath, hold.Path, legalHoldSecret)
+			verification.Checks = append(verification.Checks, checkResult(model.VerificationCheckHashes, check, err))
+		} else {
+			verification.Checks = append(verification.Checks, model.VerificationCheck{Name: model.VerificationCheckHashes, Skipped: "no secret was provided"})
+		}
+
+		if publicKey != nil {
+			check, err := parse.VerifyManifest(tempPath, hold.Path, publicKey)
+			verification.Checks = append(verification.Checks, checkResult(model.VerificationCheckManifest, check, err))
+		} else {
+			verification.Checks = append(verification.Checks, model.VerificationCheck{Name: model.VerificationCheckManifest, Skipped: "no public key was provided"})
+		}
+
+		check, err := parse.VerifyLedger(tempPath, hold.Path)
+		if errors.Is(err, parse.ErrNoLedger) {
+			verification.Checks = append(verification.Checks, model.VerificationCheck{Name: model.VerificationCheckLedger, Skipped: "exported by an older version of the plugin"})
+		} else {
+			verification.Checks = append(verification.Checks, checkResult(model.VerificationCheckLedger, check, err))
+		}
+
+		verifications = append(verifications, verification)
+	}
+	fmt.Println()
+
+	return model.NewVerificationReport(verifications), nil
+}
+
+// checkResult returns the check, or records err in it if the check could not be completed.
+func checkResult(name string, check model.VerificationCheck, err error) model.VerificationCheck {
+	if err != nil {
+		failed := model.NewVerificationCheck(name, nil)
+		failed.Error = err.Error()
+		return failed
+	}
+	return check
+}
+
+// writeVerificationReport prints the report and, if reportPath is not empty, writes it there as
+// verification.json and verification.txt.
+func writeVerificationReport(report model.VerificationReport, reportPath string) error {
+	if err := report.WriteText(os.Stdout); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	if reportPath == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(reportPath, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(reportPath, verificationReportJSONPath), data, 0644); err != nil {
+		return err
+	}
+
+	var text bytes.Buffer
+	if err = report.WriteText(&text); err != nil {
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(reportPath, verificationReportTextPath), text.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Verification report written to: %s\n", filepath.Join(reportPath, verificationReportJSONPath))
+	fmt.Println()
+	return nil
+}
+
 // ExtractZip extracts all files from the specified zip archive and saves them to the given output path.
 func ExtractZip(zipPath string, outputPath string) error {
 	r, err := zip.OpenReader(zipPath)
@@ -164,14 +378,14 @@ func ProcessLegalHold(hold model.LegalHold, outputPath string) error {
 	}
 	fmt.Println()
 
-	// Build a FileID to file path lookup table.
-	originalFileLookup, err := parse.ProcessFiles(hold)
+	// Build a FileID to attachment lookup table.
+	attachmentLookup, err := parse.ProcessFiles(hold)
 	if err != nil {
 		return err
 	}
 
 	// Move all attachments into position in the output folders.
-	fileLookup, err := view.MoveFiles(originalFileLookup, outputPath)
+	fileLookup, err := view.MoveFiles(attachmentLookup, outputPath)
 	if err != nil {
 		return err
 	}
@@ -182,8 +396,12 @@ func ProcessLegalHold(hold model.LegalHold, outputPath string) error {
 			return err
 		}
 
-		// Augment posts with the path to the file attachments using the fileID LUT.
-		postsWithFiles := parse.AddFilesToPosts(posts, fileLookup)
+		// Augment posts with the path to the file attachments using the fileID LUT, and with
+		// their revisions, reactions and acknowledgements.
+		postsWithFiles, err := augmentPosts(channel, posts, fileLookup)
+		if err != nil {
+			return err
+		}
 
 		// Get channel and team data from lookups, or create fallback if not found
 		var firstPost *model.Post
@@ -210,7 +428,10 @@ func ProcessLegalHold(hold model.LegalHold, outputPath string) error {
 				return err
 			}
 
-			postsWithFiles := parse.AddFilesToPosts(posts, fileLookup)
+			postsWithFiles, err := augmentPosts(channel, posts, fileLookup)
+			if err != nil {
+				return err
+			}
 
 			// Get channel and team data from lookups, or create fallback if not found
 			var firstPost *model.Post
@@ -231,7 +452,10 @@ func ProcessLegalHold(hold model.LegalHold, outputPath string) error {
 				return err
 			}
 
-			postsWithFiles := parse.AddFilesToPosts(posts, fileLookup)
+			postsWithFiles, err := augmentPosts(channel, posts, fileLookup)
+			if err != nil {
+				return err
+			}
 
 			allPosts[channel.ID] = postsWithFiles
 		}
@@ -289,3 +513,26 @@ func extractItem(f *zip.File, outputPath string) error {
 	}
 	return nil
 }
+
+// augmentPosts adds the file attachments, revisions, reactions and acknowledgements of the posts
+// in the channel to them.
+func augmentPosts(channel model.Channel, posts []*model.Post, fileLookup model.FileLookup) ([]*model.PostWithFiles, error) {
+	revisions, err := parse.LoadRevisions(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	reactions, err := parse.LoadReactions(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	acknowledgements, err := parse.LoadAcknowledgements(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	postsWithFiles := parse.AddRevisionsToPosts(parse.AddFilesToPosts(posts, fileLookup), revisions)
+
+	return parse.AddInteractionsToPosts(postsWithFiles, reactions, acknowledgements), nil
+}
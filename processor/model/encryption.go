@@ -0,0 +1,196 @@
+package model
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	EncryptionInfoPath = "encryption.json"
+
+	// EncryptionAlgorithm is the only format the plugin encrypts legal holds with.
+	EncryptionAlgorithm = "AES-256-GCM-CHUNKED"
+	EncryptionKeySize   = 32
+
+	// EncryptionMagic starts every encrypted file, followed by the salt of the file key.
+	EncryptionMagic           = "MMLHENC2"
+	encryptionFileSaltSize    = 32
+	encryptionNoncePrefixSize = 7
+	encryptionChunkSize       = 64 * 1024
+)
+
+var (
+	dataKeyAdditionalData = []byte("legal_hold_data_key")
+	fileKeyInfo           = []byte("legal_hold_file_key")
+)
+
+// IsEncryptionMagic returns true if magic starts a file encrypted by the plugin.
+func IsEncryptionMagic(magic []byte) bool {
+	return string(magic) == EncryptionMagic
+}
+
+// fileKey derives the key of a file from the data key of its legal hold and the salt of the file
+// with HKDF-SHA256, as the plugin does. A single block of the expansion is a whole key.
+func fileKey(dataKey, salt []byte) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(dataKey)
+
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write(fileKeyInfo)
+	expand.Write([]byte{1})
+	return expand.Sum(nil)
+}
+
+// EncryptionInfo holds the data key of an encrypted legal hold, wrapped by the master key
+// configured in the plugin.
+type EncryptionInfo struct {
+	Algorithm      string `json:"algorithm"`
+	MasterKeyID    string `json:"master_key_id"`
+	WrappedDataKey []byte `json:"wrapped_data_key"`
+}
+
+// ParseEncryptionKey decodes a base64-encoded master key, as set in the plugin configuration.
+func ParseEncryptionKey(value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding the encryption key: %w", err)
+	}
+
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("the encryption key must be %d bytes long, not %d", EncryptionKeySize, len(key))
+	}
+
+	return key, nil
+}
+
+// DataKey unwraps the data key with masterKey.
+func (i EncryptionInfo) DataKey(masterKey []byte) ([]byte, error) {
+	if i.Algorithm != EncryptionAlgorithm {
+		return nil, fmt.Errorf("unsupported encryption algorithm %s", i.Algorithm)
+	}
+
+	fingerprint := sha256.Sum256(masterKey)
+	if keyID := hex.EncodeToString(fingerprint[:8]); i.MasterKeyID != keyID {
+		return nil, fmt.Errorf("the legal hold was encrypted with master key %s, not %s", i.MasterKeyID, keyID)
+	}
+
+	gcm, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(i.WrappedDataKey) < gcm.NonceSize() {
+		return nil, errors.New("the wrapped data key is too short")
+	}
+
+	nonce, wrapped := i.WrappedDataKey[:gcm.NonceSize()], i.WrappedDataKey[gcm.NonceSize():]
+	dataKey, err := gcm.Open(nil, nonce, wrapped, dataKeyAdditionalData)
+	if err != nil {
+		return nil, fmt.Errorf("error unwrapping the data key: %w", err)
+	}
+
+	return dataKey, nil
+}
+
+// DecryptingReader decrypts a file encrypted by the plugin. The file is made of EncryptionMagic
+// and a random 32-byte salt, followed by chunks of up to 64 KiB of plaintext, each sealed with
+// AES-256-GCM under the key derived from the data key and the salt, and a nonce made of 7 zero
+// bytes, the index of the chunk and a flag marking the last chunk. Reading fails if any chunk was
+// altered, reordered or removed.
+type DecryptingReader struct {
+	source  *bufio.Reader
+	gcm     cipher.AEAD
+	counter uint32
+	chunk   []byte
+	pending []byte
+	done    bool
+}
+
+// NewDecryptingReader returns a reader of the plaintext of the encrypted file read from source.
+func NewDecryptingReader(source io.Reader, dataKey []byte) (*DecryptingReader, error) {
+	magic := make([]byte, len(EncryptionMagic))
+	if _, err := io.ReadFull(source, magic); err != nil {
+		return nil, fmt.Errorf("error reading encryption header: %w", err)
+	}
+
+	if string(magic) != EncryptionMagic {
+		return nil, errors.New("the file is not encrypted")
+	}
+
+	salt := make([]byte, encryptionFileSaltSize)
+	if _, err := io.ReadFull(source, salt); err != nil {
+		return nil, fmt.Errorf("error reading encryption header: %w", err)
+	}
+
+	gcm, err := newGCM(fileKey(dataKey, salt))
+	if err != nil {
+		return nil, err
+	}
+
+	return &DecryptingReader{
+		source: bufio.NewReader(source),
+		gcm:    gcm,
+		chunk:  make([]byte, encryptionChunkSize+gcm.Overhead()),
+	}, nil
+}
+
+func (r *DecryptingReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(r.source, r.chunk)
+		last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !last {
+			return 0, err
+		}
+		if !last {
+			if _, peekErr := r.source.Peek(1); errors.Is(peekErr, io.EOF) {
+				last = true
+			} else if peekErr != nil {
+				return 0, peekErr
+			}
+		}
+
+		nonce := make([]byte, encryptionNoncePrefixSize, r.gcm.NonceSize())
+		nonce = binary.BigEndian.AppendUint32(nonce, r.counter)
+		if last {
+			nonce = append(nonce, 1)
+		} else {
+			nonce = append(nonce, 0)
+		}
+
+		plaintext, err := r.gcm.Open(nil, nonce, r.chunk[:n], nil)
+		if err != nil {
+			return 0, fmt.Errorf("error decrypting chunk %d: %w", r.counter, err)
+		}
+
+		r.pending = plaintext
+		r.counter++
+		r.done = last
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,24 @@
+package model
+
+// FileReference represents one file attachment of a batch of posts, whose content is stored
+// once per legal hold at BlobPath, relative to the legal hold directory.
+// It must be kept in sync with model.LegalHoldFileReference from mattermost-plugin-legal-hold
+type FileReference struct {
+	FileID   string `csv:"FileId"`
+	FileName string `csv:"FileName"`
+	FileSize int64  `csv:"FileSize"`
+	MimeType string `csv:"MimeType"`
+	Digest   string `csv:"Digest"`
+	BlobPath string `csv:"BlobPath"`
+}
+
+// Attachment is a file attachment found in a legal hold, before it is moved to the output.
+type Attachment struct {
+	// Path is the path of the content of the attachment.
+	Path string
+	// Name is the name of the attachment, which is the base name of Path for attachments exported
+	// before their content was stored by digest.
+	Name string
+	// Shared is set if the content at Path may belong to other attachments too.
+	Shared bool
+}
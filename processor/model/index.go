@@ -21,17 +21,43 @@ type LegalHoldIndexUser struct {
 type LegalHoldIndexUsers map[string]LegalHoldIndexUser
 
 type LegalHoldIndexDetails struct {
-	ID                   string `json:"id"`
-	Name                 string `json:"name"`
-	DisplayName          string `json:"display_name"`
-	StartsAt             int64  `json:"starts_at"`
-	LastExecutionEndedAt int64  `json:"last_execution_ended_at"`
+	ID                   string           `json:"id"`
+	Name                 string           `json:"name"`
+	DisplayName          string           `json:"display_name"`
+	StartsAt             int64            `json:"starts_at"`
+	LastExecutionEndedAt int64            `json:"last_execution_ended_at"`
+	Filter               *LegalHoldFilter `json:"filter,omitempty"`
+}
+
+// LegalHoldFilter describes the filter the legal hold data was collected under.
+// It must be kept in sync with model.LegalHoldFilter from mattermost-plugin-legal-hold
+type LegalHoldFilter struct {
+	Terms               []string `json:"terms,omitempty"`
+	Hashtags            []string `json:"hashtags,omitempty"`
+	IncludeChannelTypes []string `json:"include_channel_types,omitempty"`
+	ExcludeChannelTypes []string `json:"exclude_channel_types,omitempty"`
 }
 
 type LegalHoldIndex struct {
-	Users     LegalHoldIndexUsers   `json:"users"`
-	LegalHold LegalHoldIndexDetails `json:"legal_hold"`
-	Teams     []*LegalHoldTeam      `json:"teams"`
+	Users          LegalHoldIndexUsers      `json:"users"`
+	LegalHold      LegalHoldIndexDetails    `json:"legal_hold"`
+	Teams          []*LegalHoldTeam         `json:"teams"`
+	FileExceptions []LegalHoldFileException `json:"file_exceptions,omitempty"`
+}
+
+// LegalHoldFileException records a file attachment that could not be exported.
+// It must be kept in sync with model.LegalHoldFileException from mattermost-plugin-legal-hold
+type LegalHoldFileException struct {
+	ExecutionStartTime int64  `json:"execution_start_time"`
+	ExecutionEndTime   int64  `json:"execution_end_time"`
+	ChannelID          string `json:"channel_id"`
+	BatchPostID        string `json:"batch_post_id"`
+	FileID             string `json:"file_id"`
+	FileName           string `json:"file_name"`
+	FilePath           string `json:"file_path"`
+	Reason             string `json:"reason"`
+	Error              string `json:"error"`
+	Attempts           int    `json:"attempts"`
 }
 
 type LegalHoldTeam struct {
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const LedgerPath = "ledger.jsonl"
+
+// LedgerEntry records the SHA-256 digests of the files written by one execution of a legal hold,
+// chained to the entry before it by the digest of its line.
+type LedgerEntry struct {
+	Sequence           int64    `json:"sequence"`
+	LegalHoldID        string   `json:"legal_hold_id"`
+	ExecutionStartTime int64    `json:"execution_start_time"`
+	ExecutionEndTime   int64    `json:"execution_end_time"`
+	CreateAt           int64    `json:"create_at"`
+	PreviousDigest     string   `json:"previous_digest"`
+	Files              HashList `json:"files"`
+}
+
+// LedgerDigest returns the hex-encoded SHA-256 digest of a line of the ledger, without its
+// trailing newline.
+func LedgerDigest(line []byte) string {
+	digest := sha256.Sum256(line)
+	return hex.EncodeToString(digest[:])
+}
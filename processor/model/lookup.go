@@ -4,4 +4,5 @@ type ChannelLookup map[string]*LegalHoldChannel
 type TeamLookup map[string]*LegalHoldTeam
 type TeamForChannelLookup map[string]*LegalHoldTeam
 
-type FileLookup map[string]string // Key: FileID, Value: file path
+type FileLookup map[string]string           // Key: FileID, Value: file path
+type AttachmentLookup map[string]Attachment // Key: FileID
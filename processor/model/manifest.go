@@ -0,0 +1,63 @@
+package model
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+const (
+	ManifestPath          = "manifest.json"
+	ManifestSignaturePath = "manifest.json.sig"
+
+	// ManifestDigestAlgorithm is the only digest algorithm manifests are written with.
+	ManifestDigestAlgorithm = "sha256"
+)
+
+// Manifest lists the SHA-256 digest of every file exported for a legal hold, keyed by path. It
+// is signed with the signing key of the Mattermost installation that exported it.
+type Manifest struct {
+	LegalHoldID   string   `json:"legal_hold_id"`
+	LegalHoldName string   `json:"legal_hold_name"`
+	KeyID         string   `json:"key_id"`
+	Algorithm     string   `json:"algorithm"`
+	UpdateAt      int64    `json:"update_at"`
+	LedgerHead    string   `json:"ledger_head,omitempty"`
+	Files         HashList `json:"files"`
+}
+
+// ParsePublicKey parses a PEM-encoded PKIX Ed25519 public key, as served by the plugin.
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("no PEM-encoded public key found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing public key: %w", err)
+	}
+
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("the public key is not an Ed25519 key")
+	}
+
+	return publicKey, nil
+}
+
+// KeyID returns the hex-encoded SHA-256 fingerprint of the PKIX form of the public key, which
+// manifests record to identify the key that signed them.
+func KeyID(publicKey ed25519.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	fingerprint := sha256.Sum256(der)
+	return hex.EncodeToString(fingerprint[:]), nil
+}
@@ -33,6 +33,7 @@ type Post struct {
 	PostProps      string `csv:"PostProps"`
 	PostHashtags   string `csv:"PostHashtags"`
 	PostFileIDs    string `csv:"PostFileIds"`
+	PostIsPinned   bool   `csv:"PostIsPinned"`
 
 	IsBot bool `csv:"IsBot"`
 }
@@ -43,7 +44,67 @@ func (p Post) PrintCreateAt() string {
 	return t.Format("15:04 on 2006-01-02")
 }
 
+// PostRevision represents one revision of a post that was edited or deleted after it was created.
+// It must be kept in sync with model.LegalHoldPostRevision from mattermost-plugin-legal-hold
+type PostRevision struct {
+	RevisionOf   string `csv:"RevisionOf"`
+	RevisionType string `csv:"RevisionType"`
+	PostEditAt   int64  `csv:"PostEditAt"`
+
+	Post
+}
+
+// PrintUpdateAt prints the UpdateAt time, i.e. the time of the revision, in a human-readable format.
+func (r PostRevision) PrintUpdateAt() string {
+	t := time.Unix(0, r.PostUpdateAt*int64(time.Millisecond))
+	return t.Format("15:04 on 2006-01-02")
+}
+
+// Reaction represents one reaction to a post as recorded in a legal hold.
+// It must be kept in sync with model.LegalHoldReaction from mattermost-plugin-legal-hold
+type Reaction struct {
+	PostID       string `csv:"PostId"`
+	UserID       string `csv:"UserId"`
+	UserUsername string `csv:"UserUsername"`
+	UserEmail    string `csv:"UserEmail"`
+	EmojiName    string `csv:"EmojiName"`
+	CreateAt     int64  `csv:"CreateAt"`
+	UpdateAt     int64  `csv:"UpdateAt"`
+	DeleteAt     int64  `csv:"DeleteAt"`
+}
+
+// PrintCreateAt prints the CreateAt time in a human-readable format.
+func (r Reaction) PrintCreateAt() string {
+	t := time.Unix(0, r.CreateAt*int64(time.Millisecond))
+	return t.Format("15:04 on 2006-01-02")
+}
+
+// PrintDeleteAt prints the DeleteAt time, when the reaction was removed, in a human-readable format.
+func (r Reaction) PrintDeleteAt() string {
+	t := time.Unix(0, r.DeleteAt*int64(time.Millisecond))
+	return t.Format("15:04 on 2006-01-02")
+}
+
+// Acknowledgement represents the acknowledgement of a post by a user as recorded in a legal hold.
+// It must be kept in sync with model.LegalHoldAcknowledgement from mattermost-plugin-legal-hold
+type Acknowledgement struct {
+	PostID         string `csv:"PostId"`
+	UserID         string `csv:"UserId"`
+	UserUsername   string `csv:"UserUsername"`
+	UserEmail      string `csv:"UserEmail"`
+	AcknowledgedAt int64  `csv:"AcknowledgedAt"`
+}
+
+// PrintAcknowledgedAt prints the AcknowledgedAt time in a human-readable format.
+func (a Acknowledgement) PrintAcknowledgedAt() string {
+	t := time.Unix(0, a.AcknowledgedAt*int64(time.Millisecond))
+	return t.Format("15:04 on 2006-01-02")
+}
+
 type PostWithFiles struct {
 	*Post
-	Files []string
+	Files            []string
+	Revisions        []*PostRevision
+	Reactions        []*Reaction
+	Acknowledgements []*Acknowledgement
 }
@@ -0,0 +1,10 @@
+package model
+
+const SubsetIndexPath = "subset.json"
+
+// SubsetIndex maps to the subset.json file of a download of part of a legal hold, as selected by
+// its users, channels, teams or time range. It lists the files of the legal hold in the download,
+// so that the files it leaves out are not reported as missing.
+type SubsetIndex struct {
+	Files []string `json:"files"`
+}
@@ -0,0 +1,175 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// FileStatus is the outcome of verifying one file of a legal hold.
+type FileStatus string
+
+const (
+	// FileStatusOK means the file has the expected hash.
+	FileStatusOK FileStatus = "ok"
+	// FileStatusMismatched means the file does not have the expected hash.
+	FileStatusMismatched FileStatus = "mismatched"
+	// FileStatusMissing means the file is listed but is not in the archive.
+	FileStatusMissing FileStatus = "missing"
+	// FileStatusUnlisted means the file is in the archive but is not listed.
+	FileStatusUnlisted FileStatus = "unlisted"
+)
+
+// Names of the checks made when verifying a legal hold.
+const (
+	VerificationCheckHashes   = "hashes"
+	VerificationCheckManifest = "manifest"
+	VerificationCheckLedger   = "ledger"
+)
+
+// FileResult is the outcome of verifying one file of a legal hold against a list of hashes.
+type FileResult struct {
+	Path     string     `json:"path"`
+	Status   FileStatus `json:"status"`
+	Expected string     `json:"expected,omitempty"`
+	Actual   string     `json:"actual,omitempty"`
+}
+
+// VerificationCheck is the outcome of verifying a legal hold one way, such as against the
+// hashes.json file or the signed manifest.
+type VerificationCheck struct {
+	Name string `json:"name"`
+	// Skipped explains why the check was not made, if it was not.
+	Skipped string `json:"skipped,omitempty"`
+	// Error is the problem that stopped the check from verifying the files, if any.
+	Error   string             `json:"error,omitempty"`
+	Summary map[FileStatus]int `json:"summary"`
+	Files   []FileResult       `json:"files"`
+}
+
+// NewVerificationCheck creates a VerificationCheck from the results of verifying each file,
+// sorting them by path.
+func NewVerificationCheck(name string, files []FileResult) VerificationCheck {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	summary := map[FileStatus]int{
+		FileStatusOK:         0,
+		FileStatusMismatched: 0,
+		FileStatusMissing:    0,
+		FileStatusUnlisted:   0,
+	}
+	for _, file := range files {
+		summary[file.Status]++
+	}
+
+	if files == nil {
+		files = []FileResult{}
+	}
+
+	return VerificationCheck{
+		Name:    name,
+		Summary: summary,
+		Files:   files,
+	}
+}
+
+// OK returns true if the check was skipped, or was made and found every file as expected.
+func (c VerificationCheck) OK() bool {
+	return c.Error == "" && c.Summary[FileStatusMismatched] == 0 && c.Summary[FileStatusMissing] == 0 && c.Summary[FileStatusUnlisted] == 0
+}
+
+// LegalHoldVerification is the outcome of every check made on one legal hold.
+type LegalHoldVerification struct {
+	LegalHoldID   string              `json:"legal_hold_id"`
+	LegalHoldName string              `json:"legal_hold_name"`
+	Checks        []VerificationCheck `json:"checks"`
+}
+
+// OK returns true if every check made on the legal hold passed.
+func (v LegalHoldVerification) OK() bool {
+	for _, check := range v.Checks {
+		if !check.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// VerificationReport is the outcome of verifying every legal hold in an export.
+type VerificationReport struct {
+	Verified   bool                    `json:"verified"`
+	LegalHolds []LegalHoldVerification `json:"legal_holds"`
+}
+
+// NewVerificationReport creates the VerificationReport for the provided legal holds.
+func NewVerificationReport(legalHolds []LegalHoldVerification) VerificationReport {
+	report := VerificationReport{Verified: true, LegalHolds: legalHolds}
+	for _, legalHold := range legalHolds {
+		if !legalHold.OK() {
+			report.Verified = false
+		}
+	}
+
+	if report.LegalHolds == nil {
+		report.LegalHolds = []LegalHoldVerification{}
+	}
+
+	return report
+}
+
+// WriteText writes the report in human-readable form, listing every file that is not as
+// expected.
+func (r VerificationReport) WriteText(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	printf("Legal Hold Verification Report\n")
+	printf("==============================\n\n")
+
+	for _, legalHold := range r.LegalHolds {
+		printf("Legal Hold: %s (%s)\n", legalHold.LegalHoldName, legalHold.LegalHoldID)
+
+		for _, check := range legalHold.Checks {
+			switch {
+			case check.Skipped != "":
+				printf("- %s: skipped, %s\n", check.Name, check.Skipped)
+				continue
+			case check.Error != "":
+				printf("- %s: FAILED, %s\n", check.Name, check.Error)
+				continue
+			case check.OK():
+				printf("- %s: OK", check.Name)
+			default:
+				printf("- %s: FAILED", check.Name)
+			}
+
+			printf(" (%d ok, %d mismatched, %d missing, %d unlisted)\n",
+				check.Summary[FileStatusOK],
+				check.Summary[FileStatusMismatched],
+				check.Summary[FileStatusMissing],
+				check.Summary[FileStatusUnlisted],
+			)
+
+			for _, file := range check.Files {
+				if file.Status != FileStatusOK {
+					printf("    %-10s %s\n", file.Status, file.Path)
+				}
+			}
+		}
+		printf("\n")
+	}
+
+	if r.Verified {
+		printf("Result: all legal holds were verified.\n")
+	} else {
+		printf("Result: VERIFICATION FAILED.\n")
+	}
+
+	return err
+}
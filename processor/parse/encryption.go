@@ -0,0 +1,117 @@
+package parse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/processor/model"
+)
+
+// ErrEncryptionKeyRequired is returned by DecryptLegalHold for encrypted legal holds when no
+// encryption key was provided.
+var ErrEncryptionKeyRequired = errors.New("the legal hold is encrypted, but no encryption key was provided")
+
+// DecryptLegalHold decrypts in place the encrypted files of the legal hold at lhPath, with the
+// data key unwrapped by masterKey, returning the number of files decrypted. Legal holds that are
+// not encrypted are left alone.
+func DecryptLegalHold(lhPath string, masterKey []byte) (int, error) {
+	data, err := os.ReadFile(filepath.Join(lhPath, model.EncryptionInfoPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("error reading encryption.json file: %w", err)
+	}
+
+	if masterKey == nil {
+		return 0, ErrEncryptionKeyRequired
+	}
+
+	var info model.EncryptionInfo
+	if err = json.Unmarshal(data, &info); err != nil {
+		return 0, fmt.Errorf("error decoding encryption.json file: %w", err)
+	}
+
+	dataKey, err := info.DataKey(masterKey)
+	if err != nil {
+		return 0, err
+	}
+
+	decrypted := 0
+	err = filepath.WalkDir(lhPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		encrypted, err := isEncrypted(path)
+		if err != nil || !encrypted {
+			return err
+		}
+
+		if err = decryptFile(path, dataKey); err != nil {
+			return fmt.Errorf("error decrypting %s: %w", path, err)
+		}
+		decrypted++
+		return nil
+	})
+	if err != nil {
+		return decrypted, err
+	}
+
+	return decrypted, nil
+}
+
+func isEncrypted(path string) (bool, error) {
+	fileHandle, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer fileHandle.Close()
+
+	magic := make([]byte, len(model.EncryptionMagic))
+	if _, err = io.ReadFull(fileHandle, magic); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return model.IsEncryptionMagic(magic), nil
+}
+
+// decryptFile replaces the encrypted file at path with its plaintext, leaving it untouched if it
+// fails to decrypt.
+func decryptFile(path string, dataKey []byte) error {
+	encrypted, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer encrypted.Close()
+
+	reader, err := model.NewDecryptingReader(encrypted, dataKey)
+	if err != nil {
+		return err
+	}
+
+	plaintextPath := path + ".decrypted"
+	plaintext, err := os.Create(plaintextPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(plaintext, reader); err != nil {
+		plaintext.Close()
+		os.Remove(plaintextPath)
+		return err
+	}
+
+	if err = plaintext.Close(); err != nil {
+		os.Remove(plaintextPath)
+		return err
+	}
+
+	return os.Rename(plaintextPath, path)
+}
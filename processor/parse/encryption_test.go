@@ -0,0 +1,165 @@
+package parse
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/processor/model"
+)
+
+// encryptForTest encrypts plaintext in the format written by the plugin.
+func encryptForTest(t *testing.T, plaintext, dataKey []byte) []byte {
+	t.Helper()
+
+	salt := make([]byte, 32)
+	_, err := rand.Read(salt)
+	require.NoError(t, err)
+	encrypted := append([]byte(model.EncryptionMagic), salt...)
+
+	block, err := aes.NewCipher(fileKeyForTest(dataKey, salt))
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	for counter := uint32(0); ; counter++ {
+		size := min(len(plaintext), 64*1024)
+		last := size == len(plaintext)
+
+		nonce := binary.BigEndian.AppendUint32(make([]byte, 7), counter)
+		if last {
+			nonce = append(nonce, 1)
+		} else {
+			nonce = append(nonce, 0)
+		}
+
+		encrypted = gcm.Seal(encrypted, nonce, plaintext[:size], nil)
+		plaintext = plaintext[size:]
+		if last {
+			return encrypted
+		}
+	}
+}
+
+// fileKeyForTest derives the key of a file with HKDF-SHA256, as the plugin does.
+func fileKeyForTest(dataKey, salt []byte) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(dataKey)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write([]byte("legal_hold_file_key\x01"))
+	return expand.Sum(nil)
+}
+
+func TestFileKeyForTest(t *testing.T) {
+	// The key derived by the plugin for these inputs.
+	key := fileKeyForTest(bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32))
+	require.Equal(t, "f6e06b40a374a693de503198b0fe90503391f51d5cbac0e63689d95f7e85b6cf", hex.EncodeToString(key))
+}
+
+// encryptionInfoForTest wraps dataKey with masterKey as the plugin does.
+func encryptionInfoForTest(t *testing.T, masterKey, dataKey []byte) model.EncryptionInfo {
+	t.Helper()
+
+	block, err := aes.NewCipher(masterKey)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	require.NoError(t, err)
+
+	fingerprint := sha256.Sum256(masterKey)
+	return model.EncryptionInfo{
+		Algorithm:      model.EncryptionAlgorithm,
+		MasterKeyID:    hex.EncodeToString(fingerprint[:8]),
+		WrappedDataKey: gcm.Seal(nonce, nonce, dataKey, []byte("legal_hold_data_key")),
+	}
+}
+
+func TestDecryptLegalHold(t *testing.T) {
+	newKey := func() []byte {
+		key := make([]byte, model.EncryptionKeySize)
+		_, err := rand.Read(key)
+		require.NoError(t, err)
+		return key
+	}
+	masterKey, dataKey := newKey(), newKey()
+
+	large := bytes.Repeat([]byte("0123456789"), 20*1024)
+	files := map[string][]byte{
+		"index.json":           []byte(`{"users": {}}`),
+		"messages/batch.csv":   large,
+		"files/empty.txt":      {},
+		"plaintext/legacy.csv": []byte("written before encryption was enabled"),
+	}
+
+	setup := func(t *testing.T) string {
+		lhPath := filepath.Join(t.TempDir(), "legal_hold", "name_id")
+		for path, content := range files {
+			require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(lhPath, path)), 0755))
+			if path != "plaintext/legacy.csv" {
+				content = encryptForTest(t, content, dataKey)
+			}
+			require.NoError(t, os.WriteFile(filepath.Join(lhPath, path), content, 0644))
+		}
+
+		info, err := json.Marshal(encryptionInfoForTest(t, masterKey, dataKey))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(lhPath, model.EncryptionInfoPath), info, 0644))
+		return lhPath
+	}
+
+	t.Run("decrypts every encrypted file", func(t *testing.T) {
+		lhPath := setup(t)
+
+		decrypted, err := DecryptLegalHold(lhPath, masterKey)
+		require.NoError(t, err)
+		require.Equal(t, 3, decrypted)
+
+		for path, content := range files {
+			data, err := os.ReadFile(filepath.Join(lhPath, path))
+			require.NoError(t, err)
+			require.True(t, bytes.Equal(content, data), path)
+		}
+	})
+
+	t.Run("requires the key", func(t *testing.T) {
+		_, err := DecryptLegalHold(setup(t), nil)
+		require.ErrorIs(t, err, ErrEncryptionKeyRequired)
+	})
+
+	t.Run("rejects another key", func(t *testing.T) {
+		_, err := DecryptLegalHold(setup(t), newKey())
+		require.ErrorContains(t, err, "was encrypted with master key")
+	})
+
+	t.Run("fails on tampered files", func(t *testing.T) {
+		lhPath := setup(t)
+		path := filepath.Join(lhPath, "messages/batch.csv")
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		data[len(data)/2] ^= 1
+		require.NoError(t, os.WriteFile(path, data, 0644))
+
+		_, err = DecryptLegalHold(lhPath, masterKey)
+		require.ErrorContains(t, err, "error decrypting")
+	})
+
+	t.Run("leaves legal holds that are not encrypted alone", func(t *testing.T) {
+		decrypted, err := DecryptLegalHold(t.TempDir(), nil)
+		require.NoError(t, err)
+		require.Zero(t, decrypted)
+	})
+}
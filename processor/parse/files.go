@@ -2,55 +2,76 @@ package parse
 
 import (
 	"errors"
+	"fmt"
 	"maps"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/mattermost/mattermost-plugin-legal-hold/processor/model"
 )
 
-func ProcessFiles(legalHold model.LegalHold) (model.FileLookup, error) {
+func ProcessFiles(legalHold model.LegalHold) (model.AttachmentLookup, error) {
 	dirEntries, err := os.ReadDir(legalHold.Path)
 	if err != nil {
 		return nil, err
 	}
 
-	fileLookup := make(model.FileLookup)
+	attachmentLookup := make(model.AttachmentLookup)
 	for _, entry := range dirEntries {
 		if entry.IsDir() {
-			extra, err := processFilesInChannel(filepath.Join(legalHold.Path, entry.Name()))
+			extra, err := processFilesInChannel(legalHold.Path, filepath.Join(legalHold.Path, entry.Name()))
 			if err != nil {
 				return nil, err
 			}
 
-			maps.Copy(fileLookup, extra)
+			maps.Copy(attachmentLookup, extra)
 		}
 	}
 
-	return fileLookup, nil
+	return attachmentLookup, nil
 }
 
-func processFilesInChannel(path string) (model.FileLookup, error) {
+func processFilesInChannel(lhPath, path string) (model.AttachmentLookup, error) {
+	filesPath := filepath.Join(path, "files")
+
 	// Check if the "files" directory exists. Continue to next channel if not.
-	if _, err := os.Stat(filepath.Join(path, "files")); err != nil {
+	if _, err := os.Stat(filesPath); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil, nil
 		}
 		return nil, err
 	}
 
-	fileLookup := make(model.FileLookup)
+	attachmentLookup := make(model.AttachmentLookup)
 
 	// Loop through nested sub-folders to find files.
-	err := filepath.WalkDir(filepath.Join(path, "files"), func(filePath string, d os.DirEntry, err error) error {
+	err := filepath.WalkDir(filesPath, func(filePath string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		// Immediate parent directory name is the FileID
-		if !d.IsDir() {
-			fileID := filepath.Base(filepath.Dir(filePath))
-			fileLookup[fileID] = filepath.Join(filePath)
+		if d.IsDir() {
+			return nil
+		}
+
+		// The attachments of each batch of posts are listed in a CSV file, and stored by digest.
+		if filepath.Dir(filePath) == filesPath && filepath.Ext(filePath) == ".csv" {
+			references, err := loadFileReferences(lhPath, filePath)
+			if err != nil {
+				return err
+			}
+
+			maps.Copy(attachmentLookup, references)
+			return nil
+		}
+
+		// Attachments exported before they were stored by digest are in a directory named by
+		// their FileID.
+		fileID := filepath.Base(filepath.Dir(filePath))
+		attachmentLookup[fileID] = model.Attachment{
+			Path: filePath,
+			Name: filepath.Base(filePath),
 		}
 
 		return nil
@@ -60,5 +81,30 @@ func processFilesInChannel(path string) (model.FileLookup, error) {
 		return nil, err
 	}
 
-	return fileLookup, nil
+	return attachmentLookup, nil
+}
+
+// loadFileReferences loads the file references listed in the CSV file at path, resolving the
+// path of their content within the legal hold at lhPath.
+func loadFileReferences(lhPath, path string) (model.AttachmentLookup, error) {
+	var references []*model.FileReference
+	if err := unmarshalCSVFile(path, &references); err != nil {
+		return nil, fmt.Errorf("failed to load the file references in %s: %w", path, err)
+	}
+
+	attachmentLookup := make(model.AttachmentLookup)
+	for _, reference := range references {
+		blobPath := filepath.Join(lhPath, filepath.FromSlash(reference.BlobPath))
+		if !strings.HasPrefix(blobPath, filepath.Clean(lhPath)+string(filepath.Separator)) {
+			return nil, fmt.Errorf("file %s in %s is stored outside the legal hold at %q", reference.FileID, path, reference.BlobPath)
+		}
+
+		attachmentLookup[reference.FileID] = model.Attachment{
+			Path:   blobPath,
+			Name:   reference.FileName,
+			Shared: true,
+		}
+	}
+
+	return attachmentLookup, nil
 }
@@ -0,0 +1,51 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/processor/model"
+)
+
+func TestProcessFiles(t *testing.T) {
+	writeFile := func(t *testing.T, path, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+
+	references := "FileId,FileName,FileSize,MimeType,Digest,BlobPath\n" +
+		"file1,report.pdf,11,application/pdf,abcdef,blobs/ab/abcdef\n" +
+		"file2,copy of report.pdf,11,application/pdf,abcdef,blobs/ab/abcdef\n"
+
+	t.Run("loads referenced and legacy attachments", func(t *testing.T) {
+		lhPath := t.TempDir()
+		writeFile(t, filepath.Join(lhPath, "blobs", "ab", "abcdef"), "pdf content")
+		writeFile(t, filepath.Join(lhPath, "channel1", "files", "files-1100-post1.csv"), references)
+		writeFile(t, filepath.Join(lhPath, "channel2", "files", "files-900-post0", "file3", "notes.txt"), "notes")
+		writeFile(t, filepath.Join(lhPath, "channel2", "messages", "messages-900-post0.csv"), "")
+
+		attachments, err := ProcessFiles(model.LegalHold{Path: lhPath})
+		require.NoError(t, err)
+
+		blobPath := filepath.Join(lhPath, "blobs", "ab", "abcdef")
+		assert.Equal(t, model.AttachmentLookup{
+			"file1": {Path: blobPath, Name: "report.pdf", Shared: true},
+			"file2": {Path: blobPath, Name: "copy of report.pdf", Shared: true},
+			"file3": {Path: filepath.Join(lhPath, "channel2", "files", "files-900-post0", "file3", "notes.txt"), Name: "notes.txt"},
+		}, attachments)
+	})
+
+	t.Run("rejects blobs outside the legal hold", func(t *testing.T) {
+		lhPath := t.TempDir()
+		writeFile(t, filepath.Join(lhPath, "channel1", "files", "files-1100-post1.csv"),
+			"FileId,FileName,FileSize,MimeType,Digest,BlobPath\nfile1,passwd,1,text/plain,abcdef,../../etc/passwd\n")
+
+		_, err := ProcessFiles(model.LegalHold{Path: lhPath})
+		require.ErrorContains(t, err, "outside the legal hold")
+	})
+}
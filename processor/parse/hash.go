@@ -3,41 +3,61 @@ package parse
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/mattermost/mattermost-plugin-legal-hold/processor/model"
 )
 
+// ParseHashes verifies the legal hold at lhPath against its hashes.json file, returning an error
+// for the first file that is not as expected.
 func ParseHashes(tempPath, lhPath, secret string) error {
-	var hashes map[string]string
+	check, err := VerifyHashes(tempPath, lhPath, secret)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range check.Files {
+		switch file.Status {
+		case model.FileStatusMismatched:
+			return fmt.Errorf("hash mismatch for file: %s", file.Path)
+		case model.FileStatusMissing:
+			return fmt.Errorf("missing file: %s", file.Path)
+		case model.FileStatusUnlisted:
+			return fmt.Errorf("file not listed in hashes.json: %s", file.Path)
+		}
+	}
+
+	return nil
+}
+
+// VerifyHashes checks every file listed in the hashes.json file of the legal hold at lhPath,
+// relative to tempPath, against its HMAC hash, and reports the files of the legal hold that are
+// not listed. It returns an error if the hashes.json file cannot be read.
+func VerifyHashes(tempPath, lhPath, secret string) (model.VerificationCheck, error) {
+	var hashes model.HashList
 
 	fileHandle, err := os.Open(filepath.Join(lhPath, model.HashesPath))
 	if err != nil {
-		return fmt.Errorf("error opening hashes.json file: %w", err)
+		return model.VerificationCheck{}, fmt.Errorf("error opening hashes.json file: %w", err)
 	}
+	defer fileHandle.Close()
 
 	decoder := json.NewDecoder(fileHandle)
 	err = decoder.Decode(&hashes)
 	if err != nil {
-		return fmt.Errorf("error decoding hashes.json file: %w", err)
+		return model.VerificationCheck{}, fmt.Errorf("error decoding hashes.json file: %w", err)
 	}
 
-	for path, hash := range hashes {
-		hashReader, err := os.Open(filepath.Join(tempPath, path))
-		if err != nil {
-			return fmt.Errorf("error opening file: %w", err)
-		}
-
-		fileHash, err := model.HashReader(secret, hashReader)
-		if err != nil {
-			return fmt.Errorf("error reading hash: %w", err)
-		}
+	hash := func(reader io.Reader) (string, error) {
+		return model.HashReader(secret, reader)
+	}
 
-		if fileHash != hash {
-			return fmt.Errorf("hash mismatch for file: %s", path)
-		}
+	results, err := verifyFiles(tempPath, lhPath, hashes, hash, true)
+	if err != nil {
+		return model.VerificationCheck{}, err
 	}
 
-	return nil
+	return model.NewVerificationCheck(model.VerificationCheckHashes, results), nil
 }
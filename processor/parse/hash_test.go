@@ -1,11 +1,15 @@
 package parse
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/processor/model"
 )
 
 func TestParseHashes(t *testing.T) {
@@ -70,3 +74,63 @@ func TestParseHashes(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyHashes(t *testing.T) {
+	tempDir := t.TempDir()
+	legalHoldPath := filepath.Join(tempDir, "legal_hold", "name_id")
+	require.NoError(t, os.MkdirAll(filepath.Join(legalHoldPath, "messages"), 0755))
+
+	hash := func(content string) string {
+		h, err := model.HashReader("1234", strings.NewReader(content))
+		require.NoError(t, err)
+		return h
+	}
+
+	files := map[string]string{
+		"legal_hold/name_id/index.json":           "index",
+		"legal_hold/name_id/messages/a.csv":       "tampered",
+		"legal_hold/name_id/messages/planted.csv": "planted",
+	}
+	for path, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, path), []byte(content), 0644))
+	}
+
+	hashes, err := json.Marshal(model.HashList{
+		"legal_hold/name_id/index.json":     hash("index"),
+		"legal_hold/name_id/messages/a.csv": hash("original"),
+		"legal_hold/name_id/messages/b.csv": hash("deleted"),
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(legalHoldPath, model.HashesPath), hashes, 0644))
+
+	check, err := VerifyHashes(tempDir, legalHoldPath, "1234")
+	require.NoError(t, err)
+	require.False(t, check.OK())
+
+	// Every problem is reported, in path order.
+	require.Equal(t, []model.FileResult{
+		{Path: "legal_hold/name_id/index.json", Status: model.FileStatusOK, Expected: hash("index")},
+		{Path: "legal_hold/name_id/messages/a.csv", Status: model.FileStatusMismatched, Expected: hash("original"), Actual: hash("tampered")},
+		{Path: "legal_hold/name_id/messages/b.csv", Status: model.FileStatusMissing, Expected: hash("deleted")},
+		{Path: "legal_hold/name_id/messages/planted.csv", Status: model.FileStatusUnlisted},
+	}, check.Files)
+	require.Equal(t, map[model.FileStatus]int{
+		model.FileStatusOK:         1,
+		model.FileStatusMismatched: 1,
+		model.FileStatusMissing:    1,
+		model.FileStatusUnlisted:   1,
+	}, check.Summary)
+
+	report := model.NewVerificationReport([]model.LegalHoldVerification{{
+		LegalHoldID:   "id",
+		LegalHoldName: "name",
+		Checks:        []model.VerificationCheck{check},
+	}})
+	require.False(t, report.Verified)
+
+	var text strings.Builder
+	require.NoError(t, report.WriteText(&text))
+	require.Contains(t, text.String(), "hashes: FAILED (1 ok, 1 mismatched, 1 missing, 1 unlisted)")
+	require.Contains(t, text.String(), "unlisted   legal_hold/name_id/messages/planted.csv")
+	require.NotContains(t, text.String(), "ok         legal_hold/name_id/index.json")
+}
@@ -0,0 +1,91 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/processor/model"
+)
+
+// ErrNoLedger is returned by VerifyLedger for legal holds exported before ledgers were
+// introduced.
+var ErrNoLedger = errors.New("the legal hold has no ledger")
+
+// LedgerBreakError reports the first broken link in the chain of a ledger.
+type LedgerBreakError struct {
+	// Line is the line of the ledger where the chain breaks, starting at 1.
+	Line   int
+	Reason string
+}
+
+func (e *LedgerBreakError) Error() string {
+	return fmt.Sprintf("ledger chain broken at entry %d: %s", e.Line, e.Reason)
+}
+
+// VerifyLedger walks the chain of the ledger of the legal hold at lhPath, returning a
+// *LedgerBreakError for the first entry that does not follow from the one before it. It then
+// checks every file listed in the ledger, relative to tempPath, against the digest recorded by
+// the latest entry that lists it. Files exported before the ledger was introduced are not listed
+// in it, so they are not reported as unlisted.
+func VerifyLedger(tempPath, lhPath string) (model.VerificationCheck, error) {
+	data, err := os.ReadFile(filepath.Join(lhPath, model.LedgerPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return model.VerificationCheck{}, ErrNoLedger
+	} else if err != nil {
+		return model.VerificationCheck{}, fmt.Errorf("error reading ledger.jsonl file: %w", err)
+	}
+
+	latest := make(model.HashList)
+	var head string
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	for i, line := range lines {
+		number := i + 1
+
+		var entry model.LedgerEntry
+		if err = json.Unmarshal(line, &entry); err != nil {
+			return model.VerificationCheck{}, &LedgerBreakError{Line: number, Reason: fmt.Sprintf("the entry cannot be decoded: %v", err)}
+		}
+
+		if entry.Sequence != int64(number) {
+			return model.VerificationCheck{}, &LedgerBreakError{Line: number, Reason: fmt.Sprintf("expected sequence %d but found %d", number, entry.Sequence)}
+		}
+
+		if entry.PreviousDigest != head {
+			return model.VerificationCheck{}, &LedgerBreakError{Line: number, Reason: "the previous digest does not match the entry before it"}
+		}
+
+		for path, digest := range entry.Files {
+			latest[path] = digest
+		}
+		head = model.LedgerDigest(line)
+	}
+
+	expected, err := restrictToSubset(lhPath, latest)
+	if err != nil {
+		return model.VerificationCheck{}, err
+	}
+
+	results, err := verifyFiles(tempPath, lhPath, expected, digestReader, false)
+	if err != nil {
+		return model.VerificationCheck{}, err
+	}
+
+	return model.NewVerificationCheck(model.VerificationCheckLedger, results), nil
+}
+
+// ledgerHead returns the digest of the last entry of the ledger of the legal hold at lhPath.
+func ledgerHead(lhPath string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(lhPath, model.LedgerPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNoLedger
+	} else if err != nil {
+		return "", fmt.Errorf("error reading ledger.jsonl file: %w", err)
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	return model.LedgerDigest(lines[len(lines)-1]), nil
+}
@@ -0,0 +1,133 @@
+package parse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/processor/model"
+)
+
+func TestVerifyLedger(t *testing.T) {
+	digest := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	// chain marshals the entries, filling in their sequence and previous digests.
+	chain := func(t *testing.T, entries ...model.LedgerEntry) []string {
+		var lines []string
+		previous := ""
+		for i, entry := range entries {
+			entry.Sequence = int64(i + 1)
+			entry.PreviousDigest = previous
+			line, err := json.Marshal(entry)
+			require.NoError(t, err)
+			lines = append(lines, string(line))
+			previous = model.LedgerDigest(line)
+		}
+		return lines
+	}
+
+	entries := []model.LedgerEntry{
+		{ExecutionStartTime: 1000, ExecutionEndTime: 2000, Files: model.HashList{"index.json": digest("old index"), "a.csv": digest("a")}},
+		{ExecutionStartTime: 2000, ExecutionEndTime: 3000, Files: model.HashList{"index.json": digest("new index")}},
+		{ExecutionStartTime: 3000, ExecutionEndTime: 4000, Files: model.HashList{"b.csv": digest("b")}},
+	}
+
+	testCases := []struct {
+		name             string
+		lines            func(t *testing.T) []string
+		files            map[string]string
+		expectedLine     int
+		expectedMismatch string
+	}{
+		{
+			name:  "valid chain",
+			lines: func(t *testing.T) []string { return chain(t, entries...) },
+		},
+		{
+			name: "replaced entry",
+			lines: func(t *testing.T) []string {
+				lines := chain(t, entries...)
+				lines[1] = strings.Replace(lines[1], digest("new index"), digest("forged"), 1)
+				return lines
+			},
+			expectedLine: 3,
+		},
+		{
+			name: "removed entry",
+			lines: func(t *testing.T) []string {
+				lines := chain(t, entries...)
+				return []string{lines[0], lines[2]}
+			},
+			expectedLine: 2,
+		},
+		{
+			name:         "undecodable entry",
+			lines:        func(t *testing.T) []string { return append(chain(t, entries...)[:1], "{bogus") },
+			expectedLine: 2,
+		},
+		{
+			name:             "file changed since it was recorded",
+			lines:            func(t *testing.T) []string { return chain(t, entries...) },
+			files:            map[string]string{"a.csv": "changed"},
+			expectedMismatch: "a.csv",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+
+			legalHoldPath := filepath.Join(tempDir, "legalhold")
+			require.NoError(t, os.MkdirAll(legalHoldPath, 0755))
+
+			files := map[string]string{"index.json": "new index", "a.csv": "a", "b.csv": "b"}
+			for path, content := range testCase.files {
+				files[path] = content
+			}
+			for path, content := range files {
+				require.NoError(t, os.WriteFile(filepath.Join(tempDir, path), []byte(content), 0644))
+			}
+
+			lines := testCase.lines(t)
+			ledger := strings.Join(lines, "\n") + "\n"
+			require.NoError(t, os.WriteFile(filepath.Join(legalHoldPath, model.LedgerPath), []byte(ledger), 0644))
+
+			check, err := VerifyLedger(tempDir, legalHoldPath)
+			if testCase.expectedLine != 0 {
+				var breakErr *LedgerBreakError
+				require.ErrorAs(t, err, &breakErr)
+				require.Equal(t, testCase.expectedLine, breakErr.Line)
+				return
+			}
+			require.NoError(t, err)
+
+			// Only the latest digest of index.json is checked.
+			require.Len(t, check.Files, 3)
+			for _, file := range check.Files {
+				if file.Path == testCase.expectedMismatch {
+					require.Equal(t, model.FileStatusMismatched, file.Status)
+				} else {
+					require.Equal(t, model.FileStatusOK, file.Status, file.Path)
+				}
+			}
+
+			head, err := ledgerHead(legalHoldPath)
+			require.NoError(t, err)
+			require.Equal(t, model.LedgerDigest([]byte(lines[len(lines)-1])), head)
+		})
+	}
+
+	t.Run("no ledger", func(t *testing.T) {
+		_, err := VerifyLedger(t.TempDir(), t.TempDir())
+		require.ErrorIs(t, err, ErrNoLedger)
+	})
+}
@@ -0,0 +1,71 @@
+package parse
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/processor/model"
+)
+
+// VerifyManifest checks that the manifest of the legal hold at lhPath was signed with publicKey
+// and that the ledger ends with the entry the manifest was signed with. It then checks every file
+// the manifest lists, relative to tempPath, against its digest, and reports the files of the
+// legal hold that are not listed. It returns an error if the manifest cannot be trusted.
+func VerifyManifest(tempPath, lhPath string, publicKey ed25519.PublicKey) (model.VerificationCheck, error) {
+	data, err := os.ReadFile(filepath.Join(lhPath, model.ManifestPath))
+	if err != nil {
+		return model.VerificationCheck{}, fmt.Errorf("error reading manifest.json file: %w", err)
+	}
+
+	signature, err := os.ReadFile(filepath.Join(lhPath, model.ManifestSignaturePath))
+	if err != nil {
+		return model.VerificationCheck{}, fmt.Errorf("error reading manifest.json.sig file: %w", err)
+	}
+
+	if !ed25519.Verify(publicKey, data, signature) {
+		return model.VerificationCheck{}, fmt.Errorf("the manifest signature is not valid for the public key")
+	}
+
+	var manifest model.Manifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return model.VerificationCheck{}, fmt.Errorf("error decoding manifest.json file: %w", err)
+	}
+
+	keyID, err := model.KeyID(publicKey)
+	if err != nil {
+		return model.VerificationCheck{}, fmt.Errorf("error fingerprinting public key: %w", err)
+	}
+	if manifest.KeyID != keyID {
+		return model.VerificationCheck{}, fmt.Errorf("the manifest was signed with key %s, not %s", manifest.KeyID, keyID)
+	}
+
+	// The manifest signs the head of the ledger, which in turn chains every entry before it.
+	if manifest.LedgerHead != "" {
+		head, err := ledgerHead(lhPath)
+		if err != nil {
+			return model.VerificationCheck{}, err
+		}
+		if head != manifest.LedgerHead {
+			return model.VerificationCheck{}, fmt.Errorf("the ledger does not end with the entry the manifest was signed with")
+		}
+	}
+
+	if manifest.Algorithm != model.ManifestDigestAlgorithm {
+		return model.VerificationCheck{}, fmt.Errorf("unsupported manifest digest algorithm: %s", manifest.Algorithm)
+	}
+
+	expected, err := restrictToSubset(lhPath, manifest.Files)
+	if err != nil {
+		return model.VerificationCheck{}, err
+	}
+
+	results, err := verifyFiles(tempPath, lhPath, expected, digestReader, true)
+	if err != nil {
+		return model.VerificationCheck{}, err
+	}
+
+	return model.NewVerificationCheck(model.VerificationCheckManifest, results), nil
+}
@@ -0,0 +1,149 @@
+package parse
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/processor/model"
+)
+
+func TestVerifyManifest(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherPublicKey, otherPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keyID, err := model.KeyID(publicKey)
+	require.NoError(t, err)
+
+	digest := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	testCases := []struct {
+		name        string
+		manifest    model.Manifest
+		signWith    ed25519.PrivateKey
+		verifyWith  ed25519.PublicKey
+		fileJSON    string
+		expectedErr string
+		extraFile   string
+		subsetFiles []string
+		expected    map[string]model.FileStatus
+	}{
+		{
+			name:       "valid manifest",
+			manifest:   model.Manifest{KeyID: keyID, Algorithm: "sha256", Files: model.HashList{"file.json": digest(`{"key": "value"}`)}},
+			signWith:   privateKey,
+			verifyWith: publicKey,
+			fileJSON:   `{"key": "value"}`,
+			expected:   map[string]model.FileStatus{"file.json": model.FileStatusOK},
+		},
+		{
+			name:        "signed with another key",
+			manifest:    model.Manifest{KeyID: keyID, Algorithm: "sha256", Files: model.HashList{"file.json": digest(`{"key": "value"}`)}},
+			signWith:    otherPrivateKey,
+			verifyWith:  publicKey,
+			fileJSON:    `{"key": "value"}`,
+			expectedErr: "signature is not valid",
+		},
+		{
+			name:        "key ID of another key",
+			manifest:    model.Manifest{KeyID: keyID, Algorithm: "sha256", Files: model.HashList{"file.json": digest(`{"key": "value"}`)}},
+			signWith:    otherPrivateKey,
+			verifyWith:  otherPublicKey,
+			fileJSON:    `{"key": "value"}`,
+			expectedErr: "was signed with key",
+		},
+		{
+			name:       "incorrect file contents",
+			manifest:   model.Manifest{KeyID: keyID, Algorithm: "sha256", Files: model.HashList{"file.json": digest(`{"key": "value"}`)}},
+			signWith:   privateKey,
+			verifyWith: publicKey,
+			fileJSON:   `{bogus}`,
+			expected:   map[string]model.FileStatus{"file.json": model.FileStatusMismatched},
+		},
+		{
+			name:        "ledger head without a ledger",
+			manifest:    model.Manifest{KeyID: keyID, Algorithm: "sha256", LedgerHead: digest("entry"), Files: model.HashList{}},
+			signWith:    privateKey,
+			verifyWith:  publicKey,
+			fileJSON:    `{"key": "value"}`,
+			expectedErr: ErrNoLedger.Error(),
+		},
+		{
+			name:       "missing and unlisted files",
+			manifest:   model.Manifest{KeyID: keyID, Algorithm: "sha256", Files: model.HashList{"missing.json": digest("")}},
+			signWith:   privateKey,
+			verifyWith: publicKey,
+			fileJSON:   `{"key": "value"}`,
+			extraFile:  "legalhold/extra.csv",
+			expected: map[string]model.FileStatus{
+				"missing.json":        model.FileStatusMissing,
+				"legalhold/extra.csv": model.FileStatusUnlisted,
+			},
+		},
+		{
+			name:        "files left out of a subset",
+			manifest:    model.Manifest{KeyID: keyID, Algorithm: "sha256", Files: model.HashList{"file.json": digest(`{"key": "value"}`), "left_out.json": digest("")}},
+			signWith:    privateKey,
+			verifyWith:  publicKey,
+			fileJSON:    `{"key": "value"}`,
+			subsetFiles: []string{"file.json"},
+			expected:    map[string]model.FileStatus{"file.json": model.FileStatusOK},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+
+			legalHoldPath := filepath.Join(tempDir, "legalhold")
+			require.NoError(t, os.MkdirAll(legalHoldPath, 0755))
+
+			data, err := json.Marshal(testCase.manifest)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(filepath.Join(legalHoldPath, model.ManifestPath), data, 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(legalHoldPath, model.ManifestSignaturePath), ed25519.Sign(testCase.signWith, data), 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(tempDir, "file.json"), []byte(testCase.fileJSON), 0644))
+
+			if testCase.extraFile != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(tempDir, testCase.extraFile), []byte("extra"), 0644))
+			}
+
+			if testCase.subsetFiles != nil {
+				data, err = json.Marshal(model.SubsetIndex{Files: testCase.subsetFiles})
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(filepath.Join(legalHoldPath, model.SubsetIndexPath), data, 0644))
+			}
+
+			check, err := VerifyManifest(tempDir, legalHoldPath, testCase.verifyWith)
+			if testCase.expectedErr != "" {
+				require.ErrorContains(t, err, testCase.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			statuses := make(map[string]model.FileStatus)
+			for _, file := range check.Files {
+				statuses[file.Path] = file.Status
+			}
+			require.Equal(t, testCase.expected, statuses)
+
+			expectedOK := true
+			for _, status := range testCase.expected {
+				expectedOK = expectedOK && status == model.FileStatusOK
+			}
+			require.Equal(t, expectedOK, check.OK())
+		})
+	}
+}
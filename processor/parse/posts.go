@@ -81,6 +81,171 @@ func LoadPosts(channel model.Channel) ([]*model.Post, error) {
 	return posts, nil
 }
 
+// LoadRevisions creates a list of all the post revisions in the provided channel made within the
+// given timestamp range, ordered by the time they were made.
+func LoadRevisions(channel model.Channel) ([]*model.PostRevision, error) {
+	var allRevisions []*model.PostRevision
+	if err := loadCSVDir(filepath.Join(channel.Path, "revisions"), &allRevisions); err != nil {
+		return nil, err
+	}
+
+	var revisions []*model.PostRevision
+	for _, revision := range allRevisions {
+		if revision.PostUpdateAt >= channel.LowerBound && revision.PostUpdateAt <= channel.UpperBound {
+			revisions = append(revisions, revision)
+		}
+	}
+
+	sort.SliceStable(revisions, func(i, j int) bool {
+		return revisions[i].PostUpdateAt < revisions[j].PostUpdateAt
+	})
+
+	return revisions, nil
+}
+
+// AddRevisionsToPosts attaches each revision to the post it is a revision of. Revisions of posts
+// that are not in the list, because they were created before the legal hold started, are added
+// to it as posts of their own.
+func AddRevisionsToPosts(posts []*model.PostWithFiles, revisions []*model.PostRevision) []*model.PostWithFiles {
+	postsByID := make(map[string]*model.PostWithFiles, len(posts))
+	for _, post := range posts {
+		postsByID[post.PostID] = post
+	}
+
+	for _, revision := range revisions {
+		post, ok := postsByID[revision.RevisionOf]
+		if !ok {
+			post = &model.PostWithFiles{
+				Post:  &revision.Post,
+				Files: []string{},
+			}
+			postsByID[revision.RevisionOf] = post
+			posts = append(posts, post)
+		}
+		post.Revisions = append(post.Revisions, revision)
+	}
+
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].PostCreateAt < posts[j].PostCreateAt
+	})
+
+	return posts
+}
+
+// LoadReactions loads the reactions to the posts in the provided channel, keyed by post ID. The
+// reactions to a post can be exported more than once, so only the latest record of each is kept.
+func LoadReactions(channel model.Channel) (map[string][]*model.Reaction, error) {
+	var reactions []*model.Reaction
+	if err := loadCSVDir(filepath.Join(channel.Path, "reactions"), &reactions); err != nil {
+		return nil, err
+	}
+
+	type reactionKey struct {
+		postID, userID, emojiName string
+	}
+	latest := make(map[reactionKey]*model.Reaction)
+	var keys []reactionKey
+	for _, reaction := range reactions {
+		key := reactionKey{reaction.PostID, reaction.UserID, reaction.EmojiName}
+		existing, ok := latest[key]
+		if !ok {
+			keys = append(keys, key)
+		}
+		if !ok || reaction.UpdateAt >= existing.UpdateAt {
+			latest[key] = reaction
+		}
+	}
+
+	byPost := make(map[string][]*model.Reaction)
+	for _, key := range keys {
+		byPost[key.postID] = append(byPost[key.postID], latest[key])
+	}
+
+	for _, postReactions := range byPost {
+		sort.SliceStable(postReactions, func(i, j int) bool {
+			return postReactions[i].CreateAt < postReactions[j].CreateAt
+		})
+	}
+
+	return byPost, nil
+}
+
+// LoadAcknowledgements loads the acknowledgements of the posts in the provided channel, keyed by
+// post ID. Only the latest record of each user's acknowledgement of a post is kept.
+func LoadAcknowledgements(channel model.Channel) (map[string][]*model.Acknowledgement, error) {
+	var acknowledgements []*model.Acknowledgement
+	if err := loadCSVDir(filepath.Join(channel.Path, "acknowledgements"), &acknowledgements); err != nil {
+		return nil, err
+	}
+
+	type acknowledgementKey struct {
+		postID, userID string
+	}
+	latest := make(map[acknowledgementKey]*model.Acknowledgement)
+	var keys []acknowledgementKey
+	for _, acknowledgement := range acknowledgements {
+		key := acknowledgementKey{acknowledgement.PostID, acknowledgement.UserID}
+		if _, ok := latest[key]; !ok {
+			keys = append(keys, key)
+		}
+		// Files are read in order, so later records supersede earlier ones.
+		latest[key] = acknowledgement
+	}
+
+	byPost := make(map[string][]*model.Acknowledgement)
+	for _, key := range keys {
+		byPost[key.postID] = append(byPost[key.postID], latest[key])
+	}
+
+	return byPost, nil
+}
+
+// AddInteractionsToPosts attaches the reactions and acknowledgements of each post to it.
+func AddInteractionsToPosts(posts []*model.PostWithFiles, reactions map[string][]*model.Reaction, acknowledgements map[string][]*model.Acknowledgement) []*model.PostWithFiles {
+	for _, post := range posts {
+		post.Reactions = reactions[post.PostID]
+		post.Acknowledgements = acknowledgements[post.PostID]
+	}
+
+	return posts
+}
+
+// loadCSVDir parses every CSV file in the directory at path, in alphabetical order, appending the
+// records to out. A missing directory is not an error.
+func loadCSVDir[T any](path string, out *[]*T) error {
+	files, err := os.ReadDir(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// ReadDir returns the entries sorted by filename.
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		var records []*T
+		if err = unmarshalCSVFile(filepath.Join(path, file.Name()), &records); err != nil {
+			return err
+		}
+		*out = append(*out, records...)
+	}
+
+	return nil
+}
+
+func unmarshalCSVFile(path string, out any) error {
+	fileHandle, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fileHandle.Close()
+
+	return gocsv.UnmarshalFile(fileHandle, out)
+}
+
 func AddFilesToPosts(posts []*model.Post, fileLookup model.FileLookup) []*model.PostWithFiles {
 	var postWithFilesList []*model.PostWithFiles
 	for _, post := range posts {
@@ -129,6 +129,147 @@ team1,test-team,Test Team,test-channel,Test Channel,O,testuser,test@example.com,
 	})
 }
 
+func TestLoadRevisions(t *testing.T) {
+	t.Run("returns nil when revisions directory does not exist", func(t *testing.T) {
+		tempDir := t.TempDir()
+
+		channel := model.NewChannel(filepath.Join(tempDir, "channel1"), "channel1")
+
+		revisions, err := LoadRevisions(channel)
+
+		require.NoError(t, err)
+		assert.Nil(t, revisions)
+	})
+
+	t.Run("loads revisions in order within time bounds", func(t *testing.T) {
+		tempDir := t.TempDir()
+
+		revisionsDir := filepath.Join(tempDir, "channel1", "revisions")
+		err := os.MkdirAll(revisionsDir, 0755)
+		require.NoError(t, err)
+
+		csvContent := `RevisionOf,RevisionType,PostEditAt,TeamName,TeamDisplayName,ChannelName,ChannelDisplayName,ChannelType,UserUsername,UserEmail,UserNickname,PostId,PostCreateAt,PostUpdateAt,PostDeleteAt,PostRootId,PostOriginalId,PostMessage,PostType,PostProps,PostHashtags,PostFileIds,IsBot
+post1,edited,6000,test-team,Test Team,test-channel,Test Channel,O,testuser,test@example.com,Test,post1,1000,6000,0,,,Edited,,{},,,false
+post1,previous,0,test-team,Test Team,test-channel,Test Channel,O,testuser,test@example.com,Test,post9,1000,5000,5000,,post1,Original,,{},,,false
+post2,deleted,0,test-team,Test Team,test-channel,Test Channel,O,testuser,test@example.com,Test,post2,1000,9000,9000,,,Gone,,{},,,false`
+
+		err = os.WriteFile(filepath.Join(revisionsDir, "revisions.csv"), []byte(csvContent), 0644)
+		require.NoError(t, err)
+
+		channel := model.NewChannelWithBounds(filepath.Join(tempDir, "channel1"), "channel1", 2000, 7000)
+
+		revisions, err := LoadRevisions(channel)
+
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, "previous", revisions[0].RevisionType)
+		assert.Equal(t, "Original", revisions[0].PostMessage)
+		assert.Equal(t, "edited", revisions[1].RevisionType)
+		assert.Equal(t, "post1", revisions[1].RevisionOf)
+	})
+}
+
+func TestAddRevisionsToPosts(t *testing.T) {
+	posts := []*model.PostWithFiles{
+		{Post: &model.Post{PostID: "post2", PostCreateAt: 2000}},
+	}
+	revisions := []*model.PostRevision{
+		{RevisionOf: "post2", RevisionType: "edited", Post: model.Post{PostID: "post2", PostCreateAt: 2000}},
+		{RevisionOf: "post1", RevisionType: "deleted", Post: model.Post{PostID: "post1", PostCreateAt: 1000}},
+	}
+
+	result := AddRevisionsToPosts(posts, revisions)
+
+	require.Len(t, result, 2)
+	// The revision of a post that is not in the list becomes a post of its own.
+	assert.Equal(t, "post1", result[0].PostID)
+	assert.Equal(t, []*model.PostRevision{revisions[1]}, result[0].Revisions)
+	assert.Equal(t, "post2", result[1].PostID)
+	assert.Equal(t, []*model.PostRevision{revisions[0]}, result[1].Revisions)
+}
+
+func TestLoadReactions(t *testing.T) {
+	tempDir := t.TempDir()
+
+	reactionsDir := filepath.Join(tempDir, "channel1", "reactions")
+	err := os.MkdirAll(reactionsDir, 0755)
+	require.NoError(t, err)
+
+	first := `PostId,UserId,UserUsername,UserEmail,EmojiName,CreateAt,UpdateAt,DeleteAt
+post1,user1,testuser,test@example.com,smile,2000,2000,0
+post1,user2,otheruser,other@example.com,thumbsup,1000,1000,0`
+	second := `PostId,UserId,UserUsername,UserEmail,EmojiName,CreateAt,UpdateAt,DeleteAt
+post1,user1,testuser,test@example.com,smile,2000,3000,3000`
+
+	err = os.WriteFile(filepath.Join(reactionsDir, "posts-1.csv"), []byte(first), 0644)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(reactionsDir, "posts-2.csv"), []byte(second), 0644)
+	require.NoError(t, err)
+
+	reactions, err := LoadReactions(model.NewChannel(filepath.Join(tempDir, "channel1"), "channel1"))
+
+	require.NoError(t, err)
+	require.Len(t, reactions["post1"], 2)
+	assert.Equal(t, "thumbsup", reactions["post1"][0].EmojiName)
+	assert.Equal(t, "smile", reactions["post1"][1].EmojiName)
+	// The removal of the reaction supersedes the earlier record.
+	assert.Equal(t, int64(3000), reactions["post1"][1].DeleteAt)
+}
+
+func TestLoadAcknowledgements(t *testing.T) {
+	t.Run("returns no acknowledgements when directory does not exist", func(t *testing.T) {
+		acknowledgements, err := LoadAcknowledgements(model.NewChannel(filepath.Join(t.TempDir(), "channel1"), "channel1"))
+
+		require.NoError(t, err)
+		assert.Empty(t, acknowledgements)
+	})
+
+	t.Run("keeps the latest acknowledgement of each user", func(t *testing.T) {
+		tempDir := t.TempDir()
+
+		acknowledgementsDir := filepath.Join(tempDir, "channel1", "acknowledgements")
+		err := os.MkdirAll(acknowledgementsDir, 0755)
+		require.NoError(t, err)
+
+		first := `PostId,UserId,UserUsername,UserEmail,AcknowledgedAt
+post1,user1,testuser,test@example.com,1000`
+		second := `PostId,UserId,UserUsername,UserEmail,AcknowledgedAt
+post1,user1,testuser,test@example.com,0`
+
+		err = os.WriteFile(filepath.Join(acknowledgementsDir, "posts-1.csv"), []byte(first), 0644)
+		require.NoError(t, err)
+		err = os.WriteFile(filepath.Join(acknowledgementsDir, "posts-2.csv"), []byte(second), 0644)
+		require.NoError(t, err)
+
+		acknowledgements, err := LoadAcknowledgements(model.NewChannel(filepath.Join(tempDir, "channel1"), "channel1"))
+
+		require.NoError(t, err)
+		require.Len(t, acknowledgements["post1"], 1)
+		assert.Equal(t, int64(0), acknowledgements["post1"][0].AcknowledgedAt)
+	})
+}
+
+func TestAddInteractionsToPosts(t *testing.T) {
+	posts := []*model.PostWithFiles{
+		{Post: &model.Post{PostID: "post1"}},
+		{Post: &model.Post{PostID: "post2"}},
+	}
+	reactions := map[string][]*model.Reaction{
+		"post1": {{PostID: "post1", EmojiName: "smile"}},
+	}
+	acknowledgements := map[string][]*model.Acknowledgement{
+		"post2": {{PostID: "post2", UserID: "user1"}},
+	}
+
+	result := AddInteractionsToPosts(posts, reactions, acknowledgements)
+
+	require.Len(t, result, 2)
+	assert.Equal(t, reactions["post1"], result[0].Reactions)
+	assert.Nil(t, result[0].Acknowledgements)
+	assert.Nil(t, result[1].Reactions)
+	assert.Equal(t, acknowledgements["post2"], result[1].Acknowledgements)
+}
+
 func TestAddFilesToPosts(t *testing.T) {
 	t.Run("returns empty slice for nil posts", func(t *testing.T) {
 		fileLookup := model.FileLookup{
@@ -0,0 +1,131 @@
+package parse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/processor/model"
+)
+
+// verificationFiles are the files of a legal hold that record how to verify it, rather than
+// being verified themselves.
+var verificationFiles = map[string]bool{
+	model.HashesPath:            true,
+	model.ManifestPath:          true,
+	model.ManifestSignaturePath: true,
+	model.LedgerPath:            true,
+	model.EncryptionInfoPath:    true,
+	model.SubsetIndexPath:       true,
+}
+
+// verifyFiles compares every file in the expected list, with paths relative to tempPath, against
+// the hash computed by hash. If reportUnlisted is true, it also reports any other file of the
+// legal hold at lhPath as unlisted. It only fails if a file cannot be read.
+func verifyFiles(tempPath, lhPath string, expected model.HashList, hash func(io.Reader) (string, error), reportUnlisted bool) ([]model.FileResult, error) {
+	results := make([]model.FileResult, 0, len(expected))
+
+	for path, expectedHash := range expected {
+		result := model.FileResult{Path: path, Expected: expectedHash}
+
+		actual, err := hashFile(filepath.Join(tempPath, filepath.FromSlash(path)), hash)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			result.Status = model.FileStatusMissing
+		case err != nil:
+			return nil, err
+		case actual != expectedHash:
+			result.Status = model.FileStatusMismatched
+			result.Actual = actual
+		default:
+			result.Status = model.FileStatusOK
+		}
+
+		results = append(results, result)
+	}
+
+	if !reportUnlisted {
+		return results, nil
+	}
+
+	err := filepath.WalkDir(lhPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || (filepath.Dir(path) == filepath.Clean(lhPath) && verificationFiles[entry.Name()]) {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(tempPath, path)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+
+		if _, ok := expected[relativePath]; !ok {
+			results = append(results, model.FileResult{Path: relativePath, Status: model.FileStatusUnlisted})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the files of the legal hold: %w", err)
+	}
+
+	return results, nil
+}
+
+// restrictToSubset leaves out of the expected list the files that are not in the download, if the
+// legal hold at lhPath was downloaded in part. The hashes.json file of such a download only lists
+// the files in it, but the manifest and the ledger describe the whole legal hold.
+func restrictToSubset(lhPath string, expected model.HashList) (model.HashList, error) {
+	data, err := os.ReadFile(filepath.Join(lhPath, model.SubsetIndexPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return expected, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading subset.json file: %w", err)
+	}
+
+	var subset model.SubsetIndex
+	if err = json.Unmarshal(data, &subset); err != nil {
+		return nil, fmt.Errorf("error decoding subset.json file: %w", err)
+	}
+
+	restricted := make(model.HashList, len(subset.Files))
+	for _, path := range subset.Files {
+		if hash, ok := expected[path]; ok {
+			restricted[path] = hash
+		}
+	}
+
+	return restricted, nil
+}
+
+func hashFile(path string, hash func(io.Reader) (string, error)) (string, error) {
+	fileHandle, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fileHandle.Close()
+
+	digest, err := hash(fileHandle)
+	if err != nil {
+		return "", fmt.Errorf("error reading file %s: %w", path, err)
+	}
+
+	return digest, nil
+}
+
+func digestReader(reader io.Reader) (string, error) {
+	digester := sha256.New()
+	if _, err := io.Copy(digester, reader); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(digester.Sum(nil)), nil
+}
@@ -2,26 +2,31 @@ package view
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/mattermost/mattermost-plugin-legal-hold/processor/model"
 )
 
-func MoveFiles(originalFileLookup model.FileLookup, outputPath string) (model.FileLookup, error) {
+func MoveFiles(attachmentLookup model.AttachmentLookup, outputPath string) (model.FileLookup, error) {
 	fileLookup := make(model.FileLookup)
 
-	for id, path := range originalFileLookup {
-		fmt.Printf("Moving file %s at %s\n", id, path)
+	for id, attachment := range attachmentLookup {
+		fmt.Printf("Moving file %s at %s\n", id, attachment.Path)
+
+		outputDirectory := filepath.Join(outputPath, "files", id)
+		name := attachmentName(id, attachment.Name)
+		destination := filepath.Join(outputDirectory, name)
+
 		// Check if input file path exists
-		if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(attachment.Path); os.IsNotExist(err) {
 			// File has already been moved.
 			continue
 		} else if err != nil {
 			return nil, err
 		}
 
-		outputDirectory := filepath.Join(outputPath, "files", id)
 		fmt.Printf("output dir: %s\n", outputDirectory)
 
 		// Create outputDirectory if it doesn't exist
@@ -34,17 +39,62 @@ func MoveFiles(originalFileLookup model.FileLookup, outputPath string) (model.Fi
 			return nil, err
 		}
 
-		// Move the file
-		destination := filepath.Join(outputDirectory, filepath.Base(path))
-		err := os.Rename(path, destination)
+		// Move the file, or link it if its content is shared with other attachments.
+		var err error
+		if attachment.Shared {
+			err = linkFile(attachment.Path, destination)
+		} else {
+			err = os.Rename(attachment.Path, destination)
+		}
 		if err != nil {
 			return nil, err
 		}
 
 		// Add it's new path to the new lookup
-		fileLookup[id] = filepath.Join("files", id, filepath.Base(path))
+		fileLookup[id] = filepath.Join("files", id, name)
 		fmt.Printf("Destination: %s\n", destination)
 	}
 
 	return fileLookup, nil
 }
+
+// attachmentName returns the name of the file for the attachment with the provided id in the
+// output, falling back to the id if its name cannot be used as a file name.
+func attachmentName(id, name string) string {
+	cleanName := filepath.Base(name)
+	if cleanName == "" || cleanName == "." || cleanName == ".." || cleanName == string(filepath.Separator) {
+		return id
+	}
+
+	return cleanName
+}
+
+// linkFile makes the file at source available at destination too, as a hard link if possible
+// and as a copy otherwise.
+func linkFile(source, destination string) error {
+	if _, err := os.Stat(destination); err == nil {
+		return nil
+	}
+
+	if err := os.Link(source, destination); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
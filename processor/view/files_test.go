@@ -0,0 +1,51 @@
+package view
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/processor/model"
+)
+
+func TestMoveFiles(t *testing.T) {
+	inputPath := t.TempDir()
+	outputPath := t.TempDir()
+
+	blobPath := filepath.Join(inputPath, "abcdef")
+	require.NoError(t, os.WriteFile(blobPath, []byte("pdf content"), 0600))
+	legacyPath := filepath.Join(inputPath, "notes.txt")
+	require.NoError(t, os.WriteFile(legacyPath, []byte("notes"), 0600))
+
+	attachments := model.AttachmentLookup{
+		"file1": {Path: blobPath, Name: "report.pdf", Shared: true},
+		"file2": {Path: blobPath, Name: "../copy of report.pdf", Shared: true},
+		"file3": {Path: blobPath, Name: "..", Shared: true},
+		"file4": {Path: legacyPath, Name: "notes.txt"},
+	}
+
+	fileLookup, err := MoveFiles(attachments, outputPath)
+	require.NoError(t, err)
+	assert.Equal(t, model.FileLookup{
+		"file1": filepath.Join("files", "file1", "report.pdf"),
+		"file2": filepath.Join("files", "file2", "copy of report.pdf"),
+		"file3": filepath.Join("files", "file3", "file3"),
+		"file4": filepath.Join("files", "file4", "notes.txt"),
+	}, fileLookup)
+
+	// Every attachment sharing the content gets a copy of it.
+	for _, path := range []string{"file1/report.pdf", "file2/copy of report.pdf", "file3/file3"} {
+		data, readErr := os.ReadFile(filepath.Join(outputPath, "files", filepath.FromSlash(path)))
+		require.NoError(t, readErr)
+		assert.Equal(t, "pdf content", string(data))
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputPath, "files", "file4", "notes.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "notes", string(data))
+	_, err = os.Stat(legacyPath)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -191,6 +191,69 @@ func TestWriteIndexFile(t *testing.T) {
 		// Verify user-channel specific link
 		assert.Contains(t, contentStr, "user123_channel456.html")
 	})
+
+	t.Run("shows the filter the data was collected under", func(t *testing.T) {
+		tempDir, err := os.MkdirTemp("", "legal-hold-test")
+		require.NoError(t, err)
+		defer os.RemoveAll(tempDir)
+
+		legalHold := model.LegalHold{
+			ID:   "lh1",
+			Name: "Test Legal Hold",
+			Path: tempDir,
+		}
+
+		legalHoldIndex := model.LegalHoldIndex{
+			LegalHold: model.LegalHoldIndexDetails{
+				ID:          "lh1",
+				Name:        "test-hold",
+				DisplayName: "Test Legal Hold",
+				Filter: &model.LegalHoldFilter{
+					Terms:    []string{"project falcon"},
+					Hashtags: []string{"#falcon"},
+				},
+			},
+			Teams: []*model.LegalHoldTeam{},
+			Users: model.LegalHoldIndexUsers{},
+		}
+
+		err = WriteIndexFile(legalHold, legalHoldIndex, model.TeamLookup{}, model.ChannelLookup{}, model.TeamForChannelLookup{}, tempDir)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(tempDir, "index.html"))
+		require.NoError(t, err)
+
+		contentStr := string(content)
+		assert.Contains(t, contentStr, "project falcon")
+		assert.Contains(t, contentStr, "#falcon")
+	})
+
+	t.Run("lists the file attachments that could not be exported", func(t *testing.T) {
+		tempDir := t.TempDir()
+
+		legalHoldIndex := model.LegalHoldIndex{
+			LegalHold: model.LegalHoldIndexDetails{
+				ID:          "lh1",
+				Name:        "test-hold",
+				DisplayName: "Test Legal Hold",
+			},
+			Teams: []*model.LegalHoldTeam{},
+			Users: model.LegalHoldIndexUsers{},
+			FileExceptions: []model.LegalHoldFileException{
+				{ChannelID: "channel1", FileID: "file1", FileName: "contract.pdf", Reason: "missing"},
+			},
+		}
+
+		err := WriteIndexFile(model.LegalHold{ID: "lh1", Path: tempDir}, legalHoldIndex, model.TeamLookup{}, model.ChannelLookup{}, model.TeamForChannelLookup{}, tempDir)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(tempDir, "index.html"))
+		require.NoError(t, err)
+
+		contentStr := string(content)
+		assert.Contains(t, contentStr, "1 file attachment(s) could not be exported")
+		assert.Contains(t, contentStr, "contract.pdf (file1) in channel channel1: missing")
+	})
 }
 
 func TestWriteChannel(t *testing.T) {
@@ -292,6 +355,85 @@ func TestWriteChannel(t *testing.T) {
 		assert.Contains(t, contentStr, "@testuser")
 		assert.False(t, strings.Contains(contentStr, "No messages were recorded"))
 	})
+
+	t.Run("shows the revisions of posts", func(t *testing.T) {
+		tempDir := t.TempDir()
+
+		channel := model.Channel{
+			ID:   "channel1",
+			Path: filepath.Join(tempDir, "channel1"),
+		}
+
+		post := &model.Post{
+			PostID:       "post1",
+			PostMessage:  "Edited message",
+			PostCreateAt: 1609459200000,
+			UserUsername: "testuser",
+		}
+		posts := []*model.PostWithFiles{
+			{
+				Post:  post,
+				Files: []string{},
+				Revisions: []*model.PostRevision{
+					{RevisionOf: "post1", RevisionType: "previous", Post: model.Post{PostMessage: "Original message", PostUpdateAt: 1609459300000}},
+					{RevisionOf: "post1", RevisionType: "deleted", Post: model.Post{PostMessage: "Edited message", PostUpdateAt: 1609459400000}},
+				},
+			},
+		}
+
+		err := WriteChannel(model.LegalHold{ID: "lh1", Path: tempDir}, channel, posts, &model.LegalHoldTeam{}, &model.LegalHoldChannel{ID: "channel1"}, tempDir)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(tempDir, "channel1.html"))
+		require.NoError(t, err)
+
+		contentStr := string(content)
+		assert.Contains(t, contentStr, "previous at")
+		assert.Contains(t, contentStr, "Original message")
+		assert.Contains(t, contentStr, "deleted at")
+	})
+
+	t.Run("shows pins, reactions and acknowledgements", func(t *testing.T) {
+		tempDir := t.TempDir()
+
+		channel := model.Channel{
+			ID:   "channel1",
+			Path: filepath.Join(tempDir, "channel1"),
+		}
+
+		posts := []*model.PostWithFiles{
+			{
+				Post: &model.Post{
+					PostID:       "post1",
+					PostMessage:  "Pinned message",
+					PostCreateAt: 1609459200000,
+					PostIsPinned: true,
+					UserUsername: "testuser",
+				},
+				Files: []string{},
+				Reactions: []*model.Reaction{
+					{PostID: "post1", UserUsername: "otheruser", EmojiName: "thumbsup", CreateAt: 1609459300000},
+					{PostID: "post1", UserUsername: "testuser", EmojiName: "smile", CreateAt: 1609459300000, DeleteAt: 1609459400000},
+				},
+				Acknowledgements: []*model.Acknowledgement{
+					{PostID: "post1", UserUsername: "otheruser", AcknowledgedAt: 1609459500000},
+				},
+			},
+		}
+
+		err := WriteChannel(model.LegalHold{ID: "lh1", Path: tempDir}, channel, posts, &model.LegalHoldTeam{}, &model.LegalHoldChannel{ID: "channel1"}, tempDir)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(tempDir, "channel1.html"))
+		require.NoError(t, err)
+
+		contentStr := string(content)
+		assert.Contains(t, contentStr, `<div class="pinned">Pinned</div>`)
+		assert.Contains(t, contentStr, ":thumbsup: by @otheruser")
+		assert.Contains(t, contentStr, ":smile: by @testuser")
+		assert.Contains(t, contentStr, "(removed at")
+		assert.Contains(t, contentStr, "Acknowledged by @otheruser")
+	})
 }
 
 func TestWriteUserAllChannels(t *testing.T) {
@@ -3,11 +3,14 @@ package main
 import (
 	"archive/zip"
 	"bufio"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"path"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,21 +20,77 @@ import (
 	"github.com/mattermost/mattermost-server/v6/shared/filestore"
 	"github.com/pkg/errors"
 
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/jobs"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/legalhold"
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
 )
 
 const requestBodyMaxSizeBytes = 1024 * 1024 // 1MB
 
+// downloadWithTokenPath is the route of the download links handed to reviewers without a
+// Mattermost account, which authenticate with a download token instead of a session.
+const downloadWithTokenPath = "/api/v1/download"
+
+// downloadTokenHeader is the header download tokens are passed in. The Authorization header cannot
+// be used, as the server removes it from the requests it hands to plugins.
+const downloadTokenHeader = "X-Legal-Hold-Token"
+
+// metricsPath is the route of the metrics of the plugin, which can be scraped with the metrics
+// token instead of a session.
+const metricsPath = "/api/v1/metrics"
+
+// metricsTokenHeader is the header the metrics token is passed in.
+const metricsTokenHeader = "X-Legal-Hold-Metrics-Token"
+
 // ServeHTTP demonstrates a plugin that handles HTTP requests by greeting the world.
 func (p *Plugin) ServeHTTP(_ *plugin.Context, w http.ResponseWriter, r *http.Request) {
-	// All HTTP endpoints of this plugin require a logged-in user.
+	if r.URL.Path == downloadWithTokenPath {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		p.audited(model.AuditActionDownloadWithToken, p.downloadWithToken)(w, r)
+		return
+	}
+
+	// Prometheus scrapes the metrics with the metrics token, if one is configured, as it has no
+	// session. Requests without it fall through to the checks below.
+	if r.URL.Path == metricsPath && r.Header.Get(metricsTokenHeader) != "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !p.isMetricsToken(r.Header.Get(metricsTokenHeader)) {
+			http.Error(w, "Not authorized", http.StatusUnauthorized)
+			return
+		}
+
+		p.getMetrics(w, r)
+		return
+	}
+
+	// All other HTTP endpoints of this plugin require a logged-in user.
 	userID := r.Header.Get("Mattermost-User-ID")
 	if userID == "" {
 		http.Error(w, "Not authorized", http.StatusUnauthorized)
 		return
 	}
 
-	// All HTTP endpoints of this plugin require the user to be a System Admin
+	// Custodians acknowledge the legal hold notice from its direct message, so they need not be
+	// System Admins. They can only acknowledge the notices sent to them.
+	if acknowledgeNoticePath.MatchString(r.URL.Path) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		p.acknowledgeNotice(w, r)
+		return
+	}
+
+	// All other HTTP endpoints of this plugin require the user to be a System Admin
 	if !p.Client.User.HasPermissionTo(userID, mattermostModel.PermissionManageSystem) {
 		http.Error(w, "Not authorized", http.StatusUnauthorized)
 		return
@@ -43,16 +102,35 @@ func (p *Plugin) ServeHTTP(_ *plugin.Context, w http.ResponseWriter, r *http.Req
 
 	// Routes called by the plugin's webapp
 	router.HandleFunc("/api/v1/legalholds", p.listLegalHolds).Methods(http.MethodGet)
-	router.HandleFunc("/api/v1/legalholds", p.createLegalHold).Methods(http.MethodPost)
-	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/release", p.releaseLegalHold).Methods(http.MethodPost)
-	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}", p.updateLegalHold).Methods(http.MethodPut)
-	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/download", p.downloadLegalHold).Methods(http.MethodGet)
-	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/run", p.runSingleLegalHold).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/legalholds", p.audited(model.AuditActionCreateLegalHold, p.createLegalHold)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/release", p.audited(model.AuditActionReleaseLegalHold, p.releaseLegalHold)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/restore", p.audited(model.AuditActionRestoreLegalHold, p.restoreLegalHold)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}", p.audited(model.AuditActionUpdateLegalHold, p.updateLegalHold)).Methods(http.MethodPut)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/download", p.audited(model.AuditActionDownloadLegalHold, p.downloadLegalHold)).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/run", p.audited(model.AuditActionRunLegalHold, p.runSingleLegalHold)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/cancel", p.audited(model.AuditActionCancelLegalHold, p.cancelLegalHold)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/executions", p.listLegalHoldExecutions).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/custodians", p.listCustodians).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/packages", p.listExportPackages).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/packages", p.audited(model.AuditActionCreateExportPackage, p.createExportPackage)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/packages/{package_id:[A-Za-z0-9]+}", p.getExportPackage).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/packages/{package_id:[A-Za-z0-9]+}", p.audited(model.AuditActionDeleteExportPackage, p.deleteExportPackage)).Methods(http.MethodDelete)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/packages/{package_id:[A-Za-z0-9]+}/download", p.audited(model.AuditActionDownloadExportPackage, p.downloadExportPackage)).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/download_tokens", p.listDownloadTokens).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/download_tokens", p.audited(model.AuditActionCreateDownloadToken, p.createDownloadToken)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/legalholds/{legalhold_id:[A-Za-z0-9]+}/download_tokens/{token_id:[A-Za-z0-9]+}/revoke", p.audited(model.AuditActionRevokeDownloadToken, p.revokeDownloadToken)).Methods(http.MethodPost)
 	router.HandleFunc("/api/v1/test_amazon_s3_connection", p.testAmazonS3Connection).Methods(http.MethodPost)
 	router.HandleFunc("/api/v1/groups/search", p.searchLDAPGroups).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/preservation", p.getPreservationStatus).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/preservation/check", p.checkPreservation).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/health", p.getHealth).Methods(http.MethodGet)
+	router.HandleFunc(metricsPath, p.getMetrics).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/signing_key", p.getSigningKey).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/audit", p.listAuditEvents).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/tombstones", p.listLegalHoldTombstones).Methods(http.MethodGet)
 
 	// Other routes
-	router.HandleFunc("/api/v1/legalhold/run", p.runJobFromAPI).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/legalhold/run", p.audited(model.AuditActionRunAllLegalHolds, p.runJobFromAPI)).Methods(http.MethodPost)
 
 	p.router = router
 	p.router.ServeHTTP(w, r)
@@ -70,12 +148,10 @@ func (p *Plugin) listLegalHolds(w http.ResponseWriter, _ *http.Request) {
 	runningHolds, err := p.legalHoldJob.GetRunningLegalHolds()
 	if err != nil {
 		p.Client.Log.Error("failed to get running legal holds", err.Error())
-	} else {
-		for i, lh := range legalHolds {
-			if slices.Contains(runningHolds, lh.ID) {
-				legalHolds[i].Status = model.LegalHoldStatusExecuting
-			}
-		}
+	}
+
+	for i, lh := range legalHolds {
+		legalHolds[i].Status = lh.CurrentStatus(slices.Contains(runningHolds, lh.ID))
 	}
 
 	b, jsonErr := json.Marshal(legalHolds)
@@ -102,6 +178,8 @@ func (p *Plugin) createLegalHold(w http.ResponseWriter, r *http.Request) {
 	}
 
 	legalHold := model.NewLegalHoldFromCreate(createLegalHold)
+	auditEvent := auditEventFromRequest(r)
+	auditEvent.LegalHoldID = legalHold.ID
 
 	config := p.API.GetConfig()
 	if config == nil {
@@ -120,6 +198,8 @@ func (p *Plugin) createLegalHold(w http.ResponseWriter, r *http.Request) {
 		p.Client.Log.Error(err.Error())
 		return
 	}
+	auditEvent.SetAfter(*savedLegalHold)
+	p.notifyCustodians(*savedLegalHold)
 
 	b, jsonErr := json.Marshal(savedLegalHold)
 	if jsonErr != nil {
@@ -135,7 +215,9 @@ func (p *Plugin) createLegalHold(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// releaseLegalHold releases a LegalHold and removes all data associated with it
+// releaseLegalHold releases a LegalHold, stopping its executions at once. Its data is kept until
+// the release grace period has passed, when the legal hold job purges it, and until then the
+// LegalHold can be restored.
 func (p *Plugin) releaseLegalHold(w http.ResponseWriter, r *http.Request) {
 	legalholdID, err := RequireLegalHoldID(r)
 	if err != nil {
@@ -151,29 +233,101 @@ func (p *Plugin) releaseLegalHold(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if the legal hold is currently executing
-	if lh.Status == model.LegalHoldStatusExecuting {
-		http.Error(w, "cannot release legal hold while it is executing", http.StatusConflict)
+	if lh.ID == "" {
+		http.Error(w, "legal hold not found", http.StatusNotFound)
+		return
+	}
+
+	auditEvent := auditEventFromRequest(r)
+	auditEvent.SetBefore(*lh)
+
+	if lh.IsReleased() {
+		http.Error(w, "legal hold is already released", http.StatusConflict)
 		return
 	}
 
-	// Remove the LegalHold files.
-	err = p.FileBackend.RemoveDirectory(lh.BasePath())
+	releasedLegalHold := lh.DeepCopy()
+	releasedLegalHold.ReleasedAt = mattermostModel.GetMillis()
+	releasedLegalHold.ReleasedBy = r.Header.Get("Mattermost-User-ID")
+
+	savedLegalHold, err := p.KVStore.UpdateLegalHold(releasedLegalHold, *lh)
 	if err != nil {
-		p.API.LogError("Failed to release legal hold - failed to delete base directory", err.Error())
+		p.API.LogError("Failed to release legal hold - updating legal hold in kvstore", err.Error())
 		http.Error(w, "failed to release legal hold", http.StatusInternalServerError)
 		return
 	}
+	auditEvent.SetAfter(*savedLegalHold)
+
+	// Stop the execution of the LegalHold, if it is running. Executions stop on their own once
+	// they see that it is released, so this only makes it happen sooner.
+	if err = p.legalHoldJob.CancelLegalHold(legalholdID); err != nil {
+		p.API.LogWarn("Failed to cancel the execution of the released legal hold", "legal_hold_id", legalholdID, "error", err.Error())
+	}
+
+	b, jsonErr := json.Marshal(savedLegalHold)
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
 
-	// Delete the LegalHold from the store.
-	err = p.KVStore.DeleteLegalHold(legalholdID)
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
 	if err != nil {
-		p.API.LogError("Failed to release legal hold - deleting legal hold from kvstore", err.Error())
-		http.Error(w, "failed to release legal hold", http.StatusInternalServerError)
+		p.API.LogError("failed to write http response", err.Error())
+		return
+	}
+}
+
+// restoreLegalHold restores a released LegalHold whose data has not been purged yet, so that it
+// is executed again.
+func (p *Plugin) restoreLegalHold(w http.ResponseWriter, r *http.Request) {
+	legalholdID, err := RequireLegalHoldID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lh, err := p.KVStore.GetLegalHoldByID(legalholdID)
+	if err != nil {
+		http.Error(w, "failed to restore legal hold", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	if lh.ID == "" {
+		http.Error(w, "legal hold not found", http.StatusNotFound)
+		return
+	}
+
+	auditEvent := auditEventFromRequest(r)
+	auditEvent.SetBefore(*lh)
+
+	if !lh.IsReleased() {
+		http.Error(w, "legal hold is not released", http.StatusConflict)
+		return
+	}
+
+	// Once the grace period has passed, the legal hold job may be purging the data already.
+	gracePeriod := p.getConfiguration().ReleaseGracePeriod().Milliseconds()
+	if lh.IsDueForPurge(mattermostModel.GetMillis(), gracePeriod) {
+		http.Error(w, "the grace period of the released legal hold has passed", http.StatusConflict)
+		return
+	}
+
+	restoredLegalHold := lh.DeepCopy()
+	restoredLegalHold.ReleasedAt = 0
+	restoredLegalHold.ReleasedBy = ""
+
+	savedLegalHold, err := p.KVStore.UpdateLegalHold(restoredLegalHold, *lh)
+	if err != nil {
+		http.Error(w, "failed to restore legal hold", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
 		return
 	}
+	auditEvent.SetAfter(*savedLegalHold)
+	p.notifyCustodians(*savedLegalHold)
 
-	b, jsonErr := json.Marshal(make(map[string]any))
+	b, jsonErr := json.Marshal(savedLegalHold)
 	if jsonErr != nil {
 		http.Error(w, "Error encoding json", http.StatusInternalServerError)
 		return
@@ -183,8 +337,29 @@ func (p *Plugin) releaseLegalHold(w http.ResponseWriter, r *http.Request) {
 	_, err = w.Write(b)
 	if err != nil {
 		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
+// listLegalHoldTombstones serves the tombstones of the released legal holds that have been purged.
+func (p *Plugin) listLegalHoldTombstones(w http.ResponseWriter, _ *http.Request) {
+	tombstones, err := p.KVStore.GetLegalHoldTombstones()
+	if err != nil {
+		http.Error(w, "an error occurred fetching the legal hold tombstones", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	b, jsonErr := json.Marshal(tombstones)
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
 		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
 }
 
 // updateLegalHold updates the properties of a LegalHold
@@ -230,6 +405,14 @@ func (p *Plugin) updateLegalHold(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	auditEvent := auditEventFromRequest(r)
+	auditEvent.SetBefore(*originalLegalHold)
+
+	if originalLegalHold.IsReleased() {
+		http.Error(w, "cannot update a released legal hold", http.StatusConflict)
+		return
+	}
+
 	newLegalHold := originalLegalHold.DeepCopy()
 	newLegalHold.ApplyUpdates(updateLegalHold)
 
@@ -239,6 +422,8 @@ func (p *Plugin) updateLegalHold(w http.ResponseWriter, r *http.Request) {
 		p.Client.Log.Error(err.Error())
 		return
 	}
+	auditEvent.SetAfter(*savedLegalHold)
+	p.notifyCustodians(*savedLegalHold)
 
 	b, jsonErr := json.Marshal(savedLegalHold)
 	if jsonErr != nil {
@@ -270,8 +455,39 @@ func (p *Plugin) downloadLegalHold(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the list of files to include in the download.
-	files, err := p.FileBackend.ListDirectoryRecursively(legalHold.BasePath())
+	p.serveLegalHoldDownload(w, r, *legalHold)
+}
+
+// downloadQuery returns the query parameters of a download request, without the download token
+// if it was passed as one, so that they can be logged.
+func downloadQuery(r *http.Request) string {
+	query := r.URL.Query()
+	query.Del("token")
+	return query.Encode()
+}
+
+// serveLegalHoldDownload streams the files of the LegalHold as a zip archive, restricted to the
+// subset selected by the query parameters of the request, if any.
+func (p *Plugin) serveLegalHoldDownload(w http.ResponseWriter, r *http.Request, legalHold model.LegalHold) {
+	filter, err := model.NewDownloadFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if query := downloadQuery(r); query != "" {
+		auditEventFromRequest(r).Details["query"] = query
+	}
+
+	// Get the list of files to include in the download. A filtered download also gets the hashes
+	// of the files in it and a sub-index describing them, in place of the hashes.json file.
+	var files []string
+	var generatedFiles []generatedFile
+	if filter.IsEmpty() {
+		files, err = legalhold.ListLegalHoldFiles(p.FileBackend, legalHold)
+	} else {
+		files, generatedFiles, err = p.selectDownloadSubset(legalHold, filter)
+	}
 	if err != nil {
 		http.Error(w, "failed to download legal hold", http.StatusInternalServerError)
 		p.Client.Log.Error(err.Error())
@@ -326,6 +542,25 @@ func (p *Plugin) downloadLegalHold(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	for _, generated := range generatedFiles {
+		entryWriter, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     generated.path,
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			http.Error(w, "failed to download legal hold", http.StatusInternalServerError)
+			p.Client.Log.Error(err.Error())
+			return
+		}
+
+		if _, err = entryWriter.Write(generated.data); err != nil {
+			http.Error(w, "failed to download legal hold", http.StatusInternalServerError)
+			p.Client.Log.Error(err.Error())
+			return
+		}
+	}
+
 	if err := zipWriter.Close(); err != nil {
 		http.Error(w, "failed to download legal hold", http.StatusInternalServerError)
 		p.Client.Log.Error(err.Error())
@@ -333,6 +568,36 @@ func (p *Plugin) downloadLegalHold(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// generatedFile is a file added to a download that is not stored in the file backend.
+type generatedFile struct {
+	path string
+	data []byte
+}
+
+// selectDownloadSubset selects the files of the LegalHold that match the filter, returning them
+// together with the hashes.json file and the sub-index of the subset.
+func (p *Plugin) selectDownloadSubset(lh model.LegalHold, filter model.DownloadFilter) ([]string, []generatedFile, error) {
+	subset, hashes, err := legalhold.SelectSubset(p.FileBackend, lh, filter, mattermostModel.GetMillis())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashesData, err := json.MarshalIndent(hashes, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subsetData, err := json.MarshalIndent(subset, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return subset.Files, []generatedFile{
+		{path: path.Join(lh.BasePath(), "hashes.json"), data: hashesData},
+		{path: path.Join(lh.BasePath(), model.SubsetIndexFileName), data: subsetData},
+	}, nil
+}
+
 func (p *Plugin) runJobFromAPI(w http.ResponseWriter, _ *http.Request) {
 	_, err := w.Write([]byte("Processing all Legal Holds. Please check the MM server logs for more details."))
 	if err != nil {
@@ -374,6 +639,679 @@ func (p *Plugin) runSingleLegalHold(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// cancelLegalHold stops the running execution of a LegalHold. The execution stops after the batch
+// it is working on and resumes from there the next time the LegalHold runs.
+func (p *Plugin) cancelLegalHold(w http.ResponseWriter, r *http.Request) {
+	legalholdID, err := RequireLegalHoldID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	legalHold, err := p.KVStore.GetLegalHoldByID(legalholdID)
+	if err != nil {
+		http.Error(w, "an error occurred fetching the legal hold", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	if legalHold.ID == "" {
+		http.Error(w, "legal hold not found", http.StatusNotFound)
+		return
+	}
+
+	err = p.legalHoldJob.CancelLegalHold(legalholdID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to cancel legal hold: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: fmt.Sprintf("Cancelling Legal Hold %s. Please check the MM server logs for more details.", legalholdID),
+	}
+
+	b, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
+// listLegalHoldExecutions serves the execution history of a LegalHold, oldest first.
+func (p *Plugin) listLegalHoldExecutions(w http.ResponseWriter, r *http.Request) {
+	legalholdID, err := RequireLegalHoldID(r)
+	if err != nil {
+		http.Error(w, "failed to parse LegalHold ID", http.StatusBadRequest)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	legalHold, err := p.KVStore.GetLegalHoldByID(legalholdID)
+	if err != nil {
+		http.Error(w, "an error occurred fetching the legal hold", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	if legalHold.ID == "" {
+		http.Error(w, "legal hold not found", http.StatusNotFound)
+		return
+	}
+
+	records, err := p.KVStore.GetExecutionRecords(legalholdID)
+	if err != nil {
+		http.Error(w, "an error occurred fetching the legal hold executions", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	b, jsonErr := json.Marshal(records)
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
+// createExportPackage starts building an export package of a LegalHold in the background, and
+// serves it while it is pending.
+func (p *Plugin) createExportPackage(w http.ResponseWriter, r *http.Request) {
+	legalholdID, err := RequireLegalHoldID(r)
+	if err != nil {
+		http.Error(w, "failed to parse LegalHold ID", http.StatusBadRequest)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	legalHold, err := p.KVStore.GetLegalHoldByID(legalholdID)
+	if err != nil {
+		http.Error(w, "an error occurred fetching the legal hold", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	if legalHold.ID == "" {
+		http.Error(w, "legal hold not found", http.StatusNotFound)
+		return
+	}
+
+	if legalHold.IsReleased() {
+		http.Error(w, "cannot package a released legal hold", http.StatusConflict)
+		return
+	}
+
+	pkg, err := p.exportPackageJob.StartExportPackage(*legalHold)
+	if err != nil {
+		http.Error(w, "failed to start the export package", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+	auditEventFromRequest(r).Details["package_id"] = pkg.ID
+
+	b, jsonErr := json.Marshal(pkg)
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
+// listExportPackages serves the export packages of a LegalHold, with the progress of those still
+// being built.
+func (p *Plugin) listExportPackages(w http.ResponseWriter, r *http.Request) {
+	legalholdID, err := RequireLegalHoldID(r)
+	if err != nil {
+		http.Error(w, "failed to parse LegalHold ID", http.StatusBadRequest)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	packages, err := p.KVStore.GetExportPackages(legalholdID)
+	if err != nil {
+		http.Error(w, "an error occurred fetching the export packages", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	now := mattermostModel.GetMillis()
+	for i := range packages {
+		packages[i].FailIfStale(now, jobs.ExportPackageStaleTimeout.Milliseconds())
+	}
+
+	b, jsonErr := json.Marshal(packages)
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
+// getExportPackage serves an export package, with its progress if it is still being built.
+func (p *Plugin) getExportPackage(w http.ResponseWriter, r *http.Request) {
+	pkg := p.requireExportPackage(w, r)
+	if pkg == nil {
+		return
+	}
+
+	b, jsonErr := json.Marshal(pkg)
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
+// downloadExportPackage serves the archive of a completed export package. Range requests are
+// supported, so that interrupted downloads can be resumed.
+func (p *Plugin) downloadExportPackage(w http.ResponseWriter, r *http.Request) {
+	pkg := p.requireExportPackage(w, r)
+	if pkg == nil {
+		return
+	}
+
+	if pkg.Status != model.ExportPackageStatusCompleted {
+		http.Error(w, "export package is not completed", http.StatusConflict)
+		return
+	}
+
+	reader, err := legalhold.UnwrapFileBackend(p.FileBackend).Reader(pkg.ArchivePath())
+	if err != nil {
+		http.Error(w, "failed to download export package", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+	defer reader.Close()
+
+	// The digest identifies the archive, so that a resumed download fails over to the whole
+	// archive if it has changed.
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", pkg.ArchiveFileName()))
+	w.Header().Set("ETag", fmt.Sprintf("\"%s\"", pkg.Digest))
+	http.ServeContent(w, r, pkg.ArchiveFileName(), time.UnixMilli(pkg.CompletedAt), reader)
+}
+
+// deleteExportPackage removes an export package and its archive.
+func (p *Plugin) deleteExportPackage(w http.ResponseWriter, r *http.Request) {
+	pkg := p.requireExportPackage(w, r)
+	if pkg == nil {
+		return
+	}
+
+	if !pkg.IsFinished() {
+		http.Error(w, "cannot delete an export package while it is being built", http.StatusConflict)
+		return
+	}
+
+	if err := legalhold.RemoveExportPackage(p.KVStore, p.FileBackend, *pkg); err != nil {
+		http.Error(w, "failed to delete export package", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireExportPackage gets the export package indicated by the request, marking it as failed if
+// its build was interrupted. It writes the error response and returns nil if there is none.
+func (p *Plugin) requireExportPackage(w http.ResponseWriter, r *http.Request) *model.ExportPackage {
+	legalholdID, err := RequireLegalHoldID(r)
+	if err != nil {
+		http.Error(w, "failed to parse LegalHold ID", http.StatusBadRequest)
+		p.Client.Log.Error(err.Error())
+		return nil
+	}
+
+	packageID := mux.Vars(r)["package_id"]
+	if !mattermostModel.IsValidId(packageID) {
+		http.Error(w, "failed to parse export package ID", http.StatusBadRequest)
+		return nil
+	}
+
+	pkg, err := p.KVStore.GetExportPackage(legalholdID, packageID)
+	if err != nil {
+		http.Error(w, "an error occurred fetching the export package", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return nil
+	}
+
+	if pkg == nil {
+		http.Error(w, "export package not found", http.StatusNotFound)
+		return nil
+	}
+
+	pkg.FailIfStale(mattermostModel.GetMillis(), jobs.ExportPackageStaleTimeout.Milliseconds())
+
+	return pkg
+}
+
+// createDownloadToken creates a DownloadToken for a LegalHold, serving it together with its token.
+func (p *Plugin) createDownloadToken(w http.ResponseWriter, r *http.Request) {
+	legalholdID, err := RequireLegalHoldID(r)
+	if err != nil {
+		http.Error(w, "failed to parse LegalHold ID", http.StatusBadRequest)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	var createDownloadToken model.CreateDownloadToken
+	if err = json.NewDecoder(http.MaxBytesReader(w, r.Body, requestBodyMaxSizeBytes)).Decode(&createDownloadToken); err != nil {
+		http.Error(w, "failed to parse request body", http.StatusBadRequest)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	legalHold, err := p.KVStore.GetLegalHoldByID(legalholdID)
+	if err != nil {
+		http.Error(w, "an error occurred fetching the legal hold", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	if legalHold.ID == "" {
+		http.Error(w, "legal hold not found", http.StatusNotFound)
+		return
+	}
+
+	if legalHold.IsReleased() {
+		http.Error(w, "cannot share a released legal hold", http.StatusConflict)
+		return
+	}
+
+	now := mattermostModel.GetMillis()
+	userID := r.Header.Get("Mattermost-User-ID")
+	downloadToken := model.NewDownloadTokenFromCreate(createDownloadToken, mattermostModel.NewId(), legalHold.ID, userID, now)
+	if err = downloadToken.IsValid(now); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	signingKey, err := p.KVStore.GetOrCreateSigningKey()
+	if err != nil {
+		http.Error(w, "failed to create download token", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	token, err := model.EncodeDownloadToken(downloadToken.Claims(), signingKey)
+	if err != nil {
+		http.Error(w, "failed to create download token", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	if err = p.KVStore.SaveDownloadToken(downloadToken); err != nil {
+		http.Error(w, "failed to create download token", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	auditEvent := auditEventFromRequest(r)
+	auditEvent.Details["token_id"] = downloadToken.ID
+	auditEvent.Details["expires_at"] = strconv.FormatInt(downloadToken.ExpiresAt, 10)
+
+	p.API.LogInfo("Created legal hold download token",
+		"token_id", downloadToken.ID,
+		"legal_hold_id", downloadToken.LegalHoldID,
+		"user_id", userID,
+		"expires_at", downloadToken.ExpiresAt,
+	)
+
+	b, jsonErr := json.Marshal(model.CreatedDownloadToken{DownloadToken: downloadToken, Token: token})
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
+// listDownloadTokens serves the DownloadTokens of a LegalHold, without their tokens.
+func (p *Plugin) listDownloadTokens(w http.ResponseWriter, r *http.Request) {
+	legalholdID, err := RequireLegalHoldID(r)
+	if err != nil {
+		http.Error(w, "failed to parse LegalHold ID", http.StatusBadRequest)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	downloadTokens, err := p.KVStore.GetDownloadTokens(legalholdID)
+	if err != nil {
+		http.Error(w, "an error occurred fetching the download tokens", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	b, jsonErr := json.Marshal(downloadTokens)
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
+// revokeDownloadToken revokes a DownloadToken, so that its token can no longer be used.
+func (p *Plugin) revokeDownloadToken(w http.ResponseWriter, r *http.Request) {
+	legalholdID, err := RequireLegalHoldID(r)
+	if err != nil {
+		http.Error(w, "failed to parse LegalHold ID", http.StatusBadRequest)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	tokenID := mux.Vars(r)["token_id"]
+	if !mattermostModel.IsValidId(tokenID) {
+		http.Error(w, "failed to parse download token ID", http.StatusBadRequest)
+		return
+	}
+
+	downloadToken, err := p.KVStore.GetDownloadToken(legalholdID, tokenID)
+	if err != nil {
+		http.Error(w, "an error occurred fetching the download token", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	if downloadToken == nil {
+		http.Error(w, "download token not found", http.StatusNotFound)
+		return
+	}
+
+	if downloadToken.RevokedAt == 0 {
+		downloadToken.RevokedAt = mattermostModel.GetMillis()
+		downloadToken.RevokedBy = r.Header.Get("Mattermost-User-ID")
+		if err = p.KVStore.SaveDownloadToken(*downloadToken); err != nil {
+			http.Error(w, "failed to revoke download token", http.StatusInternalServerError)
+			p.Client.Log.Error(err.Error())
+			return
+		}
+
+		p.API.LogInfo("Revoked legal hold download token",
+			"token_id", downloadToken.ID,
+			"legal_hold_id", downloadToken.LegalHoldID,
+			"user_id", downloadToken.RevokedBy,
+		)
+	}
+
+	b, jsonErr := json.Marshal(downloadToken)
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
+// downloadWithToken serves the download of the LegalHold of a download token, passed in the
+// downloadTokenHeader header or in the token query parameter, without a session.
+// Every use of a token is logged with a hash of the token, whether it succeeds or not.
+func (p *Plugin) downloadWithToken(w http.ResponseWriter, r *http.Request) {
+	// A token passed in the query string must not leak to other sites through the Referer header,
+	// nor be kept in a cache.
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "no-store")
+
+	token := r.Header.Get(downloadTokenHeader)
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+
+	if token == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	tokenHash := model.DownloadTokenHash(token)
+	logArgs := []any{"remote_addr", r.RemoteAddr, "user_agent", r.UserAgent(), "token_hash", tokenHash}
+
+	auditEvent := auditEventFromRequest(r)
+	auditEvent.Details["token_hash"] = tokenHash
+
+	signingKey, err := p.KVStore.GetOrCreateSigningKey()
+	if err != nil {
+		http.Error(w, "failed to download legal hold", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	now := mattermostModel.GetMillis()
+	claims, err := model.ParseDownloadToken(token, signingKey, now)
+	if err != nil {
+		p.API.LogWarn("Rejected legal hold download token", append(logArgs, "reason", err.Error())...)
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	logArgs = append(logArgs, "token_id", claims.TokenID, "legal_hold_id", claims.LegalHoldID)
+
+	auditEvent.LegalHoldID = claims.LegalHoldID
+	auditEvent.Details["token_id"] = claims.TokenID
+
+	downloadToken, err := p.KVStore.GetDownloadToken(claims.LegalHoldID, claims.TokenID)
+	if err != nil {
+		http.Error(w, "failed to download legal hold", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	if downloadToken == nil {
+		p.API.LogWarn("Rejected legal hold download token", append(logArgs, "reason", "the token was deleted")...)
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !downloadToken.IsActive(now) {
+		p.API.LogWarn("Rejected legal hold download token", append(logArgs, "reason", "the token was revoked")...)
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	legalHold, err := p.KVStore.GetLegalHoldByID(claims.LegalHoldID)
+	if err != nil {
+		http.Error(w, "failed to download legal hold", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	if legalHold.ID == "" {
+		http.Error(w, "legal hold not found", http.StatusNotFound)
+		return
+	}
+
+	if legalHold.IsReleased() {
+		p.API.LogWarn("Rejected legal hold download token", append(logArgs, "reason", "the legal hold was released")...)
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	p.API.LogInfo("Legal hold downloaded with a download token", append(logArgs, "query", downloadQuery(r))...)
+
+	downloadToken.UseCount++
+	downloadToken.LastUsedAt = now
+	if err = p.KVStore.SaveDownloadToken(*downloadToken); err != nil {
+		p.API.LogWarn("Failed to record the use of a download token", "token_id", downloadToken.ID, "error", err.Error())
+	}
+
+	p.serveLegalHoldDownload(w, r, *legalHold)
+}
+
+// getPreservationStatus serves the outcome of the latest check of the data retention policies of
+// the server against the active legal holds.
+func (p *Plugin) getPreservationStatus(w http.ResponseWriter, _ *http.Request) {
+	status, err := p.KVStore.GetPreservationStatus()
+	if err != nil {
+		http.Error(w, "an error occurred fetching the preservation status", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	if status == nil {
+		status = &model.PreservationStatus{
+			Mode:        model.ParsePreservationMode(p.getConfiguration().PreservationMode),
+			HeldContent: []model.HeldContent{},
+			Conflicts:   []model.RetentionConflict{},
+		}
+	}
+
+	b, jsonErr := json.Marshal(status)
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
+// checkPreservation checks the data retention policies of the server against the active legal
+// holds immediately, rather than waiting for the next scheduled check.
+func (p *Plugin) checkPreservation(w http.ResponseWriter, r *http.Request) {
+	status, err := p.preservationJob.RunCheck(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to check preservation: %s", err.Error()), http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	b, jsonErr := json.Marshal(status)
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
+// getHealth serves the health status of the plugin.
+func (p *Plugin) getHealth(w http.ResponseWriter, _ *http.Request) {
+	health := model.HealthStatus{Healthy: true}
+
+	status, err := p.KVStore.GetPreservationStatus()
+	if err != nil {
+		http.Error(w, "an error occurred fetching the preservation status", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	// The outcome of the last check is stale once preservation has been turned off.
+	mode := model.ParsePreservationMode(p.getConfiguration().PreservationMode)
+	if status != nil && status.Mode != model.PreservationModeOff && mode != model.PreservationModeOff {
+		health.Preservation = status
+		if status.Error != "" {
+			health.Problems = append(health.Problems, fmt.Sprintf("preservation check failed: %s", status.Error))
+		}
+		if conflicts := status.UnblockedConflicts(); len(conflicts) > 0 {
+			health.Problems = append(health.Problems, fmt.Sprintf("%d data retention conflicts with active legal holds", len(conflicts)))
+		}
+		health.Healthy = status.IsHealthy()
+	}
+
+	b, jsonErr := json.Marshal(health)
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
+// isMetricsToken returns true if token is the configured metrics token. No token is accepted if
+// none is configured.
+func (p *Plugin) isMetricsToken(token string) bool {
+	expected := strings.TrimSpace(p.getConfiguration().MetricsToken)
+	return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// getMetrics serves the metrics of the processing of legal holds on this node of the cluster, in
+// the Prometheus text exposition format.
+func (p *Plugin) getMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := p.metrics.WriteTo(w); err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
+// getSigningKey serves the public key used to verify the signed manifests of the legal holds.
+func (p *Plugin) getSigningKey(w http.ResponseWriter, _ *http.Request) {
+	signingKey, err := p.KVStore.GetOrCreateSigningKey()
+	if err != nil {
+		http.Error(w, "an error occurred fetching the signing key", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	info, err := signingKey.Info()
+	if err != nil {
+		http.Error(w, "an error occurred encoding the signing key", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	b, jsonErr := json.Marshal(info)
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
 // testAmazonS3Connection tests the plugin's custom Amazon S3 connection
 func (p *Plugin) testAmazonS3Connection(w http.ResponseWriter, _ *http.Request) {
 	type messageResponse struct {
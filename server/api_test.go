@@ -1,19 +1,33 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	pluginapi "github.com/mattermost/mattermost-plugin-api"
 	"github.com/mattermost/mattermost-server/v6/model"
 	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/shared/filestore"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/config"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/legalhold"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/metrics"
+	legalHoldModel "github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/kvstore"
 )
 
 type MockLegalHoldJob struct {
@@ -44,11 +58,99 @@ func (m *MockLegalHoldJob) RunSingleLegalHold(id string) error {
 	return args.Error(0)
 }
 
+func (m *MockLegalHoldJob) CancelLegalHold(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockLegalHoldJob) OnCancelLegalHoldEvent(id string) {
+	m.Called(id)
+}
+
 func (m *MockLegalHoldJob) GetRunningLegalHolds() ([]string, error) {
 	args := m.Called()
 	return args.Get(0).([]string), args.Error(1)
 }
 
+type MockPreservationJob struct {
+	mock.Mock
+}
+
+func (m *MockPreservationJob) GetID() string {
+	args := m.Called()
+	return args.String(0)
+}
+
+func (m *MockPreservationJob) OnConfigurationChange(cfg *config.Configuration) error {
+	args := m.Called(cfg)
+	return args.Error(0)
+}
+
+func (m *MockPreservationJob) Stop(timeout time.Duration) error {
+	args := m.Called(timeout)
+	return args.Error(0)
+}
+
+func (m *MockPreservationJob) RunCheck(ctx context.Context) (*legalHoldModel.PreservationStatus, error) {
+	args := m.Called(ctx)
+	status, _ := args.Get(0).(*legalHoldModel.PreservationStatus)
+	return status, args.Error(1)
+}
+
+type MockExportPackageJob struct {
+	mock.Mock
+}
+
+func (m *MockExportPackageJob) GetID() string {
+	args := m.Called()
+	return args.String(0)
+}
+
+func (m *MockExportPackageJob) OnConfigurationChange(cfg *config.Configuration) error {
+	args := m.Called(cfg)
+	return args.Error(0)
+}
+
+func (m *MockExportPackageJob) Stop(timeout time.Duration) error {
+	args := m.Called(timeout)
+	return args.Error(0)
+}
+
+func (m *MockExportPackageJob) StartExportPackage(lh legalHoldModel.LegalHold) (*legalHoldModel.ExportPackage, error) {
+	args := m.Called(lh)
+	pkg, _ := args.Get(0).(*legalHoldModel.ExportPackage)
+	return pkg, args.Error(1)
+}
+
+type MockCustodianNoticeJob struct {
+	mock.Mock
+}
+
+func (m *MockCustodianNoticeJob) GetID() string {
+	args := m.Called()
+	return args.String(0)
+}
+
+func (m *MockCustodianNoticeJob) OnConfigurationChange(cfg *config.Configuration) error {
+	args := m.Called(cfg)
+	return args.Error(0)
+}
+
+func (m *MockCustodianNoticeJob) Stop(timeout time.Duration) error {
+	args := m.Called(timeout)
+	return args.Error(0)
+}
+
+func (m *MockCustodianNoticeJob) NotifyLegalHold(lh legalHoldModel.LegalHold) {
+	m.Called(lh)
+}
+
+func (m *MockCustodianNoticeJob) Acknowledge(legalHoldID, userID, postID string) (*legalHoldModel.CustodianNotice, error) {
+	args := m.Called(legalHoldID, userID, postID)
+	notice, _ := args.Get(0).(*legalHoldModel.CustodianNotice)
+	return notice, args.Error(1)
+}
+
 func setupTestPlugin(t *testing.T) (*Plugin, *plugintest.API) {
 	t.Helper()
 	p := &Plugin{}
@@ -59,6 +161,98 @@ func setupTestPlugin(t *testing.T) (*Plugin, *plugintest.API) {
 	return p, api
 }
 
+// mockAuditLog lets the audit events of the requests be saved, and returns a function that
+// returns those saved so far.
+func mockAuditLog(t *testing.T, api *plugintest.API) func() []legalHoldModel.AuditEvent {
+	t.Helper()
+
+	var mutex sync.Mutex
+	var events []legalHoldModel.AuditEvent
+
+	api.On("GetConfig").Return(&model.Config{}).Maybe()
+	api.On("KVSetWithOptions", mock.MatchedBy(func(key string) bool {
+		return strings.HasPrefix(key, "kvstore_audit_event_")
+	}), mock.AnythingOfType("[]uint8"), mock.AnythingOfType("model.PluginKVSetOptions")).
+		Run(func(args mock.Arguments) {
+			var event legalHoldModel.AuditEvent
+			require.NoError(t, json.Unmarshal(args.Get(1).([]byte), &event))
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			events = append(events, event)
+		}).Return(true, nil).Maybe()
+	mockAuditIndexes(api)
+
+	return func() []legalHoldModel.AuditEvent {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]legalHoldModel.AuditEvent(nil), events...)
+	}
+}
+
+// mockAuditIndexes lets the indexes of the audit events be saved, always as if they were new.
+func mockAuditIndexes(api *plugintest.API) {
+	isAuditIndex := mock.MatchedBy(func(key string) bool {
+		return strings.HasPrefix(key, "kvstore_audit_index_") || strings.HasPrefix(key, "kvstore_audit_days_")
+	})
+	api.On("KVGet", isAuditIndex).Return(nil, nil).Maybe()
+	api.On("KVSetWithOptions", isAuditIndex, mock.AnythingOfType("[]uint8"), mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Maybe()
+}
+
+// inMemoryKV is the KV store of a mocked plugin API, kept in memory so that it can be read back.
+type inMemoryKV struct {
+	mutex sync.Mutex
+	data  map[string][]byte
+}
+
+// mockInMemoryKV keeps the KV store of the mocked plugin API in memory. Atomic sets are not
+// checked against the old value.
+func mockInMemoryKV(api *plugintest.API) *inMemoryKV {
+	kv := &inMemoryKV{data: make(map[string][]byte)}
+
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8"), mock.AnythingOfType("model.PluginKVSetOptions")).
+		Run(func(args mock.Arguments) {
+			kv.mutex.Lock()
+			defer kv.mutex.Unlock()
+			kv.data[args.String(0)] = args.Get(1).([]byte)
+		}).Return(true, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+		kv.mutex.Lock()
+		defer kv.mutex.Unlock()
+		return kv.data[key]
+	}, nil)
+	api.On("KVList", 0, 1000000000).Return(func(int, int) []string {
+		kv.mutex.Lock()
+		defer kv.mutex.Unlock()
+		keys := make([]string, 0, len(kv.data))
+		for key := range kv.data {
+			keys = append(keys, key)
+		}
+		return keys
+	}, nil)
+
+	return kv
+}
+
+func (kv *inMemoryKV) set(t *testing.T, key string, value any) {
+	t.Helper()
+
+	data, err := json.Marshal(value)
+	require.NoError(t, err)
+
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+	kv.data[key] = data
+}
+
+func (kv *inMemoryKV) get(t *testing.T, key string, value any) {
+	t.Helper()
+
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+	require.NoError(t, json.Unmarshal(kv.data[key], value))
+}
+
 func TestServeHTTPAuthorization(t *testing.T) {
 	endpoints := []struct {
 		method string
@@ -67,11 +261,30 @@ func TestServeHTTPAuthorization(t *testing.T) {
 		{http.MethodGet, "/api/v1/legalholds"},
 		{http.MethodPost, "/api/v1/legalholds"},
 		{http.MethodPost, fmt.Sprintf("/api/v1/legalholds/%s/release", model.NewId())},
+		{http.MethodPost, fmt.Sprintf("/api/v1/legalholds/%s/restore", model.NewId())},
 		{http.MethodPut, fmt.Sprintf("/api/v1/legalholds/%s", model.NewId())},
 		{http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/download", model.NewId())},
 		{http.MethodPost, fmt.Sprintf("/api/v1/legalholds/%s/run", model.NewId())},
+		{http.MethodPost, fmt.Sprintf("/api/v1/legalholds/%s/cancel", model.NewId())},
+		{http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/executions", model.NewId())},
+		{http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/custodians", model.NewId())},
+		{http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/packages", model.NewId())},
+		{http.MethodPost, fmt.Sprintf("/api/v1/legalholds/%s/packages", model.NewId())},
+		{http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/packages/%s", model.NewId(), model.NewId())},
+		{http.MethodDelete, fmt.Sprintf("/api/v1/legalholds/%s/packages/%s", model.NewId(), model.NewId())},
+		{http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/packages/%s/download", model.NewId(), model.NewId())},
+		{http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/download_tokens", model.NewId())},
+		{http.MethodPost, fmt.Sprintf("/api/v1/legalholds/%s/download_tokens", model.NewId())},
+		{http.MethodPost, fmt.Sprintf("/api/v1/legalholds/%s/download_tokens/%s/revoke", model.NewId(), model.NewId())},
 		{http.MethodPost, "/api/v1/test_amazon_s3_connection"},
 		{http.MethodGet, "/api/v1/groups/search"},
+		{http.MethodGet, "/api/v1/preservation"},
+		{http.MethodPost, "/api/v1/preservation/check"},
+		{http.MethodGet, "/api/v1/health"},
+		{http.MethodGet, "/api/v1/metrics"},
+		{http.MethodGet, "/api/v1/signing_key"},
+		{http.MethodGet, "/api/v1/audit"},
+		{http.MethodGet, "/api/v1/tombstones"},
 		{http.MethodPost, "/api/v1/legalhold/run"},
 	}
 
@@ -121,6 +334,8 @@ func TestRunSingleLegalHold(t *testing.T) {
 	p.SetDriver(&plugintest.Driver{})
 	p.SetAPI(api)
 	p.Client = pluginapi.NewClient(p.API, p.Driver)
+	p.KVStore = kvstore.NewKVStore(p.Client)
+	auditEvents := mockAuditLog(t, api)
 
 	api.On("HasPermissionTo", "test_user_id", model.PermissionManageSystem).Return(true)
 	api.On("LogInfo", mock.Anything).Maybe()
@@ -162,6 +377,19 @@ func TestRunSingleLegalHold(t *testing.T) {
 	recorder = httptest.NewRecorder()
 	p.ServeHTTP(nil, recorder, req)
 	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+
+	// Both runs are audited, but not the request for an invalid ID, which matches no route.
+	events := auditEvents()
+	require.Len(t, events, 2)
+	require.Equal(t, legalHoldModel.AuditActionRunLegalHold, events[0].Action)
+	require.Equal(t, testID, events[0].LegalHoldID)
+	require.Equal(t, "test_user_id", events[0].ActorID)
+	require.Equal(t, legalHoldModel.AuditOutcomeSuccess, events[0].Outcome)
+	require.Equal(t, http.StatusOK, events[0].StatusCode)
+	require.Equal(t, lhID, events[1].LegalHoldID)
+	require.Equal(t, legalHoldModel.AuditOutcomeFailure, events[1].Outcome)
+	require.Equal(t, http.StatusInternalServerError, events[1].StatusCode)
+	require.Equal(t, "Failed to run legal hold: test error", events[1].Error)
 }
 
 func TestTestAmazonS3Connection(t *testing.T) {
@@ -211,3 +439,891 @@ func TestTestAmazonS3Connection(t *testing.T) {
 	p.ServeHTTP(nil, recorder, req)
 	require.Equal(t, http.StatusOK, recorder.Code)
 }
+
+func TestListLegalHoldExecutions(t *testing.T) {
+	p, api := setupTestPlugin(t)
+	p.KVStore = kvstore.NewKVStore(p.Client)
+
+	api.On("HasPermissionTo", "test_user_id", model.PermissionManageSystem).Return(true)
+	api.On("LogInfo", mock.Anything).Maybe()
+	api.On("LogError", mock.Anything, mock.Anything).Maybe()
+
+	lh := legalHoldModel.LegalHold{ID: model.NewId(), Name: "legalhold"}
+	record := legalHoldModel.ExecutionRecord{ID: model.NewId(), LegalHoldID: lh.ID, PostCount: 5, FileExceptions: 2}
+
+	lhJSON, err := json.Marshal(lh)
+	require.NoError(t, err)
+	recordJSON, err := json.Marshal(record)
+	require.NoError(t, err)
+
+	recordKey := fmt.Sprintf("kvstore_execution_record_%s_%s", lh.ID, record.ID)
+	api.On("KVGet", "kvstore_legal_hold_"+lh.ID).Return(lhJSON, nil)
+	api.On("KVGet", recordKey).Return(recordJSON, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("KVList", 0, 1000000000).Return([]string{recordKey}, nil)
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/executions", lh.ID), nil)
+	require.NoError(t, err)
+	req.Header.Add("Mattermost-User-Id", "test_user_id")
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(nil, recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var records []legalHoldModel.ExecutionRecord
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&records))
+	require.Equal(t, []legalHoldModel.ExecutionRecord{record}, records)
+
+	// An unknown legal hold is not found.
+	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/executions", model.NewId()), nil)
+	require.NoError(t, err)
+	req.Header.Add("Mattermost-User-Id", "test_user_id")
+
+	recorder = httptest.NewRecorder()
+	p.ServeHTTP(nil, recorder, req)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestListLegalHolds_Status(t *testing.T) {
+	p, api := setupTestPlugin(t)
+	p.KVStore = kvstore.NewKVStore(p.Client)
+
+	api.On("HasPermissionTo", "test_user_id", model.PermissionManageSystem).Return(true)
+	api.On("LogInfo", mock.Anything).Maybe()
+	api.On("LogError", mock.Anything).Maybe()
+	kv := mockInMemoryKV(api)
+
+	idle := legalHoldModel.LegalHold{ID: model.NewId(), Name: "idle"}
+	failed := legalHoldModel.LegalHold{ID: model.NewId(), Name: "failed"}
+	failed.RecordFailure(errors.New("failed to write file"), 1000)
+	executing := legalHoldModel.LegalHold{ID: model.NewId(), Name: "executing"}
+	executing.RecordFailure(errors.New("failed to write file"), 1000)
+	released := legalHoldModel.LegalHold{ID: model.NewId(), Name: "released", ReleasedAt: 2000}
+	released.RecordFailure(errors.New("failed to write file"), 1000)
+	for _, lh := range []legalHoldModel.LegalHold{idle, failed, executing, released} {
+		kv.set(t, "kvstore_legal_hold_"+lh.ID, lh)
+	}
+
+	mockJob := &MockLegalHoldJob{}
+	mockJob.On("GetRunningLegalHolds").Return([]string{executing.ID}, nil)
+	p.legalHoldJob = mockJob
+
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/legalholds", nil)
+	require.NoError(t, err)
+	req.Header.Add("Mattermost-User-Id", "test_user_id")
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(nil, recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var legalHolds []legalHoldModel.LegalHold
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&legalHolds))
+
+	statuses := make(map[string]legalHoldModel.LegalHoldStatus)
+	for _, lh := range legalHolds {
+		statuses[lh.Name] = lh.Status
+	}
+	require.Equal(t, map[string]legalHoldModel.LegalHoldStatus{
+		"idle":      "",
+		"failed":    legalHoldModel.LegalHoldStatusFailed,
+		"executing": legalHoldModel.LegalHoldStatusExecuting,
+		"released":  legalHoldModel.LegalHoldStatusReleased,
+	}, statuses)
+}
+
+func TestCancelLegalHold(t *testing.T) {
+	p, api := setupTestPlugin(t)
+	p.KVStore = kvstore.NewKVStore(p.Client)
+	mockAuditLog(t, api)
+
+	api.On("HasPermissionTo", "test_user_id", model.PermissionManageSystem).Return(true)
+	api.On("LogInfo", mock.Anything).Maybe()
+	api.On("LogError", mock.Anything, mock.Anything).Maybe()
+
+	mockJob := &MockLegalHoldJob{}
+	p.legalHoldJob = mockJob
+
+	lh := legalHoldModel.LegalHold{ID: model.NewId(), Name: "legalhold"}
+	lhJSON, err := json.Marshal(lh)
+	require.NoError(t, err)
+	api.On("KVGet", "kvstore_legal_hold_"+lh.ID).Return(lhJSON, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+
+	// Test successful cancellation
+	mockJob.On("CancelLegalHold", lh.ID).Return(nil).Once()
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/legalholds/%s/cancel", lh.ID), nil)
+	require.NoError(t, err)
+	req.Header.Add("Mattermost-User-Id", "test_user_id")
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(nil, recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// Test unknown legal hold
+	req, err = http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/legalholds/%s/cancel", model.NewId()), nil)
+	require.NoError(t, err)
+	req.Header.Add("Mattermost-User-Id", "test_user_id")
+
+	recorder = httptest.NewRecorder()
+	p.ServeHTTP(nil, recorder, req)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// Test error cancelling legal hold
+	mockJob.On("CancelLegalHold", lh.ID).Return(fmt.Errorf("test error")).Once()
+
+	req, err = http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/legalholds/%s/cancel", lh.ID), nil)
+	require.NoError(t, err)
+	req.Header.Add("Mattermost-User-Id", "test_user_id")
+
+	recorder = httptest.NewRecorder()
+	p.ServeHTTP(nil, recorder, req)
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+
+	mockJob.AssertExpectations(t)
+}
+
+func TestPreservation(t *testing.T) {
+	p, api := setupTestPlugin(t)
+	p.KVStore = kvstore.NewKVStore(p.Client)
+	p.setConfiguration(&config.Configuration{PreservationMode: "warn"})
+
+	api.On("HasPermissionTo", "test_user_id", model.PermissionManageSystem).Return(true)
+	api.On("LogInfo", mock.Anything).Maybe()
+	api.On("LogError", mock.Anything, mock.Anything).Maybe()
+
+	mockJob := &MockPreservationJob{}
+	p.preservationJob = mockJob
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, nil)
+		require.NoError(t, err)
+		req.Header.Add("Mattermost-User-Id", "test_user_id")
+
+		recorder := httptest.NewRecorder()
+		p.ServeHTTP(nil, recorder, req)
+		return recorder
+	}
+
+	t.Run("no check has run yet", func(t *testing.T) {
+		api.On("KVGet", "kvstore_preservation_status").Return(nil, nil).Twice()
+
+		recorder := serve(http.MethodGet, "/api/v1/preservation")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var status legalHoldModel.PreservationStatus
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&status))
+		require.Equal(t, legalHoldModel.PreservationModeWarn, status.Mode)
+		require.Zero(t, status.CheckedAt)
+
+		recorder = serve(http.MethodGet, "/api/v1/health")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var health legalHoldModel.HealthStatus
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&health))
+		require.True(t, health.Healthy)
+		require.Nil(t, health.Preservation)
+	})
+
+	t.Run("conflicts make the plugin unhealthy", func(t *testing.T) {
+		status := legalHoldModel.PreservationStatus{
+			Mode:      legalHoldModel.PreservationModeWarn,
+			CheckedAt: 1000,
+			Conflicts: []legalHoldModel.RetentionConflict{
+				{LegalHoldID: model.NewId(), PolicyID: legalHoldModel.GlobalRetentionPolicyID, Content: legalHoldModel.RetentionContentPosts, RetentionDays: 1},
+			},
+		}
+		statusJSON, err := json.Marshal(status)
+		require.NoError(t, err)
+		api.On("KVGet", "kvstore_preservation_status").Return(statusJSON, nil).Once()
+
+		recorder := serve(http.MethodGet, "/api/v1/health")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var health legalHoldModel.HealthStatus
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&health))
+		require.False(t, health.Healthy)
+		require.Len(t, health.Problems, 1)
+		require.NotNil(t, health.Preservation)
+		require.Equal(t, status.Conflicts, health.Preservation.Conflicts)
+	})
+
+	t.Run("conflicts blocked by a capture leave the plugin healthy", func(t *testing.T) {
+		status := legalHoldModel.PreservationStatus{
+			Mode:      legalHoldModel.PreservationModeBlock,
+			CheckedAt: 1000,
+			Conflicts: []legalHoldModel.RetentionConflict{
+				{LegalHoldID: model.NewId(), PolicyID: legalHoldModel.GlobalRetentionPolicyID, Content: legalHoldModel.RetentionContentPosts, RetentionDays: 1, Blocked: true},
+			},
+		}
+		statusJSON, err := json.Marshal(status)
+		require.NoError(t, err)
+		api.On("KVGet", "kvstore_preservation_status").Return(statusJSON, nil).Once()
+
+		recorder := serve(http.MethodGet, "/api/v1/health")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var health legalHoldModel.HealthStatus
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&health))
+		require.True(t, health.Healthy)
+		require.Empty(t, health.Problems)
+		require.NotNil(t, health.Preservation)
+	})
+
+	t.Run("the last check is ignored once preservation is off", func(t *testing.T) {
+		p.setConfiguration(&config.Configuration{PreservationMode: "off"})
+		defer p.setConfiguration(&config.Configuration{PreservationMode: "warn"})
+
+		status := legalHoldModel.PreservationStatus{
+			Mode:      legalHoldModel.PreservationModeWarn,
+			CheckedAt: 1000,
+			Conflicts: []legalHoldModel.RetentionConflict{
+				{LegalHoldID: model.NewId(), PolicyID: legalHoldModel.GlobalRetentionPolicyID, Content: legalHoldModel.RetentionContentPosts, RetentionDays: 1},
+			},
+		}
+		statusJSON, err := json.Marshal(status)
+		require.NoError(t, err)
+		api.On("KVGet", "kvstore_preservation_status").Return(statusJSON, nil).Once()
+
+		recorder := serve(http.MethodGet, "/api/v1/health")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var health legalHoldModel.HealthStatus
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&health))
+		require.True(t, health.Healthy)
+		require.Nil(t, health.Preservation)
+	})
+
+	t.Run("check runs immediately", func(t *testing.T) {
+		status := &legalHoldModel.PreservationStatus{Mode: legalHoldModel.PreservationModeWarn, CheckedAt: 2000}
+		mockJob.On("RunCheck", mock.Anything).Return(status, nil).Once()
+
+		recorder := serve(http.MethodPost, "/api/v1/preservation/check")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var result legalHoldModel.PreservationStatus
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&result))
+		require.Equal(t, int64(2000), result.CheckedAt)
+
+		mockJob.On("RunCheck", mock.Anything).Return(nil, fmt.Errorf("test error")).Once()
+
+		recorder = serve(http.MethodPost, "/api/v1/preservation/check")
+		require.Equal(t, http.StatusInternalServerError, recorder.Code)
+
+		mockJob.AssertExpectations(t)
+	})
+}
+
+func TestGetMetrics(t *testing.T) {
+	p, api := setupTestPlugin(t)
+	p.metrics = metrics.New()
+
+	api.On("HasPermissionTo", "test_user_id", model.PermissionManageSystem).Return(true)
+	api.On("HasPermissionTo", "other_user_id", model.PermissionManageSystem).Return(false)
+	api.On("LogInfo", mock.Anything).Maybe()
+
+	lhID := model.NewId()
+	p.metrics.ObserveExecution(legalHoldModel.ExecutionRecord{LegalHoldID: lhID, Duration: 2000, PostCount: 42, BytesWritten: 1024})
+	p.metrics.ObservePostsBatch(lhID, 20*time.Millisecond)
+
+	scrape := func(method, userID, token string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, "/api/v1/metrics", nil)
+		require.NoError(t, err)
+		if userID != "" {
+			req.Header.Add("Mattermost-User-Id", userID)
+		}
+		if token != "" {
+			req.Header.Add("X-Legal-Hold-Metrics-Token", token)
+		}
+
+		recorder := httptest.NewRecorder()
+		p.ServeHTTP(nil, recorder, req)
+		return recorder
+	}
+
+	requireMetrics := func(t *testing.T, recorder *httptest.ResponseRecorder) {
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+		body := recorder.Body.String()
+		require.Contains(t, body, "# TYPE legal_hold_executions_total counter\n")
+		require.Contains(t, body, fmt.Sprintf("legal_hold_executions_total{legal_hold_id=%q,outcome=\"success\"} 1\n", lhID))
+		require.Contains(t, body, fmt.Sprintf("legal_hold_posts_exported_total{legal_hold_id=%q} 42\n", lhID))
+		require.Contains(t, body, fmt.Sprintf("legal_hold_bytes_copied_total{legal_hold_id=%q} 1024\n", lhID))
+		require.Contains(t, body, fmt.Sprintf("legal_hold_execution_duration_seconds_sum{legal_hold_id=%q} 2\n", lhID))
+		require.Contains(t, body, fmt.Sprintf("legal_hold_posts_batch_query_duration_seconds_bucket{legal_hold_id=%q,le=\"0.025\"} 1\n", lhID))
+		require.Contains(t, body, fmt.Sprintf("legal_hold_posts_batch_query_duration_seconds_count{legal_hold_id=%q} 1\n", lhID))
+	}
+
+	t.Run("System Admins read the metrics", func(t *testing.T) {
+		requireMetrics(t, scrape(http.MethodGet, "test_user_id", ""))
+	})
+
+	t.Run("no token is accepted if none is configured", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, scrape(http.MethodGet, "", "secret").Code)
+		require.Equal(t, http.StatusUnauthorized, scrape(http.MethodGet, "", "").Code)
+	})
+
+	p.setConfiguration(&config.Configuration{MetricsToken: "secret"})
+
+	t.Run("Prometheus scrapes the metrics with the metrics token", func(t *testing.T) {
+		requireMetrics(t, scrape(http.MethodGet, "", "secret"))
+	})
+
+	t.Run("other tokens and methods are rejected", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, scrape(http.MethodGet, "", "secrets").Code)
+		require.Equal(t, http.StatusMethodNotAllowed, scrape(http.MethodPost, "", "secret").Code)
+		require.Equal(t, http.StatusUnauthorized, scrape(http.MethodGet, "other_user_id", "").Code)
+	})
+}
+
+func TestGetSigningKey(t *testing.T) {
+	p, api := setupTestPlugin(t)
+	p.KVStore = kvstore.NewKVStore(p.Client)
+
+	api.On("HasPermissionTo", "test_user_id", model.PermissionManageSystem).Return(true)
+	api.On("LogInfo", mock.Anything).Maybe()
+
+	signingKey, err := legalHoldModel.NewSigningKey()
+	require.NoError(t, err)
+	signingKeyJSON, err := json.Marshal(signingKey)
+	require.NoError(t, err)
+	api.On("KVGet", "kvstore_signing_key").Return(signingKeyJSON, nil).Once()
+
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/signing_key", nil)
+	require.NoError(t, err)
+	req.Header.Add("Mattermost-User-Id", "test_user_id")
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(nil, recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.String()
+	require.NotContains(t, body, "private_key")
+
+	var info legalHoldModel.SigningKeyInfo
+	require.NoError(t, json.Unmarshal([]byte(body), &info))
+
+	expected, err := signingKey.Info()
+	require.NoError(t, err)
+	require.Equal(t, expected, info)
+}
+
+func TestDownloadLegalHold_Encrypted(t *testing.T) {
+	p, api := setupTestPlugin(t)
+	p.KVStore = kvstore.NewKVStore(p.Client)
+	mockAuditLog(t, api)
+
+	api.On("HasPermissionTo", "test_user_id", model.PermissionManageSystem).Return(true)
+	api.On("LogInfo", mock.Anything).Maybe()
+
+	local, err := filestore.NewFileBackend(filestore.FileBackendSettings{
+		DriverName: "local",
+		Directory:  t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	masterKey := make([]byte, legalHoldModel.EncryptionKeySize)
+	_, err = rand.Read(masterKey)
+	require.NoError(t, err)
+	p.FileBackend = legalhold.NewEncryptedFileBackend(local, masterKey)
+
+	lh := legalHoldModel.LegalHold{ID: model.NewId(), Name: "encrypted"}
+	lhJSON, err := json.Marshal(lh)
+	require.NoError(t, err)
+	api.On("KVGet", "kvstore_legal_hold_"+lh.ID).Return(lhJSON, nil)
+
+	_, err = p.FileBackend.WriteFile(strings.NewReader("confidential"), lh.IndexPath())
+	require.NoError(t, err)
+
+	// The file is encrypted at rest.
+	stored, err := local.ReadFile(lh.IndexPath())
+	require.NoError(t, err)
+	require.NotContains(t, string(stored), "confidential")
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/download", lh.ID), nil)
+	require.NoError(t, err)
+	req.Header.Add("Mattermost-User-Id", "test_user_id")
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(nil, recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// The download is decrypted.
+	body := recorder.Body.Bytes()
+	zipReader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+
+	found := false
+	for _, file := range zipReader.File {
+		if file.Name != lh.IndexPath() {
+			continue
+		}
+		found = true
+
+		reader, openErr := file.Open()
+		require.NoError(t, openErr)
+		data, readErr := io.ReadAll(reader)
+		require.NoError(t, readErr)
+		require.Equal(t, "confidential", string(data))
+	}
+	require.True(t, found)
+}
+
+func TestDownloadLegalHold_SkipsStaging(t *testing.T) {
+	p, api := setupTestPlugin(t)
+	p.KVStore = kvstore.NewKVStore(p.Client)
+	mockAuditLog(t, api)
+
+	api.On("HasPermissionTo", "test_user_id", model.PermissionManageSystem).Return(true)
+	api.On("LogInfo", mock.Anything).Maybe()
+
+	local, err := filestore.NewFileBackend(filestore.FileBackendSettings{
+		DriverName: "local",
+		Directory:  t.TempDir(),
+	})
+	require.NoError(t, err)
+	p.FileBackend = legalhold.NewEncryptedFileBackend(local, nil)
+
+	lh := legalHoldModel.LegalHold{ID: model.NewId(), Name: "interrupted"}
+	lhJSON, err := json.Marshal(lh)
+	require.NoError(t, err)
+	api.On("KVGet", "kvstore_legal_hold_"+lh.ID).Return(lhJSON, nil)
+
+	// An interrupted execution leaves its checkpoint and a staged attachment on disk.
+	for _, filePath := range []string{
+		lh.IndexPath(),
+		lh.BasePath() + "/staging/checkpoint/index.json",
+		lh.BasePath() + "/staging/checkpoint/progress-00000001.json",
+		lh.BasePath() + "/blobs/staging/" + model.NewId(),
+	} {
+		_, err = local.WriteFile(strings.NewReader("{}"), filePath)
+		require.NoError(t, err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/download", lh.ID), nil)
+	require.NoError(t, err)
+	req.Header.Add("Mattermost-User-Id", "test_user_id")
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(nil, recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.Bytes()
+	zipReader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+
+	var names []string
+	for _, file := range zipReader.File {
+		names = append(names, file.Name)
+	}
+	require.Equal(t, []string{lh.IndexPath()}, names)
+}
+
+func TestExportPackages(t *testing.T) {
+	p, api := setupTestPlugin(t)
+	p.KVStore = kvstore.NewKVStore(p.Client)
+	mockAuditLog(t, api)
+
+	api.On("HasPermissionTo", "test_user_id", model.PermissionManageSystem).Return(true)
+	api.On("LogInfo", mock.Anything).Maybe()
+	api.On("LogError", mock.Anything, mock.Anything).Maybe()
+
+	local, err := filestore.NewFileBackend(filestore.FileBackendSettings{
+		DriverName: "local",
+		Directory:  t.TempDir(),
+	})
+	require.NoError(t, err)
+	p.FileBackend = legalhold.NewEncryptedFileBackend(local, nil)
+
+	mockJob := &MockExportPackageJob{}
+	p.exportPackageJob = mockJob
+
+	lh := legalHoldModel.LegalHold{ID: model.NewId(), Name: "packaged"}
+	lhJSON, err := json.Marshal(lh)
+	require.NoError(t, err)
+	api.On("KVGet", "kvstore_legal_hold_"+lh.ID).Return(lhJSON, nil)
+
+	serve := func(method, path string, header http.Header) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, nil)
+		require.NoError(t, err)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		req.Header.Add("Mattermost-User-Id", "test_user_id")
+
+		recorder := httptest.NewRecorder()
+		p.ServeHTTP(nil, recorder, req)
+		return recorder
+	}
+
+	t.Run("create starts the build", func(t *testing.T) {
+		pending := legalHoldModel.NewExportPackage(model.NewId(), lh.ID, model.GetMillis())
+		mockJob.On("StartExportPackage", lh).Return(&pending, nil).Once()
+
+		recorder := serve(http.MethodPost, fmt.Sprintf("/api/v1/legalholds/%s/packages", lh.ID), nil)
+		require.Equal(t, http.StatusAccepted, recorder.Code)
+
+		var pkg legalHoldModel.ExportPackage
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&pkg))
+		require.Equal(t, pending, pkg)
+
+		mockJob.AssertExpectations(t)
+	})
+
+	t.Run("pending package cannot be downloaded", func(t *testing.T) {
+		pending := legalHoldModel.NewExportPackage(model.NewId(), lh.ID, model.GetMillis())
+		pendingJSON, err := json.Marshal(pending)
+		require.NoError(t, err)
+		api.On("KVGet", fmt.Sprintf("kvstore_export_package_%s_%s", lh.ID, pending.ID)).Return(pendingJSON, nil)
+
+		recorder := serve(http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/packages/%s/download", lh.ID, pending.ID), nil)
+		require.Equal(t, http.StatusConflict, recorder.Code)
+
+		recorder = serve(http.MethodDelete, fmt.Sprintf("/api/v1/legalholds/%s/packages/%s", lh.ID, pending.ID), nil)
+		require.Equal(t, http.StatusConflict, recorder.Code)
+	})
+
+	t.Run("stale package is reported as failed", func(t *testing.T) {
+		stale := legalHoldModel.NewExportPackage(model.NewId(), lh.ID, 1000)
+		staleJSON, err := json.Marshal(stale)
+		require.NoError(t, err)
+		api.On("KVGet", fmt.Sprintf("kvstore_export_package_%s_%s", lh.ID, stale.ID)).Return(staleJSON, nil)
+
+		recorder := serve(http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/packages/%s", lh.ID, stale.ID), nil)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var pkg legalHoldModel.ExportPackage
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&pkg))
+		require.Equal(t, legalHoldModel.ExportPackageStatusFailed, pkg.Status)
+		require.NotEmpty(t, pkg.Error)
+	})
+
+	t.Run("completed package is downloaded in ranges", func(t *testing.T) {
+		archive := []byte("PK-archive-content")
+		completed := legalHoldModel.NewExportPackage(model.NewId(), lh.ID, 1000)
+		completed.Status = legalHoldModel.ExportPackageStatusCompleted
+		completed.CompletedAt = 2000
+		completed.Size = int64(len(archive))
+		completed.Digest = "digest"
+		completedJSON, err := json.Marshal(completed)
+		require.NoError(t, err)
+		api.On("KVGet", fmt.Sprintf("kvstore_export_package_%s_%s", lh.ID, completed.ID)).Return(completedJSON, nil)
+
+		_, err = local.WriteFile(bytes.NewReader(archive), completed.ArchivePath())
+		require.NoError(t, err)
+
+		path := fmt.Sprintf("/api/v1/legalholds/%s/packages/%s/download", lh.ID, completed.ID)
+		recorder := serve(http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Equal(t, archive, recorder.Body.Bytes())
+		require.Equal(t, "application/zip", recorder.Header().Get("Content-Type"))
+		require.Equal(t, "bytes", recorder.Header().Get("Accept-Ranges"))
+		require.Equal(t, `"digest"`, recorder.Header().Get("ETag"))
+		require.Contains(t, recorder.Header().Get("Content-Disposition"), completed.ArchiveFileName())
+
+		recorder = serve(http.MethodGet, path, http.Header{
+			"Range":    []string{"bytes=2-5"},
+			"If-Range": []string{`"digest"`},
+		})
+		require.Equal(t, http.StatusPartialContent, recorder.Code)
+		require.Equal(t, archive[2:6], recorder.Body.Bytes())
+		require.Equal(t, fmt.Sprintf("bytes 2-5/%d", len(archive)), recorder.Header().Get("Content-Range"))
+
+		// A resumed download of an archive that has since changed gets the whole archive.
+		recorder = serve(http.MethodGet, path, http.Header{
+			"Range":    []string{"bytes=2-5"},
+			"If-Range": []string{`"other"`},
+		})
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Equal(t, archive, recorder.Body.Bytes())
+	})
+}
+
+func TestDownloadLegalHold_Filtered(t *testing.T) {
+	p, api := setupTestPlugin(t)
+	p.KVStore = kvstore.NewKVStore(p.Client)
+	mockAuditLog(t, api)
+
+	api.On("HasPermissionTo", "test_user_id", model.PermissionManageSystem).Return(true)
+	api.On("LogInfo", mock.Anything).Maybe()
+
+	local, err := filestore.NewFileBackend(filestore.FileBackendSettings{
+		DriverName: "local",
+		Directory:  t.TempDir(),
+	})
+	require.NoError(t, err)
+	p.FileBackend = legalhold.NewEncryptedFileBackend(local, nil)
+
+	lh := legalHoldModel.LegalHold{ID: model.NewId(), Name: "filtered"}
+	lhJSON, err := json.Marshal(lh)
+	require.NoError(t, err)
+	api.On("KVGet", "kvstore_legal_hold_"+lh.ID).Return(lhJSON, nil)
+
+	channelID := model.NewId()
+	otherChannelID := model.NewId()
+	postID := model.NewId()
+	selected := fmt.Sprintf("%s/%s/messages/messages-1000-%s.csv", lh.BasePath(), channelID, postID)
+	other := fmt.Sprintf("%s/%s/messages/messages-1000-%s.csv", lh.BasePath(), otherChannelID, postID)
+	for _, filePath := range []string{lh.IndexPath(), selected, other} {
+		_, err = p.FileBackend.WriteFile(strings.NewReader("{}"), filePath)
+		require.NoError(t, err)
+	}
+	_, err = p.FileBackend.WriteFile(strings.NewReader(fmt.Sprintf(`{%q: "selected", %q: "other"}`, selected, other)), lh.BasePath()+"/hashes.json")
+	require.NoError(t, err)
+
+	serve := func(query string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/download?%s", lh.ID, query), nil)
+		require.NoError(t, err)
+		req.Header.Add("Mattermost-User-Id", "test_user_id")
+
+		recorder := httptest.NewRecorder()
+		p.ServeHTTP(nil, recorder, req)
+		return recorder
+	}
+
+	recorder := serve("start_time=2000&end_time=1000")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = serve("channel_id=" + channelID)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.Bytes()
+	zipReader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+
+	entries := make(map[string][]byte)
+	for _, file := range zipReader.File {
+		reader, openErr := file.Open()
+		require.NoError(t, openErr)
+		data, readErr := io.ReadAll(reader)
+		require.NoError(t, readErr)
+		entries[file.Name] = data
+	}
+
+	require.Len(t, entries, 4)
+	require.Contains(t, entries, lh.IndexPath())
+	require.Contains(t, entries, selected)
+
+	var hashes legalHoldModel.HashList
+	require.NoError(t, json.Unmarshal(entries[lh.BasePath()+"/hashes.json"], &hashes))
+	require.Equal(t, legalHoldModel.HashList{selected: "selected"}, hashes)
+
+	var subset legalHoldModel.LegalHoldSubsetIndex
+	require.NoError(t, json.Unmarshal(entries[lh.BasePath()+"/"+legalHoldModel.SubsetIndexFileName], &subset))
+	require.Equal(t, []string{channelID}, subset.Filter.ChannelIDs)
+	require.ElementsMatch(t, []string{lh.IndexPath(), selected}, subset.Files)
+}
+
+func TestDownloadTokens(t *testing.T) {
+	p, api := setupTestPlugin(t)
+	p.KVStore = kvstore.NewKVStore(p.Client)
+
+	api.On("HasPermissionTo", "test_user_id", model.PermissionManageSystem).Return(true)
+	api.On("LogInfo", mock.Anything).Maybe()
+	api.On("GetConfig").Return(&model.Config{})
+
+	// Keep the KV store in memory, so that download tokens can be created, used and revoked, and
+	// their audit events listed.
+	kv := mockInMemoryKV(api)
+
+	local, err := filestore.NewFileBackend(filestore.FileBackendSettings{
+		DriverName: "local",
+		Directory:  t.TempDir(),
+	})
+	require.NoError(t, err)
+	p.FileBackend = legalhold.NewEncryptedFileBackend(local, nil)
+
+	lh := legalHoldModel.LegalHold{ID: model.NewId(), Name: "shared"}
+	kv.set(t, "kvstore_legal_hold_"+lh.ID, lh)
+
+	_, err = p.FileBackend.WriteFile(strings.NewReader("{}"), lh.IndexPath())
+	require.NoError(t, err)
+
+	serve := func(method, path, userID string, body io.Reader) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, body)
+		require.NoError(t, err)
+		if userID != "" {
+			req.Header.Add("Mattermost-User-Id", userID)
+		}
+
+		recorder := httptest.NewRecorder()
+		p.ServeHTTP(nil, recorder, req)
+		return recorder
+	}
+
+	tokensPath := fmt.Sprintf("/api/v1/legalholds/%s/download_tokens", lh.ID)
+
+	api.On("LogInfo", "Created legal hold download token",
+		"token_id", mock.Anything, "legal_hold_id", lh.ID, "user_id", "test_user_id", "expires_at", mock.Anything).Once()
+	recorder := serve(http.MethodPost, tokensPath, "test_user_id", strings.NewReader(`{"description": "Outside counsel"}`))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var created legalHoldModel.CreatedDownloadToken
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&created))
+	require.NotEmpty(t, created.Token)
+	require.Equal(t, "Outside counsel", created.Description)
+	require.Equal(t, "test_user_id", created.CreatorID)
+
+	recorder = serve(http.MethodPost, tokensPath, "test_user_id", strings.NewReader(`{"expires_at": 1}`))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// The token downloads the legal hold without a session, and each use is logged.
+	downloadPath := "/api/v1/download?token=" + created.Token
+	api.On("LogInfo", "Legal hold downloaded with a download token",
+		"remote_addr", mock.Anything, "user_agent", mock.Anything, "token_hash", legalHoldModel.DownloadTokenHash(created.Token), "token_id", created.ID, "legal_hold_id", lh.ID, "query", mock.Anything).Twice()
+
+	recorder = serve(http.MethodGet, downloadPath, "", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "application/zip", recorder.Header().Get("Content-Type"))
+	require.Equal(t, "no-referrer", recorder.Header().Get("Referrer-Policy"))
+	require.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/download", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Legal-Hold-Token", created.Token)
+	recorder = httptest.NewRecorder()
+	p.ServeHTTP(nil, recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = serve(http.MethodGet, tokensPath, "test_user_id", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var tokens []legalHoldModel.DownloadToken
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&tokens))
+	require.Len(t, tokens, 1)
+	require.Equal(t, 2, tokens[0].UseCount)
+	require.NotZero(t, tokens[0].LastUsedAt)
+
+	// A forged token is rejected, logging only its hash.
+	api.On("LogWarn", "Rejected legal hold download token",
+		"remote_addr", mock.Anything, "user_agent", mock.Anything, "token_hash", legalHoldModel.DownloadTokenHash(created.Token+"x"), "reason", legalHoldModel.ErrInvalidDownloadToken.Error()).Once()
+	recorder = serve(http.MethodGet, downloadPath+"x", "", nil)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	recorder = serve(http.MethodGet, "/api/v1/download", "", nil)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	// A revoked token is rejected.
+	api.On("LogInfo", "Revoked legal hold download token",
+		"token_id", created.ID, "legal_hold_id", lh.ID, "user_id", "test_user_id").Once()
+	recorder = serve(http.MethodPost, fmt.Sprintf("%s/%s/revoke", tokensPath, created.ID), "test_user_id", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var revoked legalHoldModel.DownloadToken
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&revoked))
+	require.NotZero(t, revoked.RevokedAt)
+	require.Equal(t, "test_user_id", revoked.RevokedBy)
+
+	api.On("LogWarn", "Rejected legal hold download token",
+		"remote_addr", mock.Anything, "user_agent", mock.Anything, "token_hash", legalHoldModel.DownloadTokenHash(created.Token), "token_id", created.ID, "legal_hold_id", lh.ID, "reason", "the token was revoked").Once()
+	recorder = serve(http.MethodGet, downloadPath, "", nil)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	recorder = serve(http.MethodPost, fmt.Sprintf("%s/%s/revoke", tokensPath, model.NewId()), "test_user_id", nil)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// Every use of the token is in the audit log, without the token itself.
+	recorder = serve(http.MethodGet, fmt.Sprintf("/api/v1/audit?legal_hold_id=%s&action=%s", lh.ID, legalHoldModel.AuditActionDownloadWithToken), "test_user_id", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var page legalHoldModel.AuditEventPage
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&page))
+	require.Len(t, page.Events, 3)
+	require.False(t, page.HasNext)
+
+	var outcomes []legalHoldModel.AuditOutcome
+	for _, event := range page.Events {
+		outcomes = append(outcomes, event.Outcome)
+		require.Empty(t, event.ActorID)
+		require.Equal(t, created.ID, event.Details["token_id"])
+		require.Equal(t, legalHoldModel.DownloadTokenHash(created.Token), event.Details["token_hash"])
+		require.NotContains(t, event.Details["query"], created.Token)
+	}
+	require.ElementsMatch(t, []legalHoldModel.AuditOutcome{
+		legalHoldModel.AuditOutcomeSuccess,
+		legalHoldModel.AuditOutcomeSuccess,
+		legalHoldModel.AuditOutcomeFailure,
+	}, outcomes)
+
+	recorder = serve(http.MethodGet, "/api/v1/audit?outcome=denied", "test_user_id", nil)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	api.AssertExpectations(t)
+}
+
+func TestReleaseLegalHold(t *testing.T) {
+	p, api := setupTestPlugin(t)
+	p.KVStore = kvstore.NewKVStore(p.Client)
+	p.setConfiguration(&config.Configuration{ReleaseGracePeriodDays: 3})
+
+	api.On("HasPermissionTo", "test_user_id", model.PermissionManageSystem).Return(true)
+	api.On("LogInfo", mock.Anything).Maybe()
+	api.On("LogError", mock.Anything).Maybe()
+	api.On("GetConfig").Return(&model.Config{})
+	kv := mockInMemoryKV(api)
+
+	mockJob := &MockLegalHoldJob{}
+	p.legalHoldJob = mockJob
+
+	lh := legalHoldModel.LegalHold{
+		ID:          model.NewId(),
+		Name:        "released",
+		DisplayName: "Released",
+		UserIDs:     []string{model.NewId()},
+	}
+	lhKey := "kvstore_legal_hold_" + lh.ID
+	kv.set(t, lhKey, lh)
+
+	serve := func(method, path string, body io.Reader) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, fmt.Sprintf("/api/v1/legalholds/%s%s", lh.ID, path), body)
+		require.NoError(t, err)
+		req.Header.Add("Mattermost-User-Id", "test_user_id")
+
+		recorder := httptest.NewRecorder()
+		p.ServeHTTP(nil, recorder, req)
+		return recorder
+	}
+
+	// Releasing stops the execution of the legal hold, but keeps its data.
+	mockJob.On("CancelLegalHold", lh.ID).Return(nil).Twice()
+	recorder := serve(http.MethodPost, "/release", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var released legalHoldModel.LegalHold
+	kv.get(t, lhKey, &released)
+	require.True(t, released.IsReleased())
+	require.Equal(t, "test_user_id", released.ReleasedBy)
+
+	recorder = serve(http.MethodPost, "/release", nil)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+
+	update := fmt.Sprintf(`{"id": %q, "display_name": "Updated", "user_ids": [%q]}`, lh.ID, lh.UserIDs[0])
+	recorder = serve(http.MethodPut, "", strings.NewReader(update))
+	require.Equal(t, http.StatusConflict, recorder.Code)
+
+	// A released legal hold can be restored during the grace period.
+	recorder = serve(http.MethodPost, "/restore", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var restored legalHoldModel.LegalHold
+	kv.get(t, lhKey, &restored)
+	require.False(t, restored.IsReleased())
+	require.Empty(t, restored.ReleasedBy)
+
+	recorder = serve(http.MethodPost, "/restore", nil)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+
+	// Once the grace period has passed, it can no longer be restored.
+	recorder = serve(http.MethodPost, "/release", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	kv.get(t, lhKey, &released)
+	released.ReleasedAt -= (3 * 24 * time.Hour).Milliseconds()
+	kv.set(t, lhKey, released)
+
+	recorder = serve(http.MethodPost, "/restore", nil)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+
+	mockJob.AssertExpectations(t)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/utils"
+)
+
+// auditErrorMaxLength is the longest error message of a failed action kept in its AuditEvent.
+const auditErrorMaxLength = 1024
+
+type auditEventContextKey struct{}
+
+// audited wraps the handler of an administrative action so that an AuditEvent of the action is
+// saved once the handler returns. The actor, client, legal hold and other path parameters, and the
+// outcome are taken from the request and response, and the handler can add to the AuditEvent of
+// the request with auditEventFromRequest.
+func (p *Plugin) audited(action model.AuditAction, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		event := model.NewAuditEvent(action, mattermostModel.NewId(), mattermostModel.GetMillis())
+		event.ActorID = r.Header.Get("Mattermost-User-ID")
+		event.ClientIP = utils.GetIPAddress(r, p.trustedProxyIPHeader())
+		event.UserAgent = r.UserAgent()
+		for name, value := range mux.Vars(r) {
+			if name == "legalhold_id" {
+				event.LegalHoldID = value
+			} else {
+				event.Details[name] = value
+			}
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: w}
+		handler(writer, r.WithContext(context.WithValue(r.Context(), auditEventContextKey{}, &event)))
+
+		event.StatusCode = writer.statusCode
+		if event.StatusCode == 0 {
+			event.StatusCode = http.StatusOK
+		}
+
+		event.Outcome = model.AuditOutcomeSuccess
+		if writer.failed {
+			event.Outcome = model.AuditOutcomeFailure
+			event.Error = strings.TrimSpace(writer.errorMessage.String())
+		}
+
+		if err := p.KVStore.SaveAuditEvent(event); err != nil {
+			p.API.LogError("Failed to save audit event",
+				"action", string(event.Action),
+				"legal_hold_id", event.LegalHoldID,
+				"actor_id", event.ActorID,
+				"outcome", string(event.Outcome),
+				"error", err.Error(),
+			)
+		}
+	}
+}
+
+// auditEventFromRequest returns the AuditEvent of the action the request is for, so that its
+// handler can record what the action changed. Requests that are not audited get an AuditEvent
+// that is not saved.
+func auditEventFromRequest(r *http.Request) *model.AuditEvent {
+	if event, ok := r.Context().Value(auditEventContextKey{}).(*model.AuditEvent); ok {
+		return event
+	}
+
+	event := model.NewAuditEvent("", "", 0)
+	return &event
+}
+
+// trustedProxyIPHeader returns the headers set by the proxies of the server with the address of
+// the client, as configured for the server itself.
+func (p *Plugin) trustedProxyIPHeader() []string {
+	config := p.API.GetConfig()
+	if config == nil {
+		return nil
+	}
+
+	return config.ServiceSettings.TrustedProxyIPHeader
+}
+
+// auditResponseWriter records the status of a response, and the error message of a failed one.
+// A response fails if its status is an error, or if an error is written once it has started, as
+// happens when a download cannot be completed.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	statusCode   int
+	failed       bool
+	errorMessage strings.Builder
+}
+
+func (w *auditResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+
+	if statusCode >= http.StatusBadRequest {
+		w.failed = true
+		w.errorMessage.Reset()
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+
+	if w.failed && w.errorMessage.Len() < auditErrorMaxLength {
+		w.errorMessage.Write(b[:min(len(b), auditErrorMaxLength-w.errorMessage.Len())])
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// listAuditEvents serves a page of the audit log, most recent first, filtered by the query
+// parameters of the request.
+func (p *Plugin) listAuditEvents(w http.ResponseWriter, r *http.Request) {
+	query, err := model.NewAuditEventQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := p.KVStore.GetAuditEvents(query)
+	if err != nil {
+		http.Error(w, "an error occurred fetching the audit events", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	b, jsonErr := json.Marshal(page)
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
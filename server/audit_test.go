@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	legalHoldModel "github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/kvstore"
+)
+
+func TestAuditLog_UpdateLegalHold(t *testing.T) {
+	p, api := setupTestPlugin(t)
+	p.KVStore = kvstore.NewKVStore(p.Client)
+
+	api.On("HasPermissionTo", "test_user_id", model.PermissionManageSystem).Return(true)
+	api.On("LogInfo", mock.Anything).Maybe()
+	api.On("LogError", mock.Anything).Maybe()
+	api.On("GetConfig").Return(&model.Config{
+		ServiceSettings: model.ServiceSettings{TrustedProxyIPHeader: []string{"X-Forwarded-For"}},
+	})
+
+	var auditEvents []legalHoldModel.AuditEvent
+	api.On("KVSetWithOptions", mock.MatchedBy(func(key string) bool {
+		return strings.HasPrefix(key, "kvstore_audit_event_")
+	}), mock.AnythingOfType("[]uint8"), mock.AnythingOfType("model.PluginKVSetOptions")).
+		Run(func(args mock.Arguments) {
+			var event legalHoldModel.AuditEvent
+			require.NoError(t, json.Unmarshal(args.Get(1).([]byte), &event))
+			auditEvents = append(auditEvents, event)
+		}).Return(true, nil)
+	mockAuditIndexes(api)
+
+	lh := legalHoldModel.LegalHold{
+		ID:          model.NewId(),
+		Name:        "audited",
+		DisplayName: "Before",
+		UserIDs:     []string{model.NewId()},
+		Secret:      "secret",
+	}
+	lhJSON, err := json.Marshal(lh)
+	require.NoError(t, err)
+
+	updated := lh.DeepCopy()
+	updated.DisplayName = "After"
+	updatedJSON, err := json.Marshal(updated)
+	require.NoError(t, err)
+
+	api.On("KVGet", "kvstore_legal_hold_"+lh.ID).Return(lhJSON, nil).Once()
+	api.On("KVSetWithOptions", "kvstore_legal_hold_"+lh.ID, mock.AnythingOfType("[]uint8"), mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+	api.On("KVGet", "kvstore_legal_hold_"+lh.ID).Return(updatedJSON, nil).Once()
+
+	update := func(body string) int {
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/legalholds/%s", lh.ID), strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Add("Mattermost-User-Id", "test_user_id")
+		req.Header.Add("X-Forwarded-For", "192.168.0.1, 10.0.0.1")
+		req.RemoteAddr = "10.0.0.1:1234"
+
+		recorder := httptest.NewRecorder()
+		p.ServeHTTP(nil, recorder, req)
+		return recorder.Code
+	}
+
+	status := update(fmt.Sprintf(`{"id": %q, "display_name": "After", "user_ids": [%q]}`, lh.ID, lh.UserIDs[0]))
+	require.Equal(t, http.StatusOK, status)
+
+	status = update(fmt.Sprintf(`{"id": %q, "display_name": "After"}`, model.NewId()))
+	require.Equal(t, http.StatusBadRequest, status)
+
+	require.Len(t, auditEvents, 2)
+
+	// The update records the legal hold before and after it, without its secret.
+	event := auditEvents[0]
+	require.Equal(t, legalHoldModel.AuditActionUpdateLegalHold, event.Action)
+	require.Equal(t, legalHoldModel.AuditOutcomeSuccess, event.Outcome)
+	require.Equal(t, "test_user_id", event.ActorID)
+	require.Equal(t, lh.ID, event.LegalHoldID)
+	require.Equal(t, "192.168.0.1", event.ClientIP)
+	require.Equal(t, "Before", event.Before.DisplayName)
+	require.Equal(t, "After", event.After.DisplayName)
+	require.Empty(t, event.Before.Secret)
+	require.Empty(t, event.After.Secret)
+
+	// A rejected update is recorded as a failure, with the error returned.
+	event = auditEvents[1]
+	require.Equal(t, legalHoldModel.AuditOutcomeFailure, event.Outcome)
+	require.Equal(t, http.StatusBadRequest, event.StatusCode)
+	require.Equal(t, "invalid LegalHold ID", event.Error)
+	require.Nil(t, event.Before)
+	require.Nil(t, event.After)
+
+	api.AssertExpectations(t)
+}
@@ -1,6 +1,21 @@
 package config
 
-import "github.com/mattermost/mattermost-server/v6/model"
+import (
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// DefaultReleaseGracePeriodDays is the number of days the data of a released legal hold is kept
+// for if no valid grace period is configured.
+const DefaultReleaseGracePeriodDays = 7
+
+// DefaultCustodianNotice is the legal hold notice sent to custodians if none is configured.
+const DefaultCustodianNotice = "You have been identified as a custodian of information relevant to a legal matter, " +
+	"and your Mattermost messages and files are subject to a legal hold. " +
+	"Do not delete, alter or discard any messages, files or other information related to the matter until you are told that the legal hold has been released. " +
+	"Please acknowledge that you have read and understood this notice."
 
 // Configuration captures the plugin's external Configuration as exposed in the Mattermost server
 // Configuration, as well as values computed from the Configuration. Any public fields will be
@@ -16,6 +31,18 @@ import "github.com/mattermost/mattermost-server/v6/model"
 type Configuration struct {
 	TimeOfDay                     string
 	EnableFilestoreConnectionTest bool
+	LegalHoldWorkers              int
+	ChannelExportWorkers          int
+	PreservationMode              string
+	EncryptionKey                 string
+	ReleaseGracePeriodDays        int
+	KeepCapturedCustodians        bool
+	EnableCustodianNotices        bool
+	CustodianNotice               string
+	CustodianReminderIntervalDays int
+	ExecutionRetries              int
+	FailureAlertChannelID         string
+	MetricsToken                  string
 	AmazonS3BucketSettings        AmazonS3BucketSettings
 }
 
@@ -30,3 +57,33 @@ func (c *Configuration) Clone() *Configuration {
 	clone := *c
 	return &clone
 }
+
+// ReleaseGracePeriod returns how long the data of a released legal hold is kept for, during which
+// the legal hold can be restored.
+func (c *Configuration) ReleaseGracePeriod() time.Duration {
+	days := c.ReleaseGracePeriodDays
+	if days < 1 {
+		days = DefaultReleaseGracePeriodDays
+	}
+
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// CustodianNoticeMessage returns the legal hold notice sent to the custodians of legal holds.
+func (c *Configuration) CustodianNoticeMessage() string {
+	if strings.TrimSpace(c.CustodianNotice) == "" {
+		return DefaultCustodianNotice
+	}
+
+	return c.CustodianNotice
+}
+
+// CustodianReminderInterval returns how long to wait for a custodian to acknowledge the legal hold
+// notice before reminding them of it again, or zero if custodians are not reminded.
+func (c *Configuration) CustodianReminderInterval() time.Duration {
+	if c.CustodianReminderIntervalDays < 1 {
+		return 0
+	}
+
+	return time.Duration(c.CustodianReminderIntervalDays) * 24 * time.Hour
+}
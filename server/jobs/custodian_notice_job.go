@@ -0,0 +1,151 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/mattermost/mattermost-plugin-api/cluster"
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/config"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/legalhold"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/kvstore"
+)
+
+// CustodianNoticeInterval is the time between two runs of the custodian notice job, which sends
+// the notice to the custodians who have joined the groups of legal holds since the last run, and
+// the reminders that have become due.
+const CustodianNoticeInterval = time.Hour
+
+// CustodianNoticeJobInterface defines the interface that both real and mock implementations of the
+// custodian notice job must satisfy.
+type CustodianNoticeJobInterface interface {
+	Job
+	NotifyLegalHold(lh model.LegalHold)
+	Acknowledge(legalHoldID, userID, postID string) (*model.CustodianNotice, error)
+}
+
+// CustodianNoticeJob sends the legal hold notice to the custodians of legal holds and reminds them
+// of it until they acknowledge it, if custodian notices are enabled in the plugin configuration.
+type CustodianNoticeJob struct {
+	mux      sync.Mutex
+	job      *cluster.Job
+	enabled  bool
+	settings legalhold.NoticeSettings
+
+	id       string
+	papi     plugin.API
+	client   *pluginapi.Client
+	notifier *legalhold.Notifier
+}
+
+func NewCustodianNoticeJob(id string, api plugin.API, client *pluginapi.Client, kvstore kvstore.KVStore, botID, pluginID string) (*CustodianNoticeJob, error) {
+	return &CustodianNoticeJob{
+		id:       id,
+		papi:     api,
+		client:   client,
+		notifier: legalhold.NewNotifier(api, kvstore, botID, pluginID),
+	}, nil
+}
+
+func (j *CustodianNoticeJob) GetID() string {
+	return j.id
+}
+
+// OnConfigurationChange is called by the job manager whenever the plugin settings have changed.
+// The job only runs when custodian notices are enabled.
+func (j *CustodianNoticeJob) OnConfigurationChange(cfg *config.Configuration) error {
+	j.client.Log.Debug("CustodianNoticeJob: Configuration Changed", "enabled", cfg.EnableCustodianNotices)
+
+	if err := j.Stop(time.Second * 10); err != nil {
+		j.client.Log.Error("Error stopping custodian notice job for config change", "err", err)
+	}
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	j.enabled = cfg.EnableCustodianNotices
+	j.settings = legalhold.NoticeSettings{
+		Message:          cfg.CustodianNoticeMessage(),
+		ReminderInterval: cfg.CustodianReminderInterval(),
+	}
+
+	if !j.enabled {
+		return nil
+	}
+
+	job, err := cluster.Schedule(j.papi, j.id, cluster.MakeWaitForRoundedInterval(CustodianNoticeInterval), j.run)
+	if err != nil {
+		return fmt.Errorf("cannot start custodian notice job: %w", err)
+	}
+	j.job = job
+
+	return nil
+}
+
+// Stop stops the current job (if any).
+func (j *CustodianNoticeJob) Stop(_ time.Duration) error {
+	j.mux.Lock()
+	job := j.job
+	j.job = nil
+	j.mux.Unlock()
+
+	if job == nil {
+		return nil
+	}
+
+	if err := job.Close(); err != nil {
+		return fmt.Errorf("error closing job: %w", err)
+	}
+
+	return nil
+}
+
+func (j *CustodianNoticeJob) getSettings() (legalhold.NoticeSettings, bool) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	return j.settings, j.enabled
+}
+
+// NotifyLegalHold sends the notice to the custodians of the legal hold who have not been sent it
+// yet in the background, so that custodians added to a legal hold do not wait for the next run of
+// the job.
+func (j *CustodianNoticeJob) NotifyLegalHold(lh model.LegalHold) {
+	settings, enabled := j.getSettings()
+	if !enabled {
+		return
+	}
+
+	go func() {
+		if err := j.notifier.NotifyLegalHold(context.Background(), lh, settings, mattermostModel.GetMillis()); err != nil {
+			j.client.Log.Error("Failed to notify the custodians of the legal hold", "legal_hold_id", lh.ID, "err", err)
+		}
+	}()
+}
+
+// Acknowledge records that the custodian indicated by userID acknowledged the notice of the legal
+// hold indicated by legalHoldID from the post indicated by postID. Notices sent before custodian
+// notices were disabled can still be acknowledged.
+func (j *CustodianNoticeJob) Acknowledge(legalHoldID, userID, postID string) (*model.CustodianNotice, error) {
+	return j.notifier.Acknowledge(legalHoldID, userID, postID, mattermostModel.GetMillis())
+}
+
+// run is called by the cluster job scheduler to send the notices and reminders that are due.
+func (j *CustodianNoticeJob) run() {
+	settings, enabled := j.getSettings()
+	if !enabled {
+		return
+	}
+
+	if err := j.notifier.NotifyAll(context.Background(), settings, mattermostModel.GetMillis()); err != nil {
+		j.client.Log.Error("Failed to notify the custodians of legal holds", "err", err)
+		return
+	}
+
+	j.client.Log.Debug("Custodian notices sent")
+}
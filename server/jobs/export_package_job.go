@@ -0,0 +1,127 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/mattermost/mattermost-server/v6/shared/filestore"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/config"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/legalhold"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/kvstore"
+)
+
+// ExportPackageStaleTimeout is the time after which an export package that has not finished, nor
+// made progress, is reported as failed, as its build was interrupted.
+const ExportPackageStaleTimeout = 15 * time.Minute
+
+// ErrExportPackageJobStopped is returned when an export package is requested while the job is
+// stopped.
+var ErrExportPackageJobStopped = errors.New("export package job is stopped")
+
+// ExportPackageJobInterface defines the interface that both real and mock implementations of the
+// export package job must satisfy.
+type ExportPackageJobInterface interface {
+	Job
+	StartExportPackage(legalHold model.LegalHold) (*model.ExportPackage, error)
+}
+
+// ExportPackageJob builds export packages of legal holds in the background, on the node that they
+// were requested from.
+type ExportPackageJob struct {
+	mux    sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	builds sync.WaitGroup
+
+	id      string
+	client  *pluginapi.Client
+	kvstore kvstore.KVStore
+	builder *legalhold.PackageBuilder
+}
+
+func NewExportPackageJob(id string, api plugin.API, client *pluginapi.Client, kvstore kvstore.KVStore, filebackend filestore.FileBackend) (*ExportPackageJob, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ExportPackageJob{
+		ctx:     ctx,
+		cancel:  cancel,
+		id:      id,
+		client:  client,
+		kvstore: kvstore,
+		builder: legalhold.NewPackageBuilder(api, kvstore, filebackend),
+	}, nil
+}
+
+func (j *ExportPackageJob) GetID() string {
+	return j.id
+}
+
+// OnConfigurationChange is called by the job manager whenever the plugin settings have changed.
+// Export packages are only built on request, so there is nothing to schedule.
+func (j *ExportPackageJob) OnConfigurationChange(_ *config.Configuration) error {
+	return nil
+}
+
+// Stop cancels the builds running on this node and waits for them to exit. The cancelled export
+// packages are saved as failed. If the timeout is exceeded an error is returned.
+func (j *ExportPackageJob) Stop(timeout time.Duration) error {
+	j.mux.Lock()
+	j.cancel()
+	j.mux.Unlock()
+
+	exited := make(chan struct{})
+	go func() {
+		j.builds.Wait()
+		close(exited)
+	}()
+
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("waiting on export package builds to stop timed out after %s", timeout.String())
+	}
+}
+
+// StartExportPackage saves a new export package of the legal hold and starts building it in the
+// background, returning it while it is still pending.
+func (j *ExportPackageJob) StartExportPackage(legalHold model.LegalHold) (*model.ExportPackage, error) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if j.ctx.Err() != nil {
+		return nil, ErrExportPackageJobStopped
+	}
+
+	pkg := model.NewExportPackage(mattermostModel.NewId(), legalHold.ID, mattermostModel.GetMillis())
+	if err := j.kvstore.SaveExportPackage(pkg); err != nil {
+		return nil, err
+	}
+
+	j.builds.Add(1)
+	go func(pkg model.ExportPackage) {
+		defer j.builds.Done()
+
+		j.client.Log.Info("Building legal hold export package", "legal_hold_id", legalHold.ID, "package_id", pkg.ID)
+		if err := j.builder.Build(j.ctx, legalHold, &pkg); err != nil {
+			j.client.Log.Error("Failed to build legal hold export package", "legal_hold_id", legalHold.ID, "package_id", pkg.ID, "err", err)
+			return
+		}
+		j.client.Log.Info("Built legal hold export package",
+			"legal_hold_id", legalHold.ID,
+			"package_id", pkg.ID,
+			"file_count", pkg.FileCount,
+			"size", pkg.Size,
+		)
+	}(pkg)
+
+	return &pkg, nil
+}
@@ -2,6 +2,7 @@ package jobs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/config"
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/legalhold"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/metrics"
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/kvstore"
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/sqlstore"
@@ -23,6 +25,20 @@ import (
 
 const runOnceJobKeyPrefix = "legal_hold_run_"
 
+// legalHoldJobMinWait is the shortest time between two runs of the legal hold job.
+const legalHoldJobMinWait = time.Minute
+
+const (
+	// executionRetryBaseBackoff is how long to wait before retrying a failed legal hold execution
+	// for the first time. The wait doubles with each retry, up to executionRetryMaxBackoff.
+	executionRetryBaseBackoff = 30 * time.Second
+	executionRetryMaxBackoff  = 10 * time.Minute
+)
+
+// CancelLegalHoldClusterEventID identifies the cluster event broadcast to cancel the execution of a
+// legal hold on whichever node is running it.
+const CancelLegalHoldClusterEventID = "legal_hold_cancel"
+
 type LegalHoldRunOnceProps struct {
 	LegalHold model.LegalHold
 	ForceRun  bool
@@ -34,25 +50,33 @@ type LegalHoldJob struct {
 	runner   *runInstance
 	settings *LegalHoldJobSettings
 
+	// executions holds the cancel functions of the legal hold executions running on this node.
+	executions map[string]context.CancelFunc
+
 	id          string
 	papi        plugin.API
 	client      *pluginapi.Client
 	sqlstore    *sqlstore.SQLStore
 	kvstore     kvstore.KVStore
 	filebackend filestore.FileBackend
+	botID       string
+	metrics     *metrics.Metrics
 
 	onceScheduler *cluster.JobOnceScheduler
 }
 
-func NewLegalHoldJob(id string, api plugin.API, client *pluginapi.Client, sqlstore *sqlstore.SQLStore, kvstore kvstore.KVStore, filebackend filestore.FileBackend) (*LegalHoldJob, error) {
+func NewLegalHoldJob(id string, api plugin.API, client *pluginapi.Client, sqlstore *sqlstore.SQLStore, kvstore kvstore.KVStore, filebackend filestore.FileBackend, botID string, metrics *metrics.Metrics) (*LegalHoldJob, error) {
 	return &LegalHoldJob{
 		settings:      &LegalHoldJobSettings{},
+		executions:    make(map[string]context.CancelFunc),
 		id:            id,
 		papi:          api,
 		client:        client,
 		sqlstore:      sqlstore,
 		kvstore:       kvstore,
 		filebackend:   filebackend,
+		botID:         botID,
+		metrics:       metrics,
 		onceScheduler: cluster.GetJobOnceScheduler(api),
 	}, nil
 }
@@ -97,7 +121,7 @@ func (j *LegalHoldJob) start(settings *LegalHoldJobSettings) error {
 		return fmt.Errorf("cannot start Legal Hold job: %w", err)
 	}
 	j.job = job
-	j.client.Log.Debug("Legal Hold daily job scheduled")
+	j.client.Log.Debug("Legal Hold job scheduled")
 
 	if err := j.onceScheduler.SetCallback(j.runOnce); err != nil {
 		return fmt.Errorf("could not set callback for runOnce jobs: %w", err)
@@ -150,7 +174,8 @@ func (j *LegalHoldJob) getSettings() *LegalHoldJobSettings {
 }
 
 // nextWaitInterval is called by the cluster job scheduler to determine how long to wait until the
-// next job run.
+// next job run. The job runs daily at the configured time of day, to purge the released legal
+// holds that are due, and earlier whenever a legal hold is due to be executed before then.
 func (j *LegalHoldJob) nextWaitInterval(now time.Time, metaData cluster.JobMetadata) time.Duration {
 	settings := j.getSettings()
 
@@ -160,6 +185,16 @@ func (j *LegalHoldJob) nextWaitInterval(now time.Time, metaData cluster.JobMetad
 	}
 
 	next := settings.CalcNext(lastFinished, settings.TimeOfDay)
+	if due, ok := j.nextDueTime(); ok && due.Before(next) {
+		next = due
+	}
+
+	// A legal hold that fails to execute stays due, so it is retried after a short wait rather
+	// than straight away.
+	if earliest := lastFinished.Add(legalHoldJobMinWait); next.Before(earliest) {
+		next = earliest
+	}
+
 	delta := next.Sub(now)
 
 	j.client.Log.Debug("Legal Hold Job next run scheduled", "last", lastFinished.Format(FullLayout), "next", next.Format(FullLayout), "wait", delta.String())
@@ -167,6 +202,33 @@ func (j *LegalHoldJob) nextWaitInterval(now time.Time, metaData cluster.JobMetad
 	return delta
 }
 
+// nextDueTime returns the earliest time at which one of the legal holds that are still being
+// executed needs executing, or false if there are none.
+func (j *LegalHoldJob) nextDueTime() (time.Time, bool) {
+	legalHolds, err := j.kvstore.GetAllLegalHolds()
+	if err != nil {
+		j.client.Log.Error("Failed to fetch legal holds to schedule the Legal Hold Job", "err", err)
+		return time.Time{}, false
+	}
+
+	var due int64
+	for _, lh := range legalHolds {
+		if lh.IsReleased() || lh.IsFinished() {
+			continue
+		}
+
+		if lhDue := lh.NextDueTime(); due == 0 || lhDue < due {
+			due = lhDue
+		}
+	}
+
+	if due == 0 {
+		return time.Time{}, false
+	}
+
+	return time.UnixMilli(due), true
+}
+
 func (j *LegalHoldJob) RunAll() {
 	j.run()
 }
@@ -180,6 +242,9 @@ func (j *LegalHoldJob) RunSingleLegalHold(legalHoldID string) error {
 	if lh == nil {
 		return fmt.Errorf("legal hold not found: %s", legalHoldID)
 	}
+	if lh.IsReleased() {
+		return fmt.Errorf("legal hold is released: %s", legalHoldID)
+	}
 
 	legalHold := lh.DeepCopy()
 
@@ -200,6 +265,43 @@ func (j *LegalHoldJob) RunSingleLegalHold(legalHoldID string) error {
 	return nil
 }
 
+// CancelLegalHold cancels the execution of the legal hold indicated by legalHoldID, whichever node
+// of the cluster it is running on, as well as any pending ad-hoc run of it. The execution stops
+// after the batch it is working on, keeping its checkpoint so that the next run resumes from it.
+func (j *LegalHoldJob) CancelLegalHold(legalHoldID string) error {
+	j.onceScheduler.Cancel(runOnceJobKeyPrefix + legalHoldID)
+	j.cancelExecution(legalHoldID)
+
+	err := j.papi.PublishPluginClusterEvent(
+		mattermostModel.PluginClusterEvent{Id: CancelLegalHoldClusterEventID, Data: []byte(legalHoldID)},
+		mattermostModel.PluginClusterEventSendOptions{SendType: mattermostModel.PluginClusterEventSendTypeReliable},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to publish legal hold cancellation: %w", err)
+	}
+
+	return nil
+}
+
+// OnCancelLegalHoldEvent is called when another node of the cluster broadcasts the cancellation of
+// the legal hold indicated by legalHoldID.
+func (j *LegalHoldJob) OnCancelLegalHoldEvent(legalHoldID string) {
+	j.cancelExecution(legalHoldID)
+}
+
+// cancelExecution cancels the execution of the legal hold indicated by legalHoldID if it is
+// running on this node.
+func (j *LegalHoldJob) cancelExecution(legalHoldID string) {
+	j.mux.Lock()
+	cancel, ok := j.executions[legalHoldID]
+	j.mux.Unlock()
+
+	if ok {
+		j.client.Log.Info("Cancelling legal hold execution", "legal_hold_id", legalHoldID)
+		cancel()
+	}
+}
+
 func (j *LegalHoldJob) GetRunningLegalHolds() ([]string, error) {
 	jobs, err := j.onceScheduler.ListScheduledJobs()
 	if err != nil {
@@ -228,6 +330,7 @@ func (j *LegalHoldJob) runOnce(_ string, props any) {
 	j.client.Log.Info("Running runOnce legal hold", "legal_hold_id", runOnceProps.LegalHold.ID)
 
 	j.runWith(
+		context.Background(),
 		[]model.LegalHold{runOnceProps.LegalHold},
 		runOnceProps.ForceRun,
 	)
@@ -235,7 +338,8 @@ func (j *LegalHoldJob) runOnce(_ string, props any) {
 	j.client.Log.Info("Finished running runOnce legal hold", "legal_hold_id", runOnceProps.LegalHold.ID)
 }
 
-// run is called by the cluster job scheduler to run the legal hold job for all legal holds daily.
+// run is called by the cluster job scheduler to run the legal hold job for all the legal holds
+// that are due.
 func (j *LegalHoldJob) run() {
 	j.mux.Lock()
 	oldRunner := j.runner
@@ -249,7 +353,7 @@ func (j *LegalHoldJob) run() {
 	j.client.Log.Info("Processing all Legal Holds")
 
 	exitSignal := make(chan struct{})
-	_, canceller := context.WithCancel(context.Background())
+	ctx, canceller := context.WithCancel(context.Background())
 	runner := &runInstance{
 		canceller:  canceller,
 		exitSignal: exitSignal,
@@ -274,10 +378,60 @@ func (j *LegalHoldJob) run() {
 		return
 	}
 
-	j.runWith(legalHolds, false)
+	// Released legal holds are no longer executed, and their data is purged once their grace
+	// period has passed.
+	gracePeriod := j.getSettings().ReleaseGracePeriod
+	activeHolds := make([]model.LegalHold, 0, len(legalHolds))
+	for _, lh := range legalHolds {
+		if lh.IsReleased() {
+			j.purgeIfDue(lh, gracePeriod)
+			continue
+		}
+		activeHolds = append(activeHolds, lh)
+	}
+
+	j.runWith(ctx, activeHolds, false)
+}
+
+// purgeIfDue purges the data of the released legal hold if its grace period has passed, recording
+// the purge in the audit log. The legal hold is fetched again first, in case it has been restored.
+func (j *LegalHoldJob) purgeIfDue(lh model.LegalHold, gracePeriod time.Duration) {
+	now := mattermostModel.GetMillis()
+	if !lh.IsDueForPurge(now, gracePeriod.Milliseconds()) {
+		return
+	}
+
+	stored, err := j.kvstore.GetLegalHoldByID(lh.ID)
+	if err != nil {
+		j.client.Log.Error("Failed to fetch the released legal hold prior to purging it", "legal_hold_id", lh.ID, "err", err)
+		return
+	}
+	if !stored.IsDueForPurge(now, gracePeriod.Milliseconds()) {
+		return
+	}
+
+	event := model.NewAuditEvent(model.AuditActionPurgeLegalHold, mattermostModel.NewId(), now)
+	event.LegalHoldID = stored.ID
+	event.SetBefore(*stored)
+	event.Outcome = model.AuditOutcomeSuccess
+
+	if _, err = legalhold.Purge(j.kvstore, j.filebackend, *stored, now); err != nil {
+		j.client.Log.Error("Failed to purge released legal hold", "legal_hold_id", stored.ID, "err", err)
+		event.Outcome = model.AuditOutcomeFailure
+		event.Error = err.Error()
+	} else {
+		j.client.Log.Info("Purged released legal hold", "legal_hold_id", stored.ID, "legal_hold_name", stored.Name, "released_at", stored.ReleasedAt)
+		j.metrics.DeleteLegalHold(stored.ID)
+	}
+
+	if err = j.kvstore.SaveAuditEvent(event); err != nil {
+		j.client.Log.Error("Failed to save audit event", "action", string(event.Action), "legal_hold_id", stored.ID, "err", err)
+	}
 }
 
-func (j *LegalHoldJob) runWith(legalHolds []model.LegalHold, forceRun bool) {
+// runWith executes the legal holds provided, up to LegalHoldWorkers of them in parallel, stopping
+// early if ctx is cancelled.
+func (j *LegalHoldJob) runWith(ctx context.Context, legalHolds []model.LegalHold, forceRun bool) {
 	j.client.Log.Info("Running Legal Hold Job")
 
 	var settings *LegalHoldJobSettings
@@ -285,54 +439,219 @@ func (j *LegalHoldJob) runWith(legalHolds []model.LegalHold, forceRun bool) {
 	settings = j.settings.Clone()
 	j.mux.Unlock()
 
+	queue := make(chan model.LegalHold)
+
+	var wg sync.WaitGroup
+	for i := 0; i < max(settings.LegalHoldWorkers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for lh := range queue {
+				j.runLegalHold(ctx, lh, forceRun, settings)
+			}
+		}()
+	}
+
+enqueue:
 	for _, lh := range legalHolds {
-		now := mattermostModel.GetMillis()
+		select {
+		case queue <- lh:
+		case <-ctx.Done():
+			j.client.Log.Info("Legal Hold Job cancelled")
+			break enqueue
+		}
+	}
+	close(queue)
+	wg.Wait()
+}
 
-		legalHold := lh.DeepCopy()
+// runLegalHold runs as many executions of the legal hold as are needed to bring it up to date,
+// until ctx or the execution of the legal hold itself is cancelled. It does nothing if the legal
+// hold is already running on this node.
+func (j *LegalHoldJob) runLegalHold(ctx context.Context, lh model.LegalHold, forceRun bool, settings *LegalHoldJobSettings) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-		for {
-			if legalHold.IsFinished() {
-				j.client.Log.Debug(fmt.Sprintf("Legal Hold %s has ended and therefore does not need another execution.", legalHold.ID))
-				break
-			}
+	j.mux.Lock()
+	if _, running := j.executions[lh.ID]; running {
+		j.mux.Unlock()
+		j.client.Log.Info("Legal hold is already running on this node; skipping", "legal_hold_id", lh.ID)
+		return
+	}
+	j.executions[lh.ID] = cancel
+	j.mux.Unlock()
+
+	defer func() {
+		j.mux.Lock()
+		delete(j.executions, lh.ID)
+		j.mux.Unlock()
+	}()
+
+	now := mattermostModel.GetMillis()
 
-			if !forceRun && !legalHold.NeedsExecuting(now) {
+	legalHold := lh.DeepCopy()
+
+	// attempt is the number of times the current execution of the legal hold has been retried.
+	attempt := 0
+
+	for {
+		if legalHold.IsReleased() {
+			j.client.Log.Debug(fmt.Sprintf("Legal Hold %s has been released and therefore is no longer executed.", legalHold.ID))
+			break
+		}
+
+		if legalHold.IsFinished() {
+			j.client.Log.Debug(fmt.Sprintf("Legal Hold %s has ended and therefore does not need another execution.", legalHold.ID))
+			break
+		}
+
+		if !forceRun && !legalHold.NeedsExecuting(now) {
+			if legalHold.CurrentStatus(false) == model.LegalHoldStatusFailed {
+				j.client.Log.Debug(fmt.Sprintf("Legal Hold %s failed to execute and is tried again at %s.", legalHold.ID, time.UnixMilli(legalHold.NextDueTime()).UTC().Format(FullLayout)))
+			} else {
 				j.client.Log.Debug(fmt.Sprintf("Legal Hold %s is not yet ready to be executed again.", legalHold.ID))
-				break
 			}
-			if legalHold.LastExecutionEndedAt >= now {
-				j.client.Log.Debug(fmt.Sprintf("Legal Hold %s was already executed after the current time.", legalHold.ID))
+			break
+		}
+		if legalHold.LastExecutionEndedAt >= now {
+			j.client.Log.Debug(fmt.Sprintf("Legal Hold %s was already executed after the current time.", legalHold.ID))
+			break
+		}
+
+		j.client.Log.Debug(fmt.Sprintf("Creating Legal Hold Execution for legal hold: %s", legalHold.ID))
+		lhe := legalhold.NewExecution(legalHold, j.papi, j.sqlstore, j.kvstore, j.filebackend)
+		lhe.ChannelWorkers = settings.ChannelExportWorkers
+		lhe.KeepCapturedCustodians = settings.KeepCapturedCustodians
+		lhe.Metrics = j.metrics
+		j.resumeFromCheckpoint(&lhe)
+
+		if !forceRun && attempt == 0 {
+			j.metrics.SetQueueLag(legalHold.ID, time.Duration(mattermostModel.GetMillis()-legalHold.NextDueTime())*time.Millisecond)
+		}
+
+		updatedLH, err := lhe.Execute(ctx, now)
+		if err != nil {
+			if ctx.Err() != nil {
+				j.client.Log.Info("Legal hold execution cancelled", "legal_hold_id", legalHold.ID)
 				break
 			}
 
-			j.client.Log.Debug(fmt.Sprintf("Creating Legal Hold Execution for legal hold: %s", legalHold.ID))
-			lhe := legalhold.NewExecution(legalHold, j.papi, j.sqlstore, j.kvstore, j.filebackend)
+			if errors.Is(err, legalhold.ErrExecutionLocked) {
+				j.client.Log.Info("Legal hold is being executed by another node; skipping", "legal_hold_id", legalHold.ID)
+				break
+			}
 
-			if updatedLH, err := lhe.Execute(now); err != nil {
-				j.client.Log.Error("An error occurred executing the legal hold.", err)
-			} else {
-				// Update legal hold with the new execution details (last execution time and last message)
-				// Also set it to IDLE again since the execution has ended.
-				stored, err := j.kvstore.GetLegalHoldByID(legalHold.ID)
-				if err != nil {
-					j.client.Log.Error("Failed to fetch the LegalHold prior to updating", err)
-					break
-				}
-				legalHold = stored.DeepCopy()
-				legalHold.LastExecutionEndedAt = updatedLH.LastExecutionEndedAt
-				legalHold.HasMessages = updatedLH.HasMessages
-
-				newLH, err := j.kvstore.UpdateLegalHold(legalHold, *stored)
-				if err != nil {
-					j.client.Log.Error("Failed to update legal hold", err)
-					break
+			if attempt < settings.ExecutionRetries {
+				attempt++
+				j.metrics.IncExecutionRetries(legalHold.ID)
+				backoff := executionRetryBackoff(attempt)
+				j.client.Log.Warn("An error occurred executing the legal hold; retrying.", "legal_hold_id", legalHold.ID, "attempt", attempt, "backoff", backoff.String(), "err", err)
+
+				select {
+				case <-time.After(backoff):
+					continue
+				case <-ctx.Done():
+					j.client.Log.Info("Legal hold execution cancelled", "legal_hold_id", legalHold.ID)
 				}
-				j.client.Log.Info("legal hold executed", "legal_hold_id", newLH.ID, "legal_hold_name", newLH.Name)
+				break
 			}
+
+			j.client.Log.Error("An error occurred executing the legal hold.", "legal_hold_id", legalHold.ID, "attempts", attempt+1, "err", err)
+			j.metrics.IncExecutionFailures(legalHold.ID)
+			j.recordFailure(legalHold.ID, err, settings)
+			break
 		}
+
+		// Update legal hold with the new execution details (last execution time and last message)
+		// Also set it to IDLE again since the execution has ended.
+		stored, err := j.kvstore.GetLegalHoldByID(legalHold.ID)
+		if err != nil {
+			j.client.Log.Error("Failed to fetch the LegalHold prior to updating", err)
+			break
+		}
+		legalHold = stored.DeepCopy()
+		legalHold.LastExecutionEndedAt = updatedLH.LastExecutionEndedAt
+		legalHold.HasMessages = updatedLH.HasMessages
+		legalHold.ClearFailures()
+		attempt = 0
+
+		newLH, err := j.kvstore.UpdateLegalHold(legalHold, *stored)
+		if err != nil {
+			j.client.Log.Error("Failed to update legal hold", err)
+			break
+		}
+		j.client.Log.Info("legal hold executed", "legal_hold_id", newLH.ID, "legal_hold_name", newLH.Name)
+	}
+}
+
+// executionRetryBackoff returns how long to wait before retrying the execution of a legal hold for
+// the attempt-th time, doubling from executionRetryBaseBackoff up to executionRetryMaxBackoff.
+func executionRetryBackoff(attempt int) time.Duration {
+	backoff := executionRetryBaseBackoff
+	for i := 1; i < attempt && backoff < executionRetryMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, executionRetryMaxBackoff)
+}
+
+// recordFailure records on the legal hold indicated by legalHoldID that it failed to execute with
+// the error provided, even after being retried, and alerts the failure alert channel, if any.
+func (j *LegalHoldJob) recordFailure(legalHoldID string, execErr error, settings *LegalHoldJobSettings) {
+	stored, err := j.kvstore.GetLegalHoldByID(legalHoldID)
+	if err != nil {
+		j.client.Log.Error("Failed to fetch the LegalHold prior to recording its failure", "legal_hold_id", legalHoldID, "err", err)
+		return
+	}
+
+	legalHold := stored.DeepCopy()
+	legalHold.RecordFailure(execErr, mattermostModel.GetMillis())
+
+	failedLH, err := j.kvstore.UpdateLegalHold(legalHold, *stored)
+	if err != nil {
+		j.client.Log.Error("Failed to record the failure of the legal hold", "legal_hold_id", legalHoldID, "err", err)
+		return
+	}
+
+	if settings.FailureAlertChannelID == "" {
+		return
+	}
+
+	if err = legalhold.SendFailureAlert(j.papi, j.botID, settings.FailureAlertChannelID, *failedLH); err != nil {
+		j.client.Log.Error("Failed to send the legal hold failure alert", "legal_hold_id", legalHoldID, "channel_id", settings.FailureAlertChannelID, "err", err)
+	}
+}
+
+// resumeFromCheckpoint restores the Execution from the checkpoint left behind by an interrupted
+// run of the same legal hold, if there is one covering the same window.
+func (j *LegalHoldJob) resumeFromCheckpoint(lhe *legalhold.Execution) {
+	checkpoint, err := j.kvstore.GetExecutionCheckpoint(lhe.LegalHold.ID)
+	if err != nil {
+		j.client.Log.Error("Failed to fetch the execution checkpoint; starting the execution over", "legal_hold_id", lhe.LegalHold.ID, "err", err)
+		return
+	}
+
+	if checkpoint == nil {
+		return
 	}
 
-	_ = settings
+	resumed, err := lhe.Resume(checkpoint)
+	if err != nil {
+		j.client.Log.Error("Failed to restore the execution from its checkpoint; starting the execution over", "legal_hold_id", lhe.LegalHold.ID, "err", err)
+		return
+	}
+
+	if !resumed {
+		j.client.Log.Info("Ignoring execution checkpoint for a different window", "legal_hold_id", lhe.LegalHold.ID)
+		return
+	}
+
+	j.client.Log.Info("Resuming legal hold execution from checkpoint",
+		"legal_hold_id", lhe.LegalHold.ID,
+		"start_time", lhe.ExecutionStartTime,
+		"end_time", lhe.ExecutionEndTime,
+		"checkpoint_time", checkpoint.UpdateAt,
+	)
 }
 
 type runInstance struct {
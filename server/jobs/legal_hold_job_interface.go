@@ -14,4 +14,6 @@ type LegalHoldJobInterface interface {
 	Stop(timeout time.Duration) error
 	RunAll()
 	RunSingleLegalHold(id string) error
+	CancelLegalHold(id string) error
+	OnCancelLegalHoldEvent(id string)
 }
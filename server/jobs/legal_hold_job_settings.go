@@ -2,6 +2,7 @@ package jobs
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/config"
@@ -10,28 +11,49 @@ import (
 const (
 	FullLayout      = "Jan 2, 2006 3:04pm -0700"
 	TimeOfDayLayout = "3:04pm -0700"
+
+	DefaultLegalHoldWorkers     = 2
+	DefaultChannelExportWorkers = 4
+	DefaultExecutionRetries     = 3
 )
 
 type LegalHoldJobSettings struct {
-	EnableLegalHoldJobs bool
-	TimeOfDay           time.Time
+	EnableLegalHoldJobs    bool
+	TimeOfDay              time.Time
+	LegalHoldWorkers       int
+	ChannelExportWorkers   int
+	ReleaseGracePeriod     time.Duration
+	KeepCapturedCustodians bool
+	ExecutionRetries       int
+	FailureAlertChannelID  string
 }
 
 func (s *LegalHoldJobSettings) Clone() *LegalHoldJobSettings {
 	return &LegalHoldJobSettings{
-		EnableLegalHoldJobs: s.EnableLegalHoldJobs,
-		TimeOfDay:           s.TimeOfDay,
+		EnableLegalHoldJobs:    s.EnableLegalHoldJobs,
+		TimeOfDay:              s.TimeOfDay,
+		LegalHoldWorkers:       s.LegalHoldWorkers,
+		ChannelExportWorkers:   s.ChannelExportWorkers,
+		ReleaseGracePeriod:     s.ReleaseGracePeriod,
+		KeepCapturedCustodians: s.KeepCapturedCustodians,
+		ExecutionRetries:       s.ExecutionRetries,
+		FailureAlertChannelID:  s.FailureAlertChannelID,
 	}
 }
 
 func (s *LegalHoldJobSettings) String() string {
-	return fmt.Sprintf("enabled=%T, tod=%s", s.EnableLegalHoldJobs, s.TimeOfDay.Format(TimeOfDayLayout))
+	return fmt.Sprintf("enabled=%T, tod=%s, legal_hold_workers=%d, channel_export_workers=%d, release_grace_period=%s, keep_captured_custodians=%t, execution_retries=%d, failure_alert_channel_id=%s",
+		s.EnableLegalHoldJobs, s.TimeOfDay.Format(TimeOfDayLayout), s.LegalHoldWorkers, s.ChannelExportWorkers, s.ReleaseGracePeriod, s.KeepCapturedCustodians, s.ExecutionRetries, s.FailureAlertChannelID)
 }
 
 func parseLegaHoldJobSettings(cfg *config.Configuration) (*LegalHoldJobSettings, error) {
 	if cfg == nil {
 		return &LegalHoldJobSettings{
-			EnableLegalHoldJobs: false,
+			EnableLegalHoldJobs:  false,
+			LegalHoldWorkers:     DefaultLegalHoldWorkers,
+			ChannelExportWorkers: DefaultChannelExportWorkers,
+			ReleaseGracePeriod:   (&config.Configuration{}).ReleaseGracePeriod(),
+			ExecutionRetries:     DefaultExecutionRetries,
 		}, nil
 	}
 
@@ -40,9 +62,30 @@ func parseLegaHoldJobSettings(cfg *config.Configuration) (*LegalHoldJobSettings,
 		return nil, fmt.Errorf("cannot parse `Time of day`: %w", err)
 	}
 
+	legalHoldWorkers := cfg.LegalHoldWorkers
+	if legalHoldWorkers < 1 {
+		legalHoldWorkers = DefaultLegalHoldWorkers
+	}
+
+	channelExportWorkers := cfg.ChannelExportWorkers
+	if channelExportWorkers < 1 {
+		channelExportWorkers = DefaultChannelExportWorkers
+	}
+
+	executionRetries := cfg.ExecutionRetries
+	if executionRetries < 0 {
+		executionRetries = DefaultExecutionRetries
+	}
+
 	return &LegalHoldJobSettings{
-		EnableLegalHoldJobs: true,
-		TimeOfDay:           tod,
+		EnableLegalHoldJobs:    true,
+		TimeOfDay:              tod,
+		LegalHoldWorkers:       legalHoldWorkers,
+		ChannelExportWorkers:   channelExportWorkers,
+		ReleaseGracePeriod:     cfg.ReleaseGracePeriod(),
+		KeepCapturedCustodians: cfg.KeepCapturedCustodians,
+		ExecutionRetries:       executionRetries,
+		FailureAlertChannelID:  strings.TrimSpace(cfg.FailureAlertChannelID),
 	}, nil
 }
 
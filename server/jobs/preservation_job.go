@@ -0,0 +1,134 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/mattermost/mattermost-plugin-api/cluster"
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/mattermost/mattermost-server/v6/shared/filestore"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/config"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/legalhold"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/sqlstore"
+)
+
+// PreservationCheckInterval is the time between two preservation checks. It is short compared to
+// the daily legal hold job so that retention policy changes are caught before the next run of the
+// data retention job of the server.
+const PreservationCheckInterval = time.Hour
+
+// PreservationJobInterface defines the interface that both real and mock implementations of the
+// preservation job must satisfy.
+type PreservationJobInterface interface {
+	Job
+	RunCheck(ctx context.Context) (*model.PreservationStatus, error)
+}
+
+// PreservationJob periodically checks the data retention policies of the server against the
+// active legal holds, in the PreservationMode set in the plugin configuration.
+type PreservationJob struct {
+	mux                    sync.Mutex
+	job                    *cluster.Job
+	mode                   model.PreservationMode
+	keepCapturedCustodians bool
+
+	id        string
+	papi      plugin.API
+	client    *pluginapi.Client
+	preserver *legalhold.Preserver
+}
+
+// NewPreservationJob creates the PreservationJob, which captures the content of legal holds with
+// capture in PreservationModeBlock.
+func NewPreservationJob(id string, api plugin.API, client *pluginapi.Client, sqlstore *sqlstore.SQLStore, kvstore kvstore.KVStore, filebackend filestore.FileBackend, capture legalhold.CaptureFunc) (*PreservationJob, error) {
+	return &PreservationJob{
+		mode:      model.PreservationModeOff,
+		id:        id,
+		papi:      api,
+		client:    client,
+		preserver: legalhold.NewPreserver(api, sqlstore, kvstore, filebackend, capture),
+	}, nil
+}
+
+func (j *PreservationJob) GetID() string {
+	return j.id
+}
+
+// OnConfigurationChange is called by the job manager whenever the plugin settings have changed.
+// The job is only scheduled while preservation is on.
+func (j *PreservationJob) OnConfigurationChange(cfg *config.Configuration) error {
+	mode := model.ParsePreservationMode(cfg.PreservationMode)
+	j.client.Log.Debug("PreservationJob: Configuration Changed", "mode", mode)
+
+	if err := j.Stop(time.Second * 10); err != nil {
+		j.client.Log.Error("Error stopping preservation job for config change", "err", err)
+	}
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	j.mode = mode
+	j.keepCapturedCustodians = cfg.KeepCapturedCustodians
+
+	if mode == model.PreservationModeOff {
+		return nil
+	}
+
+	job, err := cluster.Schedule(j.papi, j.id, cluster.MakeWaitForRoundedInterval(PreservationCheckInterval), j.run)
+	if err != nil {
+		return fmt.Errorf("cannot start preservation job: %w", err)
+	}
+	j.job = job
+
+	return nil
+}
+
+// Stop stops the current job (if any).
+func (j *PreservationJob) Stop(_ time.Duration) error {
+	j.mux.Lock()
+	job := j.job
+	j.job = nil
+	j.mux.Unlock()
+
+	if job == nil {
+		return nil
+	}
+
+	if err := job.Close(); err != nil {
+		return fmt.Errorf("error closing job: %w", err)
+	}
+
+	return nil
+}
+
+// RunCheck runs a preservation check immediately and returns its outcome.
+func (j *PreservationJob) RunCheck(ctx context.Context) (*model.PreservationStatus, error) {
+	j.mux.Lock()
+	mode := j.mode
+	keepCapturedCustodians := j.keepCapturedCustodians
+	j.mux.Unlock()
+
+	return j.preserver.Check(ctx, mode, keepCapturedCustodians, mattermostModel.GetMillis())
+}
+
+// run is called by the cluster job scheduler to run the preservation check.
+func (j *PreservationJob) run() {
+	status, err := j.RunCheck(context.Background())
+	if err != nil {
+		j.client.Log.Error("Preservation check failed", "err", err)
+		return
+	}
+
+	j.client.Log.Debug("Preservation check completed",
+		"mode", status.Mode,
+		"legal_hold_count", len(status.HeldContent),
+		"conflict_count", len(status.Conflicts),
+	)
+}
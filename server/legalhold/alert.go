@@ -0,0 +1,58 @@
+package legalhold
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	mm_model "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+// SendFailureAlert posts an alert that the legal hold failed to execute, even after being retried,
+// to the channel indicated by channelID from the bot indicated by botID. The failure must already
+// be recorded on the legal hold.
+func SendFailureAlert(papi plugin.API, botID, channelID string, lh model.LegalHold) error {
+	if lh.CurrentStatus(false) != model.LegalHoldStatusFailed {
+		return errors.New("the legal hold has not failed")
+	}
+
+	post := &mm_model.Post{
+		UserId:    botID,
+		ChannelId: channelID,
+	}
+	mm_model.ParseSlackAttachment(post, []*mm_model.SlackAttachment{{
+		Color: "#d24b4e",
+		Title: "Legal hold failed: " + lh.DisplayName,
+		Text:  fmt.Sprintf("The legal hold `%s` failed to execute, and will be tried again by the Legal Hold task.", lh.Name),
+		Fields: []*mm_model.SlackAttachmentField{
+			{
+				Title: "Error",
+				Value: lh.LastError,
+			},
+			{
+				Title: "Failed at",
+				Value: time.UnixMilli(lh.LastFailedAt).UTC().Format(time.RFC1123),
+				Short: true,
+			},
+			{
+				Title: "Failed runs in a row",
+				Value: lh.ConsecutiveFailures,
+				Short: true,
+			},
+			{
+				Title: "Next attempt",
+				Value: time.UnixMilli(lh.NextDueTime()).UTC().Format(time.RFC1123),
+				Short: true,
+			},
+		},
+	}})
+
+	if _, appErr := papi.CreatePost(post); appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package legalhold
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+func TestSendFailureAlert(t *testing.T) {
+	api := &plugintest.API{}
+	botID := mattermostModel.NewId()
+	channelID := mattermostModel.NewId()
+
+	lh := model.LegalHold{
+		ID:          mattermostModel.NewId(),
+		Name:        "matter",
+		DisplayName: "Matter",
+	}
+	lh.RecordFailure(errors.New("failed to write file"), 1000)
+	lh.RecordFailure(errors.New("failed to read posts"), 2000)
+
+	var alert *mattermostModel.Post
+	api.On("CreatePost", mock.MatchedBy(func(post *mattermostModel.Post) bool {
+		return post.ChannelId == channelID && post.UserId == botID
+	})).Run(func(args mock.Arguments) {
+		alert = args.Get(0).(*mattermostModel.Post)
+	}).Return(&mattermostModel.Post{}, nil).Once()
+
+	require.NoError(t, SendFailureAlert(api, botID, channelID, lh))
+
+	attachments := alert.Attachments()
+	require.Len(t, attachments, 1)
+	require.Equal(t, "Legal hold failed: Matter", attachments[0].Title)
+	require.Len(t, attachments[0].Fields, 4)
+	require.Equal(t, "failed to read posts", attachments[0].Fields[0].Value)
+	require.EqualValues(t, 2, attachments[0].Fields[2].Value)
+	require.Equal(t, time.UnixMilli(lh.NextDueTime()).UTC().Format(time.RFC1123), attachments[0].Fields[3].Value)
+
+	api.On("CreatePost", mock.Anything).Return(nil, mattermostModel.NewAppError("CreatePost", "app.post.save.app_error", nil, "", 400)).Once()
+	require.Error(t, SendFailureAlert(api, botID, channelID, lh))
+
+	// A legal hold that has not failed is not alerted about.
+	lh.ClearFailures()
+	require.Error(t, SendFailureAlert(api, botID, channelID, lh))
+
+	api.AssertExpectations(t)
+}
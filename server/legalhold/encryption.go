@@ -0,0 +1,556 @@
+package legalhold
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/mattermost/mattermost-server/v6/shared/filestore"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+const (
+	// encryptionMagic starts every encrypted file, so that encrypted and plaintext files can be
+	// told apart in legal holds that were encrypted after they started. It is followed by a random
+	// salt, from which the key of the file is derived.
+	encryptionMagic = "MMLHENC2"
+	// encryptionFileSaltSize is the size of the random salt that follows encryptionMagic.
+	encryptionFileSaltSize = 32
+	// encryptionNoncePrefixSize is the size of the zero prefix of the nonce of each chunk. The
+	// prefix needs no randomness, as every file has a key of its own.
+	encryptionNoncePrefixSize = 7
+	// encryptionChunkSize is the size of the plaintext of each encrypted chunk but the last.
+	encryptionChunkSize = 64 * 1024
+)
+
+// fileKeyInfo binds the keys derived for files to their use.
+const fileKeyInfo = "legal_hold_file_key"
+
+// blobNameKeyInfo binds the keys derived for naming blobs to their use.
+const blobNameKeyInfo = "legal_hold_blob_name_key"
+
+// ErrEncryptionKeyRequired is returned when reading or writing the files of an encrypted legal
+// hold without an encryption key configured.
+var ErrEncryptionKeyRequired = errors.New("the legal hold is encrypted, but no encryption key is configured")
+
+// encryptedFileBackend wraps a filestore.FileBackend, transparently encrypting the files written
+// under the base path of a legal hold with the data key of that legal hold, and decrypting them
+// when they are read. Files outside legal holds, such as the attachments they copy, are left
+// alone.
+type encryptedFileBackend struct {
+	filestore.FileBackend
+
+	// masterKey wraps the data keys of the legal holds. Without one, nothing is encrypted, but
+	// encrypted legal holds are still protected from being written to in plaintext.
+	masterKey []byte
+
+	mu sync.Mutex
+	// dataKeys caches the data key of each legal hold by base path, or nil if the legal hold is
+	// not encrypted.
+	dataKeys map[string][]byte
+}
+
+// NewEncryptedFileBackend wraps backend so that the files of legal holds are encrypted with
+// masterKey, which may be nil to disable encryption.
+func NewEncryptedFileBackend(backend filestore.FileBackend, masterKey []byte) filestore.FileBackend {
+	return &encryptedFileBackend{
+		FileBackend: backend,
+		masterKey:   masterKey,
+		dataKeys:    make(map[string][]byte),
+	}
+}
+
+// UnwrapFileBackend returns the backend wrapped by NewEncryptedFileBackend, which reads and writes
+// files exactly as they are stored, or backend itself if it is not wrapped.
+func UnwrapFileBackend(backend filestore.FileBackend) filestore.FileBackend {
+	if encrypted, ok := backend.(*encryptedFileBackend); ok {
+		return encrypted.FileBackend
+	}
+
+	return backend
+}
+
+// legalHoldBasePath returns the base path of the legal hold that the file at filePath belongs
+// to, and false if the file does not belong to a legal hold or is its EncryptionInfo.
+func legalHoldBasePath(filePath string) (string, bool) {
+	parts := strings.SplitN(path.Clean(filePath), "/", 3)
+	if len(parts) < 3 || parts[0] != "legal_hold" || parts[2] == model.EncryptionInfoFileName {
+		return "", false
+	}
+
+	return parts[0] + "/" + parts[1], true
+}
+
+// dataKey returns the data key of the legal hold at basePath, or nil if it is not encrypted. If
+// create is true and a master key is configured, a data key is created for a legal hold that
+// does not have one yet.
+func (b *encryptedFileBackend) dataKey(basePath string, create bool) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if key, ok := b.dataKeys[basePath]; ok && (key != nil || !create || b.masterKey == nil) {
+		return key, nil
+	}
+
+	infoPath := path.Join(basePath, model.EncryptionInfoFileName)
+	exists, err := b.FileBackend.FileExists(infoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if encryption info exists: %w", err)
+	}
+
+	if exists {
+		data, readErr := b.FileBackend.ReadFile(infoPath)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read encryption info: %w", readErr)
+		}
+
+		var info model.EncryptionInfo
+		if err = json.Unmarshal(data, &info); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal encryption info: %w", err)
+		}
+
+		if b.masterKey == nil {
+			return nil, ErrEncryptionKeyRequired
+		}
+
+		key, keyErr := info.DataKey(b.masterKey)
+		if keyErr != nil {
+			return nil, keyErr
+		}
+
+		b.dataKeys[basePath] = key
+		return key, nil
+	}
+
+	if !create || b.masterKey == nil {
+		b.dataKeys[basePath] = nil
+		return nil, nil
+	}
+
+	info, key, err := model.NewEncryptionInfo(b.masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal encryption info: %w", err)
+	}
+
+	if _, err = b.FileBackend.WriteFile(bytes.NewReader(data), infoPath); err != nil {
+		return nil, fmt.Errorf("failed to write encryption info: %w", err)
+	}
+
+	b.dataKeys[basePath] = key
+	return key, nil
+}
+
+// WriteFile writes the file, encrypting it if it belongs to an encrypted legal hold. It returns
+// the number of plaintext bytes written.
+func (b *encryptedFileBackend) WriteFile(fr io.Reader, filePath string) (int64, error) {
+	basePath, ok := legalHoldBasePath(filePath)
+	if !ok {
+		return b.FileBackend.WriteFile(fr, filePath)
+	}
+
+	key, err := b.dataKey(basePath, true)
+	if err != nil {
+		return 0, err
+	}
+	if key == nil {
+		return b.FileBackend.WriteFile(fr, filePath)
+	}
+
+	counter := &countingReader{reader: fr}
+	encrypted, err := newEncryptingReader(counter, key)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = b.FileBackend.WriteFile(encrypted, filePath); err != nil {
+		return 0, err
+	}
+
+	return counter.count, nil
+}
+
+// AppendFile appends to the file. Encrypted files are rewritten whole, as their chunks cannot
+// be extended.
+func (b *encryptedFileBackend) AppendFile(fr io.Reader, filePath string) (int64, error) {
+	basePath, ok := legalHoldBasePath(filePath)
+	if !ok {
+		return b.FileBackend.AppendFile(fr, filePath)
+	}
+
+	key, err := b.dataKey(basePath, true)
+	if err != nil {
+		return 0, err
+	}
+	if key == nil {
+		return b.FileBackend.AppendFile(fr, filePath)
+	}
+
+	existing, err := b.ReadFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+
+	appended, err := io.ReadAll(fr)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = b.WriteFile(io.MultiReader(bytes.NewReader(existing), bytes.NewReader(appended)), filePath); err != nil {
+		return 0, err
+	}
+
+	return int64(len(appended)), nil
+}
+
+// CopyFile copies the file, encrypting the copy if it belongs to an encrypted legal hold.
+func (b *encryptedFileBackend) CopyFile(oldPath, newPath string) error {
+	basePath, ok := legalHoldBasePath(newPath)
+	if !ok {
+		return b.FileBackend.CopyFile(oldPath, newPath)
+	}
+
+	key, err := b.dataKey(basePath, true)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return b.FileBackend.CopyFile(oldPath, newPath)
+	}
+
+	reader, err := b.Reader(oldPath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	_, err = b.WriteFile(reader, newPath)
+	return err
+}
+
+// ReadFile reads the file, decrypting it if it is encrypted.
+func (b *encryptedFileBackend) ReadFile(filePath string) ([]byte, error) {
+	data, err := b.FileBackend.ReadFile(filePath)
+	if err != nil || len(data) < len(encryptionMagic) || string(data[:len(encryptionMagic)]) != encryptionMagic {
+		return data, err
+	}
+
+	key, err := b.readKey(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := newDecryptingReader(bytes.NewReader(data), key)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(decrypted)
+}
+
+// Reader opens the file, decrypting it if it is encrypted. The reader of an encrypted file can
+// only be read sequentially.
+func (b *encryptedFileBackend) Reader(filePath string) (filestore.ReadCloseSeeker, error) {
+	reader, err := b.FileBackend.Reader(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, len(encryptionMagic))
+	n, err := io.ReadFull(reader, magic)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		reader.Close()
+		return nil, err
+	}
+
+	if n < len(magic) || string(magic) != encryptionMagic {
+		if _, err = reader.Seek(0, io.SeekStart); err != nil {
+			reader.Close()
+			return nil, err
+		}
+		return reader, nil
+	}
+
+	key, err := b.readKey(filePath)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+
+	decrypted, err := newDecryptingReader(io.MultiReader(bytes.NewReader(magic), reader), key)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+
+	return &sequentialReadCloser{Reader: decrypted, closer: reader}, nil
+}
+
+// RemoveDirectory removes the directory, forgetting the data keys of the legal holds in it.
+func (b *encryptedFileBackend) RemoveDirectory(dirPath string) error {
+	b.mu.Lock()
+	for basePath := range b.dataKeys {
+		if basePath == path.Clean(dirPath) || strings.HasPrefix(basePath, path.Clean(dirPath)+"/") {
+			delete(b.dataKeys, basePath)
+		}
+	}
+	b.mu.Unlock()
+
+	return b.FileBackend.RemoveDirectory(dirPath)
+}
+
+// readKey returns the data key to decrypt the encrypted file at filePath.
+func (b *encryptedFileBackend) readKey(filePath string) ([]byte, error) {
+	basePath, ok := legalHoldBasePath(filePath)
+	if !ok {
+		return nil, fmt.Errorf("the file %s is encrypted, but does not belong to a legal hold", filePath)
+	}
+
+	key, err := b.dataKey(basePath, false)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("the file %s is encrypted, but its legal hold has no encryption info", filePath)
+	}
+
+	return key, nil
+}
+
+// fileKey derives the key of a file from the data key of its legal hold and the random salt of
+// the file, so that no two files are encrypted with the same key.
+func fileKey(dataKey, salt []byte) ([]byte, error) {
+	key, err := hkdf.Key(sha256.New, dataKey, salt, fileKeyInfo, len(dataKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive file key: %w", err)
+	}
+
+	return key, nil
+}
+
+// blobNameKey returns the key with which the blobs of the legal hold at basePath are named, derived
+// from its data key, or nil if backend does not encrypt the legal hold.
+func blobNameKey(backend filestore.FileBackend, basePath string) ([]byte, error) {
+	encrypted, ok := backend.(*encryptedFileBackend)
+	if !ok {
+		return nil, nil
+	}
+
+	dataKey, err := encrypted.dataKey(basePath, true)
+	if err != nil || dataKey == nil {
+		return nil, err
+	}
+
+	key, err := hkdf.Key(sha256.New, dataKey, nil, blobNameKeyInfo, len(dataKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive blob name key: %w", err)
+	}
+
+	return key, nil
+}
+
+// chunkNonce returns the nonce of the chunk at index counter, flagging the last chunk so that a
+// stream truncated at a chunk boundary fails to decrypt.
+func chunkNonce(counter uint32, last bool) []byte {
+	nonce := make([]byte, encryptionNoncePrefixSize, encryptionNoncePrefixSize+5)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+func newChunkGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// encryptingReader encrypts the stream read from its source in chunks of encryptionChunkSize,
+// after a header made of encryptionMagic and a random salt. The chunks are encrypted with a key
+// derived from the data key and the salt, under nonces made of a zero prefix and the index of
+// the chunk.
+type encryptingReader struct {
+	source  *bufio.Reader
+	gcm     cipher.AEAD
+	counter uint32
+	chunk   []byte
+	pending []byte
+	done    bool
+}
+
+func newEncryptingReader(source io.Reader, dataKey []byte) (*encryptingReader, error) {
+	salt := make([]byte, encryptionFileSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate file salt: %w", err)
+	}
+
+	key, err := fileKey(dataKey, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newChunkGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptingReader{
+		source:  bufio.NewReader(source),
+		gcm:     gcm,
+		chunk:   make([]byte, encryptionChunkSize),
+		pending: append([]byte(encryptionMagic), salt...),
+	}, nil
+}
+
+func (r *encryptingReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(r.source, r.chunk)
+		last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !last {
+			return 0, err
+		}
+		if !last {
+			if _, peekErr := r.source.Peek(1); errors.Is(peekErr, io.EOF) {
+				last = true
+			} else if peekErr != nil {
+				return 0, peekErr
+			}
+		}
+
+		r.pending = r.gcm.Seal(nil, chunkNonce(r.counter, last), r.chunk[:n], nil)
+		r.counter++
+		r.done = last
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// decryptingReader decrypts a stream written by encryptingReader, failing
+// if any chunk was altered, reordered or removed.
+type decryptingReader struct {
+	source  *bufio.Reader
+	gcm     cipher.AEAD
+	counter uint32
+	chunk   []byte
+	pending []byte
+	done    bool
+}
+
+func newDecryptingReader(source io.Reader, dataKey []byte) (*decryptingReader, error) {
+	magic := make([]byte, len(encryptionMagic))
+	if _, err := io.ReadFull(source, magic); err != nil {
+		return nil, fmt.Errorf("failed to read encryption header: %w", err)
+	}
+
+	if string(magic) != encryptionMagic {
+		return nil, errors.New("the file is not encrypted")
+	}
+
+	salt := make([]byte, encryptionFileSaltSize)
+	if _, err := io.ReadFull(source, salt); err != nil {
+		return nil, fmt.Errorf("failed to read encryption header: %w", err)
+	}
+
+	key, err := fileKey(dataKey, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newChunkGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptingReader{
+		source: bufio.NewReader(source),
+		gcm:    gcm,
+		chunk:  make([]byte, encryptionChunkSize+gcm.Overhead()),
+	}, nil
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(r.source, r.chunk)
+		last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !last {
+			return 0, err
+		}
+		if !last {
+			if _, peekErr := r.source.Peek(1); errors.Is(peekErr, io.EOF) {
+				last = true
+			} else if peekErr != nil {
+				return 0, peekErr
+			}
+		}
+
+		plaintext, err := r.gcm.Open(nil, chunkNonce(r.counter, last), r.chunk[:n], nil)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decrypt chunk %d: %w", r.counter, err)
+		}
+
+		r.pending = plaintext
+		r.counter++
+		r.done = last
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// sequentialReadCloser adapts a decrypted stream to filestore.ReadCloseSeeker. It cannot seek.
+type sequentialReadCloser struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (r *sequentialReadCloser) Seek(int64, int) (int64, error) {
+	return 0, errors.New("encrypted files cannot be seeked")
+}
+
+func (r *sequentialReadCloser) Close() error {
+	return r.closer.Close()
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
@@ -0,0 +1,267 @@
+package legalhold
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/shared/filestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+// newLocalFileBackend returns a local filestore.FileBackend in a temporary directory, together
+// with the directory.
+func newLocalFileBackend(t *testing.T) (filestore.FileBackend, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	backend, err := filestore.NewFileBackend(filestore.FileBackendSettings{
+		DriverName: "local",
+		Directory:  dir,
+	})
+	require.NoError(t, err)
+
+	return backend, dir
+}
+
+func newMasterKey(t *testing.T) []byte {
+	t.Helper()
+
+	key := make([]byte, model.EncryptionKeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	return key
+}
+
+func TestEncryptedFileBackend_RoundTrip(t *testing.T) {
+	local, dir := newLocalFileBackend(t)
+	backend := NewEncryptedFileBackend(local, newMasterKey(t))
+
+	sizes := []int{0, 1, encryptionChunkSize - 1, encryptionChunkSize, encryptionChunkSize + 1, 3*encryptionChunkSize + 17}
+	for _, size := range sizes {
+		content := make([]byte, size)
+		_, err := rand.Read(content)
+		require.NoError(t, err)
+
+		filePath := "legal_hold/hold_id/messages/file.csv"
+		written, err := backend.WriteFile(bytes.NewReader(content), filePath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(size), written)
+
+		// The file is encrypted at rest.
+		stored, err := os.ReadFile(filepath.Join(dir, filePath))
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(stored, []byte(encryptionMagic)))
+		// A random plaintext of a few bytes can turn up in the random salt and ciphertext by chance.
+		if size > 16 {
+			assert.False(t, bytes.Contains(stored, content))
+		}
+
+		data, err := backend.ReadFile(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, len(content), len(data), "size %d", size)
+		assert.True(t, bytes.Equal(content, data), "size %d", size)
+
+		reader, err := backend.Reader(filePath)
+		require.NoError(t, err)
+		data, err = io.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		assert.True(t, bytes.Equal(content, data), "size %d", size)
+	}
+
+	// The data key is stored wrapped, next to the files of the legal hold.
+	infoData, err := os.ReadFile(filepath.Join(dir, "legal_hold/hold_id", model.EncryptionInfoFileName))
+	require.NoError(t, err)
+	var info model.EncryptionInfo
+	require.NoError(t, json.Unmarshal(infoData, &info))
+	assert.Equal(t, model.EncryptionAlgorithm, info.Algorithm)
+}
+
+func TestEncryptedFileBackend_FileKeys(t *testing.T) {
+	local, dir := newLocalFileBackend(t)
+	backend := NewEncryptedFileBackend(local, newMasterKey(t))
+
+	// Identical files are encrypted with keys of their own, so their ciphertexts differ.
+	content := bytes.Repeat([]byte("a"), encryptionChunkSize+10)
+	var stored [][]byte
+	for _, filePath := range []string{"legal_hold/hold_id/messages/a.csv", "legal_hold/hold_id/messages/b.csv"} {
+		_, err := backend.WriteFile(bytes.NewReader(content), filePath)
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(dir, filePath))
+		require.NoError(t, err)
+		stored = append(stored, data)
+	}
+
+	header := len(encryptionMagic) + encryptionFileSaltSize
+	assert.NotEqual(t, stored[0][len(encryptionMagic):header], stored[1][len(encryptionMagic):header])
+	assert.NotEqual(t, stored[0][header:], stored[1][header:])
+}
+
+func TestEncryptedFileBackend_CopyAndAppend(t *testing.T) {
+	local, dir := newLocalFileBackend(t)
+	masterKey := newMasterKey(t)
+	backend := NewEncryptedFileBackend(local, masterKey)
+
+	// Attachments outside legal holds are left in plaintext.
+	_, err := backend.WriteFile(strings.NewReader("attachment"), "data/file.txt")
+	require.NoError(t, err)
+	stored, err := os.ReadFile(filepath.Join(dir, "data/file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "attachment", string(stored))
+
+	// Copies into legal holds are encrypted.
+	require.NoError(t, backend.CopyFile("data/file.txt", "legal_hold/hold_id/files/file.txt"))
+	stored, err = os.ReadFile(filepath.Join(dir, "legal_hold/hold_id/files/file.txt"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(stored), "attachment")
+
+	appended, err := backend.AppendFile(strings.NewReader(" appended"), "legal_hold/hold_id/files/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(" appended")), appended)
+
+	// A new backend with the same master key reads the files back.
+	backend = NewEncryptedFileBackend(local, masterKey)
+	data, err := backend.ReadFile("legal_hold/hold_id/files/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "attachment appended", string(data))
+}
+
+func TestEncryptedFileBackend_Tampering(t *testing.T) {
+	local, dir := newLocalFileBackend(t)
+	backend := NewEncryptedFileBackend(local, newMasterKey(t))
+
+	content := bytes.Repeat([]byte("a"), 2*encryptionChunkSize+10)
+	filePath := "legal_hold/hold_id/messages/file.csv"
+	_, err := backend.WriteFile(bytes.NewReader(content), filePath)
+	require.NoError(t, err)
+
+	stored, err := os.ReadFile(filepath.Join(dir, filePath))
+	require.NoError(t, err)
+
+	chunk := encryptionChunkSize + 16
+	header := len(encryptionMagic) + encryptionFileSaltSize
+	concat := func(parts ...[]byte) []byte {
+		var result []byte
+		for _, part := range parts {
+			result = append(result, part...)
+		}
+		return result
+	}
+	flipped := concat(stored)
+	flipped[header+5] ^= 1
+	flippedSalt := concat(stored)
+	flippedSalt[len(encryptionMagic)] ^= 1
+
+	testCases := map[string][]byte{
+		"flipped bit":            flipped,
+		"flipped salt":           flippedSalt,
+		"truncated at a chunk":   stored[:header+chunk],
+		"truncated in a chunk":   stored[:len(stored)-3],
+		"reordered chunks":       concat(stored[:header], stored[header+chunk:header+2*chunk], stored[header:header+chunk], stored[header+2*chunk:]),
+		"header only":            stored[:header],
+		"removed the last chunk": stored[:header+2*chunk],
+	}
+
+	for name, tampered := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, filePath), tampered, 0600))
+
+			_, err := backend.ReadFile(filePath)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestEncryptedFileBackend_WithoutMasterKey(t *testing.T) {
+	local, dir := newLocalFileBackend(t)
+
+	// Without a master key nothing is encrypted.
+	backend := NewEncryptedFileBackend(local, nil)
+	_, err := backend.WriteFile(strings.NewReader("plaintext"), "legal_hold/plain_id/index.json")
+	require.NoError(t, err)
+	stored, err := os.ReadFile(filepath.Join(dir, "legal_hold/plain_id/index.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "plaintext", string(stored))
+
+	// Files written before a legal hold was encrypted stay readable.
+	encrypting := NewEncryptedFileBackend(local, newMasterKey(t))
+	data, err := encrypting.ReadFile("legal_hold/plain_id/index.json")
+	require.NoError(t, err)
+	assert.Equal(t, "plaintext", string(data))
+	reader, err := encrypting.Reader("legal_hold/plain_id/index.json")
+	require.NoError(t, err)
+	data, err = io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, "plaintext", string(data))
+
+	// Encrypted legal holds are neither read nor written to without the master key.
+	_, err = encrypting.WriteFile(strings.NewReader("secret"), "legal_hold/secret_id/index.json")
+	require.NoError(t, err)
+
+	_, err = backend.WriteFile(strings.NewReader("leak"), "legal_hold/secret_id/other.json")
+	require.ErrorIs(t, err, ErrEncryptionKeyRequired)
+	_, err = backend.ReadFile("legal_hold/secret_id/index.json")
+	require.ErrorIs(t, err, ErrEncryptionKeyRequired)
+
+	// Nor with another master key.
+	_, err = NewEncryptedFileBackend(local, newMasterKey(t)).ReadFile("legal_hold/secret_id/index.json")
+	require.ErrorContains(t, err, "was wrapped with master key")
+}
+
+func TestExecution_EncryptedArtifacts(t *testing.T) {
+	local, dir := newLocalFileBackend(t)
+	backend := NewEncryptedFileBackend(local, newMasterKey(t))
+
+	lh := model.LegalHold{
+		ID:              mattermostModel.NewId(),
+		Name:            "encrypted-hold",
+		StartsAt:        1000,
+		ExecutionLength: 1000,
+		Secret:          "secret",
+	}
+	kv := newTestKVStore()
+
+	ex := NewExecution(lh, nil, nil, kv, backend)
+	messagesPath := lh.BasePath() + "/messages.csv"
+	require.NoError(t, ex.writeCSVFile(messagesPath, "id,message\n1,confidential\n"))
+	require.NoError(t, ex.WriteFileHashes())
+	require.NoError(t, ex.WriteLedgerEntry(2500))
+	require.NoError(t, ex.WriteManifest(2500))
+
+	// Every artifact but the encryption info is encrypted at rest.
+	files, err := local.ListDirectoryRecursively(lh.BasePath())
+	require.NoError(t, err)
+	require.Len(t, files, 6)
+	for _, file := range files {
+		stored, readErr := os.ReadFile(filepath.Join(dir, file))
+		require.NoError(t, readErr)
+		if strings.HasSuffix(file, model.EncryptionInfoFileName) {
+			continue
+		}
+		assert.True(t, bytes.HasPrefix(stored, []byte(encryptionMagic)), file)
+	}
+
+	// Hashes and digests are of the plaintext, so that exports can be verified once decrypted.
+	data, err := backend.ReadFile(lh.BasePath() + "/" + model.ManifestFileName)
+	require.NoError(t, err)
+	var manifest model.Manifest
+	require.NoError(t, json.Unmarshal(data, &manifest))
+	assert.Equal(t, sha256Hex("id,message\n1,confidential\n"), manifest.Files[messagesPath])
+
+	signature, err := backend.ReadFile(lh.BasePath() + "/" + model.ManifestSignatureFileName)
+	require.NoError(t, err)
+	assert.True(t, kv.signingKey.Verify(data, signature))
+}
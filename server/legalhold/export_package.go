@@ -0,0 +1,230 @@
+package legalhold
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	mm_model "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/mattermost/mattermost-server/v6/shared/filestore"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/kvstore"
+)
+
+// exportPackageProgressInterval is the least time between two saves of the progress of the build
+// of an ExportPackage.
+const exportPackageProgressInterval = 5 * time.Second
+
+// PackageBuilder builds the archives of ExportPackages on the file backend. The files of the legal
+// hold are packaged exactly as they are stored, so that the files of encrypted legal holds stay
+// encrypted in the archive, to be decrypted by the processor.
+type PackageBuilder struct {
+	papi        plugin.API
+	kvstore     kvstore.KVStore
+	fileBackend filestore.FileBackend
+}
+
+// NewPackageBuilder creates a new PackageBuilder that is ready to use.
+func NewPackageBuilder(papi plugin.API, kvstore kvstore.KVStore, fileBackend filestore.FileBackend) *PackageBuilder {
+	return &PackageBuilder{
+		papi:        papi,
+		kvstore:     kvstore,
+		fileBackend: UnwrapFileBackend(fileBackend),
+	}
+}
+
+// Build builds the archive of the ExportPackage pkg of the LegalHold lh, saving its progress as it
+// goes. The ExportPackage is saved as completed or failed once the build ends, and the archive of
+// a failed build is removed.
+func (b *PackageBuilder) Build(ctx context.Context, lh model.LegalHold, pkg *model.ExportPackage) error {
+	err := b.build(ctx, lh, pkg)
+
+	pkg.UpdateAt = mm_model.GetMillis()
+	if err != nil {
+		pkg.Status = model.ExportPackageStatusFailed
+		pkg.Error = err.Error()
+		if removeErr := b.fileBackend.RemoveFile(pkg.ArchivePath()); removeErr != nil {
+			b.papi.LogWarn("Failed to remove the archive of a failed export package", "package_id", pkg.ID, "error", removeErr.Error())
+		}
+	} else {
+		pkg.Status = model.ExportPackageStatusCompleted
+		pkg.CompletedAt = pkg.UpdateAt
+	}
+
+	if saveErr := b.kvstore.SaveExportPackage(*pkg); saveErr != nil && err == nil {
+		err = saveErr
+	}
+
+	return err
+}
+
+func (b *PackageBuilder) build(ctx context.Context, lh model.LegalHold, pkg *model.ExportPackage) error {
+	files, err := ListLegalHoldFiles(b.fileBackend, lh)
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	pkg.Status = model.ExportPackageStatusRunning
+	pkg.FileCount = len(files)
+	for _, file := range files {
+		size, sizeErr := b.fileBackend.FileSize(file)
+		if sizeErr != nil {
+			return fmt.Errorf("failed to get the size of %s: %w", file, sizeErr)
+		}
+		pkg.TotalBytes += size
+	}
+
+	pkg.UpdateAt = mm_model.GetMillis()
+	if err = b.kvstore.SaveExportPackage(*pkg); err != nil {
+		return err
+	}
+
+	// The archive is streamed to the file backend as it is written, and hashed on the way.
+	pipeReader, pipeWriter := io.Pipe()
+	digester := sha256.New()
+	type writeResult struct {
+		written int64
+		err     error
+	}
+	done := make(chan writeResult, 1)
+	go func() {
+		written, writeErr := b.fileBackend.WriteFile(io.TeeReader(pipeReader, digester), pkg.ArchivePath())
+		// Unblock the archive writer if the file backend stopped reading early.
+		_ = pipeReader.CloseWithError(writeErr)
+		done <- writeResult{written: written, err: writeErr}
+	}()
+
+	err = b.writeArchive(ctx, lh, pkg, files, pipeWriter)
+	_ = pipeWriter.CloseWithError(err)
+
+	result := <-done
+	if err != nil {
+		return err
+	}
+	if result.err != nil {
+		return fmt.Errorf("failed to write the archive: %w", result.err)
+	}
+
+	pkg.Size = result.written
+	pkg.Digest = hex.EncodeToString(digester.Sum(nil))
+
+	return nil
+}
+
+// writeArchive writes the zip archive of the files of the LegalHold lh to w, followed by its
+// manifest.
+func (b *PackageBuilder) writeArchive(ctx context.Context, lh model.LegalHold, pkg *model.ExportPackage, files []string, w io.Writer) error {
+	zipWriter := zip.NewWriter(w)
+	progress := &packageProgress{builder: b, pkg: pkg, savedAt: time.Now()}
+
+	manifest := model.ExportPackageManifest{
+		PackageID:     pkg.ID,
+		LegalHoldID:   lh.ID,
+		LegalHoldName: lh.Name,
+		CreateAt:      pkg.CreateAt,
+		Algorithm:     model.ManifestDigestAlgorithm,
+		Files:         make([]model.ExportPackageFile, 0, len(files)),
+	}
+
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		entry, err := b.addFile(zipWriter, file, progress)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to the archive: %w", file, err)
+		}
+		manifest.Files = append(manifest.Files, entry)
+
+		pkg.ProcessedFiles++
+		progress.save()
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	entryWriter, err := zipWriter.CreateHeader(&zip.FileHeader{
+		Name:     model.ExportPackageManifestFileName,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = entryWriter.Write(data); err != nil {
+		return err
+	}
+
+	return zipWriter.Close()
+}
+
+// addFile adds the file at filePath to the archive, returning its entry in the manifest.
+func (b *PackageBuilder) addFile(zipWriter *zip.Writer, filePath string, progress *packageProgress) (model.ExportPackageFile, error) {
+	reader, err := b.fileBackend.Reader(filePath)
+	if err != nil {
+		return model.ExportPackageFile{}, err
+	}
+	defer reader.Close()
+
+	entryWriter, err := zipWriter.CreateHeader(&zip.FileHeader{
+		Name:     filePath,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return model.ExportPackageFile{}, err
+	}
+
+	digester := sha256.New()
+	written, err := io.Copy(io.MultiWriter(entryWriter, digester, progress), reader)
+	if err != nil {
+		return model.ExportPackageFile{}, err
+	}
+
+	return model.ExportPackageFile{
+		Path:   filePath,
+		Size:   written,
+		Digest: hex.EncodeToString(digester.Sum(nil)),
+	}, nil
+}
+
+// packageProgress counts the bytes added to the archive of an ExportPackage, saving the progress
+// of the build every exportPackageProgressInterval so that it can be reported while it runs.
+type packageProgress struct {
+	builder *PackageBuilder
+	pkg     *model.ExportPackage
+	savedAt time.Time
+}
+
+func (p *packageProgress) Write(data []byte) (int, error) {
+	p.pkg.ProcessedBytes += int64(len(data))
+	p.save()
+	return len(data), nil
+}
+
+// save saves the progress if it has not been saved for exportPackageProgressInterval. Failing to
+// save the progress does not fail the build.
+func (p *packageProgress) save() {
+	if time.Since(p.savedAt) < exportPackageProgressInterval {
+		return
+	}
+
+	p.savedAt = time.Now()
+	p.pkg.UpdateAt = mm_model.GetMillis()
+	if err := p.builder.kvstore.SaveExportPackage(*p.pkg); err != nil {
+		p.builder.papi.LogWarn("Failed to save the progress of an export package", "package_id", p.pkg.ID, "error", err.Error())
+	}
+}
@@ -0,0 +1,153 @@
+package legalhold
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+func TestPackageBuilder_Build(t *testing.T) {
+	local, dir := newLocalFileBackend(t)
+	backend := NewEncryptedFileBackend(local, newMasterKey(t))
+
+	lh := model.LegalHold{
+		ID:   mattermostModel.NewId(),
+		Name: "packaged-hold",
+	}
+	kv := newTestKVStore()
+
+	files := map[string]string{
+		lh.BasePath() + "/index.json":                `{"users":{}}`,
+		lh.BasePath() + "/channel_id/messages/a.csv": "id,message\n1,confidential\n",
+		lh.BasePath() + "/blobs/ab/abcdef0123456789": "attachment",
+	}
+	for filePath, content := range files {
+		_, err := backend.WriteFile(bytes.NewReader([]byte(content)), filePath)
+		require.NoError(t, err)
+	}
+
+	pkg := model.NewExportPackage(mattermostModel.NewId(), lh.ID, 1000)
+	builder := NewPackageBuilder(&testAPI{}, kv, backend)
+	require.NoError(t, builder.Build(context.Background(), lh, &pkg))
+
+	assert.Equal(t, model.ExportPackageStatusCompleted, pkg.Status)
+	assert.NotZero(t, pkg.CompletedAt)
+	assert.Equal(t, 4, pkg.FileCount)
+	assert.Equal(t, 4, pkg.ProcessedFiles)
+	assert.Equal(t, pkg.TotalBytes, pkg.ProcessedBytes)
+	require.NotEmpty(t, kv.packages)
+	assert.Equal(t, pkg, kv.packages[len(kv.packages)-1])
+
+	// The archive is written outside the legal hold, in plaintext.
+	archive, err := os.ReadFile(filepath.Join(dir, pkg.ArchivePath()))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(archive)), pkg.Size)
+	assert.Equal(t, sha256Hex(string(archive)), pkg.Digest)
+
+	zipReader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	entries := make(map[string][]byte)
+	for _, file := range zipReader.File {
+		reader, openErr := file.Open()
+		require.NoError(t, openErr)
+		data, readErr := io.ReadAll(reader)
+		require.NoError(t, readErr)
+		require.NoError(t, reader.Close())
+		entries[file.Name] = data
+	}
+
+	var manifest model.ExportPackageManifest
+	require.NoError(t, json.Unmarshal(entries[model.ExportPackageManifestFileName], &manifest))
+	assert.Equal(t, pkg.ID, manifest.PackageID)
+	assert.Equal(t, lh.Name, manifest.LegalHoldName)
+	require.Len(t, manifest.Files, 4)
+	require.Len(t, entries, 5)
+
+	// The files are packaged as stored, so those of encrypted legal holds stay encrypted.
+	for _, file := range manifest.Files {
+		stored, readErr := os.ReadFile(filepath.Join(dir, file.Path))
+		require.NoError(t, readErr)
+		assert.Equal(t, stored, entries[file.Path], file.Path)
+		assert.Equal(t, sha256Hex(string(stored)), file.Digest, file.Path)
+		assert.Equal(t, int64(len(stored)), file.Size, file.Path)
+
+		if content, ok := files[file.Path]; ok {
+			assert.True(t, bytes.HasPrefix(stored, []byte(encryptionMagic)), file.Path)
+			assert.NotContains(t, string(stored), content, file.Path)
+		} else {
+			assert.Equal(t, lh.BasePath()+"/"+model.EncryptionInfoFileName, file.Path)
+		}
+	}
+}
+
+func TestPackageBuilder_BuildSkipsStaging(t *testing.T) {
+	local, dir := newLocalFileBackend(t)
+
+	// An interrupted execution leaves its checkpoint and a staged attachment on disk.
+	lh := model.LegalHold{ID: mattermostModel.NewId(), Name: "interrupted-hold"}
+	for _, filePath := range []string{
+		lh.IndexPath(),
+		lh.BasePath() + "/blobs/ab/abcdef0123456789",
+		lh.BasePath() + "/" + checkpointStagingDir + "/index.json",
+		lh.BasePath() + "/" + checkpointStagingDir + "/progress-00000001.json",
+		lh.BasePath() + "/" + blobStagingDir + "/" + mattermostModel.NewId(),
+	} {
+		_, err := local.WriteFile(bytes.NewReader([]byte("content")), filePath)
+		require.NoError(t, err)
+	}
+
+	kv := newTestKVStore()
+	pkg := model.NewExportPackage(mattermostModel.NewId(), lh.ID, 1000)
+	require.NoError(t, NewPackageBuilder(&testAPI{}, kv, local).Build(context.Background(), lh, &pkg))
+	assert.Equal(t, 2, pkg.FileCount)
+
+	archive, err := os.ReadFile(filepath.Join(dir, pkg.ArchivePath()))
+	require.NoError(t, err)
+	zipReader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	var names []string
+	for _, file := range zipReader.File {
+		names = append(names, file.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		model.ExportPackageManifestFileName,
+		lh.IndexPath(),
+		lh.BasePath() + "/blobs/ab/abcdef0123456789",
+	}, names)
+}
+
+func TestPackageBuilder_BuildCancelled(t *testing.T) {
+	local, dir := newLocalFileBackend(t)
+
+	lh := model.LegalHold{ID: mattermostModel.NewId(), Name: "cancelled-hold"}
+	_, err := local.WriteFile(bytes.NewReader([]byte("content")), lh.BasePath()+"/index.json")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	kv := newTestKVStore()
+	pkg := model.NewExportPackage(mattermostModel.NewId(), lh.ID, 1000)
+	err = NewPackageBuilder(&testAPI{}, kv, local).Build(ctx, lh, &pkg)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// A failed build is saved with its error, and leaves no archive behind.
+	assert.Equal(t, model.ExportPackageStatusFailed, pkg.Status)
+	assert.Contains(t, pkg.Error, context.Canceled.Error())
+	assert.Equal(t, pkg, kv.packages[len(kv.packages)-1])
+	_, err = os.Stat(filepath.Join(dir, pkg.ArchivePath()))
+	assert.True(t, os.IsNotExist(err))
+}
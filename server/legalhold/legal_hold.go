@@ -4,14 +4,20 @@ import (
 	"bytes"
 	"context"
 	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"maps"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gocarina/gocsv"
@@ -20,6 +26,7 @@ import (
 	"github.com/mattermost/mattermost-server/v6/plugin"
 	"github.com/mattermost/mattermost-server/v6/shared/filestore"
 
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/metrics"
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/kvstore"
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/sqlstore"
@@ -28,9 +35,33 @@ import (
 
 const PostExportBatchLimit = 10000
 
-// executionWaitForLockTimeout is the time to wait for the lock to be acquired before failing the execution.
-// This is to prevent multiple executions at the same time.
-const executionWaitForLockTimeout = 5 * time.Second
+// executionWaitForLockTimeout is the time to wait for the lock to be acquired before skipping the execution.
+// This is to prevent multiple executions of the same legal hold at the same time.
+var executionWaitForLockTimeout = 5 * time.Second
+
+// fileCopyAttempts is the number of times copying a file attachment is attempted before it is
+// recorded as an exception.
+const fileCopyAttempts = 3
+
+// fileCopyRetryDelay is the delay before retrying a failed copy of a file attachment, doubled
+// after each retry.
+var fileCopyRetryDelay = 2 * time.Second
+
+// blobStagingDir and checkpointStagingDir are the directories, relative to the base path of the
+// legal hold, in which an execution stages the attachments and the checkpoint it has yet to
+// complete. Neither is part of the held data.
+const (
+	blobStagingDir       = "blobs/staging"
+	checkpointStagingDir = "staging/checkpoint"
+)
+
+// ErrExecutionLocked is returned by Execution.Execute when another execution of the same legal
+// hold holds the cluster mutex. The execution is skipped rather than failed, as the other one is
+// already bringing the legal hold up to date.
+var ErrExecutionLocked = errors.New("another execution of the legal hold is running")
+
+// errAttachmentMissing indicates that a file attachment was not found in the file store.
+var errAttachmentMissing = errors.New("file attachment not found")
 
 // Execution represents one execution of a LegalHold, i.e. a daily (or other duration)
 // batch process to hold all data relating to that particular LegalHold. It is defined by the
@@ -41,15 +72,61 @@ type Execution struct {
 	ExecutionStartTime int64
 	ExecutionEndTime   int64
 
+	// ChannelWorkers is the number of channels exported in parallel. Values below 1 mean 1.
+	ChannelWorkers int
+
+	// KeepCapturedCustodians keeps the people captured as members of the groups of the LegalHold
+	// by earlier Executions in scope, even if they have left the groups since.
+	KeepCapturedCustodians bool
+
+	// Metrics collects the metrics of the Execution, if set.
+	Metrics *metrics.Metrics
+
 	papi        plugin.API
 	store       *sqlstore.SQLStore
 	kvstore     kvstore.KVStore
 	fileBackend filestore.FileBackend
 
 	channelIDs []string
+	cursors    map[string]model.LegalHoldCursor
+	// revisionCursors track the export of the revisions of older posts in each channel.
+	revisionCursors map[string]model.LegalHoldCursor
+	resumed         bool
+
+	// exportAcknowledgements is set if the server supports post acknowledgements.
+	exportAcknowledgements bool
 
 	index  model.LegalHoldIndex
 	hashes model.HashList
+	// digests are the SHA-256 digests of the exported files, listed in the signed manifest.
+	digests model.HashList
+
+	record model.ExecutionRecord
+
+	// unstagedHashes are the keys of the files hashed since progress was last staged for the
+	// checkpoint. pendingExceptions are the file exceptions of the batch each channel is
+	// exporting, which move to unstagedExceptions once the cursor past the batch is committed,
+	// so that a resumed run does not record the exceptions of a batch it exports again.
+	unstagedHashes     []string
+	pendingExceptions  map[string][]model.LegalHoldFileException
+	unstagedExceptions []model.LegalHoldFileException
+	// checkpointSequence is the number of the last progress file staged for the checkpoint.
+	checkpointSequence int
+
+	// mu guards the state shared by the workers exporting channels in parallel: the cursors,
+	// hashes, record and LegalHold.HasMessages.
+	mu sync.Mutex
+	// checkpointMu serializes the saving of checkpoints, so that progress is staged in order.
+	checkpointMu sync.Mutex
+	// blobLocks holds a lock for each blob being stored, guarded by mu, so that the workers
+	// storing the same content one after the other store it once and count it as deduplicated.
+	blobLocks map[string]*blobLock
+}
+
+// blobLock serializes the storing of one blob, and is dropped once nobody waits for it.
+type blobLock struct {
+	mu      sync.Mutex
+	waiters int
 }
 
 // NewExecution creates a new Execution that is ready to use.
@@ -63,34 +140,140 @@ func NewExecution(legalHold model.LegalHold, papi plugin.API, store *sqlstore.SQ
 		fileBackend:        fileBackend,
 		index:              model.NewLegalHoldIndex(),
 		papi:               papi,
+		cursors:            make(map[string]model.LegalHoldCursor),
+		revisionCursors:    make(map[string]model.LegalHoldCursor),
 		hashes:             make(map[string]string),
+		digests:            make(map[string]string),
+		pendingExceptions:  make(map[string][]model.LegalHoldFileException),
+		blobLocks:          make(map[string]*blobLock),
 	}
 }
 
-// Execute executes the Execution and returns the updated LegalHold.
-func (ex *Execution) Execute(now int64) (*model.LegalHold, error) {
-	// Lock multiple executions behind a cluster mutex
-	mutex, err := cluster.NewMutex(ex.papi, "legal_hold_execution")
+// Resume restores the state of the Execution from a checkpoint saved by an earlier, interrupted
+// run of the same Execution, together with the index and hashes it staged in the file backend,
+// so that only the remaining work is done. It returns false, leaving the Execution untouched, if
+// the checkpoint does not cover the same window as this Execution or its staged progress cannot
+// be read.
+func (ex *Execution) Resume(cp *model.ExecutionCheckpoint) (bool, error) {
+	if !cp.Matches(ex.LegalHold.ID, ex.ExecutionStartTime, ex.ExecutionEndTime) {
+		return false, nil
+	}
+
+	index, hashes, digests, err := ex.readCheckpointProgress(cp.Sequence)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the staged progress of the execution: %w", err)
+	}
+
+	ex.channelIDs = cp.ChannelIDs
+	if cp.Cursors != nil {
+		ex.cursors = cp.Cursors
+	}
+	if cp.RevisionCursors != nil {
+		ex.revisionCursors = cp.RevisionCursors
+	}
+	ex.index = index
+	ex.hashes = hashes
+	ex.digests = digests
+	ex.checkpointSequence = cp.Sequence
+	ex.LegalHold.HasMessages = ex.LegalHold.HasMessages || cp.HasMessages
+	ex.resumed = true
+
+	return true, nil
+}
+
+// Execute executes the Execution and returns the updated LegalHold. An ExecutionRecord describing
+// the run is saved whether or not the Execution succeeds, unless it is skipped with
+// ErrExecutionLocked.
+//
+// Cancelling ctx stops the Execution between batches of posts and file attachments. The checkpoint
+// of the work done so far is kept, so the next Execution of the LegalHold resumes from it.
+func (ex *Execution) Execute(ctx context.Context, now int64) (*model.LegalHold, error) {
+	ex.record = model.ExecutionRecord{
+		ID:          mm_model.NewId(),
+		LegalHoldID: ex.LegalHold.ID,
+		WindowStart: ex.ExecutionStartTime,
+		WindowEnd:   ex.ExecutionEndTime,
+		StartedAt:   mm_model.GetMillis(),
+		Resumed:     ex.resumed,
+	}
+
+	legalHold, err := ex.execute(ctx, now)
+	if errors.Is(err, ErrExecutionLocked) {
+		return nil, err
+	}
+
+	ex.record.EndedAt = mm_model.GetMillis()
+	ex.record.Duration = ex.record.EndedAt - ex.record.StartedAt
+	ex.record.ChannelCount = len(ex.channelIDs)
+	if err != nil {
+		ex.record.Error = err.Error()
+		ex.record.Cancelled = errors.Is(err, context.Canceled)
+	}
+
+	if saveErr := ex.kvstore.SaveExecutionRecord(ex.record); saveErr != nil {
+		ex.papi.LogError("Failed to save legal hold execution record", "legal_hold_id", ex.LegalHold.ID, "error", saveErr.Error())
+	}
+	ex.Metrics.ObserveExecution(ex.record)
+
+	return legalHold, err
+}
+
+// Record returns the ExecutionRecord describing the last run of this Execution.
+func (ex *Execution) Record() model.ExecutionRecord {
+	return ex.record
+}
+
+func (ex *Execution) execute(ctx context.Context, now int64) (*model.LegalHold, error) {
+	// Lock executions of the same LegalHold behind a cluster mutex, leaving other LegalHolds free
+	// to execute in parallel.
+	mutex, err := cluster.NewMutex(ex.papi, "legal_hold_execution_"+ex.LegalHold.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cluster mutex: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), executionWaitForLockTimeout)
+	lockCtx, cancel := context.WithTimeout(ctx, executionWaitForLockTimeout)
 	defer cancel()
 
-	if lockErr := mutex.LockWithContext(ctx); lockErr != nil {
+	if lockErr := mutex.LockWithContext(lockCtx); lockErr != nil {
+		if ctx.Err() == nil && errors.Is(lockErr, context.DeadlineExceeded) {
+			return nil, ErrExecutionLocked
+		}
 		return nil, fmt.Errorf("failed to lock cluster mutex: %w", lockErr)
 	}
 	defer func() {
 		mutex.Unlock()
 	}()
 
-	err = ex.GetChannels()
+	// A resumed Execution already has its channels from the checkpoint.
+	if !ex.resumed {
+		err = ex.GetChannels()
+		if err != nil {
+			return nil, err
+		}
+
+		err = ex.stageCheckpointIndex()
+		if err != nil {
+			return nil, err
+		}
+
+		err = ex.saveCheckpoint()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = ex.ExportData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove anything left staged by an earlier run that failed while copying a file attachment.
+	err = ex.fileBackend.RemoveDirectory(ex.blobStagingPath())
 	if err != nil {
 		return nil, err
 	}
 
-	err = ex.ExportData()
+	err = ex.WriteFileExceptions()
 	if err != nil {
 		return nil, err
 	}
@@ -105,6 +288,27 @@ func (ex *Execution) Execute(now int64) (*model.LegalHold, error) {
 		return nil, err
 	}
 
+	err = ex.WriteLedgerEntry(now)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ex.WriteManifest(now)
+	if err != nil {
+		return nil, err
+	}
+
+	// The Execution is complete, so there is nothing left to resume.
+	err = ex.kvstore.DeleteExecutionCheckpoint(ex.LegalHold.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Anything left behind is removed by the next Execution before it stages its own progress.
+	if err = ex.fileBackend.RemoveDirectory(ex.checkpointStagingPath()); err != nil {
+		ex.papi.LogWarn("Failed to remove the staged checkpoint of the legal hold execution", "legal_hold_id", ex.LegalHold.ID, "error", err.Error())
+	}
+
 	// Update the LegalHold with execution results
 	// Ensure that the LastExecutionEndedAt is not in the future, useful when running the job manually
 	ex.LegalHold.LastExecutionEndedAt = utils.Min(ex.ExecutionEndTime, now)
@@ -115,9 +319,9 @@ func (ex *Execution) Execute(now int64) (*model.LegalHold, error) {
 // GetChannels populates the list of channels that the Execution needs to cover within the
 // internal state of the Execution struct.
 func (ex *Execution) GetChannels() error {
-	targetUsers, appErr := getUsersForGroups(ex.papi, ex.LegalHold.GroupIDs)
-	if appErr != nil {
-		return appErr
+	targetUsers, err := ex.getGroupCustodians(mm_model.GetMillis())
+	if err != nil {
+		return err
 	}
 
 	for _, userID := range ex.LegalHold.UserIDs {
@@ -163,30 +367,47 @@ func (ex *Execution) GetChannels() error {
 
 		// Add to channels index
 		for _, channelID := range channelIDs {
-			if idx, ok := ex.index.Users[user.Id]; !ok {
-				ex.index.Users[user.Id] = model.LegalHoldIndexUser{
-					Username: user.Username,
-					Email:    user.Email,
-					Channels: []model.LegalHoldChannelMembership{
-						{
-							ChannelID: channelID,
-							StartTime: ex.ExecutionStartTime,
-							EndTime:   ex.ExecutionEndTime,
-						},
-					},
-				}
-			} else {
-				ex.index.Users[user.Id] = model.LegalHoldIndexUser{
-					Username: user.Username,
-					Email:    user.Email,
-					Channels: append(idx.Channels, model.LegalHoldChannelMembership{
-						ChannelID: channelID,
-						StartTime: ex.ExecutionStartTime,
-						EndTime:   ex.ExecutionEndTime,
-					}),
-				}
-			}
+			ex.addChannelMembershipToIndex(user.Id, user.Username, user.Email, model.LegalHoldChannelMembership{
+				ChannelID: channelID,
+				StartTime: ex.ExecutionStartTime,
+				EndTime:   ex.ExecutionEndTime,
+			})
+		}
+	}
+
+	// Channels held directly, or as part of a held team, are included regardless of who is in them.
+	scopedChannelIDs, err := ex.store.GetChannelIDsForTeamsDuring(ex.LegalHold.TeamIDs, ex.ExecutionStartTime, ex.ExecutionEndTime)
+	if err != nil {
+		return err
+	}
+	scopedChannelIDs = utils.DeduplicateStringSlice(append(scopedChannelIDs, ex.LegalHold.ChannelIDs...))
+
+	ex.papi.LogDebug(
+		"Legal hold executor - GetChannels",
+		"scoped_channel_count", len(scopedChannelIDs),
+		"start_time", ex.ExecutionStartTime,
+		"end_time", ex.ExecutionEndTime,
+	)
+
+	ex.channelIDs = append(ex.channelIDs, scopedChannelIDs...)
+
+	// Record everyone who was a member of the held channels during the execution window.
+	members, err := ex.store.GetChannelMembersDuring(scopedChannelIDs, ex.ExecutionStartTime, ex.ExecutionEndTime)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		endTime := ex.ExecutionEndTime
+		if member.LeaveTime != nil {
+			endTime = utils.Min(*member.LeaveTime, ex.ExecutionEndTime)
 		}
+
+		ex.addChannelMembershipToIndex(member.UserID, member.Username, member.Email, model.LegalHoldChannelMembership{
+			ChannelID: member.ChannelID,
+			StartTime: utils.Max(member.JoinTime, ex.ExecutionStartTime),
+			EndTime:   endTime,
+		})
 	}
 
 	ex.channelIDs = utils.DeduplicateStringSlice(ex.channelIDs)
@@ -194,144 +415,824 @@ func (ex *Execution) GetChannels() error {
 	return nil
 }
 
-// ExportData is the main function to run the batch data export for this Execution.
-func (ex *Execution) ExportData() error {
+// getGroupCustodians resolves the members of the groups of the LegalHold at the time provided in
+// "now", recording a snapshot of each group in the index for this Execution. If
+// KeepCapturedCustodians is set, the people captured as members of the groups by earlier
+// Executions are returned too, even if they have left the groups since.
+func (ex *Execution) getGroupCustodians(now int64) ([]*mm_model.User, error) {
+	if len(ex.LegalHold.GroupIDs) == 0 {
+		return nil, nil
+	}
+
+	previousIndex := model.NewLegalHoldIndex()
+	if ex.KeepCapturedCustodians {
+		if err := readJSONFile(ex.fileBackend, ex.indexPath(), &previousIndex); err != nil {
+			return nil, err
+		}
+	}
+
+	var custodians []*mm_model.User
+	for _, groupID := range ex.LegalHold.GroupIDs {
+		group, appErr := ex.papi.GetGroup(groupID)
+		if appErr != nil {
+			return nil, appErr
+		}
+
+		members, err := getGroupMembers(ex.papi, groupID)
+		if err != nil {
+			return nil, err
+		}
+
+		indexGroup := &model.LegalHoldIndexGroup{
+			ID:          group.Id,
+			DisplayName: group.DisplayName,
+			Snapshots: []model.LegalHoldGroupSnapshot{{
+				ExecutionStartTime: ex.ExecutionStartTime,
+				ExecutionEndTime:   ex.ExecutionEndTime,
+				CapturedAt:         now,
+				UserIDs:            make([]string, 0, len(members)),
+			}},
+			Members: make([]model.LegalHoldGroupMember, 0, len(members)),
+		}
+		if group.Name != nil {
+			indexGroup.Name = *group.Name
+		}
+
+		for _, member := range members {
+			indexGroup.Snapshots[0].UserIDs = append(indexGroup.Snapshots[0].UserIDs, member.Id)
+			indexGroup.Members = append(indexGroup.Members, model.LegalHoldGroupMember{
+				UserID:   member.Id,
+				Username: member.Username,
+				Email:    member.Email,
+			})
+		}
+		ex.index.Groups = append(ex.index.Groups, indexGroup)
+		custodians = append(custodians, members...)
+
+		previousGroup := previousIndex.Group(groupID)
+		if previousGroup == nil {
+			continue
+		}
+
+		for _, userID := range previousGroup.CapturedUserIDs() {
+			if slices.Contains(indexGroup.Snapshots[0].UserIDs, userID) {
+				continue
+			}
+
+			user, appErr := ex.papi.GetUser(userID)
+			if appErr != nil {
+				return nil, appErr
+			}
+
+			ex.papi.LogDebug("Legal hold executor - keeping captured group member in scope", "legal_hold_id", ex.LegalHold.ID, "group_id", groupID, "user_id", userID)
+			custodians = append(custodians, user)
+		}
+	}
+
+	return custodians, nil
+}
+
+// addChannelMembershipToIndex records the membership of a channel by a user in the index for this
+// Execution, combining it with any membership of the same channel already recorded for that user.
+func (ex *Execution) addChannelMembershipToIndex(userID, username, email string, membership model.LegalHoldChannelMembership) {
+	idx := ex.index.Users[userID]
+
+	found := false
+	for i, existing := range idx.Channels {
+		if existing.ChannelID == membership.ChannelID {
+			idx.Channels[i] = existing.Combine(membership)
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		idx.Channels = append(idx.Channels, membership)
+	}
+
+	ex.index.Users[userID] = model.LegalHoldIndexUser{
+		Username: username,
+		Email:    email,
+		Channels: idx.Channels,
+	}
+}
+
+// ExportData is the main function to run the batch data export for this Execution. The channels
+// are exported in parallel by ChannelWorkers workers, and a checkpoint is saved after every batch
+// so that an interrupted Execution can be resumed from where it stopped. If ctx is cancelled, or
+// the export of any channel fails, ExportData stops before the next batch and returns the error.
+func (ex *Execution) ExportData(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var err error
+	ex.exportAcknowledgements, err = ex.store.HasPostAcknowledgements(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	channelIDs := make(chan string)
+	errs := make(chan error, len(ex.channelIDs))
+
+	var wg sync.WaitGroup
+	for i := 0; i < max(ex.ChannelWorkers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for channelID := range channelIDs {
+				if err := ex.exportChannel(ctx, channelID); err != nil {
+					errs <- err
+					cancel()
+				}
+			}
+		}()
+	}
+
 	for _, channelID := range ex.channelIDs {
-		cursor := model.NewLegalHoldCursor(ex.ExecutionStartTime)
-		for {
-			var posts []model.LegalHoldPost
-			var err error
+		channelIDs <- channelID
+	}
+	close(channelIDs)
+	wg.Wait()
+	close(errs)
+
+	// Report the error that stopped the export rather than the cancellations it caused.
+	var firstErr error
+	for err := range errs {
+		if firstErr == nil || (errors.Is(firstErr, context.Canceled) && !errors.Is(err, context.Canceled)) {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// exportChannel exports the posts of one channel followed by the revisions of its older posts.
+func (ex *Execution) exportChannel(ctx context.Context, channelID string) error {
+	if err := ex.exportChannelPosts(ctx, channelID); err != nil {
+		return err
+	}
+
+	return ex.exportChannelRevisions(ctx, channelID)
+}
+
+// exportChannelPosts exports the posts of one channel, batch by batch, continuing from the cursor
+// of the channel if it was partially exported by an earlier run.
+func (ex *Execution) exportChannelPosts(ctx context.Context, channelID string) error {
+	ex.mu.Lock()
+	cursor, ok := ex.cursors[channelID]
+	ex.mu.Unlock()
+	if !ok {
+		cursor = model.NewLegalHoldCursor(ex.ExecutionStartTime)
+	}
+
+	for !cursor.Completed {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var posts []model.LegalHoldPost
+		var err error
+
+		queryStart := time.Now()
+		posts, cursor, err = ex.store.GetPostsBatch(ctx, channelID, ex.ExecutionEndTime, ex.LegalHold.Filter, cursor, PostExportBatchLimit)
+		ex.Metrics.ObservePostsBatch(ex.LegalHold.ID, time.Since(queryStart))
+		if err != nil {
+			return err
+		}
 
-			posts, cursor, err = ex.store.GetPostsBatch(channelID, ex.ExecutionEndTime, cursor, PostExportBatchLimit)
+		if len(posts) > 0 {
+			err = ex.exportPostsBatch(ctx, channelID, posts)
 			if err != nil {
 				return err
 			}
+		}
 
-			if len(posts) == 0 {
-				break
-			}
+		ex.commitCursor(channelID, cursor)
+
+		err = ex.saveCheckpoint()
+		if err != nil {
+			return err
+		}
+	}
 
-			ex.papi.LogDebug("Legal hold executor - ExportData", "channel_id", channelID, "post_count", len(posts))
+	return nil
+}
+
+// commitCursor records that the posts of a channel were exported up to cursor, along with the
+// file exceptions of the batches before it.
+func (ex *Execution) commitCursor(channelID string, cursor model.LegalHoldCursor) {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	ex.cursors[channelID] = cursor
+	ex.unstagedExceptions = append(ex.unstagedExceptions, ex.pendingExceptions[channelID]...)
+	delete(ex.pendingExceptions, channelID)
+}
+
+// exportChannelRevisions exports the revisions made during the window of this Execution to the
+// posts of one channel created before it, batch by batch, continuing from the revision cursor of
+// the channel if they were partially exported by an earlier run.
+func (ex *Execution) exportChannelRevisions(ctx context.Context, channelID string) error {
+	ex.mu.Lock()
+	cursor, ok := ex.revisionCursors[channelID]
+	ex.mu.Unlock()
+	if !ok {
+		cursor = model.NewLegalHoldRevisionCursor(ex.ExecutionStartTime)
+	}
+
+	for !cursor.Completed {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-			err = ex.WritePostsBatchToFile(channelID, posts)
+		var revisions []model.LegalHoldPostRevision
+		var err error
+
+		revisions, cursor, err = ex.store.GetPostRevisionsBatch(ctx, channelID, ex.ExecutionStartTime, ex.ExecutionEndTime, ex.LegalHold.Filter, cursor, PostExportBatchLimit)
+		if err != nil {
+			return err
+		}
+
+		if len(revisions) > 0 {
+			err = ex.WriteRevisionsBatchToFile(channelID, revisions)
 			if err != nil {
 				return err
 			}
 
-			// Since at this point we have posts, ensure the `HasMessages` is set to true so users can
-			// download the legal hold.
-			ex.LegalHold.HasMessages = true
-
-			// Extract the FileIDs to export
-			var fileIDs []string
-			for _, post := range posts {
-				var postFileIDs []string
-				err = json.Unmarshal([]byte(post.PostFileIDs), &postFileIDs)
-				if err != nil {
-					return err
+			postIDs := make([]string, 0, len(revisions))
+			for _, revision := range revisions {
+				if revision.RevisionType != model.PostRevisionTypePrevious {
+					postIDs = append(postIDs, revision.PostID)
 				}
-				fileIDs = append(fileIDs, postFileIDs...)
 			}
 
-			ex.papi.LogDebug("Legal hold executor - ExportData", "channel_id", channelID, "file_count", len(fileIDs))
-
-			err = ex.ExportFiles(channelID, posts[0].PostCreateAt, posts[0].PostID, fileIDs)
+			batchKey := ex.revisionsBatchPath(channelID, revisions[0].PostUpdateAt, revisions[0].PostID)
+			err = ex.exportInteractions(ctx, channelID, batchKey, postIDs)
 			if err != nil {
 				return err
 			}
+		}
 
-			if len(posts) < PostExportBatchLimit {
-				break
-			}
+		ex.mu.Lock()
+		ex.revisionCursors[channelID] = cursor
+		ex.mu.Unlock()
+
+		err = ex.saveCheckpoint()
+		if err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-// WritePostsBatchToFile writes a batch of posts from a channel to the appropriate file
-// in the file backend.
-func (ex *Execution) WritePostsBatchToFile(channelID string, posts []model.LegalHoldPost) error {
-	msgKey := ex.messagesBatchPath(channelID, posts[0].PostCreateAt, posts[0].PostID)
+// exportPostsBatch writes one batch of posts from a channel, along with their file attachments,
+// to the file backend.
+func (ex *Execution) exportPostsBatch(ctx context.Context, channelID string, posts []model.LegalHoldPost) error {
+	ex.papi.LogDebug("Legal hold executor - ExportData", "channel_id", channelID, "post_count", len(posts))
 
-	csvContent, err := gocsv.MarshalString(&posts)
+	err := ex.WritePostsBatchToFile(channelID, posts)
 	if err != nil {
 		return err
 	}
 
-	csvReader := strings.NewReader(csvContent)
+	// Since at this point we have posts, ensure the `HasMessages` is set to true so users can
+	// download the legal hold.
+	ex.mu.Lock()
+	ex.record.PostCount += len(posts)
+	ex.LegalHold.HasMessages = true
+	ex.mu.Unlock()
+
+	// Extract the FileIDs to export
+	var fileIDs []string
+	for _, post := range posts {
+		var postFileIDs []string
+		err = json.Unmarshal([]byte(post.PostFileIDs), &postFileIDs)
+		if err != nil {
+			return err
+		}
+		fileIDs = append(fileIDs, postFileIDs...)
+	}
+
+	postIDs := make([]string, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.PostID)
+	}
+
+	batchKey := ex.messagesBatchPath(channelID, posts[0].PostCreateAt, posts[0].PostID)
+	err = ex.exportInteractions(ctx, channelID, batchKey, postIDs)
+	if err != nil {
+		return err
+	}
+
+	ex.papi.LogDebug("Legal hold executor - ExportData", "channel_id", channelID, "file_count", len(fileIDs))
+
+	return ex.ExportFiles(ctx, channelID, posts[0].PostCreateAt, posts[0].PostID, fileIDs)
+}
+
+// exportInteractions writes the reactions to, and acknowledgements of, the posts with the provided
+// IDs to files named after the batch file the posts were written to.
+func (ex *Execution) exportInteractions(ctx context.Context, channelID string, batchKey string, postIDs []string) error {
+	batchName := strings.TrimSuffix(path.Base(batchKey), ".csv")
+
+	reactions, err := ex.store.GetReactionsForPosts(ctx, postIDs)
+	if err != nil {
+		return err
+	}
+
+	if len(reactions) > 0 {
+		csvContent, err := gocsv.MarshalString(&reactions)
+		if err != nil {
+			return err
+		}
+
+		err = ex.writeCSVFile(ex.interactionsBatchPath(channelID, "reactions", batchName), csvContent)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !ex.exportAcknowledgements {
+		return nil
+	}
+
+	acknowledgements, err := ex.store.GetAcknowledgementsForPosts(ctx, postIDs)
+	if err != nil {
+		return err
+	}
+
+	if len(acknowledgements) > 0 {
+		csvContent, err := gocsv.MarshalString(&acknowledgements)
+		if err != nil {
+			return err
+		}
+
+		err = ex.writeCSVFile(ex.interactionsBatchPath(channelID, "acknowledgements", batchName), csvContent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveCheckpoint persists the progress of this Execution so that it can be resumed later. The
+// hashes and digests recorded, and the file exceptions of the batches committed, since the last
+// checkpoint are staged in the file backend first, and the checkpoint itself only records the
+// channels and cursors.
+func (ex *Execution) saveCheckpoint() error {
+	ex.checkpointMu.Lock()
+	defer ex.checkpointMu.Unlock()
+
+	ex.mu.Lock()
+	progress := model.CheckpointProgress{
+		Hashes:         make(model.HashList, len(ex.unstagedHashes)),
+		Digests:        make(model.HashList, len(ex.unstagedHashes)),
+		FileExceptions: ex.unstagedExceptions,
+	}
+	for _, key := range ex.unstagedHashes {
+		progress.Hashes[key] = ex.hashes[key]
+		if digest, ok := ex.digests[key]; ok {
+			progress.Digests[key] = digest
+		}
+	}
+	checkpoint := model.ExecutionCheckpoint{
+		LegalHoldID:        ex.LegalHold.ID,
+		ExecutionStartTime: ex.ExecutionStartTime,
+		ExecutionEndTime:   ex.ExecutionEndTime,
+		ChannelIDs:         ex.channelIDs,
+		Cursors:            maps.Clone(ex.cursors),
+		RevisionCursors:    maps.Clone(ex.revisionCursors),
+		HasMessages:        ex.LegalHold.HasMessages,
+	}
+	ex.unstagedHashes = nil
+	ex.unstagedExceptions = nil
+	ex.mu.Unlock()
+
+	if !progress.IsEmpty() {
+		if err := ex.stageCheckpointFile(ex.checkpointProgressPath(ex.checkpointSequence+1), progress); err != nil {
+			return fmt.Errorf("failed to stage execution checkpoint progress: %w", err)
+		}
+		ex.checkpointSequence++
+	}
+	checkpoint.Sequence = ex.checkpointSequence
+
+	err := ex.kvstore.SaveExecutionCheckpoint(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to save execution checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// stageCheckpointIndex replaces anything staged for the checkpoint of an earlier Execution with
+// the index of the custodians and groups of this Execution.
+func (ex *Execution) stageCheckpointIndex() error {
+	err := ex.fileBackend.RemoveDirectory(ex.checkpointStagingPath())
+	if err != nil {
+		return fmt.Errorf("failed to remove the staged execution checkpoint: %w", err)
+	}
+
+	ex.mu.Lock()
+	index := ex.index
+	ex.mu.Unlock()
+
+	if err = ex.stageCheckpointFile(ex.checkpointIndexPath(), index); err != nil {
+		return fmt.Errorf("failed to stage execution checkpoint index: %w", err)
+	}
+
+	ex.checkpointSequence = 0
+	return nil
+}
+
+// stageCheckpointFile writes v as JSON to the file at filePath in the checkpoint staging area.
+func (ex *Execution) stageCheckpointFile(filePath string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = ex.fileBackend.WriteFile(bytes.NewReader(data), filePath)
+	return err
+}
+
+// readCheckpointFile reads the JSON file at filePath in the checkpoint staging area into v.
+func (ex *Execution) readCheckpointFile(filePath string, v any) error {
+	data, err := ex.fileBackend.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// readCheckpointProgress rebuilds the index, hashes and digests of an interrupted Execution from
+// its staged index and the progress files staged up to and including sequence. Progress files
+// staged after the checkpoint was last saved belong to batches that will be exported again.
+func (ex *Execution) readCheckpointProgress(sequence int) (model.LegalHoldIndex, model.HashList, model.HashList, error) {
+	index := model.NewLegalHoldIndex()
+	hashes := make(model.HashList)
+	digests := make(model.HashList)
+
+	if err := ex.readCheckpointFile(ex.checkpointIndexPath(), &index); err != nil {
+		return index, nil, nil, err
+	}
+	if index.Users == nil {
+		index.Users = make(model.LegalHoldIndexUsers)
+	}
+
+	for i := 1; i <= sequence; i++ {
+		var progress model.CheckpointProgress
+		if err := ex.readCheckpointFile(ex.checkpointProgressPath(i), &progress); err != nil {
+			return index, nil, nil, err
+		}
+
+		maps.Copy(hashes, progress.Hashes)
+		maps.Copy(digests, progress.Digests)
+		index.FileExceptions = append(index.FileExceptions, progress.FileExceptions...)
+	}
+
+	return index, hashes, digests, nil
+}
+
+// WriteRevisionsBatchToFile writes a batch of post revisions from a channel to the appropriate
+// file in the file backend.
+func (ex *Execution) WriteRevisionsBatchToFile(channelID string, revisions []model.LegalHoldPostRevision) error {
+	revisionsKey := ex.revisionsBatchPath(channelID, revisions[0].PostUpdateAt, revisions[0].PostID)
+
+	csvContent, err := gocsv.MarshalString(&revisions)
+	if err != nil {
+		return err
+	}
+
+	err = ex.writeCSVFile(revisionsKey, csvContent)
+	if err != nil {
+		return err
+	}
+
+	ex.mu.Lock()
+	ex.record.RevisionCount += len(revisions)
+	ex.LegalHold.HasMessages = true
+	ex.mu.Unlock()
+
+	return nil
+}
+
+// writeCSVFile writes the CSV content to the file indicated by key in the file backend and
+// records its hash.
+func (ex *Execution) writeCSVFile(key string, csvContent string) error {
+	written, err := ex.fileBackend.WriteFile(strings.NewReader(csvContent), key)
+	if err != nil {
+		return err
+	}
+
+	ex.mu.Lock()
+	ex.record.BytesWritten += written
+	ex.mu.Unlock()
+
+	return ex.hashFile(key, strings.NewReader(csvContent))
+}
+
+// WritePostsBatchToFile writes a batch of posts from a channel to the appropriate file
+// in the file backend.
+func (ex *Execution) WritePostsBatchToFile(channelID string, posts []model.LegalHoldPost) error {
+	msgKey := ex.messagesBatchPath(channelID, posts[0].PostCreateAt, posts[0].PostID)
 
-	_, err = ex.fileBackend.WriteFile(csvReader, msgKey)
+	csvContent, err := gocsv.MarshalString(&posts)
 	if err != nil {
 		return err
 	}
 
-	hashReader := strings.NewReader(csvContent)
+	csvReader := strings.NewReader(csvContent)
 
-	h, err := hashFromReader(ex.LegalHold.Secret, hashReader)
+	written, err := ex.fileBackend.WriteFile(csvReader, msgKey)
 	if err != nil {
 		return err
 	}
+	ex.mu.Lock()
+	ex.record.BytesWritten += written
+	ex.mu.Unlock()
 
-	err = ex.WriteFileHash(msgKey, h)
-
-	return err
+	return ex.hashFile(msgKey, strings.NewReader(csvContent))
 }
 
 // ExportFiles exports the file attachments with the provided FileIDs to the file backend.
-func (ex *Execution) ExportFiles(channelID string, batchCreateAt int64, batchPostID string, fileIDs []string) error {
+func (ex *Execution) ExportFiles(ctx context.Context, channelID string, batchCreateAt int64, batchPostID string, fileIDs []string) error {
 	if len(fileIDs) == 0 {
 		return nil
 	}
 
 	// Batch get the FileInfos for the FileIDs.
-	fileInfos, err := ex.store.GetFileInfosByIDs(fileIDs)
+	fileInfos, err := ex.store.GetFileInfosByIDs(ctx, fileIDs)
 	if err != nil {
 		return err
 	}
 
-	// Copy the files from one to another.
+	return ex.exportFileInfos(ctx, channelID, batchCreateAt, batchPostID, fileInfos)
+}
+
+// exportFileInfos stores the content of the file attachments described by fileInfos in the blob
+// area of the legal hold, and writes the references of the batch to them.
+func (ex *Execution) exportFileInfos(ctx context.Context, channelID string, batchCreateAt int64, batchPostID string, fileInfos []model.FileInfo) error {
+	references := make([]model.LegalHoldFileReference, 0, len(fileInfos))
 	for _, fileInfo := range fileInfos {
-		destPath, err := ex.filePath(
-			channelID,
-			batchCreateAt,
-			batchPostID,
-			fileInfo.ID,
-			fileInfo.Name,
-		)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		exception := model.LegalHoldFileException{
+			ExecutionStartTime: ex.ExecutionStartTime,
+			ExecutionEndTime:   ex.ExecutionEndTime,
+			ChannelID:          channelID,
+			BatchPostID:        batchPostID,
+			FileID:             fileInfo.ID,
+			FileName:           fileInfo.Name,
+			FilePath:           fileInfo.Path,
+		}
+
+		fileName, err := attachmentFileName(fileInfo.Name)
 		if err != nil {
 			ex.papi.LogWarn(
 				"Skipping file with unsafe name in legal hold export",
 				"file_id", fileInfo.ID,
 				"error", err.Error(),
 			)
+			exception.Reason = model.FileExceptionInvalidName
+			exception.Error = err.Error()
+			ex.addFileException(exception)
 			continue
 		}
 
-		err = ex.fileBackend.CopyFile(fileInfo.Path, destPath)
+		digest, blob, attempts, err := ex.exportAttachment(ctx, fileInfo)
 		if err != nil {
-			ex.papi.LogError(fmt.Sprintf("Failed to find file attachment to copy %s", fileInfo.Path))
-			// Continue anyway so the job doesn't get completely stuck.
-			return nil
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+
+			ex.papi.LogError(
+				"Failed to copy file attachment to legal hold export",
+				"legal_hold_id", ex.LegalHold.ID,
+				"file_id", fileInfo.ID,
+				"path", fileInfo.Path,
+				"attempts", attempts,
+				"error", err.Error(),
+			)
+			exception.Reason = model.FileExceptionFailed
+			if errors.Is(err, errAttachmentMissing) {
+				exception.Reason = model.FileExceptionMissing
+			}
+			exception.Error = err.Error()
+			exception.Attempts = attempts
+			ex.addFileException(exception)
+			continue
 		}
 
-		hashReader, err := ex.fileBackend.Reader(fileInfo.Path)
-		if err != nil {
-			return err
+		references = append(references, model.LegalHoldFileReference{
+			FileID:   fileInfo.ID,
+			FileName: fileName,
+			FileSize: fileInfo.Size,
+			MimeType: fileInfo.MimeType,
+			Digest:   digest,
+			BlobPath: blob,
+		})
+	}
+
+	if len(references) == 0 {
+		return nil
+	}
+
+	csvContent, err := gocsv.MarshalString(&references)
+	if err != nil {
+		return err
+	}
+
+	return ex.writeCSVFile(ex.fileReferencesBatchPath(channelID, batchCreateAt, batchPostID), csvContent)
+}
+
+// exportAttachment stores the content of the file attachment described by fileInfo in the blob
+// area of the legal hold, retrying failures that may be transient. It returns the digest of the
+// content, the path of its blob and the number of attempts made, with the error of the last one
+// if all failed.
+func (ex *Execution) exportAttachment(ctx context.Context, fileInfo model.FileInfo) (string, string, int, error) {
+	delay := fileCopyRetryDelay
+	for attempt := 1; ; attempt++ {
+		digest, blob, err := ex.storeBlob(fileInfo.Path)
+		if err == nil {
+			return digest, blob, attempt, nil
 		}
 
-		h, err := hashFromReader(ex.LegalHold.Secret, hashReader)
-		if err != nil {
-			return err
+		// Trying again will not make a missing file appear.
+		if exists, existsErr := ex.fileBackend.FileExists(fileInfo.Path); existsErr == nil && !exists {
+			return "", "", attempt, fmt.Errorf("%w: %w", errAttachmentMissing, err)
 		}
 
-		err = ex.WriteFileHash(destPath, h)
-		if err != nil {
-			return err
+		if attempt >= fileCopyAttempts {
+			return "", "", attempt, err
+		}
+
+		ex.papi.LogWarn(
+			"Retrying the copy of a file attachment to legal hold export",
+			"legal_hold_id", ex.LegalHold.ID,
+			"file_id", fileInfo.ID,
+			"attempt", attempt,
+			"error", err.Error(),
+		)
+
+		select {
+		case <-ctx.Done():
+			return "", "", attempt, ctx.Err()
+		case <-time.After(delay):
 		}
+		delay *= 2
 	}
+}
 
-	return nil
+// addFileException records a file attachment that could not be exported in the index.
+func (ex *Execution) addFileException(exception model.LegalHoldFileException) {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	ex.record.SkippedFiles++
+	ex.index.FileExceptions = append(ex.index.FileExceptions, exception)
+	ex.pendingExceptions[exception.ChannelID] = append(ex.pendingExceptions[exception.ChannelID], exception)
+}
+
+// WriteFileExceptions writes the file attachments that could not be exported by this Execution to
+// its exceptions file, if there are any.
+func (ex *Execution) WriteFileExceptions() error {
+	ex.mu.Lock()
+	exceptions := slices.Clone(ex.index.FileExceptions)
+	ex.record.FileExceptions = len(exceptions)
+	ex.mu.Unlock()
+
+	if len(exceptions) == 0 {
+		return nil
+	}
+
+	slices.SortFunc(exceptions, func(a, b model.LegalHoldFileException) int {
+		if a.ChannelID != b.ChannelID {
+			return strings.Compare(a.ChannelID, b.ChannelID)
+		}
+		return strings.Compare(a.FileID, b.FileID)
+	})
+
+	csvContent, err := gocsv.MarshalString(&exceptions)
+	if err != nil {
+		return err
+	}
+
+	return ex.writeCSVFile(ex.fileExceptionsPath(), csvContent)
+}
+
+// storeBlob copies the file at sourcePath into the blob area of the legal hold, unless the same
+// content is already stored there, and returns its SHA-256 digest and the path of its blob,
+// relative to the base path of the legal hold. The file is hashed as it is copied, so that it is
+// only read once.
+//
+// Blobs are named by their digest, or in encrypted legal holds by an HMAC-SHA256 of their content
+// under a key derived from the data key of the legal hold, so that the names of the blobs do not
+// reveal which files are held, nor which files two legal holds have in common.
+func (ex *Execution) storeBlob(sourcePath string) (string, string, error) {
+	nameKey, err := blobNameKey(ex.fileBackend, ex.basePath())
+	if err != nil {
+		return "", "", err
+	}
+
+	source, err := ex.fileBackend.Reader(sourcePath)
+	if err != nil {
+		return "", "", err
+	}
+	defer source.Close()
+
+	// The blob path is only known once the whole file has been read, so it is staged first.
+	stagingPath := fmt.Sprintf("%s/%s", ex.blobStagingPath(), mm_model.NewId())
+	hasher := newFileHasher(ex.LegalHold.Secret)
+	var sink io.Writer = hasher
+	var namer hash.Hash
+	if nameKey != nil {
+		namer = hmac.New(sha256.New, nameKey)
+		sink = io.MultiWriter(hasher, namer)
+	}
+
+	written, err := ex.fileBackend.WriteFile(io.TeeReader(source, sink), stagingPath)
+	if err != nil {
+		_ = ex.fileBackend.RemoveFile(stagingPath)
+		return "", "", err
+	}
+
+	digest := hasher.digest()
+	name := digest
+	if namer != nil {
+		name = hex.EncodeToString(namer.Sum(nil))
+	}
+	blob := blobPath(name)
+	key := fmt.Sprintf("%s/%s", ex.basePath(), blob)
+
+	unlock := ex.lockBlob(key)
+	defer unlock()
+
+	exists, err := ex.fileBackend.FileExists(key)
+	if err != nil {
+		_ = ex.fileBackend.RemoveFile(stagingPath)
+		return "", "", err
+	}
+
+	if exists {
+		err = ex.fileBackend.RemoveFile(stagingPath)
+	} else {
+		err = ex.fileBackend.MoveFile(stagingPath, key)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	ex.record.FileCount++
+	if exists {
+		ex.record.DeduplicatedFiles++
+	} else {
+		ex.record.BytesWritten += written
+	}
+	ex.hashes[key] = hasher.hash()
+	ex.digests[key] = digest
+	ex.unstagedHashes = append(ex.unstagedHashes, key)
+
+	return digest, blob, nil
+}
+
+// lockBlob locks the blob at key, so that only one worker at a time checks whether it is stored
+// and stores it. It returns the function that unlocks it.
+func (ex *Execution) lockBlob(key string) func() {
+	ex.mu.Lock()
+	lock, ok := ex.blobLocks[key]
+	if !ok {
+		lock = &blobLock{}
+		ex.blobLocks[key] = lock
+	}
+	lock.waiters++
+	ex.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+
+		ex.mu.Lock()
+		defer ex.mu.Unlock()
+		lock.waiters--
+		if lock.waiters == 0 {
+			delete(ex.blobLocks, key)
+		}
+	}
 }
 
 // UpdateIndexes updates the index files in the file backend in relation to this legal hold.
@@ -343,6 +1244,7 @@ func (ex *Execution) UpdateIndexes(now int64) error {
 	ex.index.LegalHold.DisplayName = ex.LegalHold.DisplayName
 	ex.index.LegalHold.Name = ex.LegalHold.Name
 	ex.index.LegalHold.StartsAt = ex.LegalHold.StartsAt
+	ex.index.LegalHold.Filter = ex.LegalHold.Filter
 	ex.index.LegalHold.LastExecutionEndedAt = utils.Min(ex.ExecutionEndTime, now)
 
 	if len(ex.channelIDs) > 0 {
@@ -404,6 +1306,9 @@ func (ex *Execution) UpdateIndexes(now int64) error {
 		ex.index = existingIndex
 	}
 
+	// Channels may have been exported in any order, so sort the index to keep the file stable.
+	ex.index.Sort()
+
 	// Write the index data out to the file backend.
 	data, err := json.MarshalIndent(ex.index, "", "  ")
 	if err != nil {
@@ -417,20 +1322,32 @@ func (ex *Execution) UpdateIndexes(now int64) error {
 		return err
 	}
 
-	hashReader := bytes.NewReader(data)
+	return ex.hashFile(filePath, bytes.NewReader(data))
+}
 
-	h, err := hashFromReader(ex.LegalHold.Secret, hashReader)
-	if err != nil {
+// hashFile records both the HMAC hash and the SHA-256 digest of the contents of the exported file
+// indicated by key, read from reader.
+func (ex *Execution) hashFile(key string, reader io.Reader) error {
+	hasher := newFileHasher(ex.LegalHold.Secret)
+	if _, err := io.Copy(hasher, reader); err != nil {
 		return err
 	}
 
-	err = ex.WriteFileHash(filePath, h)
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
 
-	return err
+	ex.hashes[key] = hasher.hash()
+	ex.digests[key] = hasher.digest()
+	ex.unstagedHashes = append(ex.unstagedHashes, key)
+	return nil
 }
 
 func (ex *Execution) WriteFileHash(key, hash string) error {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
 	ex.hashes[key] = hash
+	ex.unstagedHashes = append(ex.unstagedHashes, key)
 	return nil
 }
 
@@ -481,6 +1398,185 @@ func (ex *Execution) WriteFileHashes() error {
 	return nil
 }
 
+// WriteLedgerEntry appends an entry listing the digests of the files written by this Execution
+// to the ledger of the LegalHold, chained to the entry before it. The existing entries are
+// written back byte for byte, so the ledger only ever grows.
+func (ex *Execution) WriteLedgerEntry(now int64) error {
+	ledgerPath := path.Join(ex.basePath(), model.LedgerFileName)
+
+	var data []byte
+	if exists, err := ex.fileBackend.FileExists(ledgerPath); err != nil {
+		return fmt.Errorf("failed to check if ledger exists: %w", err)
+	} else if exists {
+		if data, err = ex.fileBackend.ReadFile(ledgerPath); err != nil {
+			return fmt.Errorf("failed to read ledger: %w", err)
+		}
+	}
+
+	last, digest, err := model.LastLedgerEntry(data)
+	if err != nil {
+		return err
+	}
+
+	// A retried Execution has already recorded its files if it failed after writing the ledger.
+	if last.Covers(ex.ExecutionStartTime, ex.ExecutionEndTime) {
+		return nil
+	}
+
+	entry := last.Next(digest)
+	entry.LegalHoldID = ex.LegalHold.ID
+	entry.ExecutionStartTime = ex.ExecutionStartTime
+	entry.ExecutionEndTime = ex.ExecutionEndTime
+	entry.CreateAt = now
+	entry.Files = ex.digests
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ledger entry: %w", err)
+	}
+
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+	data = append(data, line...)
+	data = append(data, '\n')
+
+	_, err = ex.fileBackend.WriteFile(bytes.NewReader(data), ledgerPath)
+	return err
+}
+
+// WriteManifest merges the digests of the files exported by this Execution into the manifest of
+// the LegalHold and signs it with the SigningKey of the installation. Files exported before
+// manifests were introduced, which are only listed in hashes.json, are digested from the file
+// backend so that the manifest always covers the whole export.
+func (ex *Execution) WriteManifest(now int64) error {
+	signingKey, err := ex.kvstore.GetOrCreateSigningKey()
+	if err != nil {
+		return fmt.Errorf("failed to get signing key: %w", err)
+	}
+
+	keyID, err := signingKey.KeyID()
+	if err != nil {
+		return err
+	}
+
+	manifestPath := path.Join(ex.basePath(), model.ManifestFileName)
+	signaturePath := path.Join(ex.basePath(), model.ManifestSignatureFileName)
+
+	manifest := model.Manifest{Files: make(model.HashList)}
+	if exists, existsErr := ex.fileBackend.FileExists(manifestPath); existsErr != nil {
+		return fmt.Errorf("failed to check if manifest exists: %w", existsErr)
+	} else if exists {
+		data, readErr := ex.fileBackend.ReadFile(manifestPath)
+		if readErr != nil {
+			return fmt.Errorf("failed to read manifest: %w", readErr)
+		}
+
+		signature, readErr := ex.fileBackend.ReadFile(signaturePath)
+		if readErr != nil {
+			return fmt.Errorf("failed to read manifest signature: %w", readErr)
+		}
+
+		// Never re-sign a manifest that was altered since it was last signed.
+		if !signingKey.Verify(data, signature) {
+			return fmt.Errorf("the signature of the existing manifest %s is not valid", manifestPath)
+		}
+
+		if err = json.Unmarshal(data, &manifest); err != nil {
+			return fmt.Errorf("failed to unmarshal manifest: %w", err)
+		}
+		if manifest.Files == nil {
+			manifest.Files = make(model.HashList)
+		}
+	}
+
+	maps.Copy(manifest.Files, ex.digests)
+
+	hashesData, err := ex.fileBackend.ReadFile(fmt.Sprintf("%s/hashes.json", ex.basePath()))
+	if err != nil {
+		return fmt.Errorf("failed to open hashes.json file: %w", err)
+	}
+
+	var hashes model.HashList
+	if err = json.Unmarshal(hashesData, &hashes); err != nil {
+		return fmt.Errorf("failed to unmarshal hashes.json file: %w", err)
+	}
+
+	for key := range hashes {
+		if _, ok := manifest.Files[key]; ok {
+			continue
+		}
+
+		digest, digestErr := ex.digestStoredFile(key)
+		if digestErr != nil {
+			return digestErr
+		}
+		manifest.Files[key] = digest
+	}
+
+	ledgerHead, err := ex.ledgerHead()
+	if err != nil {
+		return err
+	}
+
+	manifest.LegalHoldID = ex.LegalHold.ID
+	manifest.LegalHoldName = ex.LegalHold.Name
+	manifest.LedgerHead = ledgerHead
+	manifest.KeyID = keyID
+	manifest.Algorithm = model.ManifestDigestAlgorithm
+	manifest.UpdateAt = now
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	if _, err = ex.fileBackend.WriteFile(bytes.NewReader(data), manifestPath); err != nil {
+		return err
+	}
+
+	if _, err = ex.fileBackend.WriteFile(bytes.NewReader(signingKey.Sign(data)), signaturePath); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ledgerHead returns the LedgerDigest of the last entry of the ledger of the LegalHold, or empty
+// if there is no ledger.
+func (ex *Execution) ledgerHead() (string, error) {
+	ledgerPath := path.Join(ex.basePath(), model.LedgerFileName)
+
+	exists, err := ex.fileBackend.FileExists(ledgerPath)
+	if err != nil || !exists {
+		return "", err
+	}
+
+	data, err := ex.fileBackend.ReadFile(ledgerPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read ledger: %w", err)
+	}
+
+	_, digest, err := model.LastLedgerEntry(data)
+	return digest, err
+}
+
+// digestStoredFile returns the SHA-256 digest of the exported file indicated by key.
+func (ex *Execution) digestStoredFile(key string) (string, error) {
+	reader, err := ex.fileBackend.Reader(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s to digest it: %w", key, err)
+	}
+	defer reader.Close()
+
+	digester := sha256.New()
+	if _, err = io.Copy(digester, reader); err != nil {
+		return "", fmt.Errorf("failed to digest %s: %w", key, err)
+	}
+
+	return hex.EncodeToString(digester.Sum(nil)), nil
+}
+
 // basePath returns the base file storage path for this Execution.
 func (ex *Execution) basePath() string {
 	return ex.LegalHold.BasePath()
@@ -503,36 +1599,117 @@ func (ex *Execution) messagesBatchPath(channelID string, batchCreateAt int64, ba
 	)
 }
 
+// revisionsBatchPath returns the file path for a given batch of post revisions.
+func (ex *Execution) revisionsBatchPath(channelID string, batchUpdateAt int64, batchPostID string) string {
+	return fmt.Sprintf(
+		"%s/revisions/revisions-%d-%s.csv",
+		ex.channelPath(channelID),
+		batchUpdateAt,
+		batchPostID,
+	)
+}
+
+// interactionsBatchPath returns the file path for the reactions or acknowledgements, as given by
+// kind, of the posts in the batch file named batchName.
+func (ex *Execution) interactionsBatchPath(channelID string, kind string, batchName string) string {
+	return fmt.Sprintf("%s/%s/%s.csv", ex.channelPath(channelID), kind, batchName)
+}
+
 // indexPath returns the file path for the Index file for this LegalHold.
 func (ex *Execution) indexPath() string {
 	return ex.LegalHold.IndexPath()
 }
 
-// filePath returns the file path for a given file attachment within
-// this Execution. The fileName is reduced to its base component before being
-// used in path construction, and the resulting path is verified to stay within
-// the Execution's base path so that a hostile FileInfo cannot cause writes
-// outside the legal hold directory.
-func (ex *Execution) filePath(channelID string, batchCreateAt int64, batchPostID string, fileID string, fileName string) (string, error) {
-	cleanName := filepath.Base(fileName)
-	if cleanName == "" || cleanName == "." || cleanName == ".." || cleanName == "/" {
-		return "", fmt.Errorf("invalid file name %q", fileName)
-	}
-
-	p := path.Join(
+// fileReferencesBatchPath returns the file path for the references to the file attachments of a
+// given message batch.
+func (ex *Execution) fileReferencesBatchPath(channelID string, batchCreateAt int64, batchPostID string) string {
+	return fmt.Sprintf(
+		"%s/files/files-%d-%s.csv",
 		ex.channelPath(channelID),
-		"files",
-		fmt.Sprintf("files-%d-%s", batchCreateAt, batchPostID),
-		fileID,
-		cleanName,
+		batchCreateAt,
+		batchPostID,
+	)
+}
+
+// fileExceptionsPath returns the file path for the file attachments that could not be exported
+// by this Execution.
+func (ex *Execution) fileExceptionsPath() string {
+	return fmt.Sprintf(
+		"%s/exceptions/exceptions-%d-%d.csv",
+		ex.basePath(),
+		ex.ExecutionStartTime,
+		ex.ExecutionEndTime,
 	)
+}
+
+// blobStagingPath returns the directory in which file attachments are staged while they are
+// copied into the blob area of the legal hold.
+func (ex *Execution) blobStagingPath() string {
+	return fmt.Sprintf("%s/%s", ex.basePath(), blobStagingDir)
+}
+
+// checkpointStagingPath returns the directory in which the partial index and hashes of this
+// Execution are staged for its checkpoint until it completes.
+func (ex *Execution) checkpointStagingPath() string {
+	return fmt.Sprintf("%s/%s", ex.basePath(), checkpointStagingDir)
+}
+
+// checkpointIndexPath returns the file path of the index staged for the checkpoint.
+func (ex *Execution) checkpointIndexPath() string {
+	return fmt.Sprintf("%s/index.json", ex.checkpointStagingPath())
+}
+
+// checkpointProgressPath returns the file path of the progress staged for the checkpoint under
+// the provided sequence number.
+func (ex *Execution) checkpointProgressPath(sequence int) string {
+	return fmt.Sprintf("%s/progress-%08d.json", ex.checkpointStagingPath(), sequence)
+}
 
-	base := ex.basePath()
-	if cleaned := filepath.ToSlash(filepath.Clean(p)); cleaned != p || !strings.HasPrefix(cleaned, base+"/") {
-		return "", fmt.Errorf("constructed legal hold file path %q escapes base %q", p, base)
+// ListLegalHoldFiles lists the files stored for the LegalHold lh, leaving out the attachments
+// and checkpoint staged by an execution that is running or was interrupted.
+func ListLegalHoldFiles(fileBackend filestore.FileBackend, lh model.LegalHold) ([]string, error) {
+	basePath := lh.BasePath()
+	files, err := fileBackend.ListDirectoryRecursively(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the files of the legal hold: %w", err)
+	}
+
+	stagingPrefixes := []string{
+		fmt.Sprintf("%s/%s/", basePath, blobStagingDir),
+		fmt.Sprintf("%s/%s/", basePath, checkpointStagingDir),
+	}
+	listed := make([]string, 0, len(files))
+	for _, file := range files {
+		staged := false
+		for _, prefix := range stagingPrefixes {
+			if strings.HasPrefix(file, prefix) {
+				staged = true
+				break
+			}
+		}
+		if !staged {
+			listed = append(listed, file)
+		}
+	}
+	return listed, nil
+}
+
+// blobPath returns the path, relative to the base path of the legal hold, of the blob with the
+// provided name, as chosen by storeBlob.
+func blobPath(name string) string {
+	return fmt.Sprintf("blobs/%s/%s", name[:2], name)
+}
+
+// attachmentFileName returns the name under which a file attachment named fileName is listed in
+// the export, reduced to its base component so that a hostile FileInfo cannot name a file outside
+// of the directory it is extracted to.
+func attachmentFileName(fileName string) (string, error) {
+	cleanName := filepath.Base(fileName)
+	if cleanName == "" || cleanName == "." || cleanName == ".." || cleanName == "/" {
+		return "", fmt.Errorf("invalid file name %q", fileName)
 	}
 
-	return p, nil
+	return cleanName, nil
 }
 
 // hashFromReader returns the HMAC-SHA512 hash of the reader's contents.
@@ -547,28 +1724,70 @@ func hashFromReader(secret string, reader io.Reader) (string, error) {
 	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
 }
 
-func getUsersForGroups(api plugin.API, groupIDs []string) ([]*mm_model.User, error) {
-	const GroupPageLimit = 100
-	const GroupPageSize = 50
+// fileHasher computes both the HMAC-SHA512 hash and the SHA-256 digest of the content written to
+// it, so that a file only needs to be read once.
+type fileHasher struct {
+	hmac   hash.Hash
+	sha256 hash.Hash
+}
+
+func newFileHasher(secret string) *fileHasher {
+	return &fileHasher{
+		hmac:   hmac.New(sha512.New, []byte(secret)),
+		sha256: sha256.New(),
+	}
+}
+
+func (h *fileHasher) Write(p []byte) (int, error) {
+	h.hmac.Write(p)
+	h.sha256.Write(p)
+	return len(p), nil
+}
+
+// hash returns the HMAC-SHA512 hash, in the same form as hashFromReader.
+func (h *fileHasher) hash() string {
+	return fmt.Sprintf("%x", h.hmac.Sum(nil))
+}
+
+// digest returns the hex encoded SHA-256 digest.
+func (h *fileHasher) digest() string {
+	return hex.EncodeToString(h.sha256.Sum(nil))
+}
 
+func getUsersForGroups(api plugin.API, groupIDs []string) ([]*mm_model.User, error) {
 	var allUsers []*mm_model.User
 	for _, groupID := range groupIDs {
-		currPage := 0
-		for {
-			users, appErr := api.GetGroupMemberUsers(groupID, currPage, GroupPageSize)
-			if appErr != nil {
-				return nil, appErr
-			}
-			if currPage > GroupPageLimit {
-				return nil, fmt.Errorf("cannot execute legal hold: a group (%s) exceeds the maximum number of members (%d)", groupID, GroupPageLimit*GroupPageSize)
-			}
-			if len(users) < 1 {
-				break
-			}
-			allUsers = append(allUsers, users...)
-			currPage++
+		users, err := getGroupMembers(api, groupID)
+		if err != nil {
+			return nil, err
 		}
+		allUsers = append(allUsers, users...)
 	}
 
 	return allUsers, nil
 }
+
+// getGroupMembers returns the current members of the group indicated by groupID.
+func getGroupMembers(api plugin.API, groupID string) ([]*mm_model.User, error) {
+	const GroupPageLimit = 100
+	const GroupPageSize = 50
+
+	var members []*mm_model.User
+	currPage := 0
+	for {
+		users, appErr := api.GetGroupMemberUsers(groupID, currPage, GroupPageSize)
+		if appErr != nil {
+			return nil, appErr
+		}
+		if currPage > GroupPageLimit {
+			return nil, fmt.Errorf("cannot execute legal hold: a group (%s) exceeds the maximum number of members (%d)", groupID, GroupPageLimit*GroupPageSize)
+		}
+		if len(users) < 1 {
+			break
+		}
+		members = append(members, users...)
+		currPage++
+	}
+
+	return members, nil
+}
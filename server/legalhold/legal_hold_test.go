@@ -3,18 +3,30 @@ package legalhold
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	dbsql "database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/gocarina/gocsv"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
 	"github.com/mattermost/mattermost-server/v6/shared/filestore"
 
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/kvstore"
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/sqlstore"
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/utils"
 
@@ -120,22 +132,325 @@ func TestApp_LegalHoldExecution_Execute(t *testing.T) {
 	// TODO: Do some proper assertions here to really test the functionality.
 }
 
-func TestExecution_FilePath(t *testing.T) {
-	ex := &Execution{
-		LegalHold: &model.LegalHold{
-			ID:   "abc123",
-			Name: "holdname",
-		},
+func TestApp_LegalHoldExecution_ResumeAfterFailure(t *testing.T) {
+	th := sqlstore.SetupHelper(t).SetupBasic(t)
+	defer th.TearDown(t)
+
+	const channelCount = 3
+	const postCount = 5
+
+	startTime := mattermostModel.GetMillis() - 1000
+
+	channels, err := th.CreateChannelsWithChannelMemberHistory(channelCount, "resume-test", th.User1.Id, th.Team1.Id)
+	require.NoError(t, err)
+
+	for _, channel := range channels {
+		_, err = th.CreatePostsWithAttachments(postCount, th.User1.Id, channel.Id)
+		require.NoError(t, err)
+	}
+
+	lh := model.LegalHold{
+		ID:              mattermostModel.NewId(),
+		Name:            "resume-test",
+		DisplayName:     "Resume Test",
+		UserIDs:         []string{th.User1.Id},
+		StartsAt:        startTime,
+		ExecutionLength: 1000000,
+		Secret:          mattermostModel.NewId(),
+	}
+
+	api := &testAPI{users: map[string]*mattermostModel.User{th.User1.Id: th.User1}}
+	kv := newTestKVStore()
+
+	// Kill the first execution when it writes the messages of the second channel.
+	failingBackend := &testFileBackend{FileBackend: th.FileBackend, failAfterWrites: 1}
+	first := NewExecution(lh, api, th.Store, kv, failingBackend)
+	require.NoError(t, first.GetChannels())
+	require.Len(t, first.channelIDs, channelCount)
+	require.NoError(t, first.stageCheckpointIndex())
+	require.NoError(t, first.saveCheckpoint())
+	require.Error(t, first.ExportData(context.Background()))
+
+	checkpoint, err := kv.GetExecutionCheckpoint(lh.ID)
+	require.NoError(t, err)
+	require.NotNil(t, checkpoint)
+	require.True(t, checkpoint.HasMessages)
+	require.True(t, checkpoint.Cursors[first.channelIDs[0]].Completed)
+	require.NotContains(t, checkpoint.Cursors, first.channelIDs[1])
+	require.Positive(t, checkpoint.Sequence)
+
+	// The second execution picks up from the checkpoint and only exports the remaining channels.
+	countingBackend := &testFileBackend{FileBackend: th.FileBackend}
+	second := NewExecution(lh, api, th.Store, kv, countingBackend)
+	second.ChannelWorkers = 4
+	resumed, err := second.Resume(checkpoint)
+	require.NoError(t, err)
+	require.True(t, resumed)
+	require.Len(t, second.hashes, 1+postCount)
+	require.NoError(t, second.ExportData(context.Background()))
+	require.NoError(t, second.UpdateIndexes(mattermostModel.GetMillis()))
+	require.NoError(t, second.WriteFileHashes())
+
+	require.Equal(t, channelCount-1, countingBackend.messageWrites())
+	require.Equal(t, (channelCount-1)*postCount, second.Record().PostCount)
+	require.Positive(t, second.Record().BytesWritten)
+	require.Len(t, second.hashes, channelCount*(1+postCount)+1)
+	require.Len(t, second.index.Users[th.User1.Id].Channels, channelCount)
+}
+
+func TestExecution_Resume(t *testing.T) {
+	lh := model.LegalHold{
+		ID:              mattermostModel.NewId(),
+		StartsAt:        1000,
+		ExecutionLength: 1000,
+	}
+
+	fileBackend, _ := newLocalFileBackend(t)
+	kv := newTestKVStore()
+
+	// An interrupted execution stages its index and hashes, and checkpoints its cursors.
+	first := NewExecution(lh, nil, nil, kv, fileBackend)
+	first.channelIDs = []string{"channel1", "channel2"}
+	first.index.Users["user1"] = model.LegalHoldIndexUser{Username: "user1"}
+	require.NoError(t, first.stageCheckpointIndex())
+	require.NoError(t, first.saveCheckpoint())
+
+	first.LegalHold.HasMessages = true
+	require.NoError(t, first.hashFile("path1", strings.NewReader("content")))
+	first.addFileException(model.LegalHoldFileException{ChannelID: "channel1", FileID: "file1"})
+	first.commitCursor("channel1", model.LegalHoldCursor{Completed: true})
+
+	// The exceptions of a batch whose cursor is not committed yet are not staged, as the batch
+	// is exported again on resume.
+	first.addFileException(model.LegalHoldFileException{ChannelID: "channel2", FileID: "file2"})
+	require.NoError(t, first.saveCheckpoint())
+
+	require.NoError(t, first.WriteFileHash("path2", "hash2"))
+	require.NoError(t, first.saveCheckpoint())
+
+	// A checkpoint without new hashes or exceptions stages nothing.
+	require.NoError(t, first.saveCheckpoint())
+
+	checkpoint, err := kv.GetExecutionCheckpoint(lh.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, checkpoint.Sequence)
+
+	ex := NewExecution(lh, nil, nil, kv, fileBackend)
+	resumed, err := ex.Resume(checkpoint)
+	require.NoError(t, err)
+	require.True(t, resumed)
+	require.Equal(t, first.channelIDs, ex.channelIDs)
+	require.Equal(t, first.cursors, ex.cursors)
+	require.Equal(t, first.hashes, ex.hashes)
+	require.Equal(t, first.digests, ex.digests)
+	require.Equal(t, first.index.Users, ex.index.Users)
+	require.Equal(t, []model.LegalHoldFileException{{ChannelID: "channel1", FileID: "file1"}}, ex.index.FileExceptions)
+	require.True(t, ex.LegalHold.HasMessages)
+
+	// Progress staged after the checkpoint was last saved is not restored.
+	checkpoint.Sequence = 1
+	ex = NewExecution(lh, nil, nil, kv, fileBackend)
+	resumed, err = ex.Resume(checkpoint)
+	require.NoError(t, err)
+	require.True(t, resumed)
+	require.Contains(t, ex.hashes, "path1")
+	require.NotContains(t, ex.hashes, "path2")
+
+	// A checkpoint whose staged progress is missing is not resumed.
+	require.NoError(t, fileBackend.RemoveDirectory(first.checkpointStagingPath()))
+	ex = NewExecution(lh, nil, nil, kv, fileBackend)
+	resumed, err = ex.Resume(checkpoint)
+	require.Error(t, err)
+	require.False(t, resumed)
+	require.Empty(t, ex.channelIDs)
+
+	// A checkpoint for a different window is ignored.
+	lh.LastExecutionEndedAt = 2000
+	ex = NewExecution(lh, nil, nil, kv, fileBackend)
+	resumed, err = ex.Resume(checkpoint)
+	require.NoError(t, err)
+	require.False(t, resumed)
+	require.Empty(t, ex.channelIDs)
+	require.False(t, ex.LegalHold.HasMessages)
+}
+
+func TestExecution_WriteManifest(t *testing.T) {
+	fileBackend, err := filestore.NewFileBackend(filestore.FileBackendSettings{
+		DriverName: "local",
+		Directory:  t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	lh := model.LegalHold{
+		ID:              mattermostModel.NewId(),
+		Name:            "manifest-hold",
+		StartsAt:        1000,
+		ExecutionLength: 1000,
+		Secret:          "secret",
+	}
+	kv := newTestKVStore()
+
+	// An earlier execution exported a file before manifests existed, so it is only in hashes.json.
+	oldPath := lh.BasePath() + "/old.csv"
+	_, err = fileBackend.WriteFile(strings.NewReader("old content"), oldPath)
+	require.NoError(t, err)
+	oldHash, err := hashFromReader(lh.Secret, strings.NewReader("old content"))
+	require.NoError(t, err)
+	hashesData, err := json.Marshal(model.HashList{oldPath: oldHash})
+	require.NoError(t, err)
+	_, err = fileBackend.WriteFile(bytes.NewReader(hashesData), lh.BasePath()+"/hashes.json")
+	require.NoError(t, err)
+
+	ex := NewExecution(lh, nil, nil, kv, fileBackend)
+	newPath := lh.BasePath() + "/new.csv"
+	_, err = fileBackend.WriteFile(strings.NewReader("new content"), newPath)
+	require.NoError(t, err)
+	require.NoError(t, ex.hashFile(newPath, strings.NewReader("new content")))
+	require.NoError(t, ex.WriteFileHashes())
+	require.NoError(t, ex.WriteManifest(3000))
+
+	readManifest := func() ([]byte, model.Manifest) {
+		data, readErr := fileBackend.ReadFile(lh.BasePath() + "/" + model.ManifestFileName)
+		require.NoError(t, readErr)
+		signature, readErr := fileBackend.ReadFile(lh.BasePath() + "/" + model.ManifestSignatureFileName)
+		require.NoError(t, readErr)
+		require.True(t, kv.signingKey.Verify(data, signature))
+
+		var manifest model.Manifest
+		require.NoError(t, json.Unmarshal(data, &manifest))
+		return data, manifest
+	}
+
+	keyID, err := kv.signingKey.KeyID()
+	require.NoError(t, err)
+
+	_, manifest := readManifest()
+	assert.Equal(t, lh.ID, manifest.LegalHoldID)
+	assert.Equal(t, keyID, manifest.KeyID)
+	assert.Equal(t, model.ManifestDigestAlgorithm, manifest.Algorithm)
+	assert.Equal(t, int64(3000), manifest.UpdateAt)
+	assert.Equal(t, model.HashList{
+		oldPath: sha256Hex("old content"),
+		newPath: sha256Hex("new content"),
+	}, manifest.Files)
+
+	// A manifest altered since it was signed is never re-signed.
+	data, _ := readManifest()
+	tampered := bytes.Replace(data, []byte(sha256Hex("old content")), []byte(sha256Hex("forged")), 1)
+	_, err = fileBackend.WriteFile(bytes.NewReader(tampered), lh.BasePath()+"/"+model.ManifestFileName)
+	require.NoError(t, err)
+
+	ex = NewExecution(lh, nil, nil, kv, fileBackend)
+	require.ErrorContains(t, ex.WriteManifest(4000), "is not valid")
+}
+
+func TestExecution_WriteLedgerEntry(t *testing.T) {
+	fileBackend, err := filestore.NewFileBackend(filestore.FileBackendSettings{
+		DriverName: "local",
+		Directory:  t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	lh := model.LegalHold{
+		ID:              mattermostModel.NewId(),
+		Name:            "ledger-hold",
+		StartsAt:        1000,
+		ExecutionLength: 1000,
+		Secret:          "secret",
+	}
+	ledgerPath := lh.BasePath() + "/" + model.LedgerFileName
+
+	readLedger := func() [][]byte {
+		data, readErr := fileBackend.ReadFile(ledgerPath)
+		require.NoError(t, readErr)
+		return bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n"))
 	}
-	const (
-		channelID     = "channelid1"
-		batchCreateAt = int64(1700000000000)
-		batchPostID   = "postid1"
-		fileID        = "fileid1"
-	)
-	basePath := ex.LegalHold.BasePath()
-	expectedPrefix := basePath + "/"
 
+	ex := NewExecution(lh, nil, nil, newTestKVStore(), fileBackend)
+	require.NoError(t, ex.hashFile(lh.BasePath()+"/first.csv", strings.NewReader("first")))
+	require.NoError(t, ex.WriteLedgerEntry(2500))
+
+	lines := readLedger()
+	require.Len(t, lines, 1)
+
+	var first model.LedgerEntry
+	require.NoError(t, json.Unmarshal(lines[0], &first))
+	assert.Equal(t, int64(1), first.Sequence)
+	assert.Equal(t, lh.ID, first.LegalHoldID)
+	assert.Empty(t, first.PreviousDigest)
+	assert.Equal(t, model.HashList{lh.BasePath() + "/first.csv": sha256Hex("first")}, first.Files)
+
+	// Retrying the same window does not record its files twice.
+	require.NoError(t, ex.WriteLedgerEntry(2600))
+	require.Len(t, readLedger(), 1)
+
+	lh.LastExecutionEndedAt = 2000
+	ex = NewExecution(lh, nil, nil, newTestKVStore(), fileBackend)
+	require.NoError(t, ex.hashFile(lh.BasePath()+"/second.csv", strings.NewReader("second")))
+	require.NoError(t, ex.WriteLedgerEntry(3500))
+
+	lines = readLedger()
+	require.Len(t, lines, 2)
+
+	var second model.LedgerEntry
+	require.NoError(t, json.Unmarshal(lines[1], &second))
+	assert.Equal(t, int64(2), second.Sequence)
+	assert.Equal(t, model.LedgerDigest(lines[0]), second.PreviousDigest)
+	assert.Equal(t, int64(2000), second.ExecutionStartTime)
+
+	head, err := ex.ledgerHead()
+	require.NoError(t, err)
+	assert.Equal(t, model.LedgerDigest(lines[1]), head)
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestExecution_ExportDataCancelled(t *testing.T) {
+	lh := model.LegalHold{
+		ID:              mattermostModel.NewId(),
+		StartsAt:        1000,
+		ExecutionLength: 1000,
+	}
+
+	ex := NewExecution(lh, nil, nil, nil, nil)
+	ex.channelIDs = []string{"channel1"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The cancelled Execution stops before fetching any batch, leaving the cursors untouched.
+	require.ErrorIs(t, ex.ExportData(ctx), context.Canceled)
+	require.Empty(t, ex.cursors)
+}
+
+func TestExecution_ExecuteLocked(t *testing.T) {
+	defer func(timeout time.Duration) { executionWaitForLockTimeout = timeout }(executionWaitForLockTimeout)
+	executionWaitForLockTimeout = 50 * time.Millisecond
+
+	lh := model.LegalHold{
+		ID:              mattermostModel.NewId(),
+		StartsAt:        1000,
+		ExecutionLength: 1000,
+	}
+
+	// Another execution holds the cluster mutex.
+	api := &plugintest.API{}
+	api.On("KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+	defer api.AssertExpectations(t)
+
+	kvs := &testKVStore{}
+	ex := NewExecution(lh, api, nil, kvs, nil)
+
+	// The Execution is skipped without saving a record of a failed run.
+	_, err := ex.Execute(context.Background(), 3000)
+	require.ErrorIs(t, err, ErrExecutionLocked)
+	require.Empty(t, kvs.records)
+}
+
+func TestAttachmentFileName(t *testing.T) {
 	testCases := []struct {
 		name      string
 		fileName  string
@@ -145,24 +460,22 @@ func TestExecution_FilePath(t *testing.T) {
 		{
 			name:     "normal filename",
 			fileName: "document.pdf",
-			expected: basePath + "/channelid1/files/files-1700000000000-postid1/fileid1/document.pdf",
+			expected: "document.pdf",
 		},
 		{
 			name:     "filename with spaces",
 			fileName: "my document.pdf",
-			expected: basePath + "/channelid1/files/files-1700000000000-postid1/fileid1/my document.pdf",
+			expected: "my document.pdf",
 		},
 		{
-			name:      "path traversal with forward slashes",
-			fileName:  "../../../../plugins/com.mattermost.plugin-legal-hold.tar.gz",
-			expectErr: false,
-			expected:  basePath + "/channelid1/files/files-1700000000000-postid1/fileid1/com.mattermost.plugin-legal-hold.tar.gz",
+			name:     "path traversal with forward slashes",
+			fileName: "../../../../plugins/com.mattermost.plugin-legal-hold.tar.gz",
+			expected: "com.mattermost.plugin-legal-hold.tar.gz",
 		},
 		{
-			name:      "absolute path",
-			fileName:  "/etc/passwd",
-			expectErr: false,
-			expected:  basePath + "/channelid1/files/files-1700000000000-postid1/fileid1/passwd",
+			name:     "absolute path",
+			fileName: "/etc/passwd",
+			expected: "passwd",
 		},
 		{
 			name:      "single dot-dot",
@@ -185,17 +498,15 @@ func TestExecution_FilePath(t *testing.T) {
 			expectErr: true,
 		},
 		{
-			name:      "trailing slash gets cleaned",
-			fileName:  "foo/",
-			expectErr: false,
-			expected:  basePath + "/channelid1/files/files-1700000000000-postid1/fileid1/foo",
+			name:     "trailing slash gets cleaned",
+			fileName: "foo/",
+			expected: "foo",
 		},
 		{
-			name:      "filename with embedded traversal via backslashes (linux-safe)",
-			fileName:  "..\\..\\plugins\\evil.tar.gz",
-			expectErr: false,
+			name:     "filename with embedded traversal via backslashes (linux-safe)",
+			fileName: "..\\..\\plugins\\evil.tar.gz",
 			// On Linux, backslashes are valid filename characters; Base returns the whole string.
-			expected: basePath + "/channelid1/files/files-1700000000000-postid1/fileid1/..\\..\\plugins\\evil.tar.gz",
+			expected: "..\\..\\plugins\\evil.tar.gz",
 		},
 		{
 			name:      "ends with dot-dot segment",
@@ -206,15 +517,366 @@ func TestExecution_FilePath(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := ex.filePath(channelID, batchCreateAt, batchPostID, fileID, tc.fileName)
+			got, err := attachmentFileName(tc.fileName)
 			if tc.expectErr {
 				require.Error(t, err)
 				return
 			}
 			require.NoError(t, err)
 			require.Equal(t, tc.expected, got)
-			require.True(t, strings.HasPrefix(got, expectedPrefix),
-				"path %q must stay inside base %q", got, basePath)
+		})
+	}
+}
+
+func TestExecution_ExportFileInfos(t *testing.T) {
+	fileBackend, err := filestore.NewFileBackend(filestore.FileBackendSettings{
+		DriverName: "local",
+		Directory:  t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	lh := model.LegalHold{
+		ID:              mattermostModel.NewId(),
+		Name:            "blob-hold",
+		StartsAt:        1000,
+		ExecutionLength: 1000,
+		Secret:          "secret",
+	}
+
+	// The same PDF was attached to three posts, two of them in the same batch.
+	attachments := map[string]string{
+		"data/original.pdf":  "pdf content",
+		"data/forwarded.pdf": "pdf content",
+		"data/reshared.pdf":  "pdf content",
+		"data/notes.txt":     "notes",
+		"data/unsafe":        "unsafe",
+	}
+	for filePath, content := range attachments {
+		_, err = fileBackend.WriteFile(strings.NewReader(content), filePath)
+		require.NoError(t, err)
+	}
+
+	ex := NewExecution(lh, &testAPI{}, nil, newTestKVStore(), fileBackend)
+	ctx := context.Background()
+
+	err = ex.exportFileInfos(ctx, "channel1", 1100, "post1", []model.FileInfo{
+		{ID: "file1", Path: "data/original.pdf", Name: "report.pdf", Size: 11, MimeType: "application/pdf"},
+		{ID: "file2", Path: "data/notes.txt", Name: "notes.txt", Size: 5, MimeType: "text/plain"},
+		{ID: "file3", Path: "data/forwarded.pdf", Name: "report.pdf", Size: 11, MimeType: "application/pdf"},
+		{ID: "file4", Path: "data/unsafe", Name: "..", Size: 6},
+	})
+	require.NoError(t, err)
+	err = ex.exportFileInfos(ctx, "channel2", 1200, "post2", []model.FileInfo{
+		{ID: "file5", Path: "data/reshared.pdf", Name: "copy of report.pdf", Size: 11, MimeType: "application/pdf"},
+	})
+	require.NoError(t, err)
+
+	// Each distinct content is stored once, and nothing is left staged.
+	pdfBlob := lh.BasePath() + "/" + blobPath(sha256Hex("pdf content"))
+	notesBlob := lh.BasePath() + "/" + blobPath(sha256Hex("notes"))
+	stored, err := fileBackend.ListDirectoryRecursively(lh.BasePath() + "/blobs")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{pdfBlob, notesBlob}, stored)
+
+	data, err := fileBackend.ReadFile(pdfBlob)
+	require.NoError(t, err)
+	assert.Equal(t, "pdf content", string(data))
+
+	// Each batch lists the attachments of its posts.
+	var references []model.LegalHoldFileReference
+	data, err = fileBackend.ReadFile(ex.fileReferencesBatchPath("channel1", 1100, "post1"))
+	require.NoError(t, err)
+	require.NoError(t, gocsv.UnmarshalBytes(data, &references))
+	require.Len(t, references, 3)
+	assert.Equal(t, model.LegalHoldFileReference{
+		FileID:   "file1",
+		FileName: "report.pdf",
+		FileSize: 11,
+		MimeType: "application/pdf",
+		Digest:   sha256Hex("pdf content"),
+		BlobPath: blobPath(sha256Hex("pdf content")),
+	}, references[0])
+	assert.Equal(t, "file2", references[1].FileID)
+	assert.Equal(t, sha256Hex("notes"), references[1].Digest)
+	assert.Equal(t, "file3", references[2].FileID)
+	assert.Equal(t, references[0].BlobPath, references[2].BlobPath)
+
+	references = nil
+	data, err = fileBackend.ReadFile(ex.fileReferencesBatchPath("channel2", 1200, "post2"))
+	require.NoError(t, err)
+	require.NoError(t, gocsv.UnmarshalBytes(data, &references))
+	require.Len(t, references, 1)
+	assert.Equal(t, "copy of report.pdf", references[0].FileName)
+	assert.Equal(t, blobPath(sha256Hex("pdf content")), references[0].BlobPath)
+
+	// The blobs are hashed as they are copied.
+	expectedHash, err := hashFromReader(lh.Secret, strings.NewReader("pdf content"))
+	require.NoError(t, err)
+	assert.Equal(t, expectedHash, ex.hashes[pdfBlob])
+	assert.Equal(t, sha256Hex("pdf content"), ex.digests[pdfBlob])
+	assert.Contains(t, ex.hashes, notesBlob)
+
+	record := ex.Record()
+	assert.Equal(t, 4, record.FileCount)
+	assert.Equal(t, 2, record.DeduplicatedFiles)
+	assert.Equal(t, 1, record.SkippedFiles)
+}
+
+func TestExecution_StoreBlobConcurrently(t *testing.T) {
+	local, _ := newLocalFileBackend(t)
+	_, err := local.WriteFile(strings.NewReader("pdf content"), "data/report.pdf")
+	require.NoError(t, err)
+
+	// Checking whether a blob is stored is slow to answer, so the workers overlap.
+	fileBackend := &testFileBackend{FileBackend: local, existsDelay: 10 * time.Millisecond}
+
+	lh := model.LegalHold{ID: mattermostModel.NewId(), Name: "concurrent-hold", Secret: "secret"}
+	ex := NewExecution(lh, &testAPI{}, nil, newTestKVStore(), fileBackend)
+
+	// Workers storing the same content at the same time store it once.
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, storeErr := ex.storeBlob("data/report.pdf")
+			errs <- storeErr
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for storeErr := range errs {
+		require.NoError(t, storeErr)
+	}
+
+	record := ex.Record()
+	assert.Equal(t, workers, record.FileCount)
+	assert.Equal(t, workers-1, record.DeduplicatedFiles)
+	assert.Equal(t, int64(len("pdf content")), record.BytesWritten)
+	assert.Empty(t, ex.blobLocks)
+}
+
+func TestExecution_ExportFileInfosEncrypted(t *testing.T) {
+	local, _ := newLocalFileBackend(t)
+	fileBackend := NewEncryptedFileBackend(local, newMasterKey(t))
+
+	_, err := fileBackend.WriteFile(strings.NewReader("pdf content"), "data/report.pdf")
+	require.NoError(t, err)
+	fileInfos := []model.FileInfo{
+		{ID: "file1", Path: "data/report.pdf", Name: "report.pdf"},
+		{ID: "file2", Path: "data/report.pdf", Name: "copy of report.pdf"},
+	}
+
+	// Two encrypted legal holds hold the same attachment.
+	var blobs []string
+	for _, name := range []string{"first-hold", "second-hold"} {
+		lh := model.LegalHold{
+			ID:              mattermostModel.NewId(),
+			Name:            name,
+			StartsAt:        1000,
+			ExecutionLength: 1000,
+			Secret:          "secret",
+		}
+		ex := NewExecution(lh, &testAPI{}, nil, newTestKVStore(), fileBackend)
+		require.NoError(t, ex.exportFileInfos(context.Background(), "channel1", 1100, "post1", fileInfos))
+
+		var references []model.LegalHoldFileReference
+		data, readErr := fileBackend.ReadFile(ex.fileReferencesBatchPath("channel1", 1100, "post1"))
+		require.NoError(t, readErr)
+		require.NoError(t, gocsv.UnmarshalBytes(data, &references))
+		require.Len(t, references, 2)
+
+		// The content is still stored once in each legal hold, and listed with its digest.
+		assert.Equal(t, references[0].BlobPath, references[1].BlobPath)
+		assert.Equal(t, sha256Hex("pdf content"), references[0].Digest)
+		assert.Equal(t, 1, ex.Record().DeduplicatedFiles)
+
+		data, readErr = fileBackend.ReadFile(lh.BasePath() + "/" + references[0].BlobPath)
+		require.NoError(t, readErr)
+		assert.Equal(t, "pdf content", string(data))
+
+		blobs = append(blobs, references[0].BlobPath)
+	}
+
+	// The blobs are not named by their digest, so their names do not give away the attachment,
+	// nor that the legal holds share it.
+	assert.NotEqual(t, blobPath(sha256Hex("pdf content")), blobs[0])
+	assert.NotEqual(t, blobs[0], blobs[1])
+}
+
+func TestExecution_ExportFileInfosExceptions(t *testing.T) {
+	retryDelay := fileCopyRetryDelay
+	fileCopyRetryDelay = time.Millisecond
+	t.Cleanup(func() {
+		fileCopyRetryDelay = retryDelay
+	})
+
+	local, err := filestore.NewFileBackend(filestore.FileBackendSettings{
+		DriverName: "local",
+		Directory:  t.TempDir(),
+	})
+	require.NoError(t, err)
+	fileBackend := &testFileBackend{FileBackend: local}
+
+	for _, filePath := range []string{"data/flaky.pdf", "data/broken.pdf", "data/after.pdf"} {
+		_, err = local.WriteFile(strings.NewReader(filePath), filePath)
+		require.NoError(t, err)
+	}
+
+	lh := model.LegalHold{
+		ID:              mattermostModel.NewId(),
+		Name:            "exceptions-hold",
+		StartsAt:        1000,
+		ExecutionLength: 1000,
+		Secret:          "secret",
+	}
+	ex := NewExecution(lh, &testAPI{}, nil, newTestKVStore(), fileBackend)
+	ctx := context.Background()
+
+	// A transient failure is retried.
+	fileBackend.failReads = fileCopyAttempts - 1
+	err = ex.exportFileInfos(ctx, "channel1", 1100, "post1", []model.FileInfo{
+		{ID: "flaky", Path: "data/flaky.pdf", Name: "flaky.pdf"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, ex.index.FileExceptions)
+
+	// Missing files, and files that keep failing, are recorded without stopping the batch.
+	fileBackend.failReads = fileCopyAttempts
+	err = ex.exportFileInfos(ctx, "channel1", 1200, "post2", []model.FileInfo{
+		{ID: "broken", Path: "data/broken.pdf", Name: "broken.pdf"},
+		{ID: "missing", Path: "data/missing.pdf", Name: "missing.pdf"},
+		{ID: "unsafe", Path: "data/unsafe", Name: ".."},
+		{ID: "after", Path: "data/after.pdf", Name: "after.pdf"},
+	})
+	require.NoError(t, err)
+
+	var references []model.LegalHoldFileReference
+	data, err := local.ReadFile(ex.fileReferencesBatchPath("channel1", 1200, "post2"))
+	require.NoError(t, err)
+	require.NoError(t, gocsv.UnmarshalBytes(data, &references))
+	require.Len(t, references, 1)
+	assert.Equal(t, "after", references[0].FileID)
+
+	reasons := make(map[string]string)
+	attempts := make(map[string]int)
+	for _, exception := range ex.index.FileExceptions {
+		assert.Equal(t, ex.ExecutionStartTime, exception.ExecutionStartTime)
+		assert.Equal(t, "channel1", exception.ChannelID)
+		assert.Equal(t, "post2", exception.BatchPostID)
+		reasons[exception.FileID] = exception.Reason
+		attempts[exception.FileID] = exception.Attempts
+	}
+	assert.Equal(t, map[string]string{
+		"broken":  model.FileExceptionFailed,
+		"missing": model.FileExceptionMissing,
+		"unsafe":  model.FileExceptionInvalidName,
+	}, reasons)
+	assert.Equal(t, fileCopyAttempts, attempts["broken"])
+	assert.Equal(t, 1, attempts["missing"])
+
+	// The exceptions are written to the exceptions file of the execution, and counted.
+	require.NoError(t, ex.WriteFileExceptions())
+	var exceptions []model.LegalHoldFileException
+	data, err = local.ReadFile(ex.fileExceptionsPath())
+	require.NoError(t, err)
+	require.NoError(t, gocsv.UnmarshalBytes(data, &exceptions))
+	require.Len(t, exceptions, 3)
+	assert.Equal(t, "broken", exceptions[0].FileID)
+	assert.Contains(t, ex.hashes, ex.fileExceptionsPath())
+
+	record := ex.Record()
+	assert.Equal(t, 3, record.FileExceptions)
+	assert.Equal(t, 3, record.SkippedFiles)
+	assert.Equal(t, 2, record.FileCount)
+}
+
+func TestExecution_AddChannelMembershipToIndex(t *testing.T) {
+	ex := &Execution{
+		index: model.NewLegalHoldIndex(),
+	}
+
+	ex.addChannelMembershipToIndex("user1", "username1", "user1@example.com", model.LegalHoldChannelMembership{
+		ChannelID: "channel1",
+		StartTime: 100,
+		EndTime:   200,
+	})
+	ex.addChannelMembershipToIndex("user1", "username1", "user1@example.com", model.LegalHoldChannelMembership{
+		ChannelID: "channel1",
+		StartTime: 50,
+		EndTime:   150,
+	})
+	ex.addChannelMembershipToIndex("user1", "username1", "user1@example.com", model.LegalHoldChannelMembership{
+		ChannelID: "channel2",
+		StartTime: 100,
+		EndTime:   200,
+	})
+
+	user, ok := ex.index.Users["user1"]
+	require.True(t, ok)
+	require.Equal(t, "username1", user.Username)
+	require.Equal(t, []model.LegalHoldChannelMembership{
+		{ChannelID: "channel1", StartTime: 50, EndTime: 200},
+		{ChannelID: "channel2", StartTime: 100, EndTime: 200},
+	}, user.Channels)
+}
+
+func TestExecution_GetGroupCustodians(t *testing.T) {
+	backend, _ := newLocalFileBackend(t)
+
+	member := &mattermostModel.User{Id: mattermostModel.NewId(), Username: "member"}
+	former := &mattermostModel.User{Id: mattermostModel.NewId(), Username: "former"}
+	groupName := "legal"
+	group := &mattermostModel.Group{Id: mattermostModel.NewId(), Name: &groupName, DisplayName: "Legal"}
+
+	api := &plugintest.API{}
+	api.On("GetGroup", group.Id).Return(group, nil)
+	api.On("GetGroupMemberUsers", group.Id, 0, 50).Return([]*mattermostModel.User{member}, nil)
+	api.On("GetGroupMemberUsers", group.Id, 1, 50).Return([]*mattermostModel.User{}, nil)
+	api.On("GetUser", former.Id).Return(former, nil)
+	api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	lh := model.LegalHold{ID: mattermostModel.NewId(), GroupIDs: []string{group.Id}, StartsAt: 1000, LastExecutionEndedAt: 2000}
+
+	// An earlier execution captured the former member in the group.
+	previousIndex := model.NewLegalHoldIndex()
+	previousIndex.Groups = []*model.LegalHoldIndexGroup{{
+		ID:        group.Id,
+		Snapshots: []model.LegalHoldGroupSnapshot{{ExecutionStartTime: 1000, ExecutionEndTime: 2000, CapturedAt: 2100, UserIDs: []string{member.Id, former.Id}}},
+		Members: []model.LegalHoldGroupMember{
+			{UserID: member.Id, Username: member.Username},
+			{UserID: former.Id, Username: former.Username},
+		},
+	}}
+	data, err := json.Marshal(previousIndex)
+	require.NoError(t, err)
+	_, err = backend.WriteFile(bytes.NewReader(data), lh.IndexPath())
+	require.NoError(t, err)
+
+	for name, keep := range map[string]bool{"only current members": false, "captured members are kept": true} {
+		t.Run(name, func(t *testing.T) {
+			ex := NewExecution(lh, api, nil, newTestKVStore(), backend)
+			ex.KeepCapturedCustodians = keep
+
+			custodians, err := ex.getGroupCustodians(3100)
+			require.NoError(t, err)
+
+			expected := []*mattermostModel.User{member}
+			if keep {
+				expected = append(expected, former)
+			}
+			require.Equal(t, expected, custodians)
+
+			// The snapshot records the actual members of the group either way.
+			require.Equal(t, []*model.LegalHoldIndexGroup{{
+				ID:          group.Id,
+				Name:        "legal",
+				DisplayName: "Legal",
+				Snapshots:   []model.LegalHoldGroupSnapshot{{ExecutionStartTime: 2000, ExecutionEndTime: ex.ExecutionEndTime, CapturedAt: 3100, UserIDs: []string{member.Id}}},
+				Members:     []model.LegalHoldGroupMember{{UserID: member.Id, Username: member.Username}},
+			}}, ex.index.Groups)
 		})
 	}
 }
@@ -271,3 +933,273 @@ func TestLegalHold_Hash(t *testing.T) {
 		})
 	}
 }
+
+// testAPI is a minimal plugin.API for executing legal holds in tests.
+type testAPI struct {
+	plugin.API
+	users map[string]*mattermostModel.User
+}
+
+func (a *testAPI) GetUser(userID string) (*mattermostModel.User, *mattermostModel.AppError) {
+	user, ok := a.users[userID]
+	if !ok {
+		return nil, mattermostModel.NewAppError("GetUser", "user_not_found", nil, "", 404)
+	}
+	return user, nil
+}
+
+func (a *testAPI) LogDebug(string, ...any) {}
+func (a *testAPI) LogInfo(string, ...any)  {}
+func (a *testAPI) LogWarn(string, ...any)  {}
+func (a *testAPI) LogError(string, ...any) {}
+
+// testKVStore is an in-memory kvstore.KVStore for the parts of it used by executions.
+type testKVStore struct {
+	kvstore.KVStore
+	mu             sync.Mutex
+	checkpoints    map[string]model.ExecutionCheckpoint
+	records        []model.ExecutionRecord
+	signingKey     *model.SigningKey
+	packages       []model.ExportPackage
+	downloadTokens []model.DownloadToken
+	notices        []model.CustodianNotice
+	tombstones     []model.LegalHoldTombstone
+	deletedHolds   []string
+}
+
+func newTestKVStore() *testKVStore {
+	return &testKVStore{checkpoints: make(map[string]model.ExecutionCheckpoint)}
+}
+
+func (kv *testKVStore) SaveExecutionCheckpoint(cp model.ExecutionCheckpoint) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	// Round trip through JSON so that the saved checkpoint does not share state with the execution.
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	var saved model.ExecutionCheckpoint
+	if err = json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	kv.checkpoints[cp.LegalHoldID] = saved
+	return nil
+}
+
+func (kv *testKVStore) GetExecutionCheckpoint(legalHoldID string) (*model.ExecutionCheckpoint, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	cp, ok := kv.checkpoints[legalHoldID]
+	if !ok {
+		return nil, nil
+	}
+	return &cp, nil
+}
+
+func (kv *testKVStore) DeleteExecutionCheckpoint(legalHoldID string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	delete(kv.checkpoints, legalHoldID)
+	return nil
+}
+
+func (kv *testKVStore) SaveExecutionRecord(record model.ExecutionRecord) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.records = append(kv.records, record)
+	return nil
+}
+
+func (kv *testKVStore) SaveExportPackage(pkg model.ExportPackage) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.packages = append(kv.packages, pkg)
+	return nil
+}
+
+func (kv *testKVStore) GetExportPackages(legalHoldID string) ([]model.ExportPackage, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	var packages []model.ExportPackage
+	for _, pkg := range kv.packages {
+		if pkg.LegalHoldID == legalHoldID {
+			packages = append(packages, pkg)
+		}
+	}
+	return packages, nil
+}
+
+func (kv *testKVStore) DeleteExportPackage(legalHoldID, id string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.packages = slices.DeleteFunc(kv.packages, func(pkg model.ExportPackage) bool {
+		return pkg.LegalHoldID == legalHoldID && pkg.ID == id
+	})
+	return nil
+}
+
+func (kv *testKVStore) GetDownloadTokens(legalHoldID string) ([]model.DownloadToken, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	var tokens []model.DownloadToken
+	for _, token := range kv.downloadTokens {
+		if token.LegalHoldID == legalHoldID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (kv *testKVStore) DeleteDownloadToken(legalHoldID, id string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.downloadTokens = slices.DeleteFunc(kv.downloadTokens, func(token model.DownloadToken) bool {
+		return token.LegalHoldID == legalHoldID && token.ID == id
+	})
+	return nil
+}
+
+func (kv *testKVStore) SaveCustodianNotice(notice model.CustodianNotice) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.notices = slices.DeleteFunc(kv.notices, func(n model.CustodianNotice) bool {
+		return n.LegalHoldID == notice.LegalHoldID && n.UserID == notice.UserID
+	})
+	kv.notices = append(kv.notices, notice)
+	return nil
+}
+
+func (kv *testKVStore) GetCustodianNotice(legalHoldID, userID string) (*model.CustodianNotice, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	for _, notice := range kv.notices {
+		if notice.LegalHoldID == legalHoldID && notice.UserID == userID {
+			return &notice, nil
+		}
+	}
+	return nil, nil
+}
+
+func (kv *testKVStore) GetCustodianNotices(legalHoldID string) ([]model.CustodianNotice, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	var notices []model.CustodianNotice
+	for _, notice := range kv.notices {
+		if notice.LegalHoldID == legalHoldID {
+			notices = append(notices, notice)
+		}
+	}
+	return notices, nil
+}
+
+func (kv *testKVStore) DeleteCustodianNotice(legalHoldID, userID string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.notices = slices.DeleteFunc(kv.notices, func(notice model.CustodianNotice) bool {
+		return notice.LegalHoldID == legalHoldID && notice.UserID == userID
+	})
+	return nil
+}
+
+func (kv *testKVStore) SaveLegalHoldTombstone(tombstone model.LegalHoldTombstone) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.tombstones = append(kv.tombstones, tombstone)
+	return nil
+}
+
+func (kv *testKVStore) DeleteLegalHold(id string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.deletedHolds = append(kv.deletedHolds, id)
+	return nil
+}
+
+func (kv *testKVStore) GetOrCreateSigningKey() (*model.SigningKey, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if kv.signingKey == nil {
+		key, err := model.NewSigningKey()
+		if err != nil {
+			return nil, err
+		}
+		kv.signingKey = key
+	}
+	return kv.signingKey, nil
+}
+
+// testFileBackend wraps a filestore.FileBackend, recording the paths written to and optionally
+// failing once a number of writes have been made, or failing a number of reads.
+type testFileBackend struct {
+	filestore.FileBackend
+	mu              sync.Mutex
+	failAfterWrites int
+	failReads       int
+	existsDelay     time.Duration
+	written         []string
+}
+
+func (b *testFileBackend) FileExists(path string) (bool, error) {
+	exists, err := b.FileBackend.FileExists(path)
+	time.Sleep(b.existsDelay)
+	return exists, err
+}
+
+func (b *testFileBackend) Reader(path string) (filestore.ReadCloseSeeker, error) {
+	b.mu.Lock()
+	if b.failReads > 0 {
+		b.failReads--
+		b.mu.Unlock()
+		return nil, errors.New("simulated read failure")
+	}
+	b.mu.Unlock()
+
+	return b.FileBackend.Reader(path)
+}
+
+func (b *testFileBackend) WriteFile(fr io.Reader, path string) (int64, error) {
+	// The progress staged for checkpoints is not part of the export.
+	if strings.Contains(path, "/staging/checkpoint/") {
+		return b.FileBackend.WriteFile(fr, path)
+	}
+
+	b.mu.Lock()
+	if b.failAfterWrites > 0 && len(b.written) >= b.failAfterWrites {
+		b.mu.Unlock()
+		return 0, errors.New("simulated write failure")
+	}
+	b.written = append(b.written, path)
+	b.mu.Unlock()
+
+	return b.FileBackend.WriteFile(fr, path)
+}
+
+func (b *testFileBackend) messageWrites() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := 0
+	for _, path := range b.written {
+		if strings.Contains(path, "/messages/") {
+			count++
+		}
+	}
+	return count
+}
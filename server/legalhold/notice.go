@@ -0,0 +1,296 @@
+package legalhold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-api/cluster"
+	mm_model "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/wiggin77/merror"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/kvstore"
+)
+
+// noticeWaitForLockTimeout is the time to wait for the notices of other legal holds to be sent.
+const noticeWaitForLockTimeout = time.Minute
+
+// acknowledgeActionID identifies the button of the notices that custodians acknowledge them with.
+const acknowledgeActionID = "acknowledge"
+
+// ErrNoticeNotFound is returned when acknowledging a notice that was not sent to the custodian.
+var ErrNoticeNotFound = errors.New("legal hold notice not found")
+
+// NoticeSettings are the settings of the notices sent to custodians.
+type NoticeSettings struct {
+	// Message is the text of the notice, which follows the name of the legal hold.
+	Message string
+	// ReminderInterval is how long to wait for a custodian to acknowledge the notice before
+	// reminding them of it, or zero if custodians are not reminded.
+	ReminderInterval time.Duration
+}
+
+// Notifier sends the legal hold notice to the custodians of legal holds in a direct message from
+// the bot, reminds the custodians who have not acknowledged it yet, and records their
+// acknowledgements.
+type Notifier struct {
+	papi     plugin.API
+	kvstore  kvstore.KVStore
+	botID    string
+	pluginID string
+}
+
+// NewNotifier creates a new Notifier that sends the notices from the bot indicated by botID, with
+// an acknowledgement button served by the plugin indicated by pluginID.
+func NewNotifier(papi plugin.API, kvstore kvstore.KVStore, botID, pluginID string) *Notifier {
+	return &Notifier{
+		papi:     papi,
+		kvstore:  kvstore,
+		botID:    botID,
+		pluginID: pluginID,
+	}
+}
+
+// NotifyAll sends the notice to the custodians of all the legal holds that have not been sent it
+// yet, and reminds the custodians who are due a reminder, at the time provided in "now". Released
+// legal holds are skipped.
+func (n *Notifier) NotifyAll(ctx context.Context, settings NoticeSettings, now int64) error {
+	unlock, err := n.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	legalHolds, err := n.kvstore.GetAllLegalHolds()
+	if err != nil {
+		return fmt.Errorf("failed to get legal holds: %w", err)
+	}
+
+	merr := merror.New()
+	for _, lh := range legalHolds {
+		if ctx.Err() != nil {
+			merr.Append(ctx.Err())
+			break
+		}
+
+		if lh.IsReleased() {
+			continue
+		}
+
+		if err = n.notify(lh, settings, now); err != nil {
+			merr.Append(fmt.Errorf("legal hold %s: %w", lh.ID, err))
+		}
+	}
+
+	return merr.ErrorOrNil()
+}
+
+// NotifyLegalHold sends the notice to the custodians of the legal hold that have not been sent it
+// yet, such as the custodians just added to it, and reminds the custodians who are due a reminder.
+func (n *Notifier) NotifyLegalHold(ctx context.Context, lh model.LegalHold, settings NoticeSettings, now int64) error {
+	if lh.IsReleased() {
+		return nil
+	}
+
+	unlock, err := n.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return n.notify(lh, settings, now)
+}
+
+// lock stops the notices from being sent by more than one node of the cluster at a time, so that
+// custodians are not sent the same notice twice.
+func (n *Notifier) lock(ctx context.Context) (func(), error) {
+	mutex, err := cluster.NewMutex(n.papi, "legal_hold_custodian_notices")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cluster mutex: %w", err)
+	}
+
+	lockCtx, cancel := context.WithTimeout(ctx, noticeWaitForLockTimeout)
+	defer cancel()
+
+	if err = mutex.LockWithContext(lockCtx); err != nil {
+		return nil, fmt.Errorf("failed to lock cluster mutex: %w", err)
+	}
+
+	return mutex.Unlock, nil
+}
+
+func (n *Notifier) notify(lh model.LegalHold, settings NoticeSettings, now int64) error {
+	custodians, err := Custodians(n.papi, lh)
+	if err != nil {
+		return fmt.Errorf("failed to get the custodians: %w", err)
+	}
+
+	notices, err := n.kvstore.GetCustodianNotices(lh.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get the custodian notices: %w", err)
+	}
+
+	noticesByUser := make(map[string]model.CustodianNotice, len(notices))
+	for _, notice := range notices {
+		noticesByUser[notice.UserID] = notice
+	}
+
+	merr := merror.New()
+	for _, user := range custodians {
+		// Deactivated users cannot read their direct messages.
+		if user.DeleteAt != 0 {
+			continue
+		}
+
+		notice, notified := noticesByUser[user.Id]
+		if notified && !notice.IsDueForReminder(now, settings.ReminderInterval.Milliseconds()) {
+			continue
+		}
+
+		postID, sendErr := n.sendNotice(lh, user.Id, settings.Message, notified)
+		if sendErr != nil {
+			merr.Append(fmt.Errorf("failed to send the notice to custodian %s: %w", user.Id, sendErr))
+			continue
+		}
+
+		if notified {
+			notice.PostIDs = append(notice.PostIDs, postID)
+			notice.RemindedAt = now
+			notice.ReminderCount++
+		} else {
+			notice = model.NewCustodianNotice(lh.ID, user.Id, postID, now)
+		}
+
+		if err = n.kvstore.SaveCustodianNotice(notice); err != nil {
+			merr.Append(fmt.Errorf("failed to save the notice of custodian %s: %w", user.Id, err))
+		}
+	}
+
+	return merr.ErrorOrNil()
+}
+
+// sendNotice sends the notice of the legal hold, or a reminder of it, to the custodian indicated
+// by userID, and returns the ID of its post.
+func (n *Notifier) sendNotice(lh model.LegalHold, userID, message string, reminder bool) (string, error) {
+	channel, appErr := n.papi.GetDirectChannel(userID, n.botID)
+	if appErr != nil {
+		return "", appErr
+	}
+
+	title := "Legal hold notice: " + lh.DisplayName
+	if reminder {
+		title = "Reminder: " + title
+	}
+
+	post := &mm_model.Post{
+		UserId:    n.botID,
+		ChannelId: channel.Id,
+	}
+	mm_model.ParseSlackAttachment(post, []*mm_model.SlackAttachment{{
+		Title: title,
+		Text:  message,
+		Actions: []*mm_model.PostAction{{
+			Id:    acknowledgeActionID,
+			Name:  "Acknowledge",
+			Type:  mm_model.PostActionTypeButton,
+			Style: "primary",
+			Integration: &mm_model.PostActionIntegration{
+				URL: fmt.Sprintf("/plugins/%s/api/v1/legalholds/%s/acknowledge", n.pluginID, lh.ID),
+			},
+		}},
+	}})
+
+	created, appErr := n.papi.CreatePost(post)
+	if appErr != nil {
+		return "", appErr
+	}
+
+	return created.Id, nil
+}
+
+// Acknowledge records that the custodian indicated by userID acknowledged the notice of the legal
+// hold indicated by legalHoldID from the post indicated by postID, which must be the notice or one
+// of its reminders. The acknowledgement button is removed from all of them.
+func (n *Notifier) Acknowledge(legalHoldID, userID, postID string, now int64) (*model.CustodianNotice, error) {
+	notice, err := n.kvstore.GetCustodianNotice(legalHoldID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the custodian notice: %w", err)
+	}
+	if notice == nil || !notice.HasPost(postID) {
+		return nil, ErrNoticeNotFound
+	}
+
+	if !notice.IsAcknowledged() {
+		notice.AcknowledgedAt = now
+		if err = n.kvstore.SaveCustodianNotice(*notice); err != nil {
+			return nil, fmt.Errorf("failed to save the custodian notice: %w", err)
+		}
+	}
+
+	for _, id := range notice.PostIDs {
+		if err = n.markAcknowledged(id, notice.AcknowledgedAt); err != nil {
+			n.papi.LogWarn("Failed to mark the legal hold notice as acknowledged", "legal_hold_id", legalHoldID, "post_id", id, "err", err.Error())
+		}
+	}
+
+	return notice, nil
+}
+
+// markAcknowledged replaces the acknowledgement button of the post of a notice with the time it
+// was acknowledged.
+func (n *Notifier) markAcknowledged(postID string, acknowledgedAt int64) error {
+	post, appErr := n.papi.GetPost(postID)
+	if appErr != nil {
+		return appErr
+	}
+
+	attachments := post.Attachments()
+	for _, attachment := range attachments {
+		attachment.Actions = nil
+		attachment.Fields = []*mm_model.SlackAttachmentField{{
+			Title: "Acknowledged",
+			Value: time.UnixMilli(acknowledgedAt).UTC().Format(time.RFC1123),
+		}}
+	}
+	mm_model.ParseSlackAttachment(post, attachments)
+
+	if _, appErr = n.papi.UpdatePost(post); appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
+
+// Custodians returns the custodians of the legal hold: its users and the members of its groups,
+// each of them once.
+func Custodians(papi plugin.API, lh model.LegalHold) ([]*mm_model.User, error) {
+	groupUsers, err := getUsersForGroups(papi, lh.GroupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*mm_model.User, 0, len(lh.UserIDs)+len(groupUsers))
+	for _, userID := range lh.UserIDs {
+		user, appErr := papi.GetUser(userID)
+		if appErr != nil {
+			return nil, appErr
+		}
+		users = append(users, user)
+	}
+	users = append(users, groupUsers...)
+
+	seen := make(map[string]struct{}, len(users))
+	custodians := make([]*mm_model.User, 0, len(users))
+	for _, user := range users {
+		if _, ok := seen[user.Id]; ok {
+			continue
+		}
+		seen[user.Id] = struct{}{}
+		custodians = append(custodians, user)
+	}
+
+	return custodians, nil
+}
@@ -0,0 +1,120 @@
+package legalhold
+
+import (
+	"testing"
+	"time"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+func TestNotifier(t *testing.T) {
+	api := &plugintest.API{}
+	kv := newTestKVStore()
+	botID := mattermostModel.NewId()
+	notifier := NewNotifier(api, kv, botID, "com.mattermost.plugin-legal-hold")
+
+	user := &mattermostModel.User{Id: mattermostModel.NewId(), Username: "user"}
+	both := &mattermostModel.User{Id: mattermostModel.NewId(), Username: "both"}
+	member := &mattermostModel.User{Id: mattermostModel.NewId(), Username: "member"}
+	deactivated := &mattermostModel.User{Id: mattermostModel.NewId(), Username: "deactivated", DeleteAt: 100}
+
+	lh := model.LegalHold{
+		ID:          mattermostModel.NewId(),
+		DisplayName: "Matter",
+		UserIDs:     []string{user.Id, both.Id},
+		GroupIDs:    []string{mattermostModel.NewId()},
+	}
+
+	api.On("GetUser", user.Id).Return(user, nil)
+	api.On("GetUser", both.Id).Return(both, nil)
+	api.On("GetGroupMemberUsers", lh.GroupIDs[0], 0, 50).Return([]*mattermostModel.User{both, member, deactivated}, nil)
+	api.On("GetGroupMemberUsers", lh.GroupIDs[0], 1, 50).Return([]*mattermostModel.User{}, nil)
+
+	posts := make(map[string]*mattermostModel.Post)
+	postsByUser := make(map[string][]string)
+	for _, u := range []*mattermostModel.User{user, both, member} {
+		channel := &mattermostModel.Channel{Id: mattermostModel.NewId()}
+		api.On("GetDirectChannel", u.Id, botID).Return(channel, nil)
+		api.On("CreatePost", mock.MatchedBy(func(post *mattermostModel.Post) bool {
+			return post.ChannelId == channel.Id
+		})).Return(func(post *mattermostModel.Post) *mattermostModel.Post {
+			created := post.Clone()
+			created.Id = mattermostModel.NewId()
+			posts[created.Id] = created
+			postsByUser[u.Id] = append(postsByUser[u.Id], created.Id)
+			return created
+		}, nil)
+	}
+
+	settings := NoticeSettings{Message: "Keep everything.", ReminderInterval: 24 * time.Hour}
+	day := settings.ReminderInterval.Milliseconds()
+
+	t.Run("custodians are sent the notice once", func(t *testing.T) {
+		require.NoError(t, notifier.notify(lh, settings, 1000))
+		require.NoError(t, notifier.notify(lh, settings, 1000+day-1))
+
+		require.Len(t, posts, 3)
+		require.NotContains(t, postsByUser, deactivated.Id)
+		for _, u := range []*mattermostModel.User{user, both, member} {
+			require.Len(t, postsByUser[u.Id], 1)
+
+			notice, err := kv.GetCustodianNotice(lh.ID, u.Id)
+			require.NoError(t, err)
+			require.Equal(t, postsByUser[u.Id], notice.PostIDs)
+			require.Equal(t, int64(1000), notice.NotifiedAt)
+		}
+
+		post := posts[postsByUser[member.Id][0]]
+		require.Equal(t, botID, post.UserId)
+		attachments := post.Attachments()
+		require.Len(t, attachments, 1)
+		require.Equal(t, "Legal hold notice: Matter", attachments[0].Title)
+		require.Equal(t, "Keep everything.", attachments[0].Text)
+		require.Len(t, attachments[0].Actions, 1)
+		require.Equal(t, "/plugins/com.mattermost.plugin-legal-hold/api/v1/legalholds/"+lh.ID+"/acknowledge", attachments[0].Actions[0].Integration.URL)
+	})
+
+	t.Run("custodians acknowledge the notice from its post", func(t *testing.T) {
+		postID := postsByUser[user.Id][0]
+		api.On("GetPost", postID).Return(posts[postID], nil).Once()
+		api.On("UpdatePost", mock.MatchedBy(func(post *mattermostModel.Post) bool {
+			return post.Id == postID && len(post.Attachments()[0].Actions) == 0
+		})).Return(posts[postID], nil).Once()
+
+		_, err := notifier.Acknowledge(lh.ID, user.Id, postsByUser[both.Id][0], 2000)
+		require.ErrorIs(t, err, ErrNoticeNotFound)
+
+		notice, err := notifier.Acknowledge(lh.ID, user.Id, postID, 2000)
+		require.NoError(t, err)
+		require.Equal(t, int64(2000), notice.AcknowledgedAt)
+
+		saved, err := kv.GetCustodianNotice(lh.ID, user.Id)
+		require.NoError(t, err)
+		require.True(t, saved.IsAcknowledged())
+	})
+
+	t.Run("custodians who have not acknowledged the notice are reminded", func(t *testing.T) {
+		require.NoError(t, notifier.notify(lh, settings, 1000+day))
+
+		require.Len(t, postsByUser[user.Id], 1)
+		for _, u := range []*mattermostModel.User{both, member} {
+			require.Len(t, postsByUser[u.Id], 2)
+
+			notice, err := kv.GetCustodianNotice(lh.ID, u.Id)
+			require.NoError(t, err)
+			require.Equal(t, 1, notice.ReminderCount)
+			require.Equal(t, 1000+day, notice.RemindedAt)
+			require.Equal(t, postsByUser[u.Id], notice.PostIDs)
+		}
+
+		reminder := posts[postsByUser[member.Id][1]]
+		require.Equal(t, "Reminder: Legal hold notice: Matter", reminder.Attachments()[0].Title)
+	})
+
+	api.AssertExpectations(t)
+}
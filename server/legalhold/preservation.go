@@ -0,0 +1,256 @@
+package legalhold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-api/cluster"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/mattermost/mattermost-server/v6/shared/filestore"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/sqlstore"
+)
+
+// preservationWaitForLockTimeout is the time to wait for another preservation check to finish.
+const preservationWaitForLockTimeout = time.Minute
+
+// CaptureFunc schedules an immediate execution of the legal hold indicated by legalHoldID, which
+// captures its content up to the current time.
+type CaptureFunc func(legalHoldID string) error
+
+// Preserver watches over the content covered by active legal holds until the legal hold job has
+// captured it. It flags that content and reports the data retention policies of the server that
+// may delete it first and, in PreservationModeBlock, captures the content of the legal holds in
+// conflict straight away. The retention policies themselves are left untouched.
+type Preserver struct {
+	papi        plugin.API
+	store       *sqlstore.SQLStore
+	kvstore     kvstore.KVStore
+	fileBackend filestore.FileBackend
+	capture     CaptureFunc
+}
+
+// NewPreserver creates a new Preserver that is ready to use, which captures the content of legal
+// holds with capture in PreservationModeBlock.
+func NewPreserver(papi plugin.API, store *sqlstore.SQLStore, kvstore kvstore.KVStore, fileBackend filestore.FileBackend, capture CaptureFunc) *Preserver {
+	return &Preserver{
+		papi:        papi,
+		store:       store,
+		kvstore:     kvstore,
+		fileBackend: fileBackend,
+		capture:     capture,
+	}
+}
+
+// Check checks the data retention policies of the server against the active legal holds at the
+// time provided in "now", applying the provided PreservationMode. The custodians of the legal
+// holds are resolved as the legal hold job does, keeping the people captured as members of their
+// groups in scope if keepCapturedCustodians is set. The PreservationStatus is saved whether or
+// not the check succeeds.
+func (p *Preserver) Check(ctx context.Context, mode model.PreservationMode, keepCapturedCustodians bool, now int64) (*model.PreservationStatus, error) {
+	mutex, err := cluster.NewMutex(p.papi, "legal_hold_preservation")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cluster mutex: %w", err)
+	}
+
+	lockCtx, cancel := context.WithTimeout(ctx, preservationWaitForLockTimeout)
+	defer cancel()
+
+	if lockErr := mutex.LockWithContext(lockCtx); lockErr != nil {
+		return nil, fmt.Errorf("failed to lock cluster mutex: %w", lockErr)
+	}
+	defer mutex.Unlock()
+
+	status := &model.PreservationStatus{
+		Mode:        mode,
+		CheckedAt:   now,
+		HeldContent: []model.HeldContent{},
+		Conflicts:   []model.RetentionConflict{},
+	}
+
+	err = p.check(ctx, status, keepCapturedCustodians, now)
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	if saveErr := p.kvstore.SavePreservationStatus(*status); saveErr != nil {
+		p.papi.LogError("Failed to save the preservation status", "err", saveErr.Error())
+	}
+
+	return status, err
+}
+
+func (p *Preserver) check(ctx context.Context, status *model.PreservationStatus, keepCapturedCustodians bool, now int64) error {
+	if status.Mode == model.PreservationModeOff {
+		return nil
+	}
+
+	settings, err := p.retentionSettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	legalHolds, err := p.kvstore.GetAllLegalHolds()
+	if err != nil {
+		return err
+	}
+
+	// Work out the channels covered by each active legal hold, from the start of the content it
+	// has not yet captured until now.
+	var activeHolds []model.LegalHold
+	heldChannels := make(map[string][]string)
+	var allChannelIDs []string
+	for _, lh := range legalHolds {
+		if lh.StartsAt > now || lh.IsFinished() || lh.IsReleased() {
+			continue
+		}
+
+		ex := NewExecution(lh, p.papi, p.store, p.kvstore, p.fileBackend)
+		ex.ExecutionEndTime = now
+		ex.KeepCapturedCustodians = keepCapturedCustodians
+		if err = ex.GetChannels(); err != nil {
+			return fmt.Errorf("failed to get the channels of legal hold %s: %w", lh.ID, err)
+		}
+
+		activeHolds = append(activeHolds, lh)
+		heldChannels[lh.ID] = ex.channelIDs
+		allChannelIDs = append(allChannelIDs, ex.channelIDs...)
+	}
+
+	teamIDs := make(map[string]string)
+	if len(allChannelIDs) > 0 {
+		metadata, metadataErr := p.store.GetChannelMetadataForIDs(allChannelIDs)
+		if metadataErr != nil {
+			return metadataErr
+		}
+		for _, channel := range metadata {
+			teamIDs[channel.ChannelID] = channel.TeamID
+		}
+	}
+
+	var captureErrs []error
+	for _, lh := range activeHolds {
+		channelIDs := heldChannels[lh.ID]
+
+		held := model.HeldContent{
+			LegalHoldID:    lh.ID,
+			LegalHoldName:  lh.Name,
+			UncapturedFrom: lh.NextExecutionStartTime(),
+			ChannelCount:   len(channelIDs),
+		}
+
+		held.PostCount, held.FileCount, err = p.store.CountPostsAndFiles(ctx, channelIDs, held.UncapturedFrom, now, lh.Filter)
+		if err != nil {
+			return fmt.Errorf("failed to count the content of legal hold %s: %w", lh.ID, err)
+		}
+
+		var conflicts []model.RetentionConflict
+		conflicting := make(map[string]int)
+		for _, channelID := range channelIDs {
+			policy := settings.PolicyFor(channelID, teamIDs[channelID])
+			if !lh.RetentionConflictsWith(policy.PostDurationDays, now) {
+				continue
+			}
+
+			if i, ok := conflicting[policy.ID]; ok {
+				conflicts[i].ChannelCount++
+				continue
+			}
+
+			conflicting[policy.ID] = len(conflicts)
+			conflicts = append(conflicts, model.RetentionConflict{
+				LegalHoldID:   lh.ID,
+				LegalHoldName: lh.Name,
+				PolicyID:      policy.ID,
+				PolicyName:    policy.DisplayName,
+				Content:       model.RetentionContentPosts,
+				RetentionDays: policy.PostDurationDays,
+				ChannelCount:  1,
+			})
+		}
+
+		// File deletion only follows the global policy.
+		if len(channelIDs) > 0 && lh.RetentionConflictsWith(settings.FileDurationDays, now) {
+			conflicts = append(conflicts, model.RetentionConflict{
+				LegalHoldID:   lh.ID,
+				LegalHoldName: lh.Name,
+				PolicyID:      settings.Global.ID,
+				PolicyName:    settings.Global.DisplayName,
+				Content:       model.RetentionContentFiles,
+				RetentionDays: settings.FileDurationDays,
+				ChannelCount:  len(channelIDs),
+			})
+		}
+
+		// Content captured by the legal hold is no longer affected by data retention, so capturing
+		// it now keeps it from being deleted first.
+		if status.Mode == model.PreservationModeBlock && len(conflicts) > 0 {
+			if captureErr := p.capture(lh.ID); captureErr != nil {
+				captureErrs = append(captureErrs, fmt.Errorf("failed to schedule the capture of legal hold %s: %w", lh.ID, captureErr))
+			} else {
+				held.CaptureScheduled = true
+				for i := range conflicts {
+					conflicts[i].Blocked = true
+				}
+				p.papi.LogInfo("Capturing the content of a legal hold early to keep it from data retention", "legal_hold_id", lh.ID)
+			}
+		}
+
+		status.Conflicts = append(status.Conflicts, conflicts...)
+		status.HeldContent = append(status.HeldContent, held)
+	}
+
+	sort.SliceStable(status.Conflicts, func(i, j int) bool {
+		a, b := status.Conflicts[i], status.Conflicts[j]
+		if a.LegalHoldID != b.LegalHoldID {
+			return a.LegalHoldID < b.LegalHoldID
+		}
+		if a.Content != b.Content {
+			return a.Content > b.Content
+		}
+		return a.PolicyID < b.PolicyID
+	})
+
+	for _, conflict := range status.UnblockedConflicts() {
+		p.papi.LogWarn("Data retention policy may delete content covered by a legal hold before it is captured",
+			"legal_hold_id", conflict.LegalHoldID,
+			"legal_hold_name", conflict.LegalHoldName,
+			"policy_id", conflict.PolicyID,
+			"policy_name", conflict.PolicyName,
+			"content", conflict.Content,
+			"retention_days", conflict.RetentionDays,
+			"channel_count", conflict.ChannelCount,
+		)
+	}
+
+	return errors.Join(captureErrs...)
+}
+
+// retentionSettings gets the data retention settings of the server.
+func (p *Preserver) retentionSettings(ctx context.Context) (model.RetentionSettings, error) {
+	config := p.papi.GetConfig()
+	if config == nil {
+		return model.RetentionSettings{}, fmt.Errorf("failed to get the server configuration")
+	}
+
+	var messageRetentionDays, fileRetentionDays int64
+	retention := config.DataRetentionSettings
+	if retention.EnableMessageDeletion != nil && *retention.EnableMessageDeletion && retention.MessageRetentionDays != nil {
+		messageRetentionDays = int64(*retention.MessageRetentionDays)
+	}
+	if retention.EnableFileDeletion != nil && *retention.EnableFileDeletion && retention.FileRetentionDays != nil {
+		fileRetentionDays = int64(*retention.FileRetentionDays)
+	}
+
+	policies, err := p.store.GetRetentionPolicies(ctx)
+	if err != nil {
+		return model.RetentionSettings{}, err
+	}
+
+	return model.NewRetentionSettings(messageRetentionDays, fileRetentionDays, policies), nil
+}
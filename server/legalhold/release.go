@@ -0,0 +1,91 @@
+package legalhold
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v6/shared/filestore"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/kvstore"
+)
+
+// Purge deletes the data of the released LegalHold lh: its files, export packages, download
+// tokens, custodian notices and execution checkpoint, and then the LegalHold itself, keeping a
+// LegalHoldTombstone in its place. If it fails part way, the LegalHold is left released so that
+// the purge can be tried again.
+func Purge(kvs kvstore.KVStore, fileBackend filestore.FileBackend, lh model.LegalHold, now int64) (*model.LegalHoldTombstone, error) {
+	if !lh.IsReleased() {
+		return nil, fmt.Errorf("legal hold %s is not released", lh.ID)
+	}
+
+	if err := fileBackend.RemoveDirectory(lh.BasePath()); err != nil {
+		return nil, fmt.Errorf("failed to delete the files of the legal hold: %w", err)
+	}
+
+	packages, err := kvs.GetExportPackages(lh.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the export packages of the legal hold: %w", err)
+	}
+
+	for _, pkg := range packages {
+		if err = RemoveExportPackage(kvs, fileBackend, pkg); err != nil {
+			return nil, fmt.Errorf("failed to delete export package %s: %w", pkg.ID, err)
+		}
+	}
+
+	downloadTokens, err := kvs.GetDownloadTokens(lh.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the download tokens of the legal hold: %w", err)
+	}
+
+	for _, downloadToken := range downloadTokens {
+		if err = kvs.DeleteDownloadToken(lh.ID, downloadToken.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete download token %s: %w", downloadToken.ID, err)
+		}
+	}
+
+	notices, err := kvs.GetCustodianNotices(lh.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the custodian notices of the legal hold: %w", err)
+	}
+
+	for _, notice := range notices {
+		if err = kvs.DeleteCustodianNotice(lh.ID, notice.UserID); err != nil {
+			return nil, fmt.Errorf("failed to delete the notice of custodian %s: %w", notice.UserID, err)
+		}
+	}
+
+	if err = kvs.DeleteExecutionCheckpoint(lh.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete the execution checkpoint of the legal hold: %w", err)
+	}
+
+	tombstone := model.NewLegalHoldTombstone(lh, now)
+	if err = kvs.SaveLegalHoldTombstone(tombstone); err != nil {
+		return nil, fmt.Errorf("failed to save the tombstone of the legal hold: %w", err)
+	}
+
+	if err = kvs.DeleteLegalHold(lh.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete the legal hold: %w", err)
+	}
+
+	return &tombstone, nil
+}
+
+// RemoveExportPackage removes the archive of an export package, if there is one, and the export
+// package itself.
+func RemoveExportPackage(kvs kvstore.KVStore, fileBackend filestore.FileBackend, pkg model.ExportPackage) error {
+	fileBackend = UnwrapFileBackend(fileBackend)
+
+	exists, err := fileBackend.FileExists(pkg.ArchivePath())
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		if err = fileBackend.RemoveFile(pkg.ArchivePath()); err != nil {
+			return err
+		}
+	}
+
+	return kvs.DeleteExportPackage(pkg.LegalHoldID, pkg.ID)
+}
@@ -0,0 +1,86 @@
+package legalhold
+
+import (
+	"strings"
+	"testing"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+func TestPurge(t *testing.T) {
+	local, _ := newLocalFileBackend(t)
+	backend := NewEncryptedFileBackend(local, newMasterKey(t))
+
+	lh := model.LegalHold{
+		ID:         mattermostModel.NewId(),
+		Name:       "purged-hold",
+		Secret:     "secret",
+		ReleasedAt: 1000,
+		ReleasedBy: mattermostModel.NewId(),
+	}
+	otherID := mattermostModel.NewId()
+
+	kv := newTestKVStore()
+	kv.checkpoints[lh.ID] = model.ExecutionCheckpoint{LegalHoldID: lh.ID}
+	kv.packages = []model.ExportPackage{
+		model.NewExportPackage(mattermostModel.NewId(), lh.ID, 1000),
+		model.NewExportPackage(mattermostModel.NewId(), otherID, 1000),
+	}
+	kv.downloadTokens = []model.DownloadToken{
+		{ID: mattermostModel.NewId(), LegalHoldID: lh.ID},
+		{ID: mattermostModel.NewId(), LegalHoldID: otherID},
+	}
+	kv.notices = []model.CustodianNotice{
+		model.NewCustodianNotice(lh.ID, mattermostModel.NewId(), mattermostModel.NewId(), 1000),
+		model.NewCustodianNotice(otherID, mattermostModel.NewId(), mattermostModel.NewId(), 1000),
+	}
+
+	_, err := backend.WriteFile(strings.NewReader("{}"), lh.IndexPath())
+	require.NoError(t, err)
+	purgedPackage := kv.packages[0]
+	_, err = local.WriteFile(strings.NewReader("zip"), purgedPackage.ArchivePath())
+	require.NoError(t, err)
+
+	t.Run("not released", func(t *testing.T) {
+		active := lh.DeepCopy()
+		active.ReleasedAt = 0
+
+		_, err = Purge(kv, backend, active, 2000)
+		require.Error(t, err)
+
+		exists, existsErr := backend.FileExists(lh.IndexPath())
+		require.NoError(t, existsErr)
+		require.True(t, exists)
+	})
+
+	tombstone, err := Purge(kv, backend, lh, 2000)
+	require.NoError(t, err)
+
+	exists, err := backend.FileExists(lh.IndexPath())
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	exists, err = local.FileExists(purgedPackage.ArchivePath())
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// Only the data of the purged legal hold is deleted.
+	require.Len(t, kv.packages, 1)
+	require.Equal(t, otherID, kv.packages[0].LegalHoldID)
+	require.Len(t, kv.downloadTokens, 1)
+	require.Equal(t, otherID, kv.downloadTokens[0].LegalHoldID)
+	require.Len(t, kv.notices, 1)
+	require.Equal(t, otherID, kv.notices[0].LegalHoldID)
+	require.NotContains(t, kv.checkpoints, lh.ID)
+	require.Equal(t, []string{lh.ID}, kv.deletedHolds)
+
+	// The tombstone records the legal hold, without its secret.
+	require.Equal(t, []model.LegalHoldTombstone{*tombstone}, kv.tombstones)
+	require.Equal(t, int64(2000), tombstone.PurgedAt)
+	require.Equal(t, lh.ID, tombstone.LegalHold.ID)
+	require.Equal(t, lh.ReleasedBy, tombstone.LegalHold.ReleasedBy)
+	require.Empty(t, tombstone.LegalHold.Secret)
+}
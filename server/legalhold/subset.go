@@ -0,0 +1,262 @@
+package legalhold
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gocarina/gocsv"
+	mm_model "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/shared/filestore"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+// subsetBaseFiles are the files at the base path of a legal hold that are part of every subset of
+// it, as they are needed to process and verify the subset. The hashes.json file is not, as a subset
+// gets its own, listing only the files in it.
+var subsetBaseFiles = []string{
+	"index.json",
+	model.ManifestFileName,
+	model.ManifestSignatureFileName,
+	model.LedgerFileName,
+}
+
+// subsetBatch is a batch file of a channel, named after the time and ID of its first post.
+type subsetBatch struct {
+	path string
+	key  string
+	time int64
+}
+
+// SelectSubset selects the files of the LegalHold lh that match the filter. The batch files of
+// each channel are selected whole, from the times in their names, so a subset may hold posts just
+// outside of the time windows of the filter. Attachments exported before they were stored by
+// digest cannot be matched to a batch, so they are selected for every selected channel.
+//
+// It returns the sub-index describing the subset, whose Files lists the selected files, and the
+// hashes of the selected files.
+func SelectSubset(fileBackend filestore.FileBackend, lh model.LegalHold, filter model.DownloadFilter, now int64) (*model.LegalHoldSubsetIndex, model.HashList, error) {
+	basePath := lh.BasePath()
+
+	files, err := ListLegalHoldFiles(fileBackend, lh)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	index := model.NewLegalHoldIndex()
+	if err = readJSONFile(fileBackend, lh.IndexPath(), &index); err != nil {
+		return nil, nil, err
+	}
+
+	// The files of each channel are in a directory named by its ID.
+	channelFiles := make(map[string][]string)
+	for _, file := range files {
+		relativePath := strings.TrimPrefix(file, basePath+"/")
+		channelID, _, found := strings.Cut(relativePath, "/")
+		if found && mm_model.IsValidId(channelID) {
+			channelFiles[channelID] = append(channelFiles[channelID], file)
+		}
+	}
+
+	channelIDs := make([]string, 0, len(channelFiles))
+	for channelID := range channelFiles {
+		channelIDs = append(channelIDs, channelID)
+	}
+	selected := filter.SelectChannels(index, channelIDs)
+
+	stored := make(map[string]bool, len(files))
+	for _, file := range files {
+		stored[file] = true
+	}
+
+	var selectedFiles []string
+	for _, name := range subsetBaseFiles {
+		if file := path.Join(basePath, name); stored[file] {
+			selectedFiles = append(selectedFiles, file)
+		}
+	}
+
+	for channelID, windows := range selected {
+		for _, file := range selectChannelFiles(channelFiles[channelID], windows) {
+			selectedFiles = append(selectedFiles, file)
+
+			if path.Dir(file) != path.Join(basePath, channelID, "files") || path.Ext(file) != ".csv" {
+				continue
+			}
+
+			blobs, blobsErr := referencedBlobs(fileBackend, basePath, file)
+			if blobsErr != nil {
+				return nil, nil, blobsErr
+			}
+			for _, blob := range blobs {
+				if stored[blob] {
+					selectedFiles = append(selectedFiles, blob)
+				}
+			}
+		}
+	}
+
+	slices.Sort(selectedFiles)
+	selectedFiles = slices.Compact(selectedFiles)
+
+	hashes := make(model.HashList)
+	allHashes := make(model.HashList)
+	if err = readJSONFile(fileBackend, path.Join(basePath, "hashes.json"), &allHashes); err != nil {
+		return nil, nil, err
+	}
+	for _, file := range selectedFiles {
+		if hash, ok := allHashes[file]; ok {
+			hashes[file] = hash
+		}
+	}
+
+	subset := model.NewLegalHoldSubsetIndex(index, filter, selected, now)
+	subset.Files = selectedFiles
+
+	return &subset, hashes, nil
+}
+
+// selectChannelFiles selects the files of a channel with content in the provided time windows.
+// Reactions, acknowledgements and file references follow the batch of posts they are named after.
+func selectChannelFiles(files []string, windows []model.DownloadWindow) []string {
+	var messages, revisions []subsetBatch
+	var followers []subsetBatch
+	var selected []string
+
+	for _, file := range files {
+		kind := path.Base(path.Dir(file))
+		batch, ok := parseBatchFileName(file)
+
+		switch {
+		case ok && kind == "messages":
+			messages = append(messages, batch)
+		case ok && kind == "revisions":
+			revisions = append(revisions, batch)
+		case ok && (kind == "reactions" || kind == "acknowledgements" || kind == "files"):
+			followers = append(followers, batch)
+		default:
+			selected = append(selected, file)
+		}
+	}
+
+	selectedMessages := selectBatches(messages, windows)
+	for _, batch := range messages {
+		if selectedMessages[batch.key] {
+			selected = append(selected, batch.path)
+		}
+	}
+
+	for _, batch := range followers {
+		if selectedMessages[batch.key] {
+			selected = append(selected, batch.path)
+		}
+	}
+
+	selectedRevisions := selectBatches(revisions, windows)
+	for _, batch := range revisions {
+		if selectedRevisions[batch.key] {
+			selected = append(selected, batch.path)
+		}
+	}
+
+	return selected
+}
+
+// selectBatches returns the keys of the batches with content in the provided time windows. Each
+// batch holds the content from its own time until that of the next batch.
+func selectBatches(batches []subsetBatch, windows []model.DownloadWindow) map[string]bool {
+	slices.SortFunc(batches, func(a, b subsetBatch) int {
+		if a.time != b.time {
+			return cmp.Compare(a.time, b.time)
+		}
+		return strings.Compare(a.key, b.key)
+	})
+
+	selected := make(map[string]bool)
+	for i, batch := range batches {
+		window := model.DownloadWindow{StartTime: batch.time, EndTime: math.MaxInt64}
+		if i+1 < len(batches) {
+			window.EndTime = batches[i+1].time
+		}
+
+		if window.OverlapsAny(windows) {
+			selected[batch.key] = true
+		}
+	}
+
+	return selected
+}
+
+// parseBatchFileName parses the name of a batch file, of the form <kind>-<time>-<post ID>.csv,
+// keying the batch by its time and post ID so that it can be matched across kinds.
+func parseBatchFileName(file string) (subsetBatch, bool) {
+	name, found := strings.CutSuffix(path.Base(file), ".csv")
+	if !found {
+		return subsetBatch{}, false
+	}
+
+	parts := strings.SplitN(name, "-", 3)
+	if len(parts) != 3 {
+		return subsetBatch{}, false
+	}
+
+	batchTime, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return subsetBatch{}, false
+	}
+
+	return subsetBatch{
+		path: file,
+		key:  parts[1] + "-" + parts[2],
+		time: batchTime,
+	}, true
+}
+
+// referencedBlobs returns the paths of the attachment content referenced by the file references
+// CSV file at filePath.
+func referencedBlobs(fileBackend filestore.FileBackend, basePath, filePath string) ([]string, error) {
+	data, err := fileBackend.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+
+	var references []model.LegalHoldFileReference
+	if err = gocsv.UnmarshalBytes(data, &references); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	blobs := make([]string, 0, len(references))
+	for _, reference := range references {
+		blobs = append(blobs, path.Join(basePath, reference.BlobPath))
+	}
+
+	return blobs, nil
+}
+
+// readJSONFile decodes the JSON file at filePath into v, leaving v as it is if there is no file.
+func readJSONFile(fileBackend filestore.FileBackend, filePath string, v any) error {
+	exists, err := fileBackend.FileExists(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to check for %s: %w", filePath, err)
+	}
+	if !exists {
+		return nil
+	}
+
+	data, err := fileBackend.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package legalhold
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+func TestSelectSubset(t *testing.T) {
+	backend, _ := newLocalFileBackend(t)
+
+	lh := model.LegalHold{ID: mattermostModel.NewId(), Name: "subset-hold"}
+	base := lh.BasePath()
+	channelID := mattermostModel.NewId()
+	otherChannelID := mattermostModel.NewId()
+	userID := mattermostModel.NewId()
+	postID := mattermostModel.NewId()
+
+	index := model.NewLegalHoldIndex()
+	index.Users[userID] = model.LegalHoldIndexUser{
+		Username: "custodian",
+		Channels: []model.LegalHoldChannelMembership{{ChannelID: channelID, StartTime: 1000, EndTime: 9000}},
+	}
+	indexData, err := json.Marshal(index)
+	require.NoError(t, err)
+
+	channel := base + "/" + channelID
+	otherChannel := base + "/" + otherChannelID
+	blobInWindow := base + "/blobs/aa/aa11"
+	blobOutOfWindow := base + "/blobs/bb/bb22"
+	files := map[string]string{
+		lh.IndexPath():                                              string(indexData),
+		base + "/" + model.ManifestFileName:                         "{}",
+		base + "/" + model.LedgerFileName:                           "{}\n",
+		base + "/exceptions/exceptions-1-2.csv":                     "FileId\n",
+		blobInWindow:                                                "in window",
+		blobOutOfWindow:                                             "out of window",
+		channel + "/messages/messages-1000-" + postID + ".csv":      "early",
+		channel + "/messages/messages-2000-" + postID + ".csv":      "overlapping",
+		channel + "/messages/messages-4000-" + postID + ".csv":      "in window",
+		channel + "/messages/messages-6000-" + postID + ".csv":      "late",
+		channel + "/reactions/messages-4000-" + postID + ".csv":     "reactions",
+		channel + "/reactions/messages-6000-" + postID + ".csv":     "late reactions",
+		channel + "/files/files-4000-" + postID + ".csv":            "FileId,FileName,FileSize,MimeType,Digest,BlobPath\nf1,a.txt,9,text/plain,aa11,blobs/aa/aa11\n",
+		channel + "/files/files-6000-" + postID + ".csv":            "FileId,FileName,FileSize,MimeType,Digest,BlobPath\nf2,b.txt,13,text/plain,bb22,blobs/bb/bb22\n",
+		channel + "/files/legacy_file_id/legacy.txt":                "legacy",
+		channel + "/revisions/revisions-3000-" + postID + ".csv":    "revisions",
+		channel + "/revisions/revisions-7000-" + postID + ".csv":    "late revisions",
+		otherChannel + "/messages/messages-4000-" + postID + ".csv": "other channel",
+	}
+
+	hashes := make(model.HashList)
+	for filePath := range files {
+		hashes[filePath] = "hash-of-" + filePath
+	}
+	hashesData, err := json.Marshal(hashes)
+	require.NoError(t, err)
+	files[base+"/hashes.json"] = string(hashesData)
+
+	for filePath, content := range files {
+		_, err = backend.WriteFile(strings.NewReader(content), filePath)
+		require.NoError(t, err)
+	}
+
+	filter := model.DownloadFilter{UserIDs: []string{userID}, StartTime: 3000, EndTime: 5000}
+	subset, subsetHashes, err := SelectSubset(backend, lh, filter, 10000)
+	require.NoError(t, err)
+
+	expected := []string{
+		channel + "/files/files-4000-" + postID + ".csv",
+		channel + "/files/legacy_file_id/legacy.txt",
+		channel + "/messages/messages-2000-" + postID + ".csv",
+		channel + "/messages/messages-4000-" + postID + ".csv",
+		channel + "/reactions/messages-4000-" + postID + ".csv",
+		channel + "/revisions/revisions-3000-" + postID + ".csv",
+		blobInWindow,
+		lh.IndexPath(),
+		base + "/" + model.LedgerFileName,
+		base + "/" + model.ManifestFileName,
+	}
+	assert.ElementsMatch(t, expected, subset.Files)
+	assert.IsIncreasing(t, subset.Files)
+
+	// Only the hashes of the selected files are kept.
+	require.Len(t, subsetHashes, len(expected))
+	for _, filePath := range expected {
+		assert.Equal(t, hashes[filePath], subsetHashes[filePath], filePath)
+	}
+
+	assert.Equal(t, filter, subset.Filter)
+	assert.Equal(t, int64(10000), subset.CreateAt)
+	assert.Equal(t, []model.SubsetChannel{
+		{ChannelID: channelID, Windows: []model.DownloadWindow{{StartTime: 3000, EndTime: 5000}}},
+	}, subset.Channels)
+	assert.Contains(t, subset.Users, userID)
+}
@@ -0,0 +1,236 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+const (
+	// OutcomeSuccess, OutcomeFailure and OutcomeCancelled are the values of the outcome label of
+	// the executions counted by legal_hold_executions_total.
+	OutcomeSuccess   = "success"
+	OutcomeFailure   = "failure"
+	OutcomeCancelled = "cancelled"
+)
+
+var (
+	// executionDurationBuckets are the upper bounds, in seconds, of the buckets of the durations
+	// of legal hold executions, from a second to two hours.
+	executionDurationBuckets = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 7200}
+
+	// batchQueryDurationBuckets are the upper bounds, in seconds, of the buckets of the latencies
+	// of the queries of batches of posts, from 5ms to 30s.
+	batchQueryDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+)
+
+// Metrics collects the metrics of the processing of legal holds on this node of the cluster, and
+// writes them in the Prometheus text exposition format. All of its methods can be called on a nil
+// Metrics, in which case they do nothing.
+type Metrics struct {
+	mu sync.Mutex
+
+	executions         *valueVec
+	executionDuration  *histogramVec
+	postsExported      *valueVec
+	revisionsExported  *valueVec
+	filesExported      *valueVec
+	bytesCopied        *valueVec
+	fileExceptions     *valueVec
+	executionRetries   *valueVec
+	executionFailures  *valueVec
+	queueLag           *valueVec
+	postsBatchDuration *histogramVec
+}
+
+// New creates a new Metrics with no series.
+func New() *Metrics {
+	return &Metrics{
+		executions: newValueVec("legal_hold_executions_total",
+			"Executions of legal holds, by outcome.", "counter", "legal_hold_id", "outcome"),
+		executionDuration: newHistogramVec("legal_hold_execution_duration_seconds",
+			"Time taken by executions of legal holds.", executionDurationBuckets, "legal_hold_id"),
+		postsExported: newValueVec("legal_hold_posts_exported_total",
+			"Posts exported by executions of legal holds.", "counter", "legal_hold_id"),
+		revisionsExported: newValueVec("legal_hold_revisions_exported_total",
+			"Post revisions exported by executions of legal holds.", "counter", "legal_hold_id"),
+		filesExported: newValueVec("legal_hold_files_exported_total",
+			"File attachments exported by executions of legal holds.", "counter", "legal_hold_id"),
+		bytesCopied: newValueVec("legal_hold_bytes_copied_total",
+			"Bytes written to the file store by executions of legal holds.", "counter", "legal_hold_id"),
+		fileExceptions: newValueVec("legal_hold_file_exceptions_total",
+			"File attachments that executions of legal holds could not export.", "counter", "legal_hold_id"),
+		executionRetries: newValueVec("legal_hold_execution_retries_total",
+			"Retries of failed executions of legal holds.", "counter", "legal_hold_id"),
+		executionFailures: newValueVec("legal_hold_execution_failures_total",
+			"Runs of the legal hold job that failed to execute a legal hold after all its retries.", "counter", "legal_hold_id"),
+		queueLag: newValueVec("legal_hold_queue_lag_seconds",
+			"Time between when the latest execution of a legal hold was due and when it started.", "gauge", "legal_hold_id"),
+		postsBatchDuration: newHistogramVec("legal_hold_posts_batch_query_duration_seconds",
+			"Latency of the queries of batches of posts exported by legal holds.", batchQueryDurationBuckets, "legal_hold_id"),
+	}
+}
+
+// ObserveExecution records the outcome of one run of an execution of a legal hold.
+func (m *Metrics) ObserveExecution(record model.ExecutionRecord) {
+	if m == nil {
+		return
+	}
+
+	outcome := OutcomeSuccess
+	if record.Cancelled {
+		outcome = OutcomeCancelled
+	} else if record.Error != "" {
+		outcome = OutcomeFailure
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := record.LegalHoldID
+	m.executions.add(1, id, outcome)
+	m.executionDuration.observe((time.Duration(record.Duration) * time.Millisecond).Seconds(), id)
+	m.postsExported.add(float64(record.PostCount), id)
+	m.revisionsExported.add(float64(record.RevisionCount), id)
+	m.filesExported.add(float64(record.FileCount), id)
+	m.bytesCopied.add(float64(record.BytesWritten), id)
+	m.fileExceptions.add(float64(record.FileExceptions), id)
+}
+
+// ObservePostsBatch records the latency of a query of a batch of posts for the legal hold
+// indicated by legalHoldID.
+func (m *Metrics) ObservePostsBatch(legalHoldID string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.postsBatchDuration.observe(duration.Seconds(), legalHoldID)
+}
+
+// IncExecutionRetries counts a retry of a failed execution of the legal hold indicated by
+// legalHoldID.
+func (m *Metrics) IncExecutionRetries(legalHoldID string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.executionRetries.add(1, legalHoldID)
+}
+
+// IncExecutionFailures counts a run of the legal hold job that failed to execute the legal hold
+// indicated by legalHoldID after all its retries.
+func (m *Metrics) IncExecutionFailures(legalHoldID string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.executionFailures.add(1, legalHoldID)
+}
+
+// SetQueueLag records how long after it was due the latest execution of the legal hold indicated
+// by legalHoldID started.
+func (m *Metrics) SetQueueLag(legalHoldID string, lag time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.queueLag.set(max(lag, 0).Seconds(), legalHoldID)
+}
+
+// DeleteLegalHold deletes the series of the legal hold indicated by legalHoldID, once it has been
+// purged.
+func (m *Metrics) DeleteLegalHold(legalHoldID string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, v := range m.valueVecs() {
+		v.deleteSeries(legalHoldID)
+	}
+	for _, v := range m.histogramVecs() {
+		v.deleteSeries(legalHoldID)
+	}
+}
+
+// WriteTo writes all the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	if m == nil {
+		return 0, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	writers := []interface{ write(io.Writer) error }{
+		m.executions,
+		m.executionDuration,
+		m.postsExported,
+		m.revisionsExported,
+		m.filesExported,
+		m.bytesCopied,
+		m.fileExceptions,
+		m.executionRetries,
+		m.executionFailures,
+		m.queueLag,
+		m.postsBatchDuration,
+	}
+	for _, writer := range writers {
+		if err := writer.write(cw); err != nil {
+			return cw.n, err
+		}
+	}
+
+	return cw.n, cw.w.Flush()
+}
+
+func (m *Metrics) valueVecs() []*valueVec {
+	return []*valueVec{
+		m.executions,
+		m.postsExported,
+		m.revisionsExported,
+		m.filesExported,
+		m.bytesCopied,
+		m.fileExceptions,
+		m.executionRetries,
+		m.executionFailures,
+		m.queueLag,
+	}
+}
+
+func (m *Metrics) histogramVecs() []*histogramVec {
+	return []*histogramVec{
+		m.executionDuration,
+		m.postsBatchDuration,
+	}
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
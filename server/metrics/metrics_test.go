@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	var b bytes.Buffer
+	n, err := m.WriteTo(&b)
+	require.NoError(t, err)
+	require.Equal(t, int64(b.Len()), n)
+	return b.String()
+}
+
+// samples returns the lines of the scraped output that are samples of the named metric.
+func samples(output, name string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, name+"{") || strings.HasPrefix(line, name+" ") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestMetrics_Executions(t *testing.T) {
+	m := New()
+
+	m.ObserveExecution(model.ExecutionRecord{
+		LegalHoldID:    "lh1",
+		Duration:       4000,
+		PostCount:      10,
+		RevisionCount:  2,
+		FileCount:      3,
+		BytesWritten:   2048,
+		FileExceptions: 1,
+	})
+	m.ObserveExecution(model.ExecutionRecord{LegalHoldID: "lh1", Duration: 90000, PostCount: 5, Error: "failed to write file"})
+	m.ObserveExecution(model.ExecutionRecord{LegalHoldID: "lh2", Duration: 500, Error: "context canceled", Cancelled: true})
+
+	output := scrape(t, m)
+
+	require.Contains(t, output, "# HELP legal_hold_executions_total Executions of legal holds, by outcome.\n# TYPE legal_hold_executions_total counter\n")
+	require.Equal(t, []string{
+		`legal_hold_executions_total{legal_hold_id="lh1",outcome="failure"} 1`,
+		`legal_hold_executions_total{legal_hold_id="lh1",outcome="success"} 1`,
+		`legal_hold_executions_total{legal_hold_id="lh2",outcome="cancelled"} 1`,
+	}, samples(output, "legal_hold_executions_total"))
+
+	require.Equal(t, []string{
+		`legal_hold_posts_exported_total{legal_hold_id="lh1"} 15`,
+		`legal_hold_posts_exported_total{legal_hold_id="lh2"} 0`,
+	}, samples(output, "legal_hold_posts_exported_total"))
+	require.Contains(t, samples(output, "legal_hold_revisions_exported_total"), `legal_hold_revisions_exported_total{legal_hold_id="lh1"} 2`)
+	require.Contains(t, samples(output, "legal_hold_files_exported_total"), `legal_hold_files_exported_total{legal_hold_id="lh1"} 3`)
+	require.Contains(t, samples(output, "legal_hold_bytes_copied_total"), `legal_hold_bytes_copied_total{legal_hold_id="lh1"} 2048`)
+	require.Contains(t, samples(output, "legal_hold_file_exceptions_total"), `legal_hold_file_exceptions_total{legal_hold_id="lh1"} 1`)
+
+	require.Contains(t, output, "# TYPE legal_hold_execution_duration_seconds histogram\n")
+	require.Equal(t, []string{
+		`legal_hold_execution_duration_seconds_bucket{legal_hold_id="lh1",le="1"} 0`,
+		`legal_hold_execution_duration_seconds_bucket{legal_hold_id="lh1",le="5"} 1`,
+		`legal_hold_execution_duration_seconds_bucket{legal_hold_id="lh1",le="15"} 1`,
+		`legal_hold_execution_duration_seconds_bucket{legal_hold_id="lh1",le="30"} 1`,
+		`legal_hold_execution_duration_seconds_bucket{legal_hold_id="lh1",le="60"} 1`,
+		`legal_hold_execution_duration_seconds_bucket{legal_hold_id="lh1",le="300"} 2`,
+		`legal_hold_execution_duration_seconds_bucket{legal_hold_id="lh1",le="900"} 2`,
+		`legal_hold_execution_duration_seconds_bucket{legal_hold_id="lh1",le="1800"} 2`,
+		`legal_hold_execution_duration_seconds_bucket{legal_hold_id="lh1",le="3600"} 2`,
+		`legal_hold_execution_duration_seconds_bucket{legal_hold_id="lh1",le="7200"} 2`,
+		`legal_hold_execution_duration_seconds_bucket{legal_hold_id="lh1",le="+Inf"} 2`,
+	}, samples(output, "legal_hold_execution_duration_seconds_bucket")[:11])
+	require.Equal(t, []string{
+		`legal_hold_execution_duration_seconds_sum{legal_hold_id="lh1"} 94`,
+		`legal_hold_execution_duration_seconds_sum{legal_hold_id="lh2"} 0.5`,
+	}, samples(output, "legal_hold_execution_duration_seconds_sum"))
+	require.Equal(t, []string{
+		`legal_hold_execution_duration_seconds_count{legal_hold_id="lh1"} 2`,
+		`legal_hold_execution_duration_seconds_count{legal_hold_id="lh2"} 1`,
+	}, samples(output, "legal_hold_execution_duration_seconds_count"))
+}
+
+func TestMetrics_PostsBatch(t *testing.T) {
+	m := New()
+
+	m.ObservePostsBatch("lh1", 3*time.Millisecond)
+	m.ObservePostsBatch("lh1", 200*time.Millisecond)
+	m.ObservePostsBatch("lh1", time.Minute)
+
+	output := scrape(t, m)
+
+	buckets := samples(output, "legal_hold_posts_batch_query_duration_seconds_bucket")
+	require.Len(t, buckets, len(batchQueryDurationBuckets)+1)
+	require.Equal(t, `legal_hold_posts_batch_query_duration_seconds_bucket{legal_hold_id="lh1",le="0.005"} 1`, buckets[0])
+	require.Equal(t, `legal_hold_posts_batch_query_duration_seconds_bucket{legal_hold_id="lh1",le="0.1"} 1`, buckets[4])
+	require.Equal(t, `legal_hold_posts_batch_query_duration_seconds_bucket{legal_hold_id="lh1",le="0.25"} 2`, buckets[5])
+	require.Equal(t, `legal_hold_posts_batch_query_duration_seconds_bucket{legal_hold_id="lh1",le="30"} 2`, buckets[11])
+	require.Equal(t, `legal_hold_posts_batch_query_duration_seconds_bucket{legal_hold_id="lh1",le="+Inf"} 3`, buckets[12])
+	require.Equal(t, []string{
+		`legal_hold_posts_batch_query_duration_seconds_sum{legal_hold_id="lh1"} 60.203`,
+	}, samples(output, "legal_hold_posts_batch_query_duration_seconds_sum"))
+	require.Equal(t, []string{
+		`legal_hold_posts_batch_query_duration_seconds_count{legal_hold_id="lh1"} 3`,
+	}, samples(output, "legal_hold_posts_batch_query_duration_seconds_count"))
+}
+
+func TestMetrics_RetriesAndLag(t *testing.T) {
+	m := New()
+
+	m.IncExecutionRetries("lh1")
+	m.IncExecutionRetries("lh1")
+	m.IncExecutionFailures("lh1")
+	m.SetQueueLag("lh1", 90*time.Second)
+	m.SetQueueLag("lh1", 30*time.Second)
+	m.SetQueueLag("lh2", -time.Second)
+
+	output := scrape(t, m)
+
+	require.Equal(t, []string{`legal_hold_execution_retries_total{legal_hold_id="lh1"} 2`}, samples(output, "legal_hold_execution_retries_total"))
+	require.Equal(t, []string{`legal_hold_execution_failures_total{legal_hold_id="lh1"} 1`}, samples(output, "legal_hold_execution_failures_total"))
+	require.Contains(t, output, "# TYPE legal_hold_queue_lag_seconds gauge\n")
+	require.Equal(t, []string{
+		`legal_hold_queue_lag_seconds{legal_hold_id="lh1"} 30`,
+		`legal_hold_queue_lag_seconds{legal_hold_id="lh2"} 0`,
+	}, samples(output, "legal_hold_queue_lag_seconds"))
+}
+
+func TestMetrics_DeleteLegalHold(t *testing.T) {
+	m := New()
+
+	for _, id := range []string{"lh1", "lh2"} {
+		m.ObserveExecution(model.ExecutionRecord{LegalHoldID: id, Duration: 1000})
+		m.ObservePostsBatch(id, time.Millisecond)
+		m.SetQueueLag(id, time.Second)
+	}
+
+	m.DeleteLegalHold("lh1")
+
+	output := scrape(t, m)
+	require.NotContains(t, output, `legal_hold_id="lh1"`)
+	require.Contains(t, output, `legal_hold_executions_total{legal_hold_id="lh2",outcome="success"} 1`)
+	require.Contains(t, output, `legal_hold_posts_batch_query_duration_seconds_count{legal_hold_id="lh2"} 1`)
+}
+
+func TestMetrics_Escaping(t *testing.T) {
+	m := New()
+	m.IncExecutionRetries("a\"b\\c\nd")
+
+	output := scrape(t, m)
+	require.Contains(t, output, `legal_hold_execution_retries_total{legal_hold_id="a\"b\\c\nd"} 1`)
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var m *Metrics
+
+	m.ObserveExecution(model.ExecutionRecord{LegalHoldID: "lh1"})
+	m.ObservePostsBatch("lh1", time.Second)
+	m.IncExecutionRetries("lh1")
+	m.IncExecutionFailures("lh1")
+	m.SetQueueLag("lh1", time.Second)
+	m.DeleteLegalHold("lh1")
+
+	require.Empty(t, scrape(t, m))
+}
@@ -0,0 +1,198 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// labelValuesSeparator joins the label values of a series into the key it is stored under. It
+// cannot appear in label values, which are IDs and fixed outcomes.
+const labelValuesSeparator = "\xff"
+
+// family is a set of series of one metric, one for each combination of the values of its labels.
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+// writeHeader writes the HELP and TYPE lines of the family.
+func (f family) writeHeader(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.kind)
+	return err
+}
+
+// labelPairs formats the labels of the series stored under key, followed by the extra label
+// provided, if any, as they appear within the braces of a sample.
+func (f family) labelPairs(key string, extra ...string) string {
+	pairs := make([]string, 0, len(f.labels)+1)
+	if len(f.labels) > 0 {
+		for i, value := range strings.Split(key, labelValuesSeparator) {
+			pairs = append(pairs, f.labels[i]+`="`+escapeLabelValue(value)+`"`)
+		}
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, extra[0]+`="`+escapeLabelValue(extra[1])+`"`)
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// valueVec is a counter or a gauge with labels.
+type valueVec struct {
+	family
+	values map[string]float64
+}
+
+func newValueVec(name, help, kind string, labels ...string) *valueVec {
+	return &valueVec{
+		family: family{name: name, help: help, kind: kind, labels: labels},
+		values: make(map[string]float64),
+	}
+}
+
+func (v *valueVec) add(delta float64, labelValues ...string) {
+	v.values[strings.Join(labelValues, labelValuesSeparator)] += delta
+}
+
+func (v *valueVec) set(value float64, labelValues ...string) {
+	v.values[strings.Join(labelValues, labelValuesSeparator)] = value
+}
+
+// deleteSeries deletes the series whose first label has the value provided.
+func (v *valueVec) deleteSeries(firstLabelValue string) {
+	for key := range v.values {
+		if strings.Split(key, labelValuesSeparator)[0] == firstLabelValue {
+			delete(v.values, key)
+		}
+	}
+}
+
+func (v *valueVec) write(w io.Writer) error {
+	if err := v.writeHeader(w); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(v.values) {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", v.name, v.labelPairs(key), formatValue(v.values[key])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// histogram is one series of a histogramVec. counts holds the number of observations in each
+// bucket, not cumulated.
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// histogramVec is a histogram with labels.
+type histogramVec struct {
+	family
+	buckets    []float64
+	histograms map[string]*histogram
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{
+		family:     family{name: name, help: help, kind: "histogram", labels: labels},
+		buckets:    buckets,
+		histograms: make(map[string]*histogram),
+	}
+}
+
+func (v *histogramVec) observe(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, labelValuesSeparator)
+	h, ok := v.histograms[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(v.buckets))}
+		v.histograms[key] = h
+	}
+
+	if i := sort.SearchFloat64s(v.buckets, value); i < len(v.buckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += value
+}
+
+// deleteSeries deletes the series whose first label has the value provided.
+func (v *histogramVec) deleteSeries(firstLabelValue string) {
+	for key := range v.histograms {
+		if strings.Split(key, labelValuesSeparator)[0] == firstLabelValue {
+			delete(v.histograms, key)
+		}
+	}
+}
+
+func (v *histogramVec) write(w io.Writer) error {
+	if err := v.writeHeader(w); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(v.histograms) {
+		h := v.histograms[key]
+
+		var cumulative uint64
+		for i, bound := range v.buckets {
+			cumulative += h.counts[i]
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, v.labelPairs(key, "le", formatValue(bound)), cumulative); err != nil {
+				return err
+			}
+		}
+
+		labels := v.labelPairs(key)
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			v.name, v.labelPairs(key, "le", "+Inf"), h.count,
+			v.name, labels, formatValue(h.sum),
+			v.name, labels, h.count); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatValue formats a sample value as the Prometheus text format expects it.
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// escapeHelp escapes the backslashes and line feeds of a HELP line.
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+// escapeLabelValue escapes the backslashes, double quotes and line feeds of a label value.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,175 @@
+package model
+
+import (
+	"net/url"
+	"strconv"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+// AuditAction is an administrative action on a LegalHold recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditActionCreateLegalHold       AuditAction = "create_legal_hold"
+	AuditActionUpdateLegalHold       AuditAction = "update_legal_hold"
+	AuditActionReleaseLegalHold      AuditAction = "release_legal_hold"
+	AuditActionRestoreLegalHold      AuditAction = "restore_legal_hold"
+	AuditActionPurgeLegalHold        AuditAction = "purge_legal_hold"
+	AuditActionRunLegalHold          AuditAction = "run_legal_hold"
+	AuditActionRunAllLegalHolds      AuditAction = "run_all_legal_holds"
+	AuditActionCancelLegalHold       AuditAction = "cancel_legal_hold"
+	AuditActionDownloadLegalHold     AuditAction = "download_legal_hold"
+	AuditActionDownloadWithToken     AuditAction = "download_with_token"
+	AuditActionCreateDownloadToken   AuditAction = "create_download_token"
+	AuditActionRevokeDownloadToken   AuditAction = "revoke_download_token"
+	AuditActionCreateExportPackage   AuditAction = "create_export_package"
+	AuditActionDownloadExportPackage AuditAction = "download_export_package"
+	AuditActionDeleteExportPackage   AuditAction = "delete_export_package"
+)
+
+// AuditOutcome is whether the action of an AuditEvent succeeded.
+type AuditOutcome string
+
+const (
+	AuditOutcomeSuccess AuditOutcome = "success"
+	AuditOutcomeFailure AuditOutcome = "failure"
+)
+
+const (
+	// DefaultAuditEventsPerPage is the number of AuditEvents in a page if none is given.
+	DefaultAuditEventsPerPage = 60
+
+	// MaxAuditEventsPerPage is the largest number of AuditEvents in a page.
+	MaxAuditEventsPerPage = 200
+)
+
+// AuditEvent records an administrative action on a LegalHold: who took it, from where, and
+// whether it succeeded. AuditEvents are never deleted, not even when their LegalHold is released.
+type AuditEvent struct {
+	ID       string       `json:"id"`
+	CreateAt int64        `json:"create_at"`
+	Action   AuditAction  `json:"action"`
+	Outcome  AuditOutcome `json:"outcome"`
+	// ActorID is the ID of the user who took the action. It is empty for downloads with a
+	// download token, whose ID is in Details instead, and for the purges of released legal holds,
+	// which are done by the legal hold job.
+	ActorID     string `json:"actor_id,omitempty"`
+	LegalHoldID string `json:"legal_hold_id,omitempty"`
+	ClientIP    string `json:"client_ip"`
+	UserAgent   string `json:"user_agent,omitempty"`
+	// StatusCode is the HTTP status of the response to the action, or zero for the actions of the
+	// legal hold job, and Error the error message of the action if it failed.
+	StatusCode int    `json:"status_code"`
+	Error      string `json:"error,omitempty"`
+	// Before and After are the LegalHold before and after the action, for the actions that change
+	// it, without its secret.
+	Before *LegalHold `json:"before,omitempty"`
+	After  *LegalHold `json:"after,omitempty"`
+	// Details are the other parameters of the action, such as the ID of a download token.
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// NewAuditEvent creates a new AuditEvent of the action, which has not completed yet.
+func NewAuditEvent(action AuditAction, id string, now int64) AuditEvent {
+	return AuditEvent{
+		ID:       id,
+		CreateAt: now,
+		Action:   action,
+		Details:  make(map[string]string),
+	}
+}
+
+// SetBefore records the LegalHold as it was before the action.
+func (e *AuditEvent) SetBefore(lh LegalHold) {
+	before := lh.Sanitized()
+	e.Before = &before
+}
+
+// SetAfter records the LegalHold as it is after the action.
+func (e *AuditEvent) SetAfter(lh LegalHold) {
+	after := lh.Sanitized()
+	e.After = &after
+}
+
+// AuditEventQuery selects a page of the AuditEvents matching all the criteria it is given.
+type AuditEventQuery struct {
+	LegalHoldID string
+	ActorID     string
+	Action      AuditAction
+	Outcome     AuditOutcome
+	// Since and Until, if not zero, limit the AuditEvents to this time range. Both ends are
+	// inclusive.
+	Since   int64
+	Until   int64
+	Page    int
+	PerPage int
+}
+
+// NewAuditEventQuery creates an AuditEventQuery from the query parameters of a request. The since
+// and until parameters are in milliseconds since the epoch, and page counts from zero.
+func NewAuditEventQuery(query url.Values) (AuditEventQuery, error) {
+	auditQuery := AuditEventQuery{
+		LegalHoldID: query.Get("legal_hold_id"),
+		ActorID:     query.Get("actor_id"),
+		Action:      AuditAction(query.Get("action")),
+		Outcome:     AuditOutcome(query.Get("outcome")),
+		PerPage:     DefaultAuditEventsPerPage,
+	}
+
+	if auditQuery.LegalHoldID != "" && !mattermostModel.IsValidId(auditQuery.LegalHoldID) {
+		return AuditEventQuery{}, errors.Errorf("invalid legal_hold_id: %s", auditQuery.LegalHoldID)
+	}
+
+	if auditQuery.ActorID != "" && !mattermostModel.IsValidId(auditQuery.ActorID) {
+		return AuditEventQuery{}, errors.Errorf("invalid actor_id: %s", auditQuery.ActorID)
+	}
+
+	if auditQuery.Outcome != "" && auditQuery.Outcome != AuditOutcomeSuccess && auditQuery.Outcome != AuditOutcomeFailure {
+		return AuditEventQuery{}, errors.Errorf("invalid outcome: %s", auditQuery.Outcome)
+	}
+
+	var err error
+	if auditQuery.Since, err = parseTime(query, "since"); err != nil {
+		return AuditEventQuery{}, err
+	}
+	if auditQuery.Until, err = parseTime(query, "until"); err != nil {
+		return AuditEventQuery{}, err
+	}
+
+	if value := query.Get("page"); value != "" {
+		if auditQuery.Page, err = strconv.Atoi(value); err != nil || auditQuery.Page < 0 {
+			return AuditEventQuery{}, errors.Errorf("invalid page: %s", value)
+		}
+	}
+
+	if value := query.Get("per_page"); value != "" {
+		if auditQuery.PerPage, err = strconv.Atoi(value); err != nil || auditQuery.PerPage < 1 || auditQuery.PerPage > MaxAuditEventsPerPage {
+			return AuditEventQuery{}, errors.Errorf("invalid per_page: %s", value)
+		}
+	}
+
+	return auditQuery, nil
+}
+
+// InTimeRange returns true if the time provided in at is within the time range of the query.
+func (q AuditEventQuery) InTimeRange(at int64) bool {
+	return at >= q.Since && (q.Until == 0 || at <= q.Until)
+}
+
+// Matches returns true if the AuditEvent matches all the criteria of the query.
+func (q AuditEventQuery) Matches(event AuditEvent) bool {
+	return q.InTimeRange(event.CreateAt) &&
+		(q.LegalHoldID == "" || event.LegalHoldID == q.LegalHoldID) &&
+		(q.ActorID == "" || event.ActorID == q.ActorID) &&
+		(q.Action == "" || event.Action == q.Action) &&
+		(q.Outcome == "" || event.Outcome == q.Outcome)
+}
+
+// AuditEventPage is a page of the AuditEvents matching an AuditEventQuery, most recent first.
+type AuditEventPage struct {
+	Events []AuditEvent `json:"events"`
+	// HasNext is true if there are more matching AuditEvents on later pages.
+	HasNext bool `json:"has_next"`
+}
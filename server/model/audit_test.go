@@ -0,0 +1,81 @@
+package model
+
+import (
+	"net/url"
+	"testing"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAuditEventQuery(t *testing.T) {
+	legalHoldID := mattermostModel.NewId()
+
+	query, err := NewAuditEventQuery(url.Values{
+		"legal_hold_id": []string{legalHoldID},
+		"action":        []string{string(AuditActionDownloadLegalHold)},
+		"outcome":       []string{string(AuditOutcomeFailure)},
+		"since":         []string{"1000"},
+		"page":          []string{"2"},
+		"per_page":      []string{"10"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, AuditEventQuery{
+		LegalHoldID: legalHoldID,
+		Action:      AuditActionDownloadLegalHold,
+		Outcome:     AuditOutcomeFailure,
+		Since:       1000,
+		Page:        2,
+		PerPage:     10,
+	}, query)
+
+	query, err = NewAuditEventQuery(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, AuditEventQuery{PerPage: DefaultAuditEventsPerPage}, query)
+
+	invalid := []url.Values{
+		{"legal_hold_id": []string{"not-an-id"}},
+		{"actor_id": []string{"../" + legalHoldID}},
+		{"outcome": []string{"denied"}},
+		{"until": []string{"tomorrow"}},
+		{"page": []string{"-1"}},
+		{"per_page": []string{"0"}},
+		{"per_page": []string{"201"}},
+	}
+	for _, values := range invalid {
+		_, err = NewAuditEventQuery(values)
+		assert.Error(t, err, values)
+	}
+}
+
+func TestAuditEventQuery_Matches(t *testing.T) {
+	event := AuditEvent{
+		CreateAt:    1000,
+		Action:      AuditActionReleaseLegalHold,
+		Outcome:     AuditOutcomeSuccess,
+		ActorID:     mattermostModel.NewId(),
+		LegalHoldID: mattermostModel.NewId(),
+	}
+
+	assert.True(t, AuditEventQuery{}.Matches(event))
+	assert.True(t, AuditEventQuery{LegalHoldID: event.LegalHoldID, ActorID: event.ActorID, Since: 1000, Until: 1000}.Matches(event))
+	assert.False(t, AuditEventQuery{LegalHoldID: mattermostModel.NewId()}.Matches(event))
+	assert.False(t, AuditEventQuery{Action: AuditActionCreateLegalHold}.Matches(event))
+	assert.False(t, AuditEventQuery{Outcome: AuditOutcomeFailure}.Matches(event))
+	assert.False(t, AuditEventQuery{Since: 1001}.Matches(event))
+	assert.False(t, AuditEventQuery{Until: 999}.Matches(event))
+}
+
+func TestAuditEvent_SetBeforeAfter(t *testing.T) {
+	lh := LegalHold{ID: mattermostModel.NewId(), Secret: "secret", Status: LegalHoldStatusExecuting}
+
+	event := NewAuditEvent(AuditActionUpdateLegalHold, mattermostModel.NewId(), 1000)
+	event.SetBefore(lh)
+	event.SetAfter(lh)
+
+	assert.Equal(t, lh.ID, event.Before.ID)
+	assert.Empty(t, event.Before.Secret)
+	assert.Empty(t, event.After.Status)
+	assert.Equal(t, "secret", lh.Secret)
+}
@@ -9,3 +9,14 @@ type ChannelMetadata struct {
 	TeamName           string
 	TeamDisplayName    string
 }
+
+// ChannelMemberHistoryEntry represents one period of membership of a channel by a user, as
+// recorded in the ChannelMemberHistory table. LeaveTime is nil if the user has not yet left.
+type ChannelMemberHistoryEntry struct {
+	ChannelID string
+	UserID    string
+	Username  string
+	Email     string
+	JoinTime  int64
+	LeaveTime *int64
+}
@@ -0,0 +1,41 @@
+package model
+
+// ExecutionCheckpoint records the progress of an Execution of a LegalHold so that it can be
+// resumed after a failure or restart instead of starting over from the beginning of its window.
+//
+// Only the channels and cursors of the Execution are kept in the checkpoint. Its partial index and
+// the hashes of the files it has written are staged in the file backend as CheckpointProgress.
+type ExecutionCheckpoint struct {
+	LegalHoldID        string                     `json:"legal_hold_id"`
+	ExecutionStartTime int64                      `json:"execution_start_time"`
+	ExecutionEndTime   int64                      `json:"execution_end_time"`
+	ChannelIDs         []string                   `json:"channel_ids"`
+	Cursors            map[string]LegalHoldCursor `json:"cursors"`
+	RevisionCursors    map[string]LegalHoldCursor `json:"revision_cursors"`
+	HasMessages        bool                       `json:"has_messages"`
+	// Sequence is the number of the last CheckpointProgress staged before the checkpoint was saved.
+	Sequence int   `json:"sequence"`
+	UpdateAt int64 `json:"update_at"`
+}
+
+// CheckpointProgress holds the hashes, digests and file exceptions recorded by an Execution since
+// the previous CheckpointProgress was staged.
+type CheckpointProgress struct {
+	Hashes         HashList                 `json:"hashes"`
+	Digests        HashList                 `json:"digests"`
+	FileExceptions []LegalHoldFileException `json:"file_exceptions"`
+}
+
+// IsEmpty returns true if there is no progress to stage.
+func (p *CheckpointProgress) IsEmpty() bool {
+	return len(p.Hashes) == 0 && len(p.Digests) == 0 && len(p.FileExceptions) == 0
+}
+
+// Matches returns true if the checkpoint was taken for an execution of the LegalHold
+// identified by legalHoldID covering exactly the window from startTime to endTime.
+func (cp *ExecutionCheckpoint) Matches(legalHoldID string, startTime, endTime int64) bool {
+	return cp != nil &&
+		cp.LegalHoldID == legalHoldID &&
+		cp.ExecutionStartTime == startTime &&
+		cp.ExecutionEndTime == endTime
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModel_ExecutionCheckpoint_Matches(t *testing.T) {
+	cp := &ExecutionCheckpoint{
+		LegalHoldID:        "legal_hold_id",
+		ExecutionStartTime: 100,
+		ExecutionEndTime:   200,
+	}
+
+	assert.True(t, cp.Matches("legal_hold_id", 100, 200))
+	assert.False(t, cp.Matches("other_legal_hold_id", 100, 200))
+	assert.False(t, cp.Matches("legal_hold_id", 200, 300))
+	assert.False(t, cp.Matches("legal_hold_id", 100, 300))
+
+	var nilCheckpoint *ExecutionCheckpoint
+	assert.False(t, nilCheckpoint.Matches("legal_hold_id", 100, 200))
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"slices"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+)
+
+// CustodianNotice records the legal hold notice sent to a custodian of a LegalHold, the reminders
+// sent after it, and when the custodian acknowledged it.
+type CustodianNotice struct {
+	LegalHoldID string `json:"legal_hold_id"`
+	UserID      string `json:"user_id"`
+	// PostIDs are the IDs of the direct messages of the notice and its reminders, the notice first.
+	PostIDs        []string `json:"post_ids"`
+	NotifiedAt     int64    `json:"notified_at"`
+	RemindedAt     int64    `json:"reminded_at,omitempty"`
+	ReminderCount  int      `json:"reminder_count"`
+	AcknowledgedAt int64    `json:"acknowledged_at,omitempty"`
+}
+
+// NewCustodianNotice creates a new CustodianNotice for the notice sent to the custodian indicated by
+// userID in the post indicated by postID.
+func NewCustodianNotice(legalHoldID, userID, postID string, now int64) CustodianNotice {
+	return CustodianNotice{
+		LegalHoldID: legalHoldID,
+		UserID:      userID,
+		PostIDs:     []string{postID},
+		NotifiedAt:  now,
+	}
+}
+
+// IsAcknowledged returns true if the custodian has acknowledged the notice.
+func (n CustodianNotice) IsAcknowledged() bool {
+	return n.AcknowledgedAt != 0
+}
+
+// IsDueForReminder returns true if the custodian has not acknowledged the notice, and the
+// reminderInterval, in milliseconds, has passed since the notice or the last reminder at the time
+// provided in now. A reminderInterval of zero disables reminders.
+func (n CustodianNotice) IsDueForReminder(now, reminderInterval int64) bool {
+	if reminderInterval <= 0 || n.IsAcknowledged() {
+		return false
+	}
+
+	return now >= max(n.NotifiedAt, n.RemindedAt)+reminderInterval
+}
+
+// HasPost returns true if the post indicated by postID is the notice or one of its reminders.
+func (n CustodianNotice) HasPost(postID string) bool {
+	return slices.Contains(n.PostIDs, postID)
+}
+
+// CustodianStatus is the acknowledgement status of a custodian of a LegalHold, whether a user of
+// the LegalHold or a member of one of its groups. The times are zero if the custodian has not been
+// sent the notice yet.
+type CustodianStatus struct {
+	UserID         string `json:"user_id"`
+	Username       string `json:"username"`
+	NotifiedAt     int64  `json:"notified_at"`
+	RemindedAt     int64  `json:"reminded_at"`
+	ReminderCount  int    `json:"reminder_count"`
+	AcknowledgedAt int64  `json:"acknowledged_at"`
+	Acknowledged   bool   `json:"acknowledged"`
+}
+
+// NewCustodianStatus creates the CustodianStatus of the user, from the CustodianNotice sent to
+// them, if any.
+func NewCustodianStatus(user *mattermostModel.User, notice *CustodianNotice) CustodianStatus {
+	status := CustodianStatus{
+		UserID:   user.Id,
+		Username: user.Username,
+	}
+
+	if notice != nil {
+		status.NotifiedAt = notice.NotifiedAt
+		status.RemindedAt = notice.RemindedAt
+		status.ReminderCount = notice.ReminderCount
+		status.AcknowledgedAt = notice.AcknowledgedAt
+		status.Acknowledged = notice.IsAcknowledged()
+	}
+
+	return status
+}
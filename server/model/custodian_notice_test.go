@@ -0,0 +1,35 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustodianNotice_IsDueForReminder(t *testing.T) {
+	notice := NewCustodianNotice("legal_hold_id", "user_id", "post_id", 1000)
+	reminded := notice
+	reminded.RemindedAt = 3000
+	acknowledged := notice
+	acknowledged.AcknowledgedAt = 1500
+
+	testCases := map[string]struct {
+		notice   CustodianNotice
+		now      int64
+		interval int64
+		expected bool
+	}{
+		"before the interval":             {notice: notice, now: 1999, interval: 1000, expected: false},
+		"after the interval":              {notice: notice, now: 2000, interval: 1000, expected: true},
+		"before the interval of reminder": {notice: reminded, now: 3999, interval: 1000, expected: false},
+		"after the interval of reminder":  {notice: reminded, now: 4000, interval: 1000, expected: true},
+		"acknowledged":                    {notice: acknowledged, now: 5000, interval: 1000, expected: false},
+		"reminders disabled":              {notice: notice, now: 5000, interval: 0, expected: false},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.notice.IsDueForReminder(tc.now, tc.interval))
+		})
+	}
+}
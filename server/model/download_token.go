@@ -0,0 +1,173 @@
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxDownloadTokenLifetime is the longest a DownloadToken can be valid for.
+	MaxDownloadTokenLifetime = 30 * 24 * time.Hour
+
+	// DefaultDownloadTokenLifetime is how long a DownloadToken is valid for if no expiry is given.
+	DefaultDownloadTokenLifetime = 7 * 24 * time.Hour
+
+	downloadTokenMaxDescriptionLength = 256
+)
+
+// downloadTokenSignaturePrefix is prepended to the claims of a DownloadToken before they are
+// signed, so that the signature of a token cannot be mistaken for that of a manifest.
+var downloadTokenSignaturePrefix = []byte("mattermost-legal-hold-download-token\n")
+
+// ErrInvalidDownloadToken is returned for download tokens that are malformed, forged or expired.
+var ErrInvalidDownloadToken = errors.New("invalid download token")
+
+// DownloadToken lets someone without a Mattermost account download one LegalHold until it
+// expires or is revoked. Only this record is stored: the token itself is handed out once, when
+// the DownloadToken is created.
+type DownloadToken struct {
+	ID          string `json:"id"`
+	LegalHoldID string `json:"legal_hold_id"`
+	// Description says who the token was created for, such as the outside counsel of a case.
+	Description string `json:"description,omitempty"`
+	CreatorID   string `json:"creator_id"`
+	CreateAt    int64  `json:"create_at"`
+	ExpiresAt   int64  `json:"expires_at"`
+	RevokedAt   int64  `json:"revoked_at,omitempty"`
+	RevokedBy   string `json:"revoked_by,omitempty"`
+	UseCount    int    `json:"use_count"`
+	LastUsedAt  int64  `json:"last_used_at,omitempty"`
+}
+
+// CreateDownloadToken is the request to create a DownloadToken. If ExpiresAt is zero, the token
+// expires after DefaultDownloadTokenLifetime.
+type CreateDownloadToken struct {
+	Description string `json:"description"`
+	ExpiresAt   int64  `json:"expires_at"`
+}
+
+// NewDownloadTokenFromCreate creates a DownloadToken for the LegalHold indicated by legalHoldID,
+// created by the user indicated by creatorID at the time provided in now.
+func NewDownloadTokenFromCreate(create CreateDownloadToken, id, legalHoldID, creatorID string, now int64) DownloadToken {
+	expiresAt := create.ExpiresAt
+	if expiresAt == 0 {
+		expiresAt = now + DefaultDownloadTokenLifetime.Milliseconds()
+	}
+
+	return DownloadToken{
+		ID:          id,
+		LegalHoldID: legalHoldID,
+		Description: strings.TrimSpace(create.Description),
+		CreatorID:   creatorID,
+		CreateAt:    now,
+		ExpiresAt:   expiresAt,
+	}
+}
+
+// CreatedDownloadToken is a DownloadToken that has just been created, together with its token,
+// which is not stored and so can only be handed out at this time.
+type CreatedDownloadToken struct {
+	DownloadToken
+	Token string `json:"token"`
+}
+
+// IsValid checks whether the DownloadToken can be created at the time provided in now,
+// returning an error describing the validation failure if not.
+func (t DownloadToken) IsValid(now int64) error {
+	if t.ExpiresAt <= now {
+		return errors.New("DownloadToken must expire in the future")
+	}
+
+	if t.ExpiresAt > now+MaxDownloadTokenLifetime.Milliseconds() {
+		return errors.New("DownloadToken must expire within 30 days")
+	}
+
+	if len(t.Description) > downloadTokenMaxDescriptionLength {
+		return errors.New("DownloadToken description must be at most 256 characters in length")
+	}
+
+	return nil
+}
+
+// IsActive returns true if the DownloadToken can be used at the time provided in now.
+func (t DownloadToken) IsActive(now int64) bool {
+	return t.RevokedAt == 0 && now < t.ExpiresAt
+}
+
+// DownloadTokenClaims are the contents of a signed download token.
+type DownloadTokenClaims struct {
+	TokenID     string `json:"tid"`
+	LegalHoldID string `json:"lhid"`
+	ExpiresAt   int64  `json:"exp"`
+}
+
+// Claims returns the claims of the token of the DownloadToken.
+func (t DownloadToken) Claims() DownloadTokenClaims {
+	return DownloadTokenClaims{
+		TokenID:     t.ID,
+		LegalHoldID: t.LegalHoldID,
+		ExpiresAt:   t.ExpiresAt,
+	}
+}
+
+// EncodeDownloadToken returns the token for the claims, signed with the SigningKey. The token is
+// the base64url-encoded claims and signature, separated by a dot.
+func EncodeDownloadToken(claims DownloadTokenClaims, key *SigningKey) (string, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", errors.Wrap(err, "could not marshal download token claims")
+	}
+
+	signature := key.Sign(append(bytes.Clone(downloadTokenSignaturePrefix), data...))
+
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// ParseDownloadToken returns the claims of the token if it was signed with the SigningKey and has
+// not expired at the time provided in now, or ErrInvalidDownloadToken if not. It does not check
+// whether the token was revoked.
+func ParseDownloadToken(token string, key *SigningKey, now int64) (DownloadTokenClaims, error) {
+	encodedData, encodedSignature, found := strings.Cut(token, ".")
+	if !found {
+		return DownloadTokenClaims{}, ErrInvalidDownloadToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encodedData)
+	if err != nil {
+		return DownloadTokenClaims{}, ErrInvalidDownloadToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return DownloadTokenClaims{}, ErrInvalidDownloadToken
+	}
+
+	if !key.Verify(append(bytes.Clone(downloadTokenSignaturePrefix), data...), signature) {
+		return DownloadTokenClaims{}, ErrInvalidDownloadToken
+	}
+
+	var claims DownloadTokenClaims
+	if err = json.Unmarshal(data, &claims); err != nil {
+		return DownloadTokenClaims{}, ErrInvalidDownloadToken
+	}
+
+	if claims.TokenID == "" || claims.LegalHoldID == "" || now >= claims.ExpiresAt {
+		return DownloadTokenClaims{}, ErrInvalidDownloadToken
+	}
+
+	return claims, nil
+}
+
+// DownloadTokenHash returns a short SHA-256 hash of the token, so that uses of a token can be told
+// apart in logs, even when it cannot be parsed, without logging the token itself.
+func DownloadTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
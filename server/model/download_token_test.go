@@ -0,0 +1,83 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadToken_EncodeAndParse(t *testing.T) {
+	key, err := NewSigningKey()
+	require.NoError(t, err)
+	otherKey, err := NewSigningKey()
+	require.NoError(t, err)
+
+	claims := DownloadTokenClaims{TokenID: "token_id", LegalHoldID: "legal_hold_id", ExpiresAt: 2000}
+	token, err := EncodeDownloadToken(claims, key)
+	require.NoError(t, err)
+
+	parsed, err := ParseDownloadToken(token, key, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, claims, parsed)
+
+	data, signature, _ := strings.Cut(token, ".")
+	forged, err := EncodeDownloadToken(DownloadTokenClaims{TokenID: "token_id", LegalHoldID: "other_hold", ExpiresAt: 2000}, key)
+	require.NoError(t, err)
+	forgedData, _, _ := strings.Cut(forged, ".")
+
+	testCases := map[string]struct {
+		token string
+		key   *SigningKey
+		now   int64
+	}{
+		"expired":              {token: token, key: key, now: 2000},
+		"signed with another":  {token: token, key: otherKey, now: 1000},
+		"claims swapped":       {token: forgedData + "." + signature, key: key, now: 1000},
+		"no signature":         {token: data, key: key, now: 1000},
+		"empty":                {token: "", key: key, now: 1000},
+		"not base64":           {token: "!!." + signature, key: key, now: 1000},
+		"truncated signature":  {token: data + "." + signature[:10], key: key, now: 1000},
+		"signature of a claim": {token: data + "." + data, key: key, now: 1000},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseDownloadToken(tc.token, tc.key, tc.now)
+			require.ErrorIs(t, err, ErrInvalidDownloadToken)
+		})
+	}
+}
+
+func TestDownloadToken_IsValid(t *testing.T) {
+	now := int64(1000)
+
+	token := NewDownloadTokenFromCreate(CreateDownloadToken{Description: " Outside counsel "}, "token_id", "legal_hold_id", "user_id", now)
+	require.NoError(t, token.IsValid(now))
+	assert.Equal(t, "Outside counsel", token.Description)
+	assert.Equal(t, now+DefaultDownloadTokenLifetime.Milliseconds(), token.ExpiresAt)
+	assert.True(t, token.IsActive(now))
+	assert.False(t, token.IsActive(token.ExpiresAt))
+
+	token.RevokedAt = now
+	assert.False(t, token.IsActive(now))
+
+	invalid := []CreateDownloadToken{
+		{ExpiresAt: now},
+		{ExpiresAt: now + MaxDownloadTokenLifetime.Milliseconds() + 1},
+		{Description: strings.Repeat("a", 257)},
+	}
+	for _, create := range invalid {
+		token = NewDownloadTokenFromCreate(create, "token_id", "legal_hold_id", "user_id", now)
+		assert.Error(t, token.IsValid(now))
+	}
+}
+
+func TestDownloadTokenHash(t *testing.T) {
+	hash := DownloadTokenHash("token")
+	require.Len(t, hash, 16)
+	require.Equal(t, hash, DownloadTokenHash("token"))
+	require.NotEqual(t, hash, DownloadTokenHash("token2"))
+	require.NotContains(t, hash, "token")
+}
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// EncryptionInfoFileName is the name of the file in the base path of a legal hold that holds
+	// its EncryptionInfo. It is the only file of an encrypted legal hold stored in plaintext.
+	EncryptionInfoFileName = "encryption.json"
+	// EncryptionAlgorithm identifies the format of the encrypted files: AES-256-GCM applied to
+	// consecutive chunks of the file, as described in the processor README.
+	EncryptionAlgorithm = "AES-256-GCM-CHUNKED"
+	// EncryptionKeySize is the size in bytes of both the master key and the data keys.
+	EncryptionKeySize = 32
+)
+
+// dataKeyAdditionalData binds a wrapped data key to its purpose.
+var dataKeyAdditionalData = []byte("legal_hold_data_key")
+
+// EncryptionInfo describes how the files of a legal hold are encrypted: with a data key of its
+// own, stored wrapped by the master key configured in the plugin settings.
+type EncryptionInfo struct {
+	Algorithm string `json:"algorithm"`
+	// MasterKeyID identifies the master key that wrapped the data key.
+	MasterKeyID    string `json:"master_key_id"`
+	WrappedDataKey []byte `json:"wrapped_data_key"`
+}
+
+// ParseEncryptionKey decodes a base64-encoded master key, as set in the plugin configuration.
+func ParseEncryptionKey(value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode the encryption key")
+	}
+
+	if len(key) != EncryptionKeySize {
+		return nil, errors.Errorf("the encryption key must be %d bytes long, not %d", EncryptionKeySize, len(key))
+	}
+
+	return key, nil
+}
+
+// EncryptionKeyID returns an identifier for the master key that does not reveal it.
+func EncryptionKeyID(masterKey []byte) string {
+	fingerprint := sha256.Sum256(masterKey)
+	return hex.EncodeToString(fingerprint[:8])
+}
+
+// NewEncryptionInfo generates a new data key, returning it together with the EncryptionInfo
+// holding it wrapped by masterKey.
+func NewEncryptionInfo(masterKey []byte) (*EncryptionInfo, []byte, error) {
+	dataKey := make([]byte, EncryptionKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, errors.Wrap(err, "could not generate data key")
+	}
+
+	gcm, err := newGCM(masterKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, nil, errors.Wrap(err, "could not generate nonce")
+	}
+
+	return &EncryptionInfo{
+		Algorithm:      EncryptionAlgorithm,
+		MasterKeyID:    EncryptionKeyID(masterKey),
+		WrappedDataKey: gcm.Seal(nonce, nonce, dataKey, dataKeyAdditionalData),
+	}, dataKey, nil
+}
+
+// DataKey unwraps the data key with masterKey.
+func (i *EncryptionInfo) DataKey(masterKey []byte) ([]byte, error) {
+	if i.Algorithm != EncryptionAlgorithm {
+		return nil, errors.Errorf("unsupported encryption algorithm %s", i.Algorithm)
+	}
+
+	if keyID := EncryptionKeyID(masterKey); i.MasterKeyID != keyID {
+		return nil, errors.Errorf("the data key was wrapped with master key %s, not %s", i.MasterKeyID, keyID)
+	}
+
+	gcm, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(i.WrappedDataKey) < gcm.NonceSize() {
+		return nil, errors.New("the wrapped data key is too short")
+	}
+
+	nonce, wrapped := i.WrappedDataKey[:gcm.NonceSize()], i.WrappedDataKey[gcm.NonceSize():]
+	dataKey, err := gcm.Open(nil, nonce, wrapped, dataKeyAdditionalData)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not unwrap the data key")
+	}
+
+	return dataKey, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create cipher")
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create GCM")
+	}
+
+	return gcm, nil
+}
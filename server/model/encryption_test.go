@@ -0,0 +1,54 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModel_ParseEncryptionKey(t *testing.T) {
+	raw := make([]byte, EncryptionKeySize)
+	_, err := rand.Read(raw)
+	require.NoError(t, err)
+
+	key, err := ParseEncryptionKey(" " + base64.StdEncoding.EncodeToString(raw) + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, raw, key)
+
+	_, err = ParseEncryptionKey("not base64!")
+	require.Error(t, err)
+
+	_, err = ParseEncryptionKey(base64.StdEncoding.EncodeToString(raw[:16]))
+	require.ErrorContains(t, err, "must be 32 bytes long")
+}
+
+func TestModel_EncryptionInfo(t *testing.T) {
+	masterKey := make([]byte, EncryptionKeySize)
+	_, err := rand.Read(masterKey)
+	require.NoError(t, err)
+
+	info, dataKey, err := NewEncryptionInfo(masterKey)
+	require.NoError(t, err)
+	require.Len(t, dataKey, EncryptionKeySize)
+	assert.Equal(t, EncryptionAlgorithm, info.Algorithm)
+	assert.Equal(t, EncryptionKeyID(masterKey), info.MasterKeyID)
+	assert.NotContains(t, string(info.WrappedDataKey), string(dataKey))
+
+	unwrapped, err := info.DataKey(masterKey)
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, unwrapped)
+
+	otherKey := make([]byte, EncryptionKeySize)
+	_, err = rand.Read(otherKey)
+	require.NoError(t, err)
+	_, err = info.DataKey(otherKey)
+	require.ErrorContains(t, err, "was wrapped with master key")
+
+	// A tampered wrapped key fails to unwrap.
+	info.WrappedDataKey[len(info.WrappedDataKey)-1] ^= 1
+	_, err = info.DataKey(masterKey)
+	require.ErrorContains(t, err, "could not unwrap")
+}
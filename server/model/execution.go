@@ -0,0 +1,39 @@
+package model
+
+// ExecutionRecord records the outcome of one run of an Execution of a LegalHold, so that
+// there is an auditable history of every time data was collected for the LegalHold.
+type ExecutionRecord struct {
+	ID          string `json:"id"`
+	LegalHoldID string `json:"legal_hold_id"`
+
+	// WindowStart and WindowEnd are the bounds of the period of time covered by the execution.
+	WindowStart int64 `json:"window_start"`
+	WindowEnd   int64 `json:"window_end"`
+
+	// StartedAt and EndedAt are the wall-clock times at which the execution ran, and Duration
+	// the time it took in milliseconds.
+	StartedAt int64 `json:"started_at"`
+	EndedAt   int64 `json:"ended_at"`
+	Duration  int64 `json:"duration"`
+
+	// Resumed indicates that the execution continued from the checkpoint of an earlier run.
+	Resumed bool `json:"resumed"`
+
+	ChannelCount  int   `json:"channel_count"`
+	PostCount     int   `json:"post_count"`
+	RevisionCount int   `json:"revision_count"`
+	FileCount     int   `json:"file_count"`
+	BytesWritten  int64 `json:"bytes_written"`
+	SkippedFiles  int   `json:"skipped_files"`
+	// DeduplicatedFiles is the number of the exported files whose content was already stored in
+	// the legal hold, so that no bytes were written for them.
+	DeduplicatedFiles int `json:"deduplicated_files"`
+	// FileExceptions is the number of file attachments that could not be exported, as listed in
+	// the exceptions file of the execution.
+	FileExceptions int `json:"file_exceptions"`
+
+	// Error is the error that caused the execution to fail, or empty if it succeeded.
+	Error string `json:"error,omitempty"`
+	// Cancelled indicates that the execution was stopped before it completed.
+	Cancelled bool `json:"cancelled"`
+}
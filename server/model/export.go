@@ -4,6 +4,8 @@ package model
 // It is based on the model.ComplianceCursor struct from Mattermost Server.
 type LegalHoldCursor struct {
 	LastPostCreateAt int64
+	// LastPostUpdateAt is used instead of LastPostCreateAt when paginating post revisions.
+	LastPostUpdateAt int64
 	LastPostID       string
 	BatchNumber      uint
 	Completed        bool
@@ -20,6 +22,17 @@ func NewLegalHoldCursor(startTime int64) LegalHoldCursor {
 	}
 }
 
+// NewLegalHoldRevisionCursor creates a new LegalHoldCursor object for paginating the revisions of
+// posts made from the provided startTime onwards.
+func NewLegalHoldRevisionCursor(startTime int64) LegalHoldCursor {
+	return LegalHoldCursor{
+		LastPostUpdateAt: startTime,
+		LastPostID:       "00000000000000000000000000",
+		BatchNumber:      0,
+		Completed:        false,
+	}
+}
+
 // LegalHoldPost represents one post and its associated data as required for a legal hold record.
 // It is based on the model.CompliancePost struct from Mattermost Server.
 type LegalHoldPost struct {
@@ -49,6 +62,105 @@ type LegalHoldPost struct {
 	PostProps      string `csv:"PostProps"`
 	PostHashtags   string `csv:"PostHashtags"`
 	PostFileIDs    string `csv:"PostFileIds"`
+	PostIsPinned   bool   `csv:"PostIsPinned"`
 
 	IsBot bool `csv:"IsBot"`
 }
+
+const (
+	// PostRevisionTypeEdited marks the current version of a post that was edited.
+	PostRevisionTypeEdited = "edited"
+	// PostRevisionTypePrevious marks a previous version of an edited post.
+	PostRevisionTypePrevious = "previous"
+	// PostRevisionTypeDeleted marks a post that was deleted.
+	PostRevisionTypeDeleted = "deleted"
+)
+
+// LegalHoldPostRevision represents one revision of a post that was edited or deleted during the
+// window of an Execution but created before it, so that it was not part of the posts exported for
+// that window.
+type LegalHoldPostRevision struct {
+	// RevisionOf is the ID of the post this is a revision of.
+	RevisionOf string `csv:"RevisionOf"`
+	// RevisionType is one of PostRevisionTypeEdited, PostRevisionTypePrevious or PostRevisionTypeDeleted.
+	RevisionType string `csv:"RevisionType"`
+	PostEditAt   int64  `csv:"PostEditAt"`
+
+	LegalHoldPost
+}
+
+// SetRevision derives RevisionOf and RevisionType from the post data. Mattermost stores the
+// previous versions of an edited post as deleted copies of it with OriginalId set to its ID.
+func (r *LegalHoldPostRevision) SetRevision() {
+	switch {
+	case r.PostOriginalID != "":
+		r.RevisionOf = r.PostOriginalID
+		r.RevisionType = PostRevisionTypePrevious
+	case r.PostDeleteAt > 0:
+		r.RevisionOf = r.PostID
+		r.RevisionType = PostRevisionTypeDeleted
+	default:
+		r.RevisionOf = r.PostID
+		r.RevisionType = PostRevisionTypeEdited
+	}
+}
+
+// LegalHoldReaction represents one reaction to a post, including reactions that were removed,
+// as required for a legal hold record.
+type LegalHoldReaction struct {
+	PostID       string `csv:"PostId"`
+	UserID       string `csv:"UserId"`
+	UserUsername string `csv:"UserUsername"`
+	UserEmail    string `csv:"UserEmail"`
+	EmojiName    string `csv:"EmojiName"`
+	CreateAt     int64  `csv:"CreateAt"`
+	UpdateAt     int64  `csv:"UpdateAt"`
+	DeleteAt     int64  `csv:"DeleteAt"`
+}
+
+// LegalHoldAcknowledgement represents the acknowledgement of a post by a user as required for a
+// legal hold record. An AcknowledgedAt of 0 means the acknowledgement was withdrawn.
+type LegalHoldAcknowledgement struct {
+	PostID         string `csv:"PostId"`
+	UserID         string `csv:"UserId"`
+	UserUsername   string `csv:"UserUsername"`
+	UserEmail      string `csv:"UserEmail"`
+	AcknowledgedAt int64  `csv:"AcknowledgedAt"`
+}
+
+// LegalHoldFileReference represents one file attachment of a batch of posts. The content of the
+// attachment is stored once per legal hold, named by its SHA-256 Digest, at BlobPath relative to
+// the base path of the legal hold, however many times it was attached.
+type LegalHoldFileReference struct {
+	FileID   string `csv:"FileId"`
+	FileName string `csv:"FileName"`
+	FileSize int64  `csv:"FileSize"`
+	MimeType string `csv:"MimeType"`
+	Digest   string `csv:"Digest"`
+	BlobPath string `csv:"BlobPath"`
+}
+
+const (
+	// FileExceptionMissing marks a file attachment that was not found in the file store.
+	FileExceptionMissing = "missing"
+	// FileExceptionFailed marks a file attachment that could not be copied, even after retrying.
+	FileExceptionFailed = "failed"
+	// FileExceptionInvalidName marks a file attachment whose name is not safe to export.
+	FileExceptionInvalidName = "invalid_name"
+)
+
+// LegalHoldFileException records a file attachment of a post that could not be exported, so that
+// its absence from the export is documented.
+type LegalHoldFileException struct {
+	ExecutionStartTime int64  `csv:"ExecutionStartTime" json:"execution_start_time"`
+	ExecutionEndTime   int64  `csv:"ExecutionEndTime" json:"execution_end_time"`
+	ChannelID          string `csv:"ChannelId" json:"channel_id"`
+	BatchPostID        string `csv:"BatchPostId" json:"batch_post_id"`
+	FileID             string `csv:"FileId" json:"file_id"`
+	FileName           string `csv:"FileName" json:"file_name"`
+	FilePath           string `csv:"FilePath" json:"file_path"`
+	// Reason is one of FileExceptionMissing, FileExceptionFailed or FileExceptionInvalidName.
+	Reason   string `csv:"Reason" json:"reason"`
+	Error    string `csv:"Error" json:"error"`
+	Attempts int    `csv:"Attempts" json:"attempts"`
+}
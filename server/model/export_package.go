@@ -0,0 +1,112 @@
+package model
+
+import "fmt"
+
+// ExportPackageStatus is the state of the build of an ExportPackage.
+type ExportPackageStatus string
+
+const (
+	// ExportPackageStatusPending marks a package that has been requested but not started.
+	ExportPackageStatusPending ExportPackageStatus = "pending"
+	// ExportPackageStatusRunning marks a package that is being built.
+	ExportPackageStatusRunning ExportPackageStatus = "running"
+	// ExportPackageStatusCompleted marks a package whose archive is ready to download.
+	ExportPackageStatusCompleted ExportPackageStatus = "completed"
+	// ExportPackageStatusFailed marks a package that could not be built.
+	ExportPackageStatusFailed ExportPackageStatus = "failed"
+)
+
+// ExportPackageManifestFileName is the name of the manifest at the root of the archive of an
+// ExportPackage.
+const ExportPackageManifestFileName = "package_manifest.json"
+
+// exportPackagesPath is the directory of the archives of all ExportPackages. It is outside the
+// legal_hold directory, so that the archives are neither part of a legal hold nor encrypted again.
+const exportPackagesPath = "legal_hold_packages"
+
+// ExportPackage is a zip archive of all the files of a LegalHold, built in the background on the
+// file backend so that it can be downloaded, and resumed, once it is complete.
+type ExportPackage struct {
+	ID          string              `json:"id"`
+	LegalHoldID string              `json:"legal_hold_id"`
+	Status      ExportPackageStatus `json:"status"`
+	CreateAt    int64               `json:"create_at"`
+	UpdateAt    int64               `json:"update_at"`
+	CompletedAt int64               `json:"completed_at,omitempty"`
+
+	// FileCount and TotalBytes describe the files of the LegalHold to be packaged, and
+	// ProcessedFiles and ProcessedBytes how many of them have been added to the archive so far.
+	FileCount      int   `json:"file_count"`
+	TotalBytes     int64 `json:"total_bytes"`
+	ProcessedFiles int   `json:"processed_files"`
+	ProcessedBytes int64 `json:"processed_bytes"`
+
+	// Size and Digest are the size and hex-encoded SHA-256 digest of the completed archive.
+	Size   int64  `json:"size,omitempty"`
+	Digest string `json:"digest,omitempty"`
+
+	// Error is the error that caused the build to fail, or empty if it did not.
+	Error string `json:"error,omitempty"`
+}
+
+// NewExportPackage creates a new pending ExportPackage of the LegalHold indicated by legalHoldID.
+func NewExportPackage(id, legalHoldID string, now int64) ExportPackage {
+	return ExportPackage{
+		ID:          id,
+		LegalHoldID: legalHoldID,
+		Status:      ExportPackageStatusPending,
+		CreateAt:    now,
+		UpdateAt:    now,
+	}
+}
+
+// ArchivePath returns the path of the archive of the ExportPackage in the file backend.
+func (p ExportPackage) ArchivePath() string {
+	return fmt.Sprintf("%s/%s/%s.zip", exportPackagesPath, p.LegalHoldID, p.ID)
+}
+
+// ArchiveFileName returns the name under which the archive is downloaded.
+func (p ExportPackage) ArchiveFileName() string {
+	return fmt.Sprintf("legalholddata-%s.zip", p.ID)
+}
+
+// IsFinished returns true if the build of the ExportPackage has ended, successfully or not.
+func (p ExportPackage) IsFinished() bool {
+	return p.Status == ExportPackageStatusCompleted || p.Status == ExportPackageStatusFailed
+}
+
+// FailIfStale marks the ExportPackage as failed if it is not finished but its build has not made
+// progress for longer than timeout at the time provided in now, as happens if the node building
+// it stops.
+func (p *ExportPackage) FailIfStale(now, timeout int64) {
+	if p.IsFinished() || now-p.UpdateAt <= timeout {
+		return
+	}
+
+	p.Status = ExportPackageStatusFailed
+	p.Error = "the build of the export package was interrupted"
+}
+
+// ExportPackagesPath returns the directory of the archives of the ExportPackages of the LegalHold
+// indicated by legalHoldID in the file backend.
+func ExportPackagesPath(legalHoldID string) string {
+	return fmt.Sprintf("%s/%s", exportPackagesPath, legalHoldID)
+}
+
+// ExportPackageManifest lists the files in the archive of an ExportPackage, as stored in the file
+// backend, so that the archive can be checked for completeness once it has been downloaded.
+type ExportPackageManifest struct {
+	PackageID     string              `json:"package_id"`
+	LegalHoldID   string              `json:"legal_hold_id"`
+	LegalHoldName string              `json:"legal_hold_name"`
+	CreateAt      int64               `json:"create_at"`
+	Algorithm     string              `json:"algorithm"`
+	Files         []ExportPackageFile `json:"files"`
+}
+
+// ExportPackageFile is one file in the archive of an ExportPackage.
+type ExportPackageFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Digest string `json:"digest"`
+}
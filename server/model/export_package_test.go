@@ -0,0 +1,26 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportPackage_FailIfStale(t *testing.T) {
+	pkg := NewExportPackage("package_id", "legal_hold_id", 1000)
+	assert.Equal(t, "legal_hold_packages/legal_hold_id/package_id.zip", pkg.ArchivePath())
+
+	pkg.FailIfStale(1500, 1000)
+	assert.Equal(t, ExportPackageStatusPending, pkg.Status)
+
+	pkg.FailIfStale(2500, 1000)
+	assert.Equal(t, ExportPackageStatusFailed, pkg.Status)
+	assert.NotEmpty(t, pkg.Error)
+
+	// A completed package never goes stale.
+	completed := NewExportPackage("package_id", "legal_hold_id", 1000)
+	completed.Status = ExportPackageStatusCompleted
+	completed.FailIfStale(5000, 1000)
+	assert.Equal(t, ExportPackageStatusCompleted, completed.Status)
+	assert.Empty(t, completed.Error)
+}
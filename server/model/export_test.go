@@ -0,0 +1,44 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLegalHoldPostRevision_SetRevision(t *testing.T) {
+	testCases := []struct {
+		name         string
+		post         LegalHoldPost
+		revisionOf   string
+		revisionType string
+	}{
+		{
+			name:         "edited post",
+			post:         LegalHoldPost{PostID: "post1"},
+			revisionOf:   "post1",
+			revisionType: PostRevisionTypeEdited,
+		},
+		{
+			name:         "previous version of an edited post",
+			post:         LegalHoldPost{PostID: "post2", PostOriginalID: "post1", PostDeleteAt: 100},
+			revisionOf:   "post1",
+			revisionType: PostRevisionTypePrevious,
+		},
+		{
+			name:         "deleted post",
+			post:         LegalHoldPost{PostID: "post1", PostDeleteAt: 100},
+			revisionOf:   "post1",
+			revisionType: PostRevisionTypeDeleted,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			revision := LegalHoldPostRevision{LegalHoldPost: tc.post}
+			revision.SetRevision()
+			require.Equal(t, tc.revisionOf, revision.RevisionOf)
+			require.Equal(t, tc.revisionType, revision.RevisionType)
+		})
+	}
+}
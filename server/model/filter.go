@@ -0,0 +1,129 @@
+package model
+
+import (
+	"slices"
+	"strings"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/utils"
+)
+
+const (
+	legalHoldFilterMaxTerms      = 50
+	legalHoldFilterMaxTermLength = 100
+)
+
+// LegalHoldFilter narrows down the posts preserved by a LegalHold. A post is preserved if it
+// matches at least one of the Terms or Hashtags (when any are specified) and is in a channel
+// whose type is allowed by IncludeChannelTypes and ExcludeChannelTypes.
+type LegalHoldFilter struct {
+	// Terms are keywords or phrases to match in the post message, case-insensitively.
+	Terms []string `json:"terms,omitempty"`
+	// Hashtags are hashtags (with or without the leading #) to match against the post hashtags.
+	Hashtags []string `json:"hashtags,omitempty"`
+	// IncludeChannelTypes, if not empty, limits the posts to channels of these types.
+	IncludeChannelTypes []mattermostModel.ChannelType `json:"include_channel_types,omitempty"`
+	// ExcludeChannelTypes excludes posts in channels of these types.
+	ExcludeChannelTypes []mattermostModel.ChannelType `json:"exclude_channel_types,omitempty"`
+}
+
+// IsEmpty returns true if the filter does not restrict the posts in any way.
+func (f *LegalHoldFilter) IsEmpty() bool {
+	return f == nil ||
+		(len(f.Terms) == 0 && len(f.Hashtags) == 0 && len(f.IncludeChannelTypes) == 0 && len(f.ExcludeChannelTypes) == 0)
+}
+
+// Normalize trims the terms and lowercases the hashtags, prefixing them with # where missing.
+func (f *LegalHoldFilter) Normalize() {
+	if f == nil {
+		return
+	}
+
+	for i, term := range f.Terms {
+		f.Terms[i] = strings.TrimSpace(term)
+	}
+
+	for i, hashtag := range f.Hashtags {
+		hashtag = strings.ToLower(strings.TrimSpace(hashtag))
+		if !strings.HasPrefix(hashtag, "#") {
+			hashtag = "#" + hashtag
+		}
+		f.Hashtags[i] = hashtag
+	}
+}
+
+// IsValid checks whether the LegalHoldFilter is valid, returning an error describing the
+// validation failure if not.
+func (f *LegalHoldFilter) IsValid() error {
+	if f == nil {
+		return nil
+	}
+
+	if len(f.Terms)+len(f.Hashtags) > legalHoldFilterMaxTerms {
+		return errors.New("LegalHold filter must have at most 50 terms and hashtags")
+	}
+
+	for _, term := range f.Terms {
+		if term == "" || len(term) > legalHoldFilterMaxTermLength {
+			return errors.New("LegalHold filter terms must be between 1 and 100 characters in length")
+		}
+	}
+
+	for _, hashtag := range f.Hashtags {
+		if len(hashtag) < 2 || len(hashtag) > legalHoldFilterMaxTermLength || strings.ContainsAny(hashtag, " \t\n") {
+			return errors.New("LegalHold filter hashtags must be single words between 1 and 100 characters in length")
+		}
+	}
+
+	validTypes := []mattermostModel.ChannelType{
+		mattermostModel.ChannelTypeOpen,
+		mattermostModel.ChannelTypePrivate,
+		mattermostModel.ChannelTypeDirect,
+		mattermostModel.ChannelTypeGroup,
+	}
+
+	for _, channelType := range f.IncludeChannelTypes {
+		if !slices.Contains(validTypes, channelType) {
+			return errors.New("LegalHold filter channel types must be one of O, P, D or G")
+		}
+		if slices.Contains(f.ExcludeChannelTypes, channelType) {
+			return errors.New("LegalHold filter cannot both include and exclude the same channel type")
+		}
+	}
+
+	for _, channelType := range f.ExcludeChannelTypes {
+		if !slices.Contains(validTypes, channelType) {
+			return errors.New("LegalHold filter channel types must be one of O, P, D or G")
+		}
+	}
+
+	return nil
+}
+
+// DeepCopy creates a deep copy of the LegalHoldFilter.
+func (f *LegalHoldFilter) DeepCopy() *LegalHoldFilter {
+	if f == nil {
+		return nil
+	}
+
+	return &LegalHoldFilter{
+		Terms:               slices.Clone(f.Terms),
+		Hashtags:            slices.Clone(f.Hashtags),
+		IncludeChannelTypes: slices.Clone(f.IncludeChannelTypes),
+		ExcludeChannelTypes: slices.Clone(f.ExcludeChannelTypes),
+	}
+}
+
+// Equals returns true if both filters restrict the posts in the same way.
+func (f *LegalHoldFilter) Equals(other *LegalHoldFilter) bool {
+	if f.IsEmpty() || other.IsEmpty() {
+		return f.IsEmpty() && other.IsEmpty()
+	}
+
+	return utils.UnorderedEqualSet(f.Terms, other.Terms) &&
+		utils.UnorderedEqualSet(f.Hashtags, other.Hashtags) &&
+		utils.UnorderedEqualSet(f.IncludeChannelTypes, other.IncludeChannelTypes) &&
+		utils.UnorderedEqualSet(f.ExcludeChannelTypes, other.ExcludeChannelTypes)
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"testing"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModel_LegalHoldFilter_IsValid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		filter  *LegalHoldFilter
+		wantErr bool
+	}{
+		{
+			name:   "Nil filter",
+			filter: nil,
+		},
+		{
+			name: "Valid filter",
+			filter: &LegalHoldFilter{
+				Terms:               []string{"project falcon", "merger"},
+				Hashtags:            []string{"#falcon"},
+				ExcludeChannelTypes: []mattermostModel.ChannelType{mattermostModel.ChannelTypeOpen},
+			},
+		},
+		{
+			name:    "Empty term",
+			filter:  &LegalHoldFilter{Terms: []string{""}},
+			wantErr: true,
+		},
+		{
+			name:    "Hashtag with spaces",
+			filter:  &LegalHoldFilter{Hashtags: []string{"#two words"}},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid channel type",
+			filter:  &LegalHoldFilter{IncludeChannelTypes: []mattermostModel.ChannelType{"X"}},
+			wantErr: true,
+		},
+		{
+			name: "Channel type both included and excluded",
+			filter: &LegalHoldFilter{
+				IncludeChannelTypes: []mattermostModel.ChannelType{mattermostModel.ChannelTypePrivate},
+				ExcludeChannelTypes: []mattermostModel.ChannelType{mattermostModel.ChannelTypePrivate},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.filter.IsValid()
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModel_LegalHoldFilter_Normalize(t *testing.T) {
+	filter := &LegalHoldFilter{
+		Terms:    []string{"  project falcon "},
+		Hashtags: []string{"Falcon", "#Merger "},
+	}
+
+	filter.Normalize()
+
+	assert.Equal(t, []string{"project falcon"}, filter.Terms)
+	assert.Equal(t, []string{"#falcon", "#merger"}, filter.Hashtags)
+}
+
+func TestModel_LegalHoldFilter_Equals(t *testing.T) {
+	var nilFilter *LegalHoldFilter
+	assert.True(t, nilFilter.Equals(&LegalHoldFilter{}))
+	assert.False(t, nilFilter.Equals(&LegalHoldFilter{Terms: []string{"a"}}))
+	assert.True(t, (&LegalHoldFilter{Terms: []string{"a"}}).Equals(&LegalHoldFilter{Terms: []string{"a"}}))
+	assert.False(t, (&LegalHoldFilter{Terms: []string{"a"}}).Equals(&LegalHoldFilter{Hashtags: []string{"#a"}}))
+	assert.True(t, (&LegalHoldFilter{Terms: []string{"a", "b"}, Hashtags: []string{"#a", "#b"}}).Equals(
+		&LegalHoldFilter{Terms: []string{"b", "a"}, Hashtags: []string{"#b", "#a"}}))
+}
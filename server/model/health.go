@@ -0,0 +1,10 @@
+package model
+
+// HealthStatus summarises the health of the plugin, as served by its health endpoint.
+type HealthStatus struct {
+	Healthy bool `json:"healthy"`
+	// Problems describe what makes the plugin unhealthy.
+	Problems []string `json:"problems,omitempty"`
+	// Preservation is the outcome of the latest preservation check, if any has run.
+	Preservation *PreservationStatus `json:"preservation,omitempty"`
+}
@@ -1,6 +1,12 @@
 package model
 
-import "github.com/mattermost/mattermost-plugin-legal-hold/server/utils"
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/utils"
+)
 
 // LegalHoldChannelMembership represents the membership of a channel by a user in the
 // LegalHoldIndexUsers.
@@ -23,17 +29,22 @@ type LegalHoldIndexUser struct {
 type LegalHoldIndexUsers map[string]LegalHoldIndexUser
 
 type LegalHoldIndexDetails struct {
-	ID                   string `json:"id"`
-	Name                 string `json:"name"`
-	DisplayName          string `json:"display_name"`
-	StartsAt             int64  `json:"starts_at"`
-	LastExecutionEndedAt int64  `json:"last_execution_ended_at"`
+	ID                   string           `json:"id"`
+	Name                 string           `json:"name"`
+	DisplayName          string           `json:"display_name"`
+	StartsAt             int64            `json:"starts_at"`
+	LastExecutionEndedAt int64            `json:"last_execution_ended_at"`
+	Filter               *LegalHoldFilter `json:"filter,omitempty"`
 }
 
 type LegalHoldIndex struct {
 	Users     LegalHoldIndexUsers   `json:"users"`
 	LegalHold LegalHoldIndexDetails `json:"legal_hold"`
 	Teams     []*LegalHoldTeam      `json:"teams"`
+	// Groups are the groups of the legal hold, with their members as resolved by each execution.
+	Groups []*LegalHoldIndexGroup `json:"groups,omitempty"`
+	// FileExceptions are the file attachments that could not be exported by any execution.
+	FileExceptions []LegalHoldFileException `json:"file_exceptions,omitempty"`
 }
 
 type LegalHoldTeam struct {
@@ -50,6 +61,39 @@ type LegalHoldChannel struct {
 	Type        string `json:"type"`
 }
 
+// LegalHoldIndexGroup records the members of a group of the legal hold, as resolved by each
+// execution, and when each of them joined or left the group while the legal hold was active.
+type LegalHoldIndexGroup struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	// Snapshots are the members of the group resolved by each execution.
+	Snapshots []LegalHoldGroupSnapshot `json:"snapshots"`
+	// Members has an entry for each period of membership of the group by a person.
+	Members []LegalHoldGroupMember `json:"members"`
+}
+
+// LegalHoldGroupSnapshot is the membership of a group resolved by the execution that covered the
+// window from ExecutionStartTime to ExecutionEndTime, at the time provided in CapturedAt.
+type LegalHoldGroupSnapshot struct {
+	ExecutionStartTime int64    `json:"execution_start_time"`
+	ExecutionEndTime   int64    `json:"execution_end_time"`
+	CapturedAt         int64    `json:"captured_at"`
+	UserIDs            []string `json:"user_ids"`
+}
+
+// LegalHoldGroupMember is a period of membership of a group by a person. Membership changes are
+// seen by executions, so JoinedAt and LeftAt are the CapturedAt of the first snapshot the person
+// is in, or is no longer in. JoinedAt is zero if the person was a member when the group was first
+// captured, and LeftAt is zero if the person is still a member.
+type LegalHoldGroupMember struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	JoinedAt int64  `json:"joined_at"`
+	LeftAt   int64  `json:"left_at"`
+}
+
 func NewLegalHoldIndex() LegalHoldIndex {
 	return LegalHoldIndex{
 		Users:     make(LegalHoldIndexUsers),
@@ -82,6 +126,148 @@ func (lhi *LegalHoldIndex) Merge(newHold *LegalHoldIndex) {
 	}
 
 	lhi.Users.Merge(&newHold.Users)
+
+	for _, newGroup := range newHold.Groups {
+		if oldGroup := lhi.Group(newGroup.ID); oldGroup != nil {
+			oldGroup.Merge(newGroup)
+		} else {
+			lhi.Groups = append(lhi.Groups, newGroup)
+		}
+	}
+
+	// Add the new FileExceptions, skipping those already recorded by an earlier attempt at the
+	// same execution.
+	for _, newException := range newHold.FileExceptions {
+		found := false
+		for _, oldException := range lhi.FileExceptions {
+			if oldException.ExecutionStartTime == newException.ExecutionStartTime &&
+				oldException.ChannelID == newException.ChannelID &&
+				oldException.FileID == newException.FileID {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			lhi.FileExceptions = append(lhi.FileExceptions, newException)
+		}
+	}
+}
+
+// Sort orders the Teams, their Channels and the channel memberships of each user by ID, so that
+// the index serializes the same way regardless of the order in which its data was collected.
+func (lhi *LegalHoldIndex) Sort() {
+	slices.SortFunc(lhi.Teams, func(a, b *LegalHoldTeam) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	for _, team := range lhi.Teams {
+		slices.SortFunc(team.Channels, func(a, b *LegalHoldChannel) int {
+			return strings.Compare(a.ID, b.ID)
+		})
+	}
+
+	for _, user := range lhi.Users {
+		slices.SortFunc(user.Channels, func(a, b LegalHoldChannelMembership) int {
+			return strings.Compare(a.ChannelID, b.ChannelID)
+		})
+	}
+
+	slices.SortFunc(lhi.Groups, func(a, b *LegalHoldIndexGroup) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	for _, group := range lhi.Groups {
+		slices.SortFunc(group.Snapshots, func(a, b LegalHoldGroupSnapshot) int {
+			return cmp.Compare(a.ExecutionStartTime, b.ExecutionStartTime)
+		})
+		slices.SortFunc(group.Members, func(a, b LegalHoldGroupMember) int {
+			if a.UserID != b.UserID {
+				return strings.Compare(a.UserID, b.UserID)
+			}
+			return cmp.Compare(a.JoinedAt, b.JoinedAt)
+		})
+	}
+
+	slices.SortFunc(lhi.FileExceptions, func(a, b LegalHoldFileException) int {
+		if a.ExecutionStartTime != b.ExecutionStartTime {
+			return cmp.Compare(a.ExecutionStartTime, b.ExecutionStartTime)
+		}
+		if a.ChannelID != b.ChannelID {
+			return strings.Compare(a.ChannelID, b.ChannelID)
+		}
+		return strings.Compare(a.FileID, b.FileID)
+	})
+}
+
+// Group returns the LegalHoldIndexGroup of the group indicated by id, or nil if the group has not
+// been captured.
+func (lhi *LegalHoldIndex) Group(id string) *LegalHoldIndexGroup {
+	for _, group := range lhi.Groups {
+		if group.ID == id {
+			return group
+		}
+	}
+
+	return nil
+}
+
+// Merge merges the new LegalHoldIndexGroup, which holds the snapshot taken by one execution and
+// the members in it, into this LegalHoldIndexGroup. The people who are in the snapshot but not in
+// the last one are recorded as having joined the group when it was taken, and those who are no
+// longer in it as having left. A snapshot already merged by an earlier attempt at the same
+// execution is skipped.
+func (group *LegalHoldIndexGroup) Merge(newGroup *LegalHoldIndexGroup) {
+	group.Name = newGroup.Name
+	group.DisplayName = newGroup.DisplayName
+
+	for _, snapshot := range newGroup.Snapshots {
+		if slices.ContainsFunc(group.Snapshots, func(s LegalHoldGroupSnapshot) bool {
+			return s.ExecutionStartTime == snapshot.ExecutionStartTime
+		}) {
+			continue
+		}
+		group.Snapshots = append(group.Snapshots, snapshot)
+
+		for i, member := range group.Members {
+			if member.LeftAt == 0 && !slices.Contains(snapshot.UserIDs, member.UserID) {
+				group.Members[i].LeftAt = snapshot.CapturedAt
+			}
+		}
+
+		for _, newMember := range newGroup.Members {
+			if !slices.Contains(snapshot.UserIDs, newMember.UserID) {
+				continue
+			}
+
+			i := slices.IndexFunc(group.Members, func(m LegalHoldGroupMember) bool {
+				return m.UserID == newMember.UserID && m.LeftAt == 0
+			})
+			if i >= 0 {
+				group.Members[i].Username = newMember.Username
+				group.Members[i].Email = newMember.Email
+				continue
+			}
+
+			group.Members = append(group.Members, LegalHoldGroupMember{
+				UserID:   newMember.UserID,
+				Username: newMember.Username,
+				Email:    newMember.Email,
+				JoinedAt: snapshot.CapturedAt,
+			})
+		}
+	}
+}
+
+// CapturedUserIDs returns the IDs of everyone who was a member of the group in any snapshot.
+func (group *LegalHoldIndexGroup) CapturedUserIDs() []string {
+	userIDs := make([]string, 0, len(group.Members))
+	for _, member := range group.Members {
+		userIDs = append(userIDs, member.UserID)
+	}
+
+	slices.Sort(userIDs)
+	return slices.Compact(userIDs)
 }
 
 // Merge merges the new LegalHoldTeam into this LegalHoldTeam.
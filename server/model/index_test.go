@@ -81,6 +81,78 @@ func TestMerge(t *testing.T) {
 	}
 }
 
+func TestLegalHoldIndex_MergeFileExceptions(t *testing.T) {
+	index := NewLegalHoldIndex()
+	index.FileExceptions = []LegalHoldFileException{
+		{ExecutionStartTime: 1000, ChannelID: "channel1", FileID: "file1", Reason: FileExceptionMissing},
+	}
+
+	// A retried execution records the same exception again, together with a new one.
+	retried := NewLegalHoldIndex()
+	retried.FileExceptions = []LegalHoldFileException{
+		{ExecutionStartTime: 1000, ChannelID: "channel1", FileID: "file1", Reason: FileExceptionMissing},
+		{ExecutionStartTime: 1000, ChannelID: "channel1", FileID: "file2", Reason: FileExceptionFailed},
+	}
+	index.Merge(&retried)
+
+	// A later execution fails to export the same file again.
+	later := NewLegalHoldIndex()
+	later.FileExceptions = []LegalHoldFileException{
+		{ExecutionStartTime: 2000, ChannelID: "channel1", FileID: "file1", Reason: FileExceptionMissing},
+	}
+	index.Merge(&later)
+
+	require.Equal(t, []LegalHoldFileException{
+		{ExecutionStartTime: 1000, ChannelID: "channel1", FileID: "file1", Reason: FileExceptionMissing},
+		{ExecutionStartTime: 1000, ChannelID: "channel1", FileID: "file2", Reason: FileExceptionFailed},
+		{ExecutionStartTime: 2000, ChannelID: "channel1", FileID: "file1", Reason: FileExceptionMissing},
+	}, index.FileExceptions)
+}
+
+func TestLegalHoldIndex_MergeGroups(t *testing.T) {
+	// groupSnapshot is the index of an execution that captured the members of group1.
+	groupSnapshot := func(start, capturedAt int64, userIDs ...string) *LegalHoldIndex {
+		group := &LegalHoldIndexGroup{
+			ID:          "group1",
+			DisplayName: "Group",
+			Snapshots: []LegalHoldGroupSnapshot{
+				{ExecutionStartTime: start, ExecutionEndTime: start + 1000, CapturedAt: capturedAt, UserIDs: userIDs},
+			},
+		}
+		for _, userID := range userIDs {
+			group.Members = append(group.Members, LegalHoldGroupMember{UserID: userID, Username: userID})
+		}
+
+		index := NewLegalHoldIndex()
+		index.Groups = []*LegalHoldIndexGroup{group}
+		return &index
+	}
+
+	index := NewLegalHoldIndex()
+	index.Merge(groupSnapshot(1000, 2100, "user1", "user2"))
+	// user2 leaves the group and user3 joins it.
+	index.Merge(groupSnapshot(2000, 3100, "user1", "user3"))
+	// A retried execution does not record the changes again.
+	index.Merge(groupSnapshot(2000, 3200, "user1"))
+	// user2 joins the group again.
+	index.Merge(groupSnapshot(3000, 4100, "user1", "user2", "user3"))
+	index.Sort()
+
+	group := index.Group("group1")
+	require.NotNil(t, group)
+	require.Len(t, group.Snapshots, 3)
+	require.Equal(t, []string{"user1", "user3"}, group.Snapshots[1].UserIDs)
+	require.Equal(t, []LegalHoldGroupMember{
+		{UserID: "user1", Username: "user1"},
+		{UserID: "user2", Username: "user2", LeftAt: 3100},
+		{UserID: "user2", Username: "user2", JoinedAt: 4100},
+		{UserID: "user3", Username: "user3", JoinedAt: 3100},
+	}, group.Members)
+	require.Equal(t, []string{"user1", "user2", "user3"}, group.CapturedUserIDs())
+
+	require.Nil(t, index.Group("group2"))
+}
+
 func TestGetLegalHoldChannelMembership(t *testing.T) {
 	type args struct {
 		channelMemberships []LegalHoldChannelMembership
@@ -153,3 +225,28 @@ func TestLegalHoldChannelMembership_Combine(t *testing.T) {
 	require.Equal(t, int64(5), lhcmCombined.StartTime)
 	require.Equal(t, int64(25), lhcmCombined.EndTime)
 }
+
+func TestLegalHoldIndex_Sort(t *testing.T) {
+	index := LegalHoldIndex{
+		Users: LegalHoldIndexUsers{
+			"user1": {
+				Channels: []LegalHoldChannelMembership{
+					{ChannelID: "channel3"},
+					{ChannelID: "channel1"},
+				},
+			},
+		},
+		Teams: []*LegalHoldTeam{
+			{ID: "team2", Channels: []*LegalHoldChannel{{ID: "channel2"}}},
+			{ID: "team1", Channels: []*LegalHoldChannel{{ID: "channel3"}, {ID: "channel1"}}},
+		},
+	}
+
+	index.Sort()
+
+	require.Equal(t, "team1", index.Teams[0].ID)
+	require.Equal(t, "team2", index.Teams[1].ID)
+	require.Equal(t, "channel1", index.Teams[0].Channels[0].ID)
+	require.Equal(t, "channel3", index.Teams[0].Channels[1].ID)
+	require.Equal(t, []LegalHoldChannelMembership{{ChannelID: "channel1"}, {ChannelID: "channel3"}}, index.Users["user1"].Channels)
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// LedgerFileName is the name of the append-only ledger in the base path of a legal hold. It holds
+// one JSON-encoded LedgerEntry per line.
+const LedgerFileName = "ledger.jsonl"
+
+// LedgerEntry records the SHA-256 digests of the files written by one execution of a LegalHold.
+// Each entry includes the digest of the line of the entry before it, so that replacing or removing
+// an earlier entry breaks the chain.
+type LedgerEntry struct {
+	// Sequence is the position of the entry in the ledger, starting at 1.
+	Sequence           int64  `json:"sequence"`
+	LegalHoldID        string `json:"legal_hold_id"`
+	ExecutionStartTime int64  `json:"execution_start_time"`
+	ExecutionEndTime   int64  `json:"execution_end_time"`
+	CreateAt           int64  `json:"create_at"`
+	// PreviousDigest is the LedgerDigest of the line of the previous entry, or empty for the
+	// first entry.
+	PreviousDigest string   `json:"previous_digest"`
+	Files          HashList `json:"files"`
+}
+
+// LedgerDigest returns the hex-encoded SHA-256 digest of a line of the ledger, without its
+// trailing newline.
+func LedgerDigest(line []byte) string {
+	digest := sha256.Sum256(line)
+	return hex.EncodeToString(digest[:])
+}
+
+// LastLedgerEntry returns the last entry of the ledger in data together with the LedgerDigest of
+// its line, or nil if the ledger is empty.
+func LastLedgerEntry(data []byte) (*LedgerEntry, string, error) {
+	data = bytes.TrimRight(data, "\n")
+	if len(data) == 0 {
+		return nil, "", nil
+	}
+
+	line := data
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		line = data[i+1:]
+	}
+
+	var entry LedgerEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return nil, "", errors.Wrap(err, "could not unmarshal the last ledger entry")
+	}
+
+	return &entry, LedgerDigest(line), nil
+}
+
+// Next returns the entry that follows this one in the ledger, given the LedgerDigest of the line
+// of this entry. Next may be called on a nil entry to start a new ledger.
+func (e *LedgerEntry) Next(digest string) LedgerEntry {
+	if e == nil {
+		return LedgerEntry{Sequence: 1}
+	}
+
+	return LedgerEntry{
+		Sequence:       e.Sequence + 1,
+		PreviousDigest: digest,
+	}
+}
+
+// Covers returns true if the entry was written by an execution covering exactly the window from
+// startTime to endTime.
+func (e *LedgerEntry) Covers(startTime, endTime int64) bool {
+	return e != nil && e.ExecutionStartTime == startTime && e.ExecutionEndTime == endTime
+}
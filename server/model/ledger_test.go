@@ -0,0 +1,45 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModel_LastLedgerEntry(t *testing.T) {
+	entry, digest, err := LastLedgerEntry(nil)
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+	assert.Empty(t, digest)
+
+	first := entry.Next("")
+	first.ExecutionStartTime = 100
+	first.ExecutionEndTime = 200
+	firstLine, err := json.Marshal(first)
+	require.NoError(t, err)
+
+	entry, digest, err = LastLedgerEntry(append(firstLine, '\n'))
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	assert.Equal(t, int64(1), entry.Sequence)
+	assert.Empty(t, entry.PreviousDigest)
+	assert.Equal(t, LedgerDigest(firstLine), digest)
+	assert.True(t, entry.Covers(100, 200))
+	assert.False(t, entry.Covers(200, 300))
+
+	second := entry.Next(digest)
+	secondLine, err := json.Marshal(second)
+	require.NoError(t, err)
+
+	data := append(append(append(firstLine, '\n'), secondLine...), '\n')
+	entry, digest, err = LastLedgerEntry(data)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), entry.Sequence)
+	assert.Equal(t, LedgerDigest(firstLine), entry.PreviousDigest)
+	assert.Equal(t, LedgerDigest(secondLine), digest)
+
+	_, _, err = LastLedgerEntry([]byte("not json\n"))
+	require.Error(t, err)
+}
@@ -12,9 +12,37 @@ import (
 // LegalHold represents one legal hold.
 type LegalHoldStatus string
 
+const (
+	// DefaultExecutionLength is the length, in milliseconds, of the executions of a LegalHold that
+	// was created without one: the LegalHold is executed once a day.
+	DefaultExecutionLength int64 = 24 * 60 * 60 * 1000
+
+	// MinExecutionLength is the shortest length, in milliseconds, of the executions of a
+	// LegalHold: it cannot be executed more often than once an hour.
+	MinExecutionLength int64 = 60 * 60 * 1000
+
+	// MaxExecutionLength is the longest length, in milliseconds, of the executions of a LegalHold:
+	// it must be executed at least once every 30 days.
+	MaxExecutionLength int64 = 30 * DefaultExecutionLength
+
+	// FailureBaseBackoff is how long, in milliseconds, a LegalHold that failed to execute waits
+	// before it is tried again. The wait doubles with each failure in a row, up to
+	// FailureMaxBackoff.
+	FailureBaseBackoff int64 = 15 * 60 * 1000
+	FailureMaxBackoff  int64 = 4 * 60 * 60 * 1000
+)
+
 const (
 	// LegalHoldStatusExecuting is the status of a legal hold that is currently being executed
 	LegalHoldStatusExecuting LegalHoldStatus = "executing"
+
+	// LegalHoldStatusReleased is the status of a legal hold that has been released, and whose data
+	// has not been purged yet
+	LegalHoldStatusReleased LegalHoldStatus = "released"
+
+	// LegalHoldStatusFailed is the status of a legal hold whose latest execution failed, even
+	// after being retried
+	LegalHoldStatusFailed LegalHoldStatus = "failed"
 )
 
 type LegalHold struct {
@@ -25,6 +53,8 @@ type LegalHold struct {
 	UpdateAt              int64    `json:"update_at"`
 	UserIDs               []string `json:"user_ids"`
 	GroupIDs              []string `json:"group_ids"`
+	ChannelIDs            []string `json:"channel_ids"`
+	TeamIDs               []string `json:"team_ids"`
 	StartsAt              int64    `json:"starts_at"`
 	EndsAt                int64    `json:"ends_at"`
 	IncludePublicChannels bool     `json:"include_public_channels"`
@@ -32,6 +62,23 @@ type LegalHold struct {
 	ExecutionLength       int64    `json:"execution_length"`
 	Secret                string   `json:"secret"`
 
+	// Filter optionally restricts the posts preserved by this LegalHold. It is fixed at creation
+	// time so that the whole export is collected under the same scope.
+	Filter *LegalHoldFilter `json:"filter,omitempty"`
+
+	// ReleasedAt is the time the LegalHold was released by the user indicated by ReleasedBy, or
+	// zero if it has not been. A released LegalHold is no longer executed, and its data is purged
+	// once the release grace period has passed, unless it is restored before then.
+	ReleasedAt int64  `json:"released_at,omitempty"`
+	ReleasedBy string `json:"released_by,omitempty"`
+
+	// ConsecutiveFailures is the number of runs of the legal hold job in a row that failed to
+	// execute the LegalHold, even after retrying. LastError is the error of the latest of them,
+	// which failed at LastFailedAt. They are reset once the LegalHold is executed successfully.
+	ConsecutiveFailures int    `json:"consecutive_failures,omitempty"`
+	LastError           string `json:"last_error,omitempty"`
+	LastFailedAt        int64  `json:"last_failed_at,omitempty"`
+
 	// HasMessages is a denormalized field that indicates whether the legal hold has messages or not, to prevent
 	// the download button from working in case of empty legal hold and prevent user confusion.
 	// This value can be dynamically calculated by checking for the index in the store, and it's updated every time
@@ -62,6 +109,12 @@ func (lh *LegalHold) DeepCopy() LegalHold {
 		ExecutionLength:       lh.ExecutionLength,
 		Secret:                lh.Secret,
 		HasMessages:           lh.HasMessages,
+		Filter:                lh.Filter.DeepCopy(),
+		ReleasedAt:            lh.ReleasedAt,
+		ReleasedBy:            lh.ReleasedBy,
+		ConsecutiveFailures:   lh.ConsecutiveFailures,
+		LastError:             lh.LastError,
+		LastFailedAt:          lh.LastFailedAt,
 	}
 
 	if len(lh.UserIDs) > 0 {
@@ -74,9 +127,28 @@ func (lh *LegalHold) DeepCopy() LegalHold {
 		copy(newLegalHold.GroupIDs, lh.GroupIDs)
 	}
 
+	if len(lh.ChannelIDs) > 0 {
+		newLegalHold.ChannelIDs = make([]string, len(lh.ChannelIDs))
+		copy(newLegalHold.ChannelIDs, lh.ChannelIDs)
+	}
+
+	if len(lh.TeamIDs) > 0 {
+		newLegalHold.TeamIDs = make([]string, len(lh.TeamIDs))
+		copy(newLegalHold.TeamIDs, lh.TeamIDs)
+	}
+
 	return newLegalHold
 }
 
+// Sanitized returns a deep copy of the LegalHold without its secret or the attributes only used
+// for display, to be kept in records of it.
+func (lh *LegalHold) Sanitized() LegalHold {
+	sanitized := lh.DeepCopy()
+	sanitized.Secret = ""
+	sanitized.Status = ""
+	return sanitized
+}
+
 // IsValidForCreate checks whether the LegalHold contains data that is valid for
 // creation. If it is not valid, it returns an error describing the validation
 // failure. It does not guarantee that creation in the store will be successful,
@@ -99,20 +171,12 @@ func (lh *LegalHold) IsValidForCreate() error {
 		return errors.New("LegalHold display name must be between 2 and 64 characters in length")
 	}
 
-	if len(lh.UserIDs) < 1 && len(lh.GroupIDs) < 1 {
-		return errors.New("LegalHold must include at least 1 user or 1 group")
-	}
-
-	for _, userID := range lh.UserIDs {
-		if !mattermostModel.IsValidId(userID) {
-			return errors.New("LegalHold users must have valid IDs")
-		}
+	if err := validateLegalHoldScope(lh.UserIDs, lh.GroupIDs, lh.ChannelIDs, lh.TeamIDs); err != nil {
+		return err
 	}
 
-	for _, groupID := range lh.GroupIDs {
-		if !mattermostModel.IsValidId(groupID) {
-			return errors.New("LegalHold groups must have valid IDs")
-		}
+	if err := lh.Filter.IsValid(); err != nil {
+		return err
 	}
 
 	if lh.StartsAt < 1 {
@@ -127,15 +191,61 @@ func (lh *LegalHold) IsValidForCreate() error {
 		return errors.New("LegalHold must end after it starts")
 	}
 
+	if lh.ExecutionLength != 0 {
+		return validateExecutionLength(lh.ExecutionLength)
+	}
+
+	return nil
+}
+
+// validateExecutionLength checks that the length of the executions of a LegalHold, in
+// milliseconds, is between MinExecutionLength and MaxExecutionLength.
+func validateExecutionLength(executionLength int64) error {
+	if executionLength < MinExecutionLength || executionLength > MaxExecutionLength {
+		return fmt.Errorf("LegalHold execution length must be between %d and %d milliseconds", MinExecutionLength, MaxExecutionLength)
+	}
+
+	return nil
+}
+
+// validateLegalHoldScope checks that a LegalHold targets at least one user, group, channel or
+// team, and that every one of the provided IDs is valid.
+func validateLegalHoldScope(userIDs, groupIDs, channelIDs, teamIDs []string) error {
+	if len(userIDs) < 1 && len(groupIDs) < 1 && len(channelIDs) < 1 && len(teamIDs) < 1 {
+		return errors.New("LegalHold must include at least 1 user, group, channel or team")
+	}
+
+	for _, userID := range userIDs {
+		if !mattermostModel.IsValidId(userID) {
+			return errors.New("LegalHold users must have valid IDs")
+		}
+	}
+
+	for _, groupID := range groupIDs {
+		if !mattermostModel.IsValidId(groupID) {
+			return errors.New("LegalHold groups must have valid IDs")
+		}
+	}
+
+	for _, channelID := range channelIDs {
+		if !mattermostModel.IsValidId(channelID) {
+			return errors.New("LegalHold channels must have valid IDs")
+		}
+	}
+
+	for _, teamID := range teamIDs {
+		if !mattermostModel.IsValidId(teamID) {
+			return errors.New("LegalHold teams must have valid IDs")
+		}
+	}
+
 	return nil
 }
 
 // NeedsExecuting returns true if, at the time provided in "now", the Legal Hold is ready to
 // be executed, or false if it is not yet ready to be executed.
 func (lh *LegalHold) NeedsExecuting(now int64) bool {
-	// The legal hold is only ready to be executed if the NextExecutionEndTime is
-	// in the past relative to the time "now".
-	return now > lh.NextExecutionEndTime()
+	return now >= lh.NextDueTime()
 }
 
 // NextExecutionStartTime returns the time at which the next execution of this
@@ -154,12 +264,91 @@ func (lh *LegalHold) NextExecutionEndTime() int64 {
 	return endTime
 }
 
+// NextDueTime returns the earliest time at which the LegalHold needs executing, once its next
+// execution has ended. A LegalHold that failed to execute is not tried again until its
+// FailureBackoff has passed since it failed.
+func (lh *LegalHold) NextDueTime() int64 {
+	// The legal hold is only ready to be executed once the NextExecutionEndTime is in the past.
+	due := lh.NextExecutionEndTime() + 1
+	if lh.IsFailing() {
+		due = utils.Max(due, lh.LastFailedAt+lh.FailureBackoff())
+	}
+	return due
+}
+
+// FailureBackoff returns how long, in milliseconds, the LegalHold waits after its latest failure
+// before it is tried again, doubling from FailureBaseBackoff with each failure in a row up to
+// FailureMaxBackoff, or zero if it is not failing.
+func (lh *LegalHold) FailureBackoff() int64 {
+	if !lh.IsFailing() {
+		return 0
+	}
+
+	backoff := FailureBaseBackoff
+	for i := 1; i < lh.ConsecutiveFailures && backoff < FailureMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return utils.Min(backoff, FailureMaxBackoff)
+}
+
+// CurrentStatus returns the status of the LegalHold, given whether it is being executed right
+// now: released, executing or failed, or empty if it is none of those.
+func (lh *LegalHold) CurrentStatus(executing bool) LegalHoldStatus {
+	switch {
+	case lh.IsReleased():
+		return LegalHoldStatusReleased
+	case executing:
+		return LegalHoldStatusExecuting
+	case lh.IsFailing():
+		return LegalHoldStatusFailed
+	default:
+		return ""
+	}
+}
+
 // IsFinished returns true if the legal hold has executed all the way to its end time or false
 // if it has not.
 func (lh *LegalHold) IsFinished() bool {
 	return lh.EndsAt != 0 && lh.LastExecutionEndedAt >= lh.EndsAt
 }
 
+// IsReleased returns true if the legal hold has been released.
+func (lh *LegalHold) IsReleased() bool {
+	return lh.ReleasedAt != 0
+}
+
+// IsFailing returns true if the latest run of the legal hold job failed to execute the legal hold.
+func (lh *LegalHold) IsFailing() bool {
+	return lh.ConsecutiveFailures > 0
+}
+
+// RecordFailure records that a run of the legal hold job failed to execute the legal hold at the
+// time provided in "now", with the error provided.
+func (lh *LegalHold) RecordFailure(err error, now int64) {
+	lh.ConsecutiveFailures++
+	lh.LastError = err.Error()
+	lh.LastFailedAt = now
+}
+
+// ClearFailures records that the legal hold was executed successfully.
+func (lh *LegalHold) ClearFailures() {
+	lh.ConsecutiveFailures = 0
+	lh.LastError = ""
+	lh.LastFailedAt = 0
+}
+
+// PurgeAt returns the time at which the data of the released legal hold is purged, once the
+// grace period provided in milliseconds has passed.
+func (lh *LegalHold) PurgeAt(gracePeriod int64) int64 {
+	return lh.ReleasedAt + gracePeriod
+}
+
+// IsDueForPurge returns true if, at the time provided in "now", the legal hold has been released
+// for longer than the grace period provided in milliseconds.
+func (lh *LegalHold) IsDueForPurge(now, gracePeriod int64) bool {
+	return lh.IsReleased() && now >= lh.PurgeAt(gracePeriod)
+}
+
 // BasePath returns the base file storage path for this legal hold.
 func (lh *LegalHold) BasePath() string {
 	return fmt.Sprintf("legal_hold/%s_%s", lh.Name, lh.ID)
@@ -176,25 +365,48 @@ type CreateLegalHold struct {
 	DisplayName           string   `json:"display_name"`
 	UserIDs               []string `json:"user_ids"`
 	GroupIDs              []string `json:"group_ids"`
+	ChannelIDs            []string `json:"channel_ids"`
+	TeamIDs               []string `json:"team_ids"`
 	StartsAt              int64    `json:"starts_at"`
 	EndsAt                int64    `json:"ends_at"`
 	IncludePublicChannels bool     `json:"include_public_channels"`
+
+	// ExecutionLength is the length, in milliseconds, of the window of time covered by each
+	// execution of the LegalHold, and so how often it is executed. DefaultExecutionLength is used
+	// if it is zero.
+	ExecutionLength int64 `json:"execution_length,omitempty"`
+
+	Filter *LegalHoldFilter `json:"filter,omitempty"`
 }
 
 // NewLegalHoldFromCreate creates and populates a new LegalHold instance from
 // the provided CreateLegalHold instance.
 func NewLegalHoldFromCreate(lhc CreateLegalHold) LegalHold {
+	var filter *LegalHoldFilter
+	if !lhc.Filter.IsEmpty() {
+		filter = lhc.Filter.DeepCopy()
+		filter.Normalize()
+	}
+
+	executionLength := lhc.ExecutionLength
+	if executionLength == 0 {
+		executionLength = DefaultExecutionLength
+	}
+
 	return LegalHold{
 		ID:                    mattermostModel.NewId(),
 		Name:                  lhc.Name,
 		DisplayName:           lhc.DisplayName,
 		UserIDs:               lhc.UserIDs,
 		GroupIDs:              lhc.GroupIDs,
+		ChannelIDs:            lhc.ChannelIDs,
+		TeamIDs:               lhc.TeamIDs,
 		StartsAt:              lhc.StartsAt,
 		EndsAt:                lhc.EndsAt,
 		IncludePublicChannels: lhc.IncludePublicChannels,
 		LastExecutionEndedAt:  0,
-		ExecutionLength:       86400000, // 24 hours
+		ExecutionLength:       executionLength,
+		Filter:                filter,
 	}
 }
 
@@ -204,8 +416,14 @@ type UpdateLegalHold struct {
 	DisplayName           string   `json:"display_name"`
 	UserIDs               []string `json:"user_ids"`
 	GroupIDs              []string `json:"group_ids"`
+	ChannelIDs            []string `json:"channel_ids"`
+	TeamIDs               []string `json:"team_ids"`
 	IncludePublicChannels bool     `json:"include_public_channels"`
 	EndsAt                int64    `json:"ends_at"`
+
+	// ExecutionLength is the new length, in milliseconds, of the executions of the LegalHold, or
+	// zero to keep the current one. It applies from the next execution onwards.
+	ExecutionLength int64 `json:"execution_length,omitempty"`
 }
 
 func (ulh UpdateLegalHold) IsValid() error {
@@ -217,26 +435,18 @@ func (ulh UpdateLegalHold) IsValid() error {
 		return errors.New("LegalHold display name must be between 2 and 64 characters in length")
 	}
 
-	if len(ulh.UserIDs) < 1 && len(ulh.GroupIDs) < 1 {
-		return errors.New("LegalHold must include at least 1 user or 1 group")
-	}
-
-	for _, userID := range ulh.UserIDs {
-		if !mattermostModel.IsValidId(userID) {
-			return errors.New("LegalHold users must have valid IDs")
-		}
-	}
-
-	for _, groupID := range ulh.GroupIDs {
-		if !mattermostModel.IsValidId(groupID) {
-			return errors.New("LegalHold groups must have valid IDs")
-		}
+	if err := validateLegalHoldScope(ulh.UserIDs, ulh.GroupIDs, ulh.ChannelIDs, ulh.TeamIDs); err != nil {
+		return err
 	}
 
 	if ulh.EndsAt < 0 {
 		return errors.New("LegalHold must end at a valid time or zero")
 	}
 
+	if ulh.ExecutionLength != 0 {
+		return validateExecutionLength(ulh.ExecutionLength)
+	}
+
 	return nil
 }
 
@@ -244,6 +454,11 @@ func (lh *LegalHold) ApplyUpdates(updates UpdateLegalHold) {
 	lh.DisplayName = updates.DisplayName
 	lh.UserIDs = updates.UserIDs
 	lh.GroupIDs = updates.GroupIDs
+	lh.ChannelIDs = updates.ChannelIDs
+	lh.TeamIDs = updates.TeamIDs
 	lh.EndsAt = updates.EndsAt
 	lh.IncludePublicChannels = updates.IncludePublicChannels
+	if updates.ExecutionLength != 0 {
+		lh.ExecutionLength = updates.ExecutionLength
+	}
 }
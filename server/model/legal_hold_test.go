@@ -1,6 +1,7 @@
 package model
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -32,10 +33,17 @@ func TestModel_LegalHold_DeepCopy(t *testing.T) {
 				CreateAt:             12345,
 				UpdateAt:             12355,
 				UserIDs:              []string{"UserID1", "UserID2"},
+				ChannelIDs:           []string{"ChannelID1"},
+				TeamIDs:              []string{"TeamID1"},
 				StartsAt:             12360,
 				EndsAt:               12370,
 				LastExecutionEndedAt: 12365,
 				ExecutionLength:      30,
+				ReleasedAt:           12380,
+				ReleasedBy:           "UserID1",
+				ConsecutiveFailures:  2,
+				LastError:            "failed",
+				LastFailedAt:         12375,
 			},
 		},
 	}
@@ -175,6 +183,55 @@ func TestModel_LegalHold_IsValidForCreate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Channel only",
+			lh: &LegalHold{
+				ID:          mattermostModel.NewId(),
+				Name:        "legalhold1",
+				DisplayName: "Channel Only Test",
+				ChannelIDs:  []string{mattermostModel.NewId()},
+				StartsAt:    70,
+				EndsAt:      0,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Team only",
+			lh: &LegalHold{
+				ID:          mattermostModel.NewId(),
+				Name:        "legalhold1",
+				DisplayName: "Team Only Test",
+				TeamIDs:     []string{mattermostModel.NewId()},
+				StartsAt:    70,
+				EndsAt:      0,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid ChannelID",
+			lh: &LegalHold{
+				ID:          mattermostModel.NewId(),
+				Name:        "legalhold1",
+				DisplayName: "Invalid ChannelID Test",
+				ChannelIDs:  []string{"invalid channel"},
+				StartsAt:    70,
+				EndsAt:      0,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid TeamID",
+			lh: &LegalHold{
+				ID:          mattermostModel.NewId(),
+				Name:        "legalhold1",
+				DisplayName: "Invalid TeamID Test",
+				UserIDs:     []string{mattermostModel.NewId()},
+				TeamIDs:     []string{"invalid team"},
+				StartsAt:    70,
+				EndsAt:      0,
+			},
+			wantErr: true,
+		},
 		{
 			name: "Invalid StartsAt",
 			lh: &LegalHold{
@@ -211,6 +268,42 @@ func TestModel_LegalHold_IsValidForCreate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Hourly executions",
+			lh: &LegalHold{
+				ID:              mattermostModel.NewId(),
+				Name:            "legalhold1",
+				DisplayName:     "Hourly executions Test",
+				UserIDs:         []string{mattermostModel.NewId()},
+				StartsAt:        80,
+				ExecutionLength: MinExecutionLength,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Executions too frequent",
+			lh: &LegalHold{
+				ID:              mattermostModel.NewId(),
+				Name:            "legalhold1",
+				DisplayName:     "Executions too frequent Test",
+				UserIDs:         []string{mattermostModel.NewId()},
+				StartsAt:        80,
+				ExecutionLength: MinExecutionLength - 1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Executions too infrequent",
+			lh: &LegalHold{
+				ID:              mattermostModel.NewId(),
+				Name:            "legalhold1",
+				DisplayName:     "Executions too infrequent Test",
+				UserIDs:         []string{mattermostModel.NewId()},
+				StartsAt:        80,
+				ExecutionLength: MaxExecutionLength + 1,
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -365,6 +458,88 @@ func TestModel_LegalHold_NextExecutionEndTime(t *testing.T) {
 	})
 }
 
+func TestModel_LegalHold_NextDueTime(t *testing.T) {
+	lh := &LegalHold{
+		StartsAt:             10,
+		LastExecutionEndedAt: 20,
+		ExecutionLength:      5,
+	}
+	assert.Equal(t, int64(26), lh.NextDueTime())
+	assert.False(t, lh.NeedsExecuting(lh.NextDueTime()-1))
+	assert.True(t, lh.NeedsExecuting(lh.NextDueTime()))
+
+	lh.EndsAt = 22
+	assert.Equal(t, int64(23), lh.NextDueTime())
+
+	// A failed legal hold waits for its backoff, however long its executions are.
+	lh.ExecutionLength = MaxExecutionLength
+	lh.EndsAt = 0
+	lh.RecordFailure(errors.New("failed"), lh.NextExecutionEndTime()+10)
+	assert.Equal(t, lh.LastFailedAt+FailureBaseBackoff, lh.NextDueTime())
+	assert.False(t, lh.NeedsExecuting(lh.NextDueTime()-1))
+	assert.True(t, lh.NeedsExecuting(lh.NextDueTime()))
+}
+
+func TestModel_LegalHold_FailureBackoff(t *testing.T) {
+	lh := &LegalHold{}
+	assert.Zero(t, lh.FailureBackoff())
+
+	lh.RecordFailure(errors.New("first"), 10)
+	assert.Equal(t, FailureBaseBackoff, lh.FailureBackoff())
+
+	lh.RecordFailure(errors.New("second"), 20)
+	assert.Equal(t, 2*FailureBaseBackoff, lh.FailureBackoff())
+
+	for i := 0; i < 100; i++ {
+		lh.RecordFailure(errors.New("again"), 30)
+	}
+	assert.Equal(t, FailureMaxBackoff, lh.FailureBackoff())
+}
+
+func TestModel_LegalHold_CurrentStatus(t *testing.T) {
+	lh := &LegalHold{}
+	assert.Equal(t, LegalHoldStatus(""), lh.CurrentStatus(false))
+	assert.Equal(t, LegalHoldStatusExecuting, lh.CurrentStatus(true))
+
+	lh.RecordFailure(errors.New("failed"), 10)
+	assert.Equal(t, LegalHoldStatusFailed, lh.CurrentStatus(false))
+	assert.Equal(t, LegalHoldStatusExecuting, lh.CurrentStatus(true))
+
+	lh.ReleasedAt = 20
+	assert.Equal(t, LegalHoldStatusReleased, lh.CurrentStatus(false))
+	assert.Equal(t, LegalHoldStatusReleased, lh.CurrentStatus(true))
+}
+
+func TestModel_NewLegalHoldFromCreate_ExecutionLength(t *testing.T) {
+	lh := NewLegalHoldFromCreate(CreateLegalHold{Name: "daily"})
+	assert.Equal(t, DefaultExecutionLength, lh.ExecutionLength)
+
+	lh = NewLegalHoldFromCreate(CreateLegalHold{Name: "hourly", ExecutionLength: MinExecutionLength})
+	assert.Equal(t, MinExecutionLength, lh.ExecutionLength)
+
+	lh.ApplyUpdates(UpdateLegalHold{DisplayName: "Unchanged"})
+	assert.Equal(t, MinExecutionLength, lh.ExecutionLength)
+
+	lh.ApplyUpdates(UpdateLegalHold{DisplayName: "Weekly", ExecutionLength: 7 * DefaultExecutionLength})
+	assert.Equal(t, 7*DefaultExecutionLength, lh.ExecutionLength)
+}
+
+func TestModel_LegalHold_RecordFailure(t *testing.T) {
+	lh := &LegalHold{}
+	assert.False(t, lh.IsFailing())
+
+	lh.RecordFailure(errors.New("first"), 10)
+	lh.RecordFailure(errors.New("second"), 20)
+	assert.True(t, lh.IsFailing())
+	assert.Equal(t, 2, lh.ConsecutiveFailures)
+	assert.Equal(t, "second", lh.LastError)
+	assert.Equal(t, int64(20), lh.LastFailedAt)
+
+	lh.ClearFailures()
+	assert.False(t, lh.IsFailing())
+	assert.Equal(t, LegalHold{}, *lh)
+}
+
 func TestModel_LegalHold_IsFinished(t *testing.T) {
 	tests := []struct {
 		name               string
@@ -413,6 +588,30 @@ func TestModel_LegalHold_IsFinished(t *testing.T) {
 	}
 }
 
+func TestModel_LegalHold_IsDueForPurge(t *testing.T) {
+	gracePeriod := (7 * 24 * time.Hour).Milliseconds()
+	releasedAt := time.Date(2023, time.April, 13, 12, 0, 0, 0, time.UTC).UnixMilli()
+
+	tests := []struct {
+		name       string
+		releasedAt int64
+		now        int64
+		want       bool
+	}{
+		{"A legal hold that is not released is never due", 0, releasedAt + 2*gracePeriod, false},
+		{"A released legal hold is not due during the grace period", releasedAt, releasedAt + gracePeriod - 1, false},
+		{"A released legal hold is due once the grace period has passed", releasedAt, releasedAt + gracePeriod, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			lh := &LegalHold{ReleasedAt: tc.releasedAt}
+			assert.Equal(t, tc.releasedAt != 0, lh.IsReleased())
+			assert.Equal(t, tc.want, lh.IsDueForPurge(tc.now, gracePeriod))
+		})
+	}
+}
+
 func TestModel_LegalHold_BasePath(t *testing.T) {
 	cases := []struct {
 		lh       *LegalHold
@@ -475,7 +674,7 @@ func TestModel_UpdateLegalHold_IsValid(t *testing.T) {
 				UserIDs:     []string{},
 				EndsAt:      0,
 			},
-			expected: "LegalHold must include at least 1 user or 1 group",
+			expected: "LegalHold must include at least 1 user, group, channel or team",
 		},
 		{
 			name: "InvalidUserIDs",
@@ -487,6 +686,36 @@ func TestModel_UpdateLegalHold_IsValid(t *testing.T) {
 			},
 			expected: "LegalHold users must have valid IDs",
 		},
+		{
+			name: "ChannelIDsOnly",
+			ulh: UpdateLegalHold{
+				ID:          model.NewId(),
+				DisplayName: "TestName",
+				ChannelIDs:  []string{model.NewId()},
+				EndsAt:      0,
+			},
+			expected: "",
+		},
+		{
+			name: "InvalidChannelIDs",
+			ulh: UpdateLegalHold{
+				ID:          model.NewId(),
+				DisplayName: "TestName",
+				ChannelIDs:  []string{"abc"},
+				EndsAt:      0,
+			},
+			expected: "LegalHold channels must have valid IDs",
+		},
+		{
+			name: "InvalidTeamIDs",
+			ulh: UpdateLegalHold{
+				ID:          model.NewId(),
+				DisplayName: "TestName",
+				TeamIDs:     []string{"abc"},
+				EndsAt:      0,
+			},
+			expected: "LegalHold teams must have valid IDs",
+		},
 		{
 			name: "NegativeEndsAt",
 			ulh: UpdateLegalHold{
@@ -498,6 +727,26 @@ func TestModel_UpdateLegalHold_IsValid(t *testing.T) {
 			},
 			expected: "LegalHold must end at a valid time or zero",
 		},
+		{
+			name: "HourlyExecutions",
+			ulh: UpdateLegalHold{
+				ID:              model.NewId(),
+				DisplayName:     "TestName",
+				UserIDs:         []string{model.NewId()},
+				ExecutionLength: MinExecutionLength,
+			},
+			expected: "",
+		},
+		{
+			name: "ExecutionsTooFrequent",
+			ulh: UpdateLegalHold{
+				ID:              model.NewId(),
+				DisplayName:     "TestName",
+				UserIDs:         []string{model.NewId()},
+				ExecutionLength: 60000,
+			},
+			expected: "LegalHold execution length must be between 3600000 and 2592000000 milliseconds",
+		},
 	}
 
 	for _, testCase := range testCases {
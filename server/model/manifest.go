@@ -0,0 +1,33 @@
+package model
+
+const (
+	// ManifestDigestAlgorithm is the algorithm of the file digests listed in a Manifest.
+	ManifestDigestAlgorithm = "sha256"
+	// ManifestFileName and ManifestSignatureFileName are the names of the manifest and its
+	// detached Ed25519 signature in the base path of a legal hold.
+	ManifestFileName          = "manifest.json"
+	ManifestSignatureFileName = "manifest.json.sig"
+)
+
+// Manifest lists the SHA-256 digest of every file exported for a LegalHold, keyed by path. It is
+// signed with the SigningKey of the installation so that the export can be verified by anyone
+// holding the public key.
+type Manifest struct {
+	LegalHoldID   string `json:"legal_hold_id"`
+	LegalHoldName string `json:"legal_hold_name"`
+	// KeyID identifies the SigningKey that signed the manifest.
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	UpdateAt  int64  `json:"update_at"`
+	// LedgerHead is the LedgerDigest of the last entry of the ledger when the manifest was signed,
+	// so that the signature also covers the whole ledger.
+	LedgerHead string   `json:"ledger_head,omitempty"`
+	Files      HashList `json:"files"`
+}
+
+// SigningKeyInfo describes the public half of the SigningKey of the installation.
+type SigningKeyInfo struct {
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"`
+	CreateAt  int64  `json:"create_at"`
+}
@@ -0,0 +1,194 @@
+package model
+
+import (
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/utils"
+)
+
+// PreservationMode determines how the plugin protects the content covered by active legal holds
+// from the data retention policies of the server until it has been captured.
+type PreservationMode string
+
+const (
+	// PreservationModeOff disables preservation checks.
+	PreservationModeOff PreservationMode = "off"
+	// PreservationModeWarn reports the data retention policies that conflict with active legal
+	// holds, without changing them.
+	PreservationModeWarn PreservationMode = "warn"
+	// PreservationModeBlock additionally captures the content of the legal holds that conflict
+	// with data retention straight away, ahead of their schedule, so that data retention cannot
+	// delete it from the server before it is stored by the legal hold.
+	PreservationModeBlock PreservationMode = "block"
+)
+
+// GlobalRetentionPolicyID identifies the global data retention policy of the server, which is
+// configured in the server configuration rather than stored like granular policies.
+const GlobalRetentionPolicyID = "global"
+
+const millisecondsInADay = 24 * 60 * 60 * 1000
+
+// RetentionContentPosts and RetentionContentFiles identify the content deleted by a data
+// retention policy.
+const (
+	RetentionContentPosts = "posts"
+	RetentionContentFiles = "files"
+)
+
+// ParsePreservationMode returns the PreservationMode for the value set in the plugin
+// configuration, treating anything unrecognised as PreservationModeOff.
+func ParsePreservationMode(value string) PreservationMode {
+	switch mode := PreservationMode(value); mode {
+	case PreservationModeWarn, PreservationModeBlock:
+		return mode
+	default:
+		return PreservationModeOff
+	}
+}
+
+// RetentionPolicy is a data retention policy of the server. Granular policies apply to the teams
+// and channels assigned to them, the global policy to everything else.
+type RetentionPolicy struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+	// PostDurationDays is the number of days posts are kept for, or negative to keep them forever.
+	PostDurationDays int64    `json:"post_duration_days"`
+	TeamIDs          []string `json:"team_ids,omitempty"`
+	ChannelIDs       []string `json:"channel_ids,omitempty"`
+}
+
+// RetentionSettings describes all the data retention configured on the server.
+type RetentionSettings struct {
+	// Global is the global policy for posts. Its PostDurationDays is negative if message deletion
+	// is disabled.
+	Global RetentionPolicy
+	// FileDurationDays is the number of days file attachments are kept for by the global policy,
+	// or negative if file deletion is disabled. File deletion ignores granular policies.
+	FileDurationDays int64
+	// Policies are the granular retention policies.
+	Policies []RetentionPolicy
+}
+
+// NewRetentionSettings creates the RetentionSettings for the provided global retention, where
+// zero or negative days mean that the deletion is disabled, and granular policies.
+func NewRetentionSettings(messageRetentionDays, fileRetentionDays int64, policies []RetentionPolicy) RetentionSettings {
+	if messageRetentionDays <= 0 {
+		messageRetentionDays = -1
+	}
+
+	if fileRetentionDays <= 0 {
+		fileRetentionDays = -1
+	}
+
+	return RetentionSettings{
+		Global: RetentionPolicy{
+			ID:               GlobalRetentionPolicyID,
+			DisplayName:      "Global retention policy",
+			PostDurationDays: messageRetentionDays,
+		},
+		FileDurationDays: fileRetentionDays,
+		Policies:         policies,
+	}
+}
+
+// PolicyFor returns the retention policy that applies to the posts of the channel indicated by
+// channelID, belonging to the team indicated by teamID. As on the server, channel policies
+// override team policies, which override the global policy.
+func (s RetentionSettings) PolicyFor(channelID, teamID string) RetentionPolicy {
+	var teamPolicy *RetentionPolicy
+	for i, policy := range s.Policies {
+		for _, id := range policy.ChannelIDs {
+			if id == channelID {
+				return policy
+			}
+		}
+
+		if teamID == "" || teamPolicy != nil {
+			continue
+		}
+
+		for _, id := range policy.TeamIDs {
+			if id == teamID {
+				teamPolicy = &s.Policies[i]
+				break
+			}
+		}
+	}
+
+	if teamPolicy != nil {
+		return *teamPolicy
+	}
+
+	return s.Global
+}
+
+// RetentionConflictsWith returns true if, at the time provided in "now", content kept for
+// retentionDays may be deleted before the LegalHold captures it, given that the legal hold job
+// may run up to a day after the end of the next execution window. Negative retentionDays keep
+// content forever.
+func (lh *LegalHold) RetentionConflictsWith(retentionDays int64, now int64) bool {
+	if retentionDays < 0 {
+		return false
+	}
+
+	captureBy := utils.Max(lh.NextExecutionEndTime(), now) + millisecondsInADay
+	return lh.NextExecutionStartTime() < captureBy-retentionDays*millisecondsInADay
+}
+
+// RetentionConflict describes a data retention policy that may delete content covered by a
+// legal hold before it is captured.
+type RetentionConflict struct {
+	LegalHoldID   string `json:"legal_hold_id"`
+	LegalHoldName string `json:"legal_hold_name"`
+	PolicyID      string `json:"policy_id"`
+	PolicyName    string `json:"policy_name"`
+	// Content is either RetentionContentPosts or RetentionContentFiles.
+	Content       string `json:"content"`
+	RetentionDays int64  `json:"retention_days"`
+	// ChannelCount is the number of channels covered by the legal hold that the policy applies to.
+	ChannelCount int `json:"channel_count"`
+	// Blocked is set in PreservationModeBlock once the capture of the content of the legal hold
+	// has been scheduled, so that the policy can no longer delete it before it is captured.
+	Blocked bool `json:"blocked"`
+}
+
+// HeldContent flags the content covered by an active legal hold that it has not captured yet:
+// the posts of its channels created since UncapturedFrom that match its filter, and their file
+// attachments.
+type HeldContent struct {
+	LegalHoldID    string `json:"legal_hold_id"`
+	LegalHoldName  string `json:"legal_hold_name"`
+	UncapturedFrom int64  `json:"uncaptured_from"`
+	ChannelCount   int    `json:"channel_count"`
+	PostCount      int64  `json:"post_count"`
+	FileCount      int64  `json:"file_count"`
+	// CaptureScheduled is set in PreservationModeBlock when the content conflicts with data
+	// retention and an immediate capture of it has been scheduled.
+	CaptureScheduled bool `json:"capture_scheduled"`
+}
+
+// PreservationStatus is the outcome of a check of the data retention policies of the server
+// against the active legal holds.
+type PreservationStatus struct {
+	Mode        PreservationMode    `json:"mode"`
+	CheckedAt   int64               `json:"checked_at"`
+	HeldContent []HeldContent       `json:"held_content"`
+	Conflicts   []RetentionConflict `json:"conflicts"`
+	// Error is the error that caused the check to fail, or empty if it succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// UnblockedConflicts returns the conflicts that may still delete content before it is captured.
+func (s *PreservationStatus) UnblockedConflicts() []RetentionConflict {
+	var conflicts []RetentionConflict
+	for _, conflict := range s.Conflicts {
+		if !conflict.Blocked {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	return conflicts
+}
+
+// IsHealthy returns true if the check succeeded and found no conflicts that are not blocked.
+func (s *PreservationStatus) IsHealthy() bool {
+	return s.Error == "" && len(s.UnblockedConflicts()) == 0
+}
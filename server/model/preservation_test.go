@@ -0,0 +1,79 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModel_ParsePreservationMode(t *testing.T) {
+	assert.Equal(t, PreservationModeWarn, ParsePreservationMode("warn"))
+	assert.Equal(t, PreservationModeBlock, ParsePreservationMode("block"))
+	assert.Equal(t, PreservationModeOff, ParsePreservationMode("off"))
+	assert.Equal(t, PreservationModeOff, ParsePreservationMode(""))
+	assert.Equal(t, PreservationModeOff, ParsePreservationMode("unknown"))
+}
+
+func TestModel_RetentionSettings_PolicyFor(t *testing.T) {
+	channelPolicy := RetentionPolicy{ID: "channel_policy", PostDurationDays: 5, ChannelIDs: []string{"channel1"}}
+	teamPolicy := RetentionPolicy{ID: "team_policy", PostDurationDays: 10, TeamIDs: []string{"team1"}}
+	settings := NewRetentionSettings(30, 0, []RetentionPolicy{teamPolicy, channelPolicy})
+
+	// Channel policies override team policies.
+	assert.Equal(t, channelPolicy, settings.PolicyFor("channel1", "team1"))
+	assert.Equal(t, teamPolicy, settings.PolicyFor("channel2", "team1"))
+	assert.Equal(t, GlobalRetentionPolicyID, settings.PolicyFor("channel3", "team2").ID)
+	assert.Equal(t, int64(30), settings.PolicyFor("channel3", "team2").PostDurationDays)
+	assert.Equal(t, GlobalRetentionPolicyID, settings.PolicyFor("channel4", "").ID)
+
+	// Disabled deletion keeps content forever.
+	assert.Equal(t, int64(-1), settings.FileDurationDays)
+	assert.Equal(t, int64(-1), NewRetentionSettings(0, 10, nil).Global.PostDurationDays)
+}
+
+func TestModel_LegalHold_RetentionConflictsWith(t *testing.T) {
+	const day = int64(millisecondsInADay)
+
+	lh := LegalHold{
+		StartsAt:             10 * day,
+		LastExecutionEndedAt: 20 * day,
+		ExecutionLength:      day,
+	}
+
+	testCases := []struct {
+		name          string
+		retentionDays int64
+		now           int64
+		expected      bool
+	}{
+		{name: "retention disabled", retentionDays: -1, now: 20*day + 1, expected: false},
+		{name: "retention longer than capture delay", retentionDays: 3, now: 20*day + 1, expected: false},
+		{name: "retention shorter than capture delay", retentionDays: 1, now: 20*day + 1, expected: true},
+		{name: "zero retention", retentionDays: 0, now: 20*day + 1, expected: true},
+		{name: "uncaptured content already past retention", retentionDays: 3, now: 25 * day, expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, lh.RetentionConflictsWith(tc.retentionDays, tc.now))
+		})
+	}
+}
+
+func TestModel_PreservationStatus_IsHealthy(t *testing.T) {
+	assert.True(t, (&PreservationStatus{Mode: PreservationModeWarn}).IsHealthy())
+	assert.False(t, (&PreservationStatus{Mode: PreservationModeWarn, Error: "failed"}).IsHealthy())
+	assert.False(t, (&PreservationStatus{Mode: PreservationModeWarn, Conflicts: []RetentionConflict{{LegalHoldID: "lh1"}}}).IsHealthy())
+
+	// Conflicts blocked by an immediate capture do not make the check unhealthy.
+	blocked := &PreservationStatus{Mode: PreservationModeBlock, Conflicts: []RetentionConflict{
+		{LegalHoldID: "lh1", Blocked: true},
+		{LegalHoldID: "lh2"},
+	}}
+	assert.False(t, blocked.IsHealthy())
+	assert.Len(t, blocked.UnblockedConflicts(), 1)
+
+	blocked.Conflicts = blocked.Conflicts[:1]
+	assert.True(t, blocked.IsHealthy())
+	assert.Empty(t, blocked.UnblockedConflicts())
+}
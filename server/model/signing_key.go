@@ -0,0 +1,90 @@
+package model
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+// SigningKey is the Ed25519 key pair of the installation used to sign the manifests of the legal
+// holds. Only the public key is ever shared, so verifying an export does not allow forging one.
+type SigningKey struct {
+	PrivateKey []byte `json:"private_key"`
+	CreateAt   int64  `json:"create_at"`
+}
+
+// NewSigningKey generates a new SigningKey.
+func NewSigningKey() (*SigningKey, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not generate signing key")
+	}
+
+	return &SigningKey{
+		PrivateKey: privateKey,
+		CreateAt:   mattermostModel.GetMillis(),
+	}, nil
+}
+
+// PublicKey returns the public half of the SigningKey.
+func (k *SigningKey) PublicKey() ed25519.PublicKey {
+	return ed25519.PrivateKey(k.PrivateKey).Public().(ed25519.PublicKey)
+}
+
+// PublicKeyPEM returns the public key in PEM-encoded PKIX form, as expected by the processor and
+// by tools such as openssl.
+func (k *SigningKey) PublicKeyPEM() ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(k.PublicKey())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal public key")
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// KeyID returns the hex-encoded SHA-256 fingerprint of the PKIX form of the public key, which
+// identifies the key that signed a manifest.
+func (k *SigningKey) KeyID() (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(k.PublicKey())
+	if err != nil {
+		return "", errors.Wrap(err, "could not marshal public key")
+	}
+
+	fingerprint := sha256.Sum256(der)
+	return hex.EncodeToString(fingerprint[:]), nil
+}
+
+// Sign signs the message with the private key.
+func (k *SigningKey) Sign(message []byte) []byte {
+	return ed25519.Sign(ed25519.PrivateKey(k.PrivateKey), message)
+}
+
+// Verify returns true if signature is a valid signature of the message by this SigningKey.
+func (k *SigningKey) Verify(message, signature []byte) bool {
+	return ed25519.Verify(k.PublicKey(), message, signature)
+}
+
+// Info returns the SigningKeyInfo describing the public half of the SigningKey.
+func (k *SigningKey) Info() (SigningKeyInfo, error) {
+	keyID, err := k.KeyID()
+	if err != nil {
+		return SigningKeyInfo{}, err
+	}
+
+	publicKey, err := k.PublicKeyPEM()
+	if err != nil {
+		return SigningKeyInfo{}, err
+	}
+
+	return SigningKeyInfo{
+		KeyID:     keyID,
+		PublicKey: string(publicKey),
+		CreateAt:  k.CreateAt,
+	}, nil
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModel_SigningKey(t *testing.T) {
+	key, err := NewSigningKey()
+	require.NoError(t, err)
+	require.NotZero(t, key.CreateAt)
+
+	message := []byte("manifest")
+	signature := key.Sign(message)
+	assert.True(t, key.Verify(message, signature))
+	assert.False(t, key.Verify([]byte("forged manifest"), signature))
+
+	// The PEM form round trips to the same public key.
+	publicKeyPEM, err := key.PublicKeyPEM()
+	require.NoError(t, err)
+	block, _ := pem.Decode(publicKeyPEM)
+	require.NotNil(t, block)
+	assert.Equal(t, "PUBLIC KEY", block.Type)
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, key.PublicKey(), publicKey)
+
+	keyID, err := key.KeyID()
+	require.NoError(t, err)
+	assert.Len(t, keyID, 64)
+
+	other, err := NewSigningKey()
+	require.NoError(t, err)
+	otherKeyID, err := other.KeyID()
+	require.NoError(t, err)
+	assert.NotEqual(t, keyID, otherKeyID)
+	assert.False(t, other.Verify(message, signature))
+}
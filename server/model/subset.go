@@ -0,0 +1,271 @@
+package model
+
+import (
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+// SubsetIndexFileName is the name of the sub-index written next to the index.json file of a
+// LegalHold in a download restricted by a DownloadFilter.
+const SubsetIndexFileName = "subset.json"
+
+// DownloadFilter restricts a download of a LegalHold to the content of some custodians, channels
+// or teams over a time range. A channel is selected if it matches every kind of ID given, and any
+// of the IDs of each kind.
+type DownloadFilter struct {
+	// UserIDs, if not empty, limits the download to the channels these users were members of,
+	// over the time they were members.
+	UserIDs []string `json:"user_ids,omitempty"`
+	// ChannelIDs, if not empty, limits the download to these channels.
+	ChannelIDs []string `json:"channel_ids,omitempty"`
+	// TeamIDs, if not empty, limits the download to the channels of these teams.
+	TeamIDs []string `json:"team_ids,omitempty"`
+	// StartTime and EndTime, if not zero, limit the download to the content of this time range.
+	StartTime int64 `json:"start_time,omitempty"`
+	EndTime   int64 `json:"end_time,omitempty"`
+}
+
+// NewDownloadFilter creates a DownloadFilter from the query parameters of a download request.
+// The user_id, channel_id and team_id parameters can be repeated or hold comma-separated lists of
+// IDs, and start_time and end_time are in milliseconds since the epoch.
+func NewDownloadFilter(query url.Values) (DownloadFilter, error) {
+	var filter DownloadFilter
+	var err error
+
+	if filter.UserIDs, err = parseIDs(query, "user_id"); err != nil {
+		return DownloadFilter{}, err
+	}
+	if filter.ChannelIDs, err = parseIDs(query, "channel_id"); err != nil {
+		return DownloadFilter{}, err
+	}
+	if filter.TeamIDs, err = parseIDs(query, "team_id"); err != nil {
+		return DownloadFilter{}, err
+	}
+	if filter.StartTime, err = parseTime(query, "start_time"); err != nil {
+		return DownloadFilter{}, err
+	}
+	if filter.EndTime, err = parseTime(query, "end_time"); err != nil {
+		return DownloadFilter{}, err
+	}
+
+	if filter.EndTime != 0 && filter.EndTime < filter.StartTime {
+		return DownloadFilter{}, errors.New("end_time must not be before start_time")
+	}
+
+	return filter, nil
+}
+
+func parseIDs(query url.Values, name string) ([]string, error) {
+	var ids []string
+	for _, value := range query[name] {
+		for _, id := range strings.Split(value, ",") {
+			id = strings.TrimSpace(id)
+			if id == "" {
+				continue
+			}
+			if !mattermostModel.IsValidId(id) {
+				return nil, errors.Errorf("invalid %s: %s", name, id)
+			}
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+func parseTime(query url.Values, name string) (int64, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || millis < 0 {
+		return 0, errors.Errorf("invalid %s: %s", name, value)
+	}
+
+	return millis, nil
+}
+
+// IsEmpty returns true if the filter does not restrict the download in any way.
+func (f DownloadFilter) IsEmpty() bool {
+	return len(f.UserIDs) == 0 && len(f.ChannelIDs) == 0 && len(f.TeamIDs) == 0 && f.StartTime == 0 && f.EndTime == 0
+}
+
+// SelectChannels returns the time windows selected by the filter for each of the channels
+// indicated by channelIDs, leaving out the channels it does not select. The teams of the channels
+// and the memberships of the users are taken from the index.
+func (f DownloadFilter) SelectChannels(index LegalHoldIndex, channelIDs []string) map[string][]DownloadWindow {
+	teamIDs := make(map[string]string)
+	for _, team := range index.Teams {
+		for _, channel := range team.Channels {
+			teamIDs[channel.ID] = team.ID
+		}
+	}
+
+	window := DownloadWindow{StartTime: f.StartTime, EndTime: f.EndTime}
+	if window.EndTime == 0 {
+		window.EndTime = math.MaxInt64
+	}
+
+	selected := make(map[string][]DownloadWindow)
+	for _, channelID := range channelIDs {
+		if len(f.ChannelIDs) > 0 && !slices.Contains(f.ChannelIDs, channelID) {
+			continue
+		}
+
+		if len(f.TeamIDs) > 0 && !slices.Contains(f.TeamIDs, teamIDs[channelID]) {
+			continue
+		}
+
+		if len(f.UserIDs) == 0 {
+			selected[channelID] = []DownloadWindow{window}
+			continue
+		}
+
+		var windows []DownloadWindow
+		for _, userID := range f.UserIDs {
+			membership, ok := getLegalHoldChannelMembership(index.Users[userID].Channels, channelID)
+			if !ok {
+				continue
+			}
+
+			membershipWindow, ok := window.Intersect(DownloadWindow{StartTime: membership.StartTime, EndTime: membership.EndTime})
+			if ok {
+				windows = append(windows, membershipWindow)
+			}
+		}
+
+		if len(windows) > 0 {
+			selected[channelID] = windows
+		}
+	}
+
+	return selected
+}
+
+// DownloadWindow is a time range, in milliseconds since the epoch, selected by a DownloadFilter.
+// Both ends are inclusive.
+type DownloadWindow struct {
+	StartTime int64 `json:"start_time"`
+	EndTime   int64 `json:"end_time"`
+}
+
+// Intersect returns the part of the time range common to both DownloadWindows, and false if there
+// is none.
+func (w DownloadWindow) Intersect(other DownloadWindow) (DownloadWindow, bool) {
+	intersection := DownloadWindow{
+		StartTime: max(w.StartTime, other.StartTime),
+		EndTime:   min(w.EndTime, other.EndTime),
+	}
+
+	return intersection, intersection.StartTime <= intersection.EndTime
+}
+
+// OverlapsAny returns true if the DownloadWindow shares part of its time range with any of the
+// provided windows.
+func (w DownloadWindow) OverlapsAny(windows []DownloadWindow) bool {
+	for _, window := range windows {
+		if _, ok := w.Intersect(window); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SubsetChannel is a channel selected by a DownloadFilter, with the time windows selected in it.
+type SubsetChannel struct {
+	ChannelID string           `json:"channel_id"`
+	Windows   []DownloadWindow `json:"windows"`
+}
+
+// LegalHoldSubsetIndex maps to the contents of the subset.json file of a download restricted by a
+// DownloadFilter. It describes the subset of the LegalHold in the download, alongside the
+// index.json file of the whole LegalHold.
+type LegalHoldSubsetIndex struct {
+	LegalHold LegalHoldIndexDetails `json:"legal_hold"`
+	Filter    DownloadFilter        `json:"filter"`
+	CreateAt  int64                 `json:"create_at"`
+	// Users and Teams are those of the index.json file, reduced to the selected channels.
+	Users    LegalHoldIndexUsers `json:"users"`
+	Teams    []*LegalHoldTeam    `json:"teams"`
+	Channels []SubsetChannel     `json:"channels"`
+	// FileExceptions are the file attachments of the selected channels that could not be exported.
+	FileExceptions []LegalHoldFileException `json:"file_exceptions,omitempty"`
+	// Files are the paths of the files of the LegalHold in the download.
+	Files []string `json:"files"`
+}
+
+// NewLegalHoldSubsetIndex creates a LegalHoldSubsetIndex of the channels selected from the index,
+// with the time windows provided in selected.
+func NewLegalHoldSubsetIndex(index LegalHoldIndex, filter DownloadFilter, selected map[string][]DownloadWindow, now int64) LegalHoldSubsetIndex {
+	subset := LegalHoldSubsetIndex{
+		LegalHold: index.LegalHold,
+		Filter:    filter,
+		CreateAt:  now,
+		Users:     make(LegalHoldIndexUsers),
+		Teams:     make([]*LegalHoldTeam, 0),
+		Channels:  make([]SubsetChannel, 0, len(selected)),
+		Files:     make([]string, 0),
+	}
+
+	for userID, user := range index.Users {
+		var channels []LegalHoldChannelMembership
+		for _, membership := range user.Channels {
+			if _, ok := selected[membership.ChannelID]; ok {
+				channels = append(channels, membership)
+			}
+		}
+
+		if len(channels) > 0 {
+			subset.Users[userID] = LegalHoldIndexUser{
+				Username: user.Username,
+				Email:    user.Email,
+				Channels: channels,
+			}
+		}
+	}
+
+	for _, team := range index.Teams {
+		var channels []*LegalHoldChannel
+		for _, channel := range team.Channels {
+			if _, ok := selected[channel.ID]; ok {
+				channels = append(channels, channel)
+			}
+		}
+
+		if len(channels) > 0 {
+			subset.Teams = append(subset.Teams, &LegalHoldTeam{
+				ID:          team.ID,
+				Name:        team.Name,
+				DisplayName: team.DisplayName,
+				Channels:    channels,
+			})
+		}
+	}
+
+	for channelID, windows := range selected {
+		subset.Channels = append(subset.Channels, SubsetChannel{ChannelID: channelID, Windows: windows})
+	}
+	slices.SortFunc(subset.Channels, func(a, b SubsetChannel) int {
+		return strings.Compare(a.ChannelID, b.ChannelID)
+	})
+
+	for _, exception := range index.FileExceptions {
+		execution := DownloadWindow{StartTime: exception.ExecutionStartTime, EndTime: exception.ExecutionEndTime}
+		if execution.OverlapsAny(selected[exception.ChannelID]) {
+			subset.FileExceptions = append(subset.FileExceptions, exception)
+		}
+	}
+
+	return subset
+}
@@ -0,0 +1,155 @@
+package model
+
+import (
+	"math"
+	"net/url"
+	"testing"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDownloadFilter(t *testing.T) {
+	userID1 := mattermostModel.NewId()
+	userID2 := mattermostModel.NewId()
+	channelID := mattermostModel.NewId()
+
+	filter, err := NewDownloadFilter(url.Values{
+		"user_id":    []string{userID1 + "," + userID2, userID1},
+		"channel_id": []string{channelID},
+		"start_time": []string{"1000"},
+		"end_time":   []string{"2000"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, DownloadFilter{
+		UserIDs:    []string{userID1, userID2},
+		ChannelIDs: []string{channelID},
+		StartTime:  1000,
+		EndTime:    2000,
+	}, filter)
+	assert.False(t, filter.IsEmpty())
+
+	filter, err = NewDownloadFilter(url.Values{})
+	require.NoError(t, err)
+	assert.True(t, filter.IsEmpty())
+
+	invalid := []url.Values{
+		{"user_id": []string{"not-an-id"}},
+		{"team_id": []string{"../" + channelID}},
+		{"start_time": []string{"yesterday"}},
+		{"end_time": []string{"-1"}},
+		{"start_time": []string{"2000"}, "end_time": []string{"1000"}},
+	}
+	for _, query := range invalid {
+		_, err = NewDownloadFilter(query)
+		assert.Error(t, err, query.Encode())
+	}
+}
+
+func TestDownloadFilter_SelectChannels(t *testing.T) {
+	teamID := mattermostModel.NewId()
+	channelID1 := mattermostModel.NewId()
+	channelID2 := mattermostModel.NewId()
+	directChannelID := mattermostModel.NewId()
+	userID := mattermostModel.NewId()
+
+	index := LegalHoldIndex{
+		Users: LegalHoldIndexUsers{
+			userID: {
+				Username: "custodian",
+				Channels: []LegalHoldChannelMembership{
+					{ChannelID: channelID1, StartTime: 1000, EndTime: 5000},
+					{ChannelID: directChannelID, StartTime: 3000, EndTime: 9000},
+				},
+			},
+		},
+		Teams: []*LegalHoldTeam{
+			{ID: teamID, Channels: []*LegalHoldChannel{{ID: channelID1}, {ID: channelID2}}},
+		},
+	}
+	channelIDs := []string{channelID1, channelID2, directChannelID}
+	all := []DownloadWindow{{StartTime: 0, EndTime: math.MaxInt64}}
+
+	testCases := []struct {
+		name     string
+		filter   DownloadFilter
+		expected map[string][]DownloadWindow
+	}{
+		{
+			name:     "time range only",
+			filter:   DownloadFilter{StartTime: 2000, EndTime: 4000},
+			expected: map[string][]DownloadWindow{channelID1: {{2000, 4000}}, channelID2: {{2000, 4000}}, directChannelID: {{2000, 4000}}},
+		},
+		{
+			name:     "channels",
+			filter:   DownloadFilter{ChannelIDs: []string{channelID2}},
+			expected: map[string][]DownloadWindow{channelID2: all},
+		},
+		{
+			name:     "teams leave out channels outside of them",
+			filter:   DownloadFilter{TeamIDs: []string{teamID}},
+			expected: map[string][]DownloadWindow{channelID1: all, channelID2: all},
+		},
+		{
+			name:     "custodian over the time of their memberships",
+			filter:   DownloadFilter{UserIDs: []string{userID}},
+			expected: map[string][]DownloadWindow{channelID1: {{1000, 5000}}, directChannelID: {{3000, 9000}}},
+		},
+		{
+			name:     "custodian and time range",
+			filter:   DownloadFilter{UserIDs: []string{userID}, StartTime: 6000},
+			expected: map[string][]DownloadWindow{directChannelID: {{6000, 9000}}},
+		},
+		{
+			name:     "custodian and team",
+			filter:   DownloadFilter{UserIDs: []string{userID}, TeamIDs: []string{teamID}},
+			expected: map[string][]DownloadWindow{channelID1: {{1000, 5000}}},
+		},
+		{
+			name:     "unknown custodian",
+			filter:   DownloadFilter{UserIDs: []string{mattermostModel.NewId()}},
+			expected: map[string][]DownloadWindow{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.filter.SelectChannels(index, channelIDs))
+		})
+	}
+}
+
+func TestNewLegalHoldSubsetIndex(t *testing.T) {
+	index := LegalHoldIndex{
+		LegalHold: LegalHoldIndexDetails{ID: "hold_id", Name: "hold"},
+		Users: LegalHoldIndexUsers{
+			"user_1": {Username: "one", Channels: []LegalHoldChannelMembership{{ChannelID: "channel_1"}, {ChannelID: "channel_2"}}},
+			"user_2": {Username: "two", Channels: []LegalHoldChannelMembership{{ChannelID: "channel_2"}}},
+		},
+		Teams: []*LegalHoldTeam{
+			{ID: "team_1", Channels: []*LegalHoldChannel{{ID: "channel_1"}, {ID: "channel_2"}}},
+			{ID: "team_2", Channels: []*LegalHoldChannel{{ID: "channel_3"}}},
+		},
+		FileExceptions: []LegalHoldFileException{
+			{ChannelID: "channel_1", FileID: "in_window", ExecutionStartTime: 1000, ExecutionEndTime: 2000},
+			{ChannelID: "channel_1", FileID: "out_of_window", ExecutionStartTime: 3000, ExecutionEndTime: 4000},
+			{ChannelID: "channel_2", FileID: "other_channel", ExecutionStartTime: 1000, ExecutionEndTime: 2000},
+		},
+	}
+
+	selected := map[string][]DownloadWindow{"channel_1": {{StartTime: 1500, EndTime: 2500}}}
+	subset := NewLegalHoldSubsetIndex(index, DownloadFilter{ChannelIDs: []string{"channel_1"}}, selected, 5000)
+
+	assert.Equal(t, index.LegalHold, subset.LegalHold)
+	assert.Equal(t, int64(5000), subset.CreateAt)
+	assert.Equal(t, LegalHoldIndexUsers{
+		"user_1": {Username: "one", Channels: []LegalHoldChannelMembership{{ChannelID: "channel_1"}}},
+	}, subset.Users)
+	require.Len(t, subset.Teams, 1)
+	assert.Equal(t, "team_1", subset.Teams[0].ID)
+	assert.Equal(t, []*LegalHoldChannel{{ID: "channel_1"}}, subset.Teams[0].Channels)
+	assert.Equal(t, []SubsetChannel{{ChannelID: "channel_1", Windows: selected["channel_1"]}}, subset.Channels)
+	require.Len(t, subset.FileExceptions, 1)
+	assert.Equal(t, "in_window", subset.FileExceptions[0].FileID)
+}
@@ -0,0 +1,18 @@
+package model
+
+// LegalHoldTombstone is kept in place of a released LegalHold once its data has been purged, as a
+// record of what was held, and when and by whom it was released.
+type LegalHoldTombstone struct {
+	// LegalHold is the LegalHold as it was when it was purged, without its secret.
+	LegalHold LegalHold `json:"legal_hold"`
+	PurgedAt  int64     `json:"purged_at"`
+}
+
+// NewLegalHoldTombstone creates the LegalHoldTombstone of the LegalHold lh, purged at the time
+// provided in now.
+func NewLegalHoldTombstone(lh LegalHold, now int64) LegalHoldTombstone {
+	return LegalHoldTombstone{
+		LegalHold: lh.Sanitized(),
+		PurgedAt:  now,
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/legalhold"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+// acknowledgeNoticePath matches the route of the acknowledgement button of legal hold notices,
+// which is called by the server on behalf of the custodian who clicked it.
+var acknowledgeNoticePath = regexp.MustCompile(`^/api/v1/legalholds/([A-Za-z0-9]+)/acknowledge$`)
+
+// notifyCustodians sends the legal hold notice to the custodians of the LegalHold who have not
+// been sent it yet, if custodian notices are enabled.
+func (p *Plugin) notifyCustodians(lh model.LegalHold) {
+	if p.custodianNoticeJob != nil {
+		p.custodianNoticeJob.NotifyLegalHold(lh)
+	}
+}
+
+// acknowledgeNotice records that the user acknowledged the legal hold notice sent to them, from
+// the post of the notice or of one of its reminders.
+func (p *Plugin) acknowledgeNotice(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	legalHoldID := acknowledgeNoticePath.FindStringSubmatch(r.URL.Path)[1]
+	if !mattermostModel.IsValidId(legalHoldID) {
+		http.Error(w, "a valid legal hold ID must be provided", http.StatusBadRequest)
+		return
+	}
+
+	var request mattermostModel.PostActionIntegrationRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, requestBodyMaxSizeBytes)).Decode(&request); err != nil {
+		http.Error(w, "failed to parse request body", http.StatusBadRequest)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	notice, err := p.custodianNoticeJob.Acknowledge(legalHoldID, userID, request.PostId)
+	if errors.Is(err, legalhold.ErrNoticeNotFound) {
+		http.Error(w, "legal hold notice not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "failed to acknowledge the legal hold notice", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	p.API.LogInfo("Custodian acknowledged legal hold notice", "legal_hold_id", legalHoldID, "user_id", userID, "acknowledged_at", notice.AcknowledgedAt)
+
+	b, jsonErr := json.Marshal(mattermostModel.PostActionIntegrationResponse{
+		EphemeralText: "Thank you. Your acknowledgement of the legal hold notice has been recorded.",
+	})
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
+
+// listCustodians serves the acknowledgement status of the legal hold notice of each current
+// custodian of a LegalHold, including the members of its groups.
+func (p *Plugin) listCustodians(w http.ResponseWriter, r *http.Request) {
+	legalholdID, err := RequireLegalHoldID(r)
+	if err != nil {
+		http.Error(w, "failed to parse LegalHold ID", http.StatusBadRequest)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	lh, err := p.KVStore.GetLegalHoldByID(legalholdID)
+	if err != nil {
+		http.Error(w, "an error occurred fetching the legal hold", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	if lh.ID == "" {
+		http.Error(w, "legal hold not found", http.StatusNotFound)
+		return
+	}
+
+	custodians, err := legalhold.Custodians(p.API, *lh)
+	if err != nil {
+		http.Error(w, "an error occurred fetching the custodians", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	notices, err := p.KVStore.GetCustodianNotices(legalholdID)
+	if err != nil {
+		http.Error(w, "an error occurred fetching the custodian notices", http.StatusInternalServerError)
+		p.Client.Log.Error(err.Error())
+		return
+	}
+
+	noticesByUser := make(map[string]model.CustodianNotice, len(notices))
+	for _, notice := range notices {
+		noticesByUser[notice.UserID] = notice
+	}
+
+	statuses := make([]model.CustodianStatus, 0, len(custodians))
+	for _, user := range custodians {
+		var notice *model.CustodianNotice
+		if n, ok := noticesByUser[user.Id]; ok {
+			notice = &n
+		}
+		statuses = append(statuses, model.NewCustodianStatus(user, notice))
+	}
+
+	b, jsonErr := json.Marshal(statuses)
+	if jsonErr != nil {
+		http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	if err != nil {
+		p.API.LogError("failed to write http response", err.Error())
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/legalhold"
+	legalHoldModel "github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/kvstore"
+)
+
+func TestCustodianNotices(t *testing.T) {
+	p, api := setupTestPlugin(t)
+	p.KVStore = kvstore.NewKVStore(p.Client)
+	kv := mockInMemoryKV(api)
+
+	mockJob := &MockCustodianNoticeJob{}
+	p.custodianNoticeJob = mockJob
+
+	api.On("HasPermissionTo", "admin_user_id", model.PermissionManageSystem).Return(true)
+	api.On("GetConfig").Return(&model.Config{}).Maybe()
+	api.On("LogInfo", mock.Anything).Maybe()
+	api.On("LogInfo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	api.On("LogError", mock.Anything).Maybe()
+
+	user := &model.User{Id: model.NewId(), Username: "user"}
+	member := &model.User{Id: model.NewId(), Username: "member"}
+	api.On("GetUser", user.Id).Return(user, nil)
+
+	serve := func(method, path, userID, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Add("Mattermost-User-Id", userID)
+
+		recorder := httptest.NewRecorder()
+		p.ServeHTTP(nil, recorder, req)
+		return recorder
+	}
+
+	var lh legalHoldModel.LegalHold
+	t.Run("custodians are notified when a legal hold is created", func(t *testing.T) {
+		mockJob.On("NotifyLegalHold", mock.MatchedBy(func(lh legalHoldModel.LegalHold) bool {
+			return lh.Name == "notified"
+		})).Once()
+
+		body := fmt.Sprintf(`{"name": "notified", "display_name": "Notified", "user_ids": [%q], "group_ids": [%q], "starts_at": 1}`, user.Id, model.NewId())
+		recorder := serve(http.MethodPost, "/api/v1/legalholds", "admin_user_id", body)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&lh))
+	})
+
+	acknowledgePath := fmt.Sprintf("/api/v1/legalholds/%s/acknowledge", lh.ID)
+	postID := model.NewId()
+
+	t.Run("custodians acknowledge the notice without being System Admins", func(t *testing.T) {
+		notice := legalHoldModel.NewCustodianNotice(lh.ID, user.Id, postID, 1000)
+		notice.AcknowledgedAt = 2000
+		mockJob.On("Acknowledge", lh.ID, user.Id, postID).Return(&notice, nil).Once()
+
+		recorder := serve(http.MethodPost, acknowledgePath, user.Id, fmt.Sprintf(`{"post_id": %q}`, postID))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var response model.PostActionIntegrationResponse
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+		require.NotEmpty(t, response.EphemeralText)
+	})
+
+	t.Run("custodians cannot acknowledge the notices of others", func(t *testing.T) {
+		mockJob.On("Acknowledge", lh.ID, member.Id, postID).Return(nil, legalhold.ErrNoticeNotFound).Once()
+
+		recorder := serve(http.MethodPost, acknowledgePath, member.Id, fmt.Sprintf(`{"post_id": %q}`, postID))
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+
+		recorder = serve(http.MethodGet, acknowledgePath, member.Id, "")
+		require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+		recorder = serve(http.MethodPost, acknowledgePath, "", fmt.Sprintf(`{"post_id": %q}`, postID))
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("the acknowledgement status of each custodian is reported", func(t *testing.T) {
+		api.On("GetGroupMemberUsers", lh.GroupIDs[0], 0, 50).Return([]*model.User{user, member}, nil).Once()
+		api.On("GetGroupMemberUsers", lh.GroupIDs[0], 1, 50).Return([]*model.User{}, nil).Once()
+
+		notice := legalHoldModel.NewCustodianNotice(lh.ID, user.Id, postID, 1000)
+		notice.RemindedAt = 1500
+		notice.ReminderCount = 1
+		notice.AcknowledgedAt = 2000
+		kv.set(t, fmt.Sprintf("kvstore_custodian_notice_%s_%s", lh.ID, user.Id), notice)
+
+		recorder := serve(http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/custodians", lh.ID), "admin_user_id", "")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var statuses []legalHoldModel.CustodianStatus
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&statuses))
+		require.Equal(t, []legalHoldModel.CustodianStatus{
+			{UserID: user.Id, Username: "user", NotifiedAt: 1000, RemindedAt: 1500, ReminderCount: 1, AcknowledgedAt: 2000, Acknowledged: true},
+			{UserID: member.Id, Username: "member"},
+		}, statuses)
+
+		recorder = serve(http.MethodGet, fmt.Sprintf("/api/v1/legalholds/%s/custodians", model.NewId()), "admin_user_id", "")
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	mockJob.AssertExpectations(t)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	pluginapi "github.com/mattermost/mattermost-plugin-api"
@@ -12,14 +13,21 @@ import (
 	"github.com/mattermost/mattermost-server/v6/shared/filestore"
 	"github.com/pkg/errors"
 
+	root "github.com/mattermost/mattermost-plugin-legal-hold"
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/config"
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/jobs"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/legalhold"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/metrics"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/kvstore"
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/store/sqlstore"
 )
 
 const (
 	LegalHoldJobID              = "legal_hold_job"
+	PreservationJobID           = "legal_hold_preservation_job"
+	ExportPackageJobID          = "legal_hold_export_package_job"
+	CustodianNoticeJobID        = "legal_hold_custodian_notice_job"
 	MattermostEntrySkuShortName = "entry"
 )
 
@@ -52,6 +60,23 @@ type Plugin struct {
 	// legalHoldJob runs the legal hold jobs
 	legalHoldJob jobs.LegalHoldJobInterface
 
+	// preservationJob checks the data retention policies against the active legal holds
+	preservationJob jobs.PreservationJobInterface
+
+	// exportPackageJob builds the export packages of legal holds in the background
+	exportPackageJob jobs.ExportPackageJobInterface
+
+	// custodianNoticeJob sends the legal hold notice to custodians and reminds them of it
+	custodianNoticeJob jobs.CustodianNoticeJobInterface
+
+	// metrics collects the metrics of the processing of legal holds, served by the metrics
+	// endpoint. It outlives the jobs, which are created again whenever the plugin is reconfigured.
+	metrics *metrics.Metrics
+
+	// botID is the ID of the bot that sends the legal hold notices to custodians, and the alerts of
+	// legal holds that fail to execute
+	botID string
+
 	// router holds the HTTP router for the plugin's rest API
 	router *mux.Router
 }
@@ -85,8 +110,19 @@ func (p *Plugin) OnActivate() error {
 
 	p.KVStore = kvstore.NewKVStore(p.Client)
 
+	p.botID, err = p.Client.Bot.EnsureBot(&mattermostModel.Bot{
+		Username:    "legal-hold",
+		DisplayName: "Legal Hold",
+		Description: "Sends the legal hold notices to the custodians of legal holds, and alerts of failed legal holds.",
+	})
+	if err != nil {
+		p.Client.Log.Error("cannot ensure the legal hold bot", "err", err)
+		return err
+	}
+
 	// Create job manager
 	p.jobManager = jobs.NewJobManager(&p.Client.Log)
+	p.metrics = metrics.New()
 
 	return p.Reconfigure()
 }
@@ -146,6 +182,13 @@ func (p *Plugin) OnConfigurationChange() error {
 	return p.Reconfigure()
 }
 
+// OnPluginClusterEvent is invoked when another node of the cluster publishes an event for this plugin.
+func (p *Plugin) OnPluginClusterEvent(_ *plugin.Context, ev mattermostModel.PluginClusterEvent) {
+	if ev.Id == jobs.CancelLegalHoldClusterEventID && p.legalHoldJob != nil {
+		p.legalHoldJob.OnCancelLegalHoldEvent(string(ev.Data))
+	}
+}
+
 func (p *Plugin) Reconfigure() error {
 	// Don't do anything if the plugin isn't activated yet.
 	if p.Client == nil {
@@ -201,7 +244,18 @@ func (p *Plugin) Reconfigure() error {
 		p.Client.Log.Debug("Skipping filestore connection test")
 	}
 
-	p.FileBackend = filesBackend
+	// Encrypt the files of legal holds at rest if an encryption key is configured. The backend is
+	// wrapped either way so that encrypted legal holds are never written to in plaintext.
+	var encryptionKey []byte
+	if conf.EncryptionKey != "" {
+		encryptionKey, err = model.ParseEncryptionKey(conf.EncryptionKey)
+		if err != nil {
+			p.Client.Log.Error("invalid encryption key", "err", err)
+			return errors.Wrap(err, "invalid encryption key")
+		}
+	}
+
+	p.FileBackend = legalhold.NewEncryptedFileBackend(filesBackend, encryptionKey)
 
 	// Check all legal holds on plugin activation to prevent corrupt states:
 	// - For legal holds that supposedly don't have messages, check if the index exist
@@ -243,14 +297,58 @@ func (p *Plugin) Reconfigure() error {
 		p.Client.Log.Info("Stopped old job")
 	}
 
+	if p.preservationJob != nil {
+		if err = p.jobManager.RemoveJob(PreservationJobID, 0); err != nil {
+			return err
+		}
+	}
+
+	// Export packages being built with the old file backend are cancelled, and saved as failed.
+	if p.exportPackageJob != nil {
+		if err = p.jobManager.RemoveJob(ExportPackageJobID, 10*time.Second); err != nil {
+			return err
+		}
+	}
+
+	if p.custodianNoticeJob != nil {
+		if err = p.jobManager.RemoveJob(CustodianNoticeJobID, 0); err != nil {
+			return err
+		}
+	}
+
 	// Create new job
-	p.legalHoldJob, err = jobs.NewLegalHoldJob(LegalHoldJobID, p.API, p.Client, p.SQLStore, p.KVStore, p.FileBackend)
+	p.legalHoldJob, err = jobs.NewLegalHoldJob(LegalHoldJobID, p.API, p.Client, p.SQLStore, p.KVStore, p.FileBackend, p.botID, p.metrics)
 	if err != nil {
 		return fmt.Errorf("cannot create legal hold job: %w", err)
 	}
 	if err := p.jobManager.AddJob(p.legalHoldJob); err != nil {
 		return fmt.Errorf("cannot add legal hold job: %w", err)
 	}
+
+	p.preservationJob, err = jobs.NewPreservationJob(PreservationJobID, p.API, p.Client, p.SQLStore, p.KVStore, p.FileBackend, p.legalHoldJob.RunSingleLegalHold)
+	if err != nil {
+		return fmt.Errorf("cannot create preservation job: %w", err)
+	}
+	if err := p.jobManager.AddJob(p.preservationJob); err != nil {
+		return fmt.Errorf("cannot add preservation job: %w", err)
+	}
+
+	p.exportPackageJob, err = jobs.NewExportPackageJob(ExportPackageJobID, p.API, p.Client, p.KVStore, p.FileBackend)
+	if err != nil {
+		return fmt.Errorf("cannot create export package job: %w", err)
+	}
+	if err := p.jobManager.AddJob(p.exportPackageJob); err != nil {
+		return fmt.Errorf("cannot add export package job: %w", err)
+	}
+
+	p.custodianNoticeJob, err = jobs.NewCustodianNoticeJob(CustodianNoticeJobID, p.API, p.Client, p.KVStore, p.botID, root.Manifest.Id)
+	if err != nil {
+		return fmt.Errorf("cannot create custodian notice job: %w", err)
+	}
+	if err := p.jobManager.AddJob(p.custodianNoticeJob); err != nil {
+		return fmt.Errorf("cannot add custodian notice job: %w", err)
+	}
+
 	_ = p.jobManager.OnConfigurationChange(p.getConfiguration())
 
 	return nil
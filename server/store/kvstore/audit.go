@@ -0,0 +1,200 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+// auditEventPrefix prefixes the keys of audit events.
+const auditEventPrefix = "kvstore_audit_event_"
+
+// auditIndexPrefix prefixes the keys of the indexes of the audit events of a day, so that a query
+// only reads the events of the days within its time range.
+const auditIndexPrefix = "kvstore_audit_index_"
+
+// auditDaysPrefix prefixes the keys of the lists of the days with audit events.
+const auditDaysPrefix = "kvstore_audit_days_"
+
+// auditIndexAll is the scope of the indexes of all audit events, as opposed to those of the
+// events of a single legal hold, whose scope is the ID of the legal hold.
+const auditIndexAll = "all"
+
+// auditDayLayout formats the days that audit events are indexed by, in UTC.
+const auditDayLayout = "20060102"
+
+// auditIndexSaveAttempts is the number of times adding an audit event to an index is attempted
+// when other events are added to it at the same time.
+const auditIndexSaveAttempts = 10
+
+// auditEventKey keys an audit event by its time, zero-padded so that the keys sort in the order
+// the events happened, and its ID.
+func auditEventKey(event model.AuditEvent) string {
+	return fmt.Sprintf("%s%013d_%s", auditEventPrefix, event.CreateAt, event.ID)
+}
+
+// auditEventTime returns the time of the audit event stored under key.
+func auditEventTime(key string) (int64, bool) {
+	createAt, _, found := strings.Cut(strings.TrimPrefix(key, auditEventPrefix), "_")
+	if !found {
+		return 0, false
+	}
+
+	at, err := strconv.ParseInt(createAt, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return at, true
+}
+
+// auditDay returns the day, in UTC, of the time provided in at.
+func auditDay(at int64) string {
+	return time.UnixMilli(at).UTC().Format(auditDayLayout)
+}
+
+func auditIndexKey(scope, day string) string {
+	return auditIndexPrefix + scope + "_" + day
+}
+
+func auditDaysKey(scope string) string {
+	return auditDaysPrefix + scope
+}
+
+func (kvs Impl) SaveAuditEvent(event model.AuditEvent) error {
+	key := auditEventKey(event)
+	if _, err := kvs.client.KV.Set(key, event); err != nil {
+		return errors.Wrap(err, "could not save audit event")
+	}
+
+	scopes := []string{auditIndexAll}
+	if event.LegalHoldID != "" {
+		scopes = append(scopes, event.LegalHoldID)
+	}
+
+	day := auditDay(event.CreateAt)
+	for _, scope := range scopes {
+		created, err := kvs.appendToList(auditIndexKey(scope, day), key)
+		if err != nil {
+			return errors.Wrap(err, "could not index audit event")
+		}
+
+		if created {
+			if _, err = kvs.appendToList(auditDaysKey(scope), day); err != nil {
+				return errors.Wrap(err, "could not index audit event")
+			}
+		}
+	}
+
+	return nil
+}
+
+// appendToList atomically appends value to the list of strings stored under key, returning true
+// if the list did not exist yet.
+func (kvs Impl) appendToList(key, value string) (bool, error) {
+	for attempt := 0; attempt < auditIndexSaveAttempts; attempt++ {
+		var oldValue []byte
+		if err := kvs.client.KV.Get(key, &oldValue); err != nil {
+			return false, err
+		}
+
+		var list []string
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &list); err != nil {
+				return false, errors.Wrapf(err, "could not unmarshal list %s", key)
+			}
+		}
+
+		// A nil oldValue only lets the list be saved if it does not exist yet.
+		saved, err := kvs.client.KV.Set(key, append(list, value), pluginapi.SetAtomic(oldValue))
+		if err != nil {
+			return false, err
+		}
+		if saved {
+			return len(oldValue) == 0, nil
+		}
+	}
+
+	return false, errors.Errorf("could not update list %s: too many concurrent updates", key)
+}
+
+// getList returns the list of strings stored under key, or nil if there is none.
+func (kvs Impl) getList(key string) ([]string, error) {
+	var list []string
+	if err := kvs.client.KV.Get(key, &list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// GetAuditEvents returns the page of the audit events matching the query, from the most recent
+// to the oldest. Only the indexes of the days within the time range of the query are read, those
+// of the legal hold of the query if it has one, and the events are read until the page is full.
+func (kvs Impl) GetAuditEvents(query model.AuditEventQuery) (*model.AuditEventPage, error) {
+	if query.PerPage < 1 || query.PerPage > model.MaxAuditEventsPerPage {
+		query.PerPage = model.MaxAuditEventsPerPage
+	}
+
+	scope := auditIndexAll
+	if query.LegalHoldID != "" {
+		scope = query.LegalHoldID
+	}
+
+	days, err := kvs.getList(auditDaysKey(scope))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get audit events")
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(days)))
+
+	skip := query.Page * query.PerPage
+	page := &model.AuditEventPage{Events: make([]model.AuditEvent, 0, query.PerPage)}
+	for _, day := range days {
+		if (query.Until != 0 && day > auditDay(query.Until)) || day < auditDay(query.Since) {
+			continue
+		}
+
+		keys, err := kvs.getList(auditIndexKey(scope, day))
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get audit events")
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+		for _, key := range keys {
+			if at, ok := auditEventTime(key); ok && !query.InTimeRange(at) {
+				continue
+			}
+
+			var event model.AuditEvent
+			if err = kvs.client.KV.Get(key, &event); err != nil {
+				return nil, errors.Wrap(err, "could not get audit events")
+			}
+
+			if event.ID == "" || !query.Matches(event) {
+				continue
+			}
+
+			if skip > 0 {
+				skip--
+				continue
+			}
+
+			if len(page.Events) == query.PerPage {
+				page.HasNext = true
+				return page, nil
+			}
+
+			page.Events = append(page.Events, event)
+		}
+	}
+
+	return page, nil
+}
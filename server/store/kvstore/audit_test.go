@@ -0,0 +1,160 @@
+package kvstore
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+// atomicKV is an in-memory plugin KV store that honours atomic sets, and records the keys read.
+type atomicKV struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	read []string
+	// beforeSet, if set, is called before each set, outside of the lock.
+	beforeSet func(key string)
+}
+
+func mockAtomicKV(api *plugintest.API) *atomicKV {
+	kv := &atomicKV{data: make(map[string][]byte)}
+
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+		kv.mu.Lock()
+		defer kv.mu.Unlock()
+		kv.read = append(kv.read, key)
+		return kv.data[key]
+	}, nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8"), mock.AnythingOfType("model.PluginKVSetOptions")).
+		Return(func(key string, value []byte, options mattermostModel.PluginKVSetOptions) bool {
+			if kv.beforeSet != nil {
+				kv.beforeSet(key)
+			}
+
+			kv.mu.Lock()
+			defer kv.mu.Unlock()
+			if current, exists := kv.data[key]; options.Atomic && (exists != (options.OldValue != nil) || !bytes.Equal(current, options.OldValue)) {
+				return false
+			}
+			kv.data[key] = value
+			return true
+		}, nil)
+
+	return kv
+}
+
+func TestKVStore_GetAuditEvents(t *testing.T) {
+	api := &plugintest.API{}
+	driver := &plugintest.Driver{}
+	client := pluginapi.NewClient(api, driver)
+
+	kvstore := NewKVStore(client)
+	kv := mockAtomicKV(api)
+
+	day := (24 * time.Hour).Milliseconds()
+	legalHoldID := mattermostModel.NewId()
+	otherLegalHoldID := mattermostModel.NewId()
+	events := []model.AuditEvent{
+		{ID: mattermostModel.NewId(), CreateAt: 100, Action: model.AuditActionCreateLegalHold, LegalHoldID: legalHoldID},
+		{ID: mattermostModel.NewId(), CreateAt: 200, Action: model.AuditActionCreateLegalHold, LegalHoldID: otherLegalHoldID},
+		{ID: mattermostModel.NewId(), CreateAt: 300, Action: model.AuditActionUpdateLegalHold, LegalHoldID: legalHoldID},
+		{ID: mattermostModel.NewId(), CreateAt: 2*day + 1000, Action: model.AuditActionDownloadLegalHold, LegalHoldID: legalHoldID},
+		{ID: mattermostModel.NewId(), CreateAt: 3 * day, Action: model.AuditActionRunAllLegalHolds},
+	}
+	for _, event := range events {
+		require.NoError(t, kvstore.SaveAuditEvent(event))
+	}
+
+	t.Run("most recent first", func(t *testing.T) {
+		page, err := kvstore.GetAuditEvents(model.AuditEventQuery{PerPage: 10})
+		require.NoError(t, err)
+		require.Equal(t, []model.AuditEvent{events[4], events[3], events[2], events[1], events[0]}, page.Events)
+		require.False(t, page.HasNext)
+	})
+
+	t.Run("filtered and paged", func(t *testing.T) {
+		query := model.AuditEventQuery{LegalHoldID: legalHoldID, PerPage: 2}
+		page, err := kvstore.GetAuditEvents(query)
+		require.NoError(t, err)
+		require.Equal(t, []model.AuditEvent{events[3], events[2]}, page.Events)
+		require.True(t, page.HasNext)
+
+		query.Page = 1
+		page, err = kvstore.GetAuditEvents(query)
+		require.NoError(t, err)
+		require.Equal(t, []model.AuditEvent{events[0]}, page.Events)
+		require.False(t, page.HasNext)
+	})
+
+	t.Run("time range", func(t *testing.T) {
+		page, err := kvstore.GetAuditEvents(model.AuditEventQuery{Since: 200, Until: 300, PerPage: 10})
+		require.NoError(t, err)
+		require.Equal(t, []model.AuditEvent{events[2], events[1]}, page.Events)
+	})
+
+	t.Run("only reads the indexes of the days in range", func(t *testing.T) {
+		kv.mu.Lock()
+		kv.read = nil
+		kv.mu.Unlock()
+
+		page, err := kvstore.GetAuditEvents(model.AuditEventQuery{LegalHoldID: legalHoldID, Since: 2 * day, PerPage: 10})
+		require.NoError(t, err)
+		require.Equal(t, []model.AuditEvent{events[3]}, page.Events)
+
+		require.Equal(t, []string{
+			auditDaysKey(legalHoldID),
+			auditIndexKey(legalHoldID, "19700103"),
+			auditEventKey(events[3]),
+		}, kv.read)
+		api.AssertNotCalled(t, "KVList", mock.Anything, mock.Anything)
+	})
+
+	t.Run("caps the page size", func(t *testing.T) {
+		for i := 0; i < model.MaxAuditEventsPerPage; i++ {
+			require.NoError(t, kvstore.SaveAuditEvent(model.AuditEvent{ID: mattermostModel.NewId(), CreateAt: 4*day + int64(i), Action: model.AuditActionRunAllLegalHolds}))
+		}
+
+		page, err := kvstore.GetAuditEvents(model.AuditEventQuery{PerPage: 10 * model.MaxAuditEventsPerPage})
+		require.NoError(t, err)
+		require.Len(t, page.Events, model.MaxAuditEventsPerPage)
+		require.True(t, page.HasNext)
+	})
+}
+
+func TestKVStore_SaveAuditEventConcurrently(t *testing.T) {
+	api := &plugintest.API{}
+	driver := &plugintest.Driver{}
+	client := pluginapi.NewClient(api, driver)
+
+	kvstore := NewKVStore(client)
+	kv := mockAtomicKV(api)
+
+	// Another event is indexed between the read and the write of the index, once.
+	other := model.AuditEvent{ID: mattermostModel.NewId(), CreateAt: 100, Action: model.AuditActionRunAllLegalHolds}
+	var once sync.Once
+	kv.beforeSet = func(key string) {
+		if key == auditIndexKey(auditIndexAll, "19700101") {
+			once.Do(func() {
+				kv.beforeSet = nil
+				require.NoError(t, kvstore.SaveAuditEvent(other))
+			})
+		}
+	}
+
+	event := model.AuditEvent{ID: mattermostModel.NewId(), CreateAt: 200, Action: model.AuditActionRunAllLegalHolds}
+	require.NoError(t, kvstore.SaveAuditEvent(event))
+
+	page, err := kvstore.GetAuditEvents(model.AuditEventQuery{PerPage: 10})
+	require.NoError(t, err)
+	require.Equal(t, []model.AuditEvent{event, other}, page.Events)
+
+	require.JSONEq(t, `["19700101"]`, string(kv.data[auditDaysKey(auditIndexAll)]))
+}
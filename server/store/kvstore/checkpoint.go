@@ -0,0 +1,51 @@
+package kvstore
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+// executionCheckpointPrefix prefixes the keys of execution checkpoints.
+const executionCheckpointPrefix = "kvstore_execution_checkpoint_"
+
+func (kvs Impl) SaveExecutionCheckpoint(cp model.ExecutionCheckpoint) error {
+	cp.UpdateAt = mattermostModel.GetMillis()
+
+	key := fmt.Sprintf("%s%s", executionCheckpointPrefix, cp.LegalHoldID)
+	if _, err := kvs.client.KV.Set(key, cp); err != nil {
+		return errors.Wrap(err, "could not save execution checkpoint")
+	}
+
+	return nil
+}
+
+// GetExecutionCheckpoint returns the execution checkpoint for the legal hold indicated by
+// legalHoldID, or nil if there is no checkpoint for that legal hold.
+func (kvs Impl) GetExecutionCheckpoint(legalHoldID string) (*model.ExecutionCheckpoint, error) {
+	key := fmt.Sprintf("%s%s", executionCheckpointPrefix, legalHoldID)
+
+	var cp model.ExecutionCheckpoint
+	if err := kvs.client.KV.Get(key, &cp); err != nil {
+		return nil, errors.Wrap(err, "could not get execution checkpoint")
+	}
+
+	if cp.LegalHoldID == "" {
+		return nil, nil
+	}
+
+	return &cp, nil
+}
+
+func (kvs Impl) DeleteExecutionCheckpoint(legalHoldID string) error {
+	key := fmt.Sprintf("%s%s", executionCheckpointPrefix, legalHoldID)
+	if err := kvs.client.KV.Delete(key); err != nil {
+		return errors.Wrap(err, "could not delete execution checkpoint")
+	}
+
+	return nil
+}
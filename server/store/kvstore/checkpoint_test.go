@@ -0,0 +1,98 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+func TestKVStore_SaveExecutionCheckpoint(t *testing.T) {
+	api := &plugintest.API{}
+	driver := &plugintest.Driver{}
+	client := pluginapi.NewClient(api, driver)
+
+	kvstore := NewKVStore(client)
+
+	cp := model.ExecutionCheckpoint{
+		LegalHoldID:        mattermostModel.NewId(),
+		ExecutionStartTime: 100,
+		ExecutionEndTime:   200,
+		ChannelIDs:         []string{mattermostModel.NewId()},
+	}
+
+	var saved model.ExecutionCheckpoint
+	api.On("KVSetWithOptions",
+		fmt.Sprintf("%s%s", executionCheckpointPrefix, cp.LegalHoldID),
+		mock.AnythingOfType("[]uint8"),
+		mock.AnythingOfType("model.PluginKVSetOptions"),
+	).Run(func(args mock.Arguments) {
+		require.NoError(t, json.Unmarshal(args.Get(1).([]uint8), &saved))
+	}).Return(true, nil).Once()
+
+	err := kvstore.SaveExecutionCheckpoint(cp)
+	require.NoError(t, err)
+	assert.Equal(t, cp.LegalHoldID, saved.LegalHoldID)
+	assert.Equal(t, cp.ChannelIDs, saved.ChannelIDs)
+	assert.NotZero(t, saved.UpdateAt)
+}
+
+func TestKVStore_GetExecutionCheckpoint(t *testing.T) {
+	api := &plugintest.API{}
+	driver := &plugintest.Driver{}
+	client := pluginapi.NewClient(api, driver)
+
+	kvstore := NewKVStore(client)
+
+	cp := model.ExecutionCheckpoint{
+		LegalHoldID:        mattermostModel.NewId(),
+		ExecutionStartTime: 100,
+		ExecutionEndTime:   200,
+		Cursors: map[string]model.LegalHoldCursor{
+			"channel1": {LastPostCreateAt: 150, LastPostID: "post1", BatchNumber: 1, Completed: true},
+		},
+	}
+	marshaled, err := json.Marshal(cp)
+	require.NoError(t, err)
+
+	api.On("KVGet", fmt.Sprintf("%s%s", executionCheckpointPrefix, cp.LegalHoldID)).
+		Return(marshaled, nil)
+
+	result, err := kvstore.GetExecutionCheckpoint(cp.LegalHoldID)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, cp, *result)
+
+	// A legal hold without a checkpoint returns nil.
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+
+	result, err = kvstore.GetExecutionCheckpoint(mattermostModel.NewId())
+	require.NoError(t, err)
+	require.Nil(t, result)
+}
+
+func TestKVStore_DeleteExecutionCheckpoint(t *testing.T) {
+	api := &plugintest.API{}
+	driver := &plugintest.Driver{}
+	client := pluginapi.NewClient(api, driver)
+
+	kvstore := NewKVStore(client)
+
+	lhID := mattermostModel.NewId()
+	api.On("KVSetWithOptions",
+		fmt.Sprintf("%s%s", executionCheckpointPrefix, lhID),
+		mock.AnythingOfType("[]uint8"),
+		mock.AnythingOfType("model.PluginKVSetOptions"),
+	).Return(true, nil).Once()
+
+	err := kvstore.DeleteExecutionCheckpoint(lhID)
+	require.NoError(t, err)
+}
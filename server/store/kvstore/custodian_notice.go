@@ -0,0 +1,76 @@
+package kvstore
+
+import (
+	"fmt"
+	"sort"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+// custodianNoticePrefix prefixes the keys of custodian notices.
+const custodianNoticePrefix = "kvstore_custodian_notice_"
+
+func custodianNoticesPrefix(legalHoldID string) string {
+	return fmt.Sprintf("%s%s_", custodianNoticePrefix, legalHoldID)
+}
+
+func (kvs Impl) SaveCustodianNotice(notice model.CustodianNotice) error {
+	key := custodianNoticesPrefix(notice.LegalHoldID) + notice.UserID
+	if _, err := kvs.client.KV.Set(key, notice); err != nil {
+		return errors.Wrap(err, "could not save custodian notice")
+	}
+
+	return nil
+}
+
+// GetCustodianNotice returns the notice sent to the custodian indicated by userID of the legal hold
+// indicated by legalHoldID, or nil if the custodian has not been sent one.
+func (kvs Impl) GetCustodianNotice(legalHoldID, userID string) (*model.CustodianNotice, error) {
+	var notice model.CustodianNotice
+	if err := kvs.client.KV.Get(custodianNoticesPrefix(legalHoldID)+userID, &notice); err != nil {
+		return nil, errors.Wrap(err, "could not get custodian notice")
+	}
+
+	if notice.UserID == "" {
+		return nil, nil
+	}
+
+	return &notice, nil
+}
+
+// GetCustodianNotices returns the notices sent to the custodians of the legal hold indicated by
+// legalHoldID, ordered from the oldest to the most recent.
+func (kvs Impl) GetCustodianNotices(legalHoldID string) ([]model.CustodianNotice, error) {
+	keys, err := kvs.client.KV.ListKeys(
+		0, 1000000000,
+		pluginapi.WithPrefix(custodianNoticesPrefix(legalHoldID)))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get custodian notices")
+	}
+
+	notices := make([]model.CustodianNotice, 0, len(keys))
+	for _, key := range keys {
+		var notice model.CustodianNotice
+		if err = kvs.client.KV.Get(key, &notice); err != nil {
+			return nil, errors.Wrap(err, "could not get custodian notices")
+		}
+		notices = append(notices, notice)
+	}
+
+	sort.SliceStable(notices, func(i, j int) bool {
+		return notices[i].NotifiedAt < notices[j].NotifiedAt
+	})
+
+	return notices, nil
+}
+
+func (kvs Impl) DeleteCustodianNotice(legalHoldID, userID string) error {
+	if err := kvs.client.KV.Delete(custodianNoticesPrefix(legalHoldID) + userID); err != nil {
+		return errors.Wrap(err, "could not delete custodian notice")
+	}
+
+	return nil
+}
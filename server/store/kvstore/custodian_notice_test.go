@@ -0,0 +1,51 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"testing"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+func TestKVStore_GetCustodianNotices(t *testing.T) {
+	api := &plugintest.API{}
+	driver := &plugintest.Driver{}
+	client := pluginapi.NewClient(api, driver)
+
+	kvstore := NewKVStore(client)
+
+	legalHoldID := mattermostModel.NewId()
+	notices := []model.CustodianNotice{
+		model.NewCustodianNotice(legalHoldID, mattermostModel.NewId(), mattermostModel.NewId(), 300),
+		model.NewCustodianNotice(legalHoldID, mattermostModel.NewId(), mattermostModel.NewId(), 100),
+	}
+
+	keys := make([]string, 0, len(notices))
+	for _, notice := range notices {
+		key := custodianNoticesPrefix(legalHoldID) + notice.UserID
+		keys = append(keys, key)
+
+		marshaled, err := json.Marshal(notice)
+		require.NoError(t, err)
+		api.On("KVGet", key).Return(marshaled, nil)
+	}
+	api.On("KVList", 0, 1000000000).Return(append(keys, "kvstore_legal_hold_other", downloadTokensPrefix(legalHoldID)+"other"), nil)
+	api.On("KVGet", custodianNoticesPrefix(legalHoldID)+"unknown").Return(nil, nil)
+
+	result, err := kvstore.GetCustodianNotices(legalHoldID)
+	require.NoError(t, err)
+	require.Equal(t, []model.CustodianNotice{notices[1], notices[0]}, result)
+
+	notice, err := kvstore.GetCustodianNotice(legalHoldID, notices[0].UserID)
+	require.NoError(t, err)
+	require.Equal(t, &notices[0], notice)
+
+	notice, err = kvstore.GetCustodianNotice(legalHoldID, "unknown")
+	require.NoError(t, err)
+	require.Nil(t, notice)
+}
@@ -0,0 +1,76 @@
+package kvstore
+
+import (
+	"fmt"
+	"sort"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+// downloadTokenPrefix prefixes the keys of download tokens.
+const downloadTokenPrefix = "kvstore_download_token_"
+
+func downloadTokensPrefix(legalHoldID string) string {
+	return fmt.Sprintf("%s%s_", downloadTokenPrefix, legalHoldID)
+}
+
+func (kvs Impl) SaveDownloadToken(token model.DownloadToken) error {
+	key := downloadTokensPrefix(token.LegalHoldID) + token.ID
+	if _, err := kvs.client.KV.Set(key, token); err != nil {
+		return errors.Wrap(err, "could not save download token")
+	}
+
+	return nil
+}
+
+// GetDownloadToken returns the download token indicated by id of the legal hold indicated by
+// legalHoldID, or nil if there is no such download token.
+func (kvs Impl) GetDownloadToken(legalHoldID, id string) (*model.DownloadToken, error) {
+	var token model.DownloadToken
+	if err := kvs.client.KV.Get(downloadTokensPrefix(legalHoldID)+id, &token); err != nil {
+		return nil, errors.Wrap(err, "could not get download token")
+	}
+
+	if token.ID == "" {
+		return nil, nil
+	}
+
+	return &token, nil
+}
+
+// GetDownloadTokens returns all the download tokens of the legal hold indicated by legalHoldID,
+// ordered from the oldest to the most recent.
+func (kvs Impl) GetDownloadTokens(legalHoldID string) ([]model.DownloadToken, error) {
+	keys, err := kvs.client.KV.ListKeys(
+		0, 1000000000,
+		pluginapi.WithPrefix(downloadTokensPrefix(legalHoldID)))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get download tokens")
+	}
+
+	tokens := make([]model.DownloadToken, 0, len(keys))
+	for _, key := range keys {
+		var token model.DownloadToken
+		if err = kvs.client.KV.Get(key, &token); err != nil {
+			return nil, errors.Wrap(err, "could not get download tokens")
+		}
+		tokens = append(tokens, token)
+	}
+
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].CreateAt < tokens[j].CreateAt
+	})
+
+	return tokens, nil
+}
+
+func (kvs Impl) DeleteDownloadToken(legalHoldID, id string) error {
+	if err := kvs.client.KV.Delete(downloadTokensPrefix(legalHoldID) + id); err != nil {
+		return errors.Wrap(err, "could not delete download token")
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"testing"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+func TestKVStore_GetDownloadTokens(t *testing.T) {
+	api := &plugintest.API{}
+	driver := &plugintest.Driver{}
+	client := pluginapi.NewClient(api, driver)
+
+	kvstore := NewKVStore(client)
+
+	legalHoldID := mattermostModel.NewId()
+	tokens := []model.DownloadToken{
+		{ID: mattermostModel.NewId(), LegalHoldID: legalHoldID, CreateAt: 300, ExpiresAt: 1000},
+		{ID: mattermostModel.NewId(), LegalHoldID: legalHoldID, CreateAt: 100, ExpiresAt: 1000},
+	}
+
+	keys := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		key := downloadTokensPrefix(legalHoldID) + token.ID
+		keys = append(keys, key)
+
+		marshaled, err := json.Marshal(token)
+		require.NoError(t, err)
+		api.On("KVGet", key).Return(marshaled, nil)
+	}
+	api.On("KVList", 0, 1000000000).Return(append(keys, "kvstore_legal_hold_other", exportPackagesPrefix(legalHoldID)+"other"), nil)
+	api.On("KVGet", downloadTokensPrefix(legalHoldID)+"unknown").Return(nil, nil)
+
+	result, err := kvstore.GetDownloadTokens(legalHoldID)
+	require.NoError(t, err)
+	require.Equal(t, []model.DownloadToken{tokens[1], tokens[0]}, result)
+
+	token, err := kvstore.GetDownloadToken(legalHoldID, tokens[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, &tokens[0], token)
+
+	token, err = kvstore.GetDownloadToken(legalHoldID, "unknown")
+	require.NoError(t, err)
+	require.Nil(t, token)
+}
@@ -0,0 +1,52 @@
+package kvstore
+
+import (
+	"fmt"
+	"sort"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+const executionRecordPrefix = "kvstore_execution_record_"
+
+func executionRecordsPrefix(legalHoldID string) string {
+	return fmt.Sprintf("%s%s_", executionRecordPrefix, legalHoldID)
+}
+
+func (kvs Impl) SaveExecutionRecord(record model.ExecutionRecord) error {
+	key := executionRecordsPrefix(record.LegalHoldID) + record.ID
+	if _, err := kvs.client.KV.Set(key, record); err != nil {
+		return errors.Wrap(err, "could not save execution record")
+	}
+
+	return nil
+}
+
+// GetExecutionRecords returns all the execution records of the legal hold indicated by
+// legalHoldID, ordered from the oldest to the most recent.
+func (kvs Impl) GetExecutionRecords(legalHoldID string) ([]model.ExecutionRecord, error) {
+	keys, err := kvs.client.KV.ListKeys(
+		0, 1000000000,
+		pluginapi.WithPrefix(executionRecordsPrefix(legalHoldID)))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get execution records")
+	}
+
+	records := make([]model.ExecutionRecord, 0, len(keys))
+	for _, key := range keys {
+		var record model.ExecutionRecord
+		if err = kvs.client.KV.Get(key, &record); err != nil {
+			return nil, errors.Wrap(err, "could not get execution records")
+		}
+		records = append(records, record)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].StartedAt < records[j].StartedAt
+	})
+
+	return records, nil
+}
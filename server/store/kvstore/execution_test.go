@@ -0,0 +1,70 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"testing"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+func TestKVStore_SaveExecutionRecord(t *testing.T) {
+	api := &plugintest.API{}
+	driver := &plugintest.Driver{}
+	client := pluginapi.NewClient(api, driver)
+
+	kvstore := NewKVStore(client)
+
+	record := model.ExecutionRecord{
+		ID:          mattermostModel.NewId(),
+		LegalHoldID: mattermostModel.NewId(),
+		PostCount:   10,
+	}
+
+	var saved model.ExecutionRecord
+	api.On("KVSetWithOptions",
+		executionRecordsPrefix(record.LegalHoldID)+record.ID,
+		mock.AnythingOfType("[]uint8"),
+		mock.AnythingOfType("model.PluginKVSetOptions"),
+	).Run(func(args mock.Arguments) {
+		require.NoError(t, json.Unmarshal(args.Get(1).([]uint8), &saved))
+	}).Return(true, nil).Once()
+
+	require.NoError(t, kvstore.SaveExecutionRecord(record))
+	require.Equal(t, record, saved)
+}
+
+func TestKVStore_GetExecutionRecords(t *testing.T) {
+	api := &plugintest.API{}
+	driver := &plugintest.Driver{}
+	client := pluginapi.NewClient(api, driver)
+
+	kvstore := NewKVStore(client)
+
+	legalHoldID := mattermostModel.NewId()
+	records := []model.ExecutionRecord{
+		{ID: mattermostModel.NewId(), LegalHoldID: legalHoldID, StartedAt: 300},
+		{ID: mattermostModel.NewId(), LegalHoldID: legalHoldID, StartedAt: 100, Error: "failed"},
+		{ID: mattermostModel.NewId(), LegalHoldID: legalHoldID, StartedAt: 200},
+	}
+
+	keys := make([]string, 0, len(records))
+	for _, record := range records {
+		key := executionRecordsPrefix(legalHoldID) + record.ID
+		keys = append(keys, key)
+
+		marshaled, err := json.Marshal(record)
+		require.NoError(t, err)
+		api.On("KVGet", key).Return(marshaled, nil)
+	}
+	api.On("KVList", 0, 1000000000).Return(append(keys, "kvstore_legal_hold_other"), nil)
+
+	result, err := kvstore.GetExecutionRecords(legalHoldID)
+	require.NoError(t, err)
+	require.Equal(t, []model.ExecutionRecord{records[1], records[2], records[0]}, result)
+}
@@ -0,0 +1,76 @@
+package kvstore
+
+import (
+	"fmt"
+	"sort"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+// exportPackagePrefix prefixes the keys of export packages.
+const exportPackagePrefix = "kvstore_export_package_"
+
+func exportPackagesPrefix(legalHoldID string) string {
+	return fmt.Sprintf("%s%s_", exportPackagePrefix, legalHoldID)
+}
+
+func (kvs Impl) SaveExportPackage(pkg model.ExportPackage) error {
+	key := exportPackagesPrefix(pkg.LegalHoldID) + pkg.ID
+	if _, err := kvs.client.KV.Set(key, pkg); err != nil {
+		return errors.Wrap(err, "could not save export package")
+	}
+
+	return nil
+}
+
+// GetExportPackage returns the export package indicated by id of the legal hold indicated by
+// legalHoldID, or nil if there is no such export package.
+func (kvs Impl) GetExportPackage(legalHoldID, id string) (*model.ExportPackage, error) {
+	var pkg model.ExportPackage
+	if err := kvs.client.KV.Get(exportPackagesPrefix(legalHoldID)+id, &pkg); err != nil {
+		return nil, errors.Wrap(err, "could not get export package")
+	}
+
+	if pkg.ID == "" {
+		return nil, nil
+	}
+
+	return &pkg, nil
+}
+
+// GetExportPackages returns all the export packages of the legal hold indicated by legalHoldID,
+// ordered from the oldest to the most recent.
+func (kvs Impl) GetExportPackages(legalHoldID string) ([]model.ExportPackage, error) {
+	keys, err := kvs.client.KV.ListKeys(
+		0, 1000000000,
+		pluginapi.WithPrefix(exportPackagesPrefix(legalHoldID)))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get export packages")
+	}
+
+	packages := make([]model.ExportPackage, 0, len(keys))
+	for _, key := range keys {
+		var pkg model.ExportPackage
+		if err = kvs.client.KV.Get(key, &pkg); err != nil {
+			return nil, errors.Wrap(err, "could not get export packages")
+		}
+		packages = append(packages, pkg)
+	}
+
+	sort.SliceStable(packages, func(i, j int) bool {
+		return packages[i].CreateAt < packages[j].CreateAt
+	})
+
+	return packages, nil
+}
+
+func (kvs Impl) DeleteExportPackage(legalHoldID, id string) error {
+	if err := kvs.client.KV.Delete(exportPackagesPrefix(legalHoldID) + id); err != nil {
+		return errors.Wrap(err, "could not delete export package")
+	}
+
+	return nil
+}
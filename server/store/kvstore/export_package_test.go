@@ -0,0 +1,51 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"testing"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+func TestKVStore_GetExportPackages(t *testing.T) {
+	api := &plugintest.API{}
+	driver := &plugintest.Driver{}
+	client := pluginapi.NewClient(api, driver)
+
+	kvstore := NewKVStore(client)
+
+	legalHoldID := mattermostModel.NewId()
+	packages := []model.ExportPackage{
+		model.NewExportPackage(mattermostModel.NewId(), legalHoldID, 300),
+		model.NewExportPackage(mattermostModel.NewId(), legalHoldID, 100),
+	}
+
+	keys := make([]string, 0, len(packages))
+	for _, pkg := range packages {
+		key := exportPackagesPrefix(legalHoldID) + pkg.ID
+		keys = append(keys, key)
+
+		marshaled, err := json.Marshal(pkg)
+		require.NoError(t, err)
+		api.On("KVGet", key).Return(marshaled, nil)
+	}
+	api.On("KVList", 0, 1000000000).Return(append(keys, "kvstore_legal_hold_other"), nil)
+	api.On("KVGet", exportPackagesPrefix(legalHoldID)+"unknown").Return(nil, nil)
+
+	result, err := kvstore.GetExportPackages(legalHoldID)
+	require.NoError(t, err)
+	require.Equal(t, []model.ExportPackage{packages[1], packages[0]}, result)
+
+	pkg, err := kvstore.GetExportPackage(legalHoldID, packages[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, &packages[0], pkg)
+
+	pkg, err = kvstore.GetExportPackage(legalHoldID, "unknown")
+	require.NoError(t, err)
+	require.Nil(t, pkg)
+}
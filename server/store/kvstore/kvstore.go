@@ -8,4 +8,37 @@ type KVStore interface {
 	GetLegalHoldByID(id string) (*model.LegalHold, error)
 	UpdateLegalHold(lh, oldValue model.LegalHold) (*model.LegalHold, error)
 	DeleteLegalHold(id string) error
+
+	SaveLegalHoldTombstone(tombstone model.LegalHoldTombstone) error
+	GetLegalHoldTombstones() ([]model.LegalHoldTombstone, error)
+
+	SaveExecutionCheckpoint(cp model.ExecutionCheckpoint) error
+	GetExecutionCheckpoint(legalHoldID string) (*model.ExecutionCheckpoint, error)
+	DeleteExecutionCheckpoint(legalHoldID string) error
+
+	SaveExecutionRecord(record model.ExecutionRecord) error
+	GetExecutionRecords(legalHoldID string) ([]model.ExecutionRecord, error)
+
+	SavePreservationStatus(status model.PreservationStatus) error
+	GetPreservationStatus() (*model.PreservationStatus, error)
+
+	GetOrCreateSigningKey() (*model.SigningKey, error)
+
+	SaveExportPackage(pkg model.ExportPackage) error
+	GetExportPackage(legalHoldID, id string) (*model.ExportPackage, error)
+	GetExportPackages(legalHoldID string) ([]model.ExportPackage, error)
+	DeleteExportPackage(legalHoldID, id string) error
+
+	SaveDownloadToken(token model.DownloadToken) error
+	GetDownloadToken(legalHoldID, id string) (*model.DownloadToken, error)
+	GetDownloadTokens(legalHoldID string) ([]model.DownloadToken, error)
+	DeleteDownloadToken(legalHoldID, id string) error
+
+	SaveCustodianNotice(notice model.CustodianNotice) error
+	GetCustodianNotice(legalHoldID, userID string) (*model.CustodianNotice, error)
+	GetCustodianNotices(legalHoldID string) ([]model.CustodianNotice, error)
+	DeleteCustodianNotice(legalHoldID, userID string) error
+
+	SaveAuditEvent(event model.AuditEvent) error
+	GetAuditEvents(query model.AuditEventQuery) (*model.AuditEventPage, error)
 }
@@ -9,8 +9,12 @@ import (
 	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
 
 	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/utils"
 )
 
+// legalHoldPrefix prefixes the keys of legal holds. The keys of the other records of the plugin
+// must not start with it, otherwise those records would be listed as legal holds by
+// GetAllLegalHolds.
 const (
 	legalHoldPrefix = "kvstore_legal_hold_"
 )
@@ -25,26 +29,6 @@ func NewKVStore(client *pluginapi.Client) KVStore {
 	}
 }
 
-// unorderedEqualSet compares two slices of user IDs regardless of order
-func unorderedEqualSet[T comparable](a, b []T) bool {
-	if len(a) != len(b) {
-		return false
-	}
-
-	setA := make(map[T]bool, len(a))
-	for _, v := range a {
-		setA[v] = true
-	}
-
-	for _, v := range b {
-		if !setA[v] {
-			return false
-		}
-	}
-
-	return true
-}
-
 func (kvs Impl) CreateLegalHold(lh model.LegalHold) (*model.LegalHold, error) {
 	// Check for duplicates by name, dates, and participants
 	existingLegalHolds, err := kvs.GetAllLegalHolds()
@@ -61,8 +45,11 @@ func (kvs Impl) CreateLegalHold(lh model.LegalHold) (*model.LegalHold, error) {
 		if existing.StartsAt == lh.StartsAt &&
 			existing.EndsAt == lh.EndsAt &&
 			existing.IncludePublicChannels == lh.IncludePublicChannels &&
-			unorderedEqualSet(existing.GroupIDs, lh.GroupIDs) &&
-			unorderedEqualSet(existing.UserIDs, lh.UserIDs) {
+			utils.UnorderedEqualSet(existing.GroupIDs, lh.GroupIDs) &&
+			utils.UnorderedEqualSet(existing.UserIDs, lh.UserIDs) &&
+			utils.UnorderedEqualSet(existing.ChannelIDs, lh.ChannelIDs) &&
+			utils.UnorderedEqualSet(existing.TeamIDs, lh.TeamIDs) &&
+			existing.Filter.Equals(lh.Filter) {
 			return nil, errors.New("could not create legal hold as a legal hold with the same participants, dates, and settings already exists")
 		}
 	}
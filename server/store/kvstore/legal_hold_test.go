@@ -450,29 +450,6 @@ func TestKVStore_CreateLegalHold_DifferentDates(t *testing.T) {
 	assert.Equal(t, newLH.Name, result.Name)
 }
 
-func TestUnorderedEqualSet(t *testing.T) {
-	// Test with identical slices
-	assert.True(t, unorderedEqualSet([]string{"a", "b", "c"}, []string{"a", "b", "c"}))
-
-	// Test with different order
-	assert.True(t, unorderedEqualSet([]string{"a", "b", "c"}, []string{"c", "a", "b"}))
-
-	// Test with different lengths
-	assert.False(t, unorderedEqualSet([]string{"a", "b"}, []string{"a", "b", "c"}))
-
-	// Test with different elements
-	assert.False(t, unorderedEqualSet([]string{"a", "b", "c"}, []string{"a", "b", "d"}))
-
-	// Test with empty slices
-	assert.True(t, unorderedEqualSet([]string{}, []string{}))
-
-	// Test with one empty slice
-	assert.False(t, unorderedEqualSet([]string{"a"}, []string{}))
-
-	// Test with duplicates
-	assert.True(t, unorderedEqualSet([]string{"a", "a", "b"}, []string{"b", "a", "a"}))
-}
-
 func TestKVStore_CreateLegalHold_WithGroupIDs(t *testing.T) {
 	api := &plugintest.API{}
 	driver := &plugintest.Driver{}
@@ -0,0 +1,33 @@
+package kvstore
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+// preservationStatusKey is the key of the outcome of the latest preservation check.
+const preservationStatusKey = "kvstore_preservation_status"
+
+func (kvs Impl) SavePreservationStatus(status model.PreservationStatus) error {
+	if _, err := kvs.client.KV.Set(preservationStatusKey, status); err != nil {
+		return errors.Wrap(err, "could not save preservation status")
+	}
+
+	return nil
+}
+
+// GetPreservationStatus returns the outcome of the latest preservation check, or nil if no check
+// has run yet.
+func (kvs Impl) GetPreservationStatus() (*model.PreservationStatus, error) {
+	var status model.PreservationStatus
+	if err := kvs.client.KV.Get(preservationStatusKey, &status); err != nil {
+		return nil, errors.Wrap(err, "could not get preservation status")
+	}
+
+	if status.Mode == "" {
+		return nil, nil
+	}
+
+	return &status, nil
+}
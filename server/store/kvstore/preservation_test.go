@@ -0,0 +1,61 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"testing"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+func TestKVStore_PreservationStatus(t *testing.T) {
+	api := &plugintest.API{}
+	driver := &plugintest.Driver{}
+	client := pluginapi.NewClient(api, driver)
+
+	kvstore := NewKVStore(client)
+
+	// No status is returned before the first check.
+	api.On("KVGet", preservationStatusKey).Return(nil, nil).Once()
+
+	result, err := kvstore.GetPreservationStatus()
+	require.NoError(t, err)
+	require.Nil(t, result)
+
+	status := model.PreservationStatus{
+		Mode:      model.PreservationModeWarn,
+		CheckedAt: 1000,
+		Conflicts: []model.RetentionConflict{
+			{LegalHoldID: "lh1", PolicyID: model.GlobalRetentionPolicyID, Content: model.RetentionContentPosts, RetentionDays: 1},
+		},
+	}
+
+	var saved []byte
+	api.On("KVSetWithOptions",
+		preservationStatusKey,
+		mock.AnythingOfType("[]uint8"),
+		mock.AnythingOfType("model.PluginKVSetOptions"),
+	).Run(func(args mock.Arguments) {
+		saved = args.Get(1).([]uint8)
+	}).Return(true, nil).Once()
+
+	err = kvstore.SavePreservationStatus(status)
+	require.NoError(t, err)
+
+	var decoded model.PreservationStatus
+	require.NoError(t, json.Unmarshal(saved, &decoded))
+	assert.Equal(t, status.Conflicts, decoded.Conflicts)
+
+	api.On("KVGet", preservationStatusKey).Return(saved, nil).Once()
+
+	result, err = kvstore.GetPreservationStatus()
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, model.PreservationModeWarn, result.Mode)
+	assert.Equal(t, status.Conflicts, result.Conflicts)
+}
@@ -0,0 +1,45 @@
+package kvstore
+
+import (
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+// signingKeyKey is the key of the signing key of the installation.
+const signingKeyKey = "kvstore_signing_key"
+
+// GetOrCreateSigningKey returns the SigningKey of the installation, generating it the first time.
+// Concurrent callers across the cluster all get the same key.
+func (kvs Impl) GetOrCreateSigningKey() (*model.SigningKey, error) {
+	var key model.SigningKey
+	if err := kvs.client.KV.Get(signingKeyKey, &key); err != nil {
+		return nil, errors.Wrap(err, "could not get signing key")
+	}
+
+	if len(key.PrivateKey) > 0 {
+		return &key, nil
+	}
+
+	newKey, err := model.NewSigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := kvs.client.KV.Set(signingKeyKey, newKey, pluginapi.SetAtomic(nil))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not save signing key")
+	}
+
+	if saved {
+		return newKey, nil
+	}
+
+	// Another node created the key first.
+	if err = kvs.client.KV.Get(signingKeyKey, &key); err != nil {
+		return nil, errors.Wrap(err, "could not get signing key")
+	}
+
+	return &key, nil
+}
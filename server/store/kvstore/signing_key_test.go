@@ -0,0 +1,77 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"testing"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+func TestKVStore_GetOrCreateSigningKey(t *testing.T) {
+	t.Run("creates the key the first time", func(t *testing.T) {
+		api := &plugintest.API{}
+		client := pluginapi.NewClient(api, &plugintest.Driver{})
+		kvstore := NewKVStore(client)
+
+		api.On("KVGet", signingKeyKey).Return(nil, nil).Once()
+		api.On("KVSetWithOptions",
+			signingKeyKey,
+			mock.AnythingOfType("[]uint8"),
+			mock.AnythingOfType("model.PluginKVSetOptions"),
+		).Return(true, nil).Once()
+
+		key, err := kvstore.GetOrCreateSigningKey()
+		require.NoError(t, err)
+		require.NotNil(t, key)
+		assert.Len(t, key.PrivateKey, 64)
+		api.AssertExpectations(t)
+	})
+
+	t.Run("returns the existing key", func(t *testing.T) {
+		api := &plugintest.API{}
+		client := pluginapi.NewClient(api, &plugintest.Driver{})
+		kvstore := NewKVStore(client)
+
+		existing, err := model.NewSigningKey()
+		require.NoError(t, err)
+		marshaled, err := json.Marshal(existing)
+		require.NoError(t, err)
+
+		api.On("KVGet", signingKeyKey).Return(marshaled, nil).Once()
+
+		key, err := kvstore.GetOrCreateSigningKey()
+		require.NoError(t, err)
+		assert.Equal(t, existing.PrivateKey, key.PrivateKey)
+		api.AssertExpectations(t)
+	})
+
+	t.Run("returns the key created concurrently by another node", func(t *testing.T) {
+		api := &plugintest.API{}
+		client := pluginapi.NewClient(api, &plugintest.Driver{})
+		kvstore := NewKVStore(client)
+
+		existing, err := model.NewSigningKey()
+		require.NoError(t, err)
+		marshaled, err := json.Marshal(existing)
+		require.NoError(t, err)
+
+		api.On("KVGet", signingKeyKey).Return(nil, nil).Once()
+		api.On("KVSetWithOptions",
+			signingKeyKey,
+			mock.AnythingOfType("[]uint8"),
+			mock.AnythingOfType("model.PluginKVSetOptions"),
+		).Return(false, nil).Once()
+		api.On("KVGet", signingKeyKey).Return(marshaled, nil).Once()
+
+		key, err := kvstore.GetOrCreateSigningKey()
+		require.NoError(t, err)
+		assert.Equal(t, existing.PrivateKey, key.PrivateKey)
+		api.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,47 @@
+package kvstore
+
+import (
+	"sort"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+// tombstonePrefix prefixes the keys of legal hold tombstones.
+const tombstonePrefix = "kvstore_tombstone_"
+
+func (kvs Impl) SaveLegalHoldTombstone(tombstone model.LegalHoldTombstone) error {
+	if _, err := kvs.client.KV.Set(tombstonePrefix+tombstone.LegalHold.ID, tombstone); err != nil {
+		return errors.Wrap(err, "could not save legal hold tombstone")
+	}
+
+	return nil
+}
+
+// GetLegalHoldTombstones returns the tombstones of all the purged legal holds, ordered from the
+// oldest purge to the most recent.
+func (kvs Impl) GetLegalHoldTombstones() ([]model.LegalHoldTombstone, error) {
+	keys, err := kvs.client.KV.ListKeys(
+		0, 1000000000,
+		pluginapi.WithPrefix(tombstonePrefix))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get legal hold tombstones")
+	}
+
+	tombstones := make([]model.LegalHoldTombstone, 0, len(keys))
+	for _, key := range keys {
+		var tombstone model.LegalHoldTombstone
+		if err = kvs.client.KV.Get(key, &tombstone); err != nil {
+			return nil, errors.Wrap(err, "could not get legal hold tombstones")
+		}
+		tombstones = append(tombstones, tombstone)
+	}
+
+	sort.SliceStable(tombstones, func(i, j int) bool {
+		return tombstones[i].PurgedAt < tombstones[j].PurgedAt
+	})
+
+	return tombstones, nil
+}
@@ -0,0 +1,41 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"testing"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	mattermostModel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+func TestKVStore_GetLegalHoldTombstones(t *testing.T) {
+	api := &plugintest.API{}
+	driver := &plugintest.Driver{}
+	client := pluginapi.NewClient(api, driver)
+
+	kvstore := NewKVStore(client)
+
+	tombstones := []model.LegalHoldTombstone{
+		{LegalHold: model.LegalHold{ID: mattermostModel.NewId(), ReleasedAt: 100}, PurgedAt: 300},
+		{LegalHold: model.LegalHold{ID: mattermostModel.NewId(), ReleasedAt: 100}, PurgedAt: 200},
+	}
+
+	keys := make([]string, 0, len(tombstones))
+	for _, tombstone := range tombstones {
+		key := tombstonePrefix + tombstone.LegalHold.ID
+		keys = append(keys, key)
+
+		marshaled, err := json.Marshal(tombstone)
+		require.NoError(t, err)
+		api.On("KVGet", key).Return(marshaled, nil)
+	}
+	api.On("KVList", 0, 1000000000).Return(append(keys, "kvstore_legal_hold_other"), nil)
+
+	result, err := kvstore.GetLegalHoldTombstones()
+	require.NoError(t, err)
+	require.Equal(t, []model.LegalHoldTombstone{tombstones[1], tombstones[0]}, result)
+}
@@ -1,6 +1,9 @@
 package sqlstore
 
 import (
+	"context"
+	"strings"
+
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
@@ -11,17 +14,165 @@ import (
 )
 
 // GetPostsBatch fetches a batch of posts from the channel specified by channelID for a legal
-// hold export, using the cursor, endTime and limit parameters to track batching state.
+// hold export, using the cursor, endTime and limit parameters to track batching state. Only posts
+// matching the filter, if provided, are returned. The query is abandoned if ctx is cancelled.
 // This method was originally based on Mattermost Server's ComplianceStore.ComplianceExport()
 // function but considerably simplified to suit the LegalHold use case better.
-func (ss SQLStore) GetPostsBatch(channelID string, endTime int64, cursor model.LegalHoldCursor, limit int) ([]model.LegalHoldPost, model.LegalHoldCursor, error) {
+func (ss SQLStore) GetPostsBatch(ctx context.Context, channelID string, endTime int64, filter *model.LegalHoldFilter, cursor model.LegalHoldCursor, limit int) ([]model.LegalHoldPost, model.LegalHoldCursor, error) {
 	var posts []model.LegalHoldPost
 
+	filterClause, filterArgs := postsFilterClause(filter)
+
 	var args []any
 	// append the named parameters of SQL query in the correct order to args
 	args = append(args, cursor.LastPostCreateAt, cursor.LastPostCreateAt, cursor.LastPostID, endTime)
-	args = append(args, channelID, limit)
+	args = append(args, channelID)
+	args = append(args, filterArgs...)
+	args = append(args, limit)
+
+	query := ss.postsSelect("") + `
+		WHERE
+		 	(
+				Posts.CreateAt > ?
+				OR (Posts.CreateAt = ? AND Posts.ID > ?)
+			)
+				AND Posts.CreateAt < ?
+			AND Channels.ID = ?
+			` + filterClause + `
+		ORDER BY Posts.CreateAt, Posts.ID
+		LIMIT ?
+	`
+
+	query = ss.replica.Rebind(query)
+
+	if err := ss.replica.SelectContext(ctx, &posts, query, args...); err != nil {
+		return nil, cursor, errors.Wrap(err, "unable to get posts batch for legal hold")
+	}
+
+	if len(posts) < limit {
+		cursor.Completed = true
+	} else {
+		cursor.LastPostCreateAt = posts[len(posts)-1].PostCreateAt
+		cursor.LastPostID = posts[len(posts)-1].PostID
+	}
+
+	cursor.BatchNumber++
+
+	return posts, cursor, nil
+}
+
+// GetPostRevisionsBatch fetches a batch of the posts from the channel specified by channelID that
+// were updated (edited or deleted) from startTime up to endTime but created before startTime, and
+// so were not exported along with the posts of that window. This includes the previous versions
+// Mattermost keeps of edited posts. The cursor, created with model.NewLegalHoldRevisionCursor, and
+// limit parameters track the batching state. If a filter is provided, only the revisions of posts
+// that matched it in any of their versions are returned, so that a post is held whole even if an
+// edit added or removed the terms it matched on.
+func (ss SQLStore) GetPostRevisionsBatch(ctx context.Context, channelID string, startTime, endTime int64, filter *model.LegalHoldFilter, cursor model.LegalHoldCursor, limit int) ([]model.LegalHoldPostRevision, model.LegalHoldCursor, error) {
+	var revisions []model.LegalHoldPostRevision
+
+	filterClause, filterArgs := revisionsFilterClause(filter)
+
+	var args []any
+	// append the named parameters of SQL query in the correct order to args
+	args = append(args, cursor.LastPostUpdateAt, cursor.LastPostUpdateAt, cursor.LastPostID, endTime, startTime)
+	args = append(args, channelID)
+	args = append(args, filterArgs...)
+	args = append(args, limit)
+
+	query := ss.postsSelect(`,
+			Posts.EditAt AS PostEditAt`) + `
+		WHERE
+			(
+				Posts.UpdateAt > ?
+				OR (Posts.UpdateAt = ? AND Posts.ID > ?)
+			)
+			AND Posts.UpdateAt < ?
+			AND Posts.CreateAt < ?
+			AND Channels.ID = ?
+			` + filterClause + `
+		ORDER BY Posts.UpdateAt, Posts.ID
+		LIMIT ?
+	`
+
+	query = ss.replica.Rebind(query)
+
+	if err := ss.replica.SelectContext(ctx, &revisions, query, args...); err != nil {
+		return nil, cursor, errors.Wrap(err, "unable to get post revisions batch for legal hold")
+	}
 
+	for i := range revisions {
+		revisions[i].SetRevision()
+	}
+
+	if len(revisions) < limit {
+		cursor.Completed = true
+	} else {
+		cursor.LastPostUpdateAt = revisions[len(revisions)-1].PostUpdateAt
+		cursor.LastPostID = revisions[len(revisions)-1].PostID
+	}
+
+	cursor.BatchNumber++
+
+	return revisions, cursor, nil
+}
+
+// CountPostsAndFiles counts the posts of the channels indicated by channelIDs created from
+// startTime up to endTime that match the filter, if provided, and the file attachments of those
+// posts.
+func (ss SQLStore) CountPostsAndFiles(ctx context.Context, channelIDs []string, startTime, endTime int64, filter *model.LegalHoldFilter) (int64, int64, error) {
+	if len(channelIDs) == 0 {
+		return 0, 0, nil
+	}
+
+	filterClause, filterArgs := postsFilterClause(filter)
+
+	var args []any
+	args = append(args, channelIDs, startTime, endTime)
+	args = append(args, filterArgs...)
+
+	where := `
+		WHERE
+			Posts.ChannelId IN (?)
+			AND Posts.CreateAt >= ?
+			AND Posts.CreateAt < ?
+			` + filterClause
+
+	postsQuery := `
+		SELECT COUNT(*)
+		FROM
+			Posts
+		JOIN
+			Channels ON Channels.ID = Posts.ChannelId` + where
+
+	filesQuery := `
+		SELECT COUNT(*)
+		FROM
+			FileInfo
+		JOIN
+			Posts ON Posts.ID = FileInfo.PostId
+		JOIN
+			Channels ON Channels.ID = Posts.ChannelId` + where
+
+	var counts [2]int64
+	for i, query := range []string{postsQuery, filesQuery} {
+		query, queryArgs, err := sqlx.In(query, args...)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "unable to count posts and files for legal hold")
+		}
+		query = ss.replica.Rebind(query)
+
+		if err = ss.replica.GetContext(ctx, &counts[i], query, queryArgs...); err != nil {
+			return 0, 0, errors.Wrap(err, "unable to count posts and files for legal hold")
+		}
+	}
+
+	return counts[0], counts[1], nil
+}
+
+// postsSelect returns the SELECT and FROM clauses of the queries exporting posts for a legal
+// hold, selecting the columns of model.LegalHoldPost followed by any extraColumns.
+func (ss SQLStore) postsSelect(extraColumns string) string {
 	dmDisplayName := `
 						(select Users.Username from Users where Users.Id = split_part(Channels.Name, '__', 1))
 							|| ', ' ||
@@ -38,7 +189,7 @@ func (ss SQLStore) GetPostsBatch(channelID string, endTime int64, cursor model.L
 						`
 	}
 
-	query := `
+	return `
 		SELECT
 			COALESCE(Teams.Name, 'direct-messages') AS TeamName,
 			COALESCE(Teams.DisplayName, 'Direct Messages') AS TeamDisplayName,
@@ -67,7 +218,8 @@ func (ss SQLStore) GetPostsBatch(channelID string, endTime int64, cursor model.L
 			Posts.Props AS PostProps,
 			Posts.Hashtags AS PostHashtags,
 			Posts.FileIds AS PostFileIDs,
-			Bots.UserId IS NOT NULL AS IsBot
+			Posts.IsPinned AS PostIsPinned,
+			Bots.UserId IS NOT NULL AS IsBot` + extraColumns + `
 		FROM
 			Posts
 		JOIN
@@ -78,33 +230,82 @@ func (ss SQLStore) GetPostsBatch(channelID string, endTime int64, cursor model.L
 			Bots ON Bots.UserId = Posts.UserId
 		LEFT OUTER JOIN
 			Teams ON Teams.ID = Channels.TeamId
-		WHERE
-		 	(
-				Posts.CreateAt > ?
-				OR (Posts.CreateAt = ? AND Posts.ID > ?)
-			)
-				AND Posts.CreateAt < ?
-			AND Channels.ID = ?
-		ORDER BY Posts.CreateAt, Posts.ID
-		LIMIT ?
 	`
+}
 
-	query = ss.replica.Rebind(query)
+// postsFilterClause builds the additional SQL conditions needed by GetPostsBatch to restrict the
+// posts to those matching the filter, along with the arguments for those conditions.
+func postsFilterClause(filter *model.LegalHoldFilter) (string, []any) {
+	return filterClause(filter, false)
+}
 
-	if err := ss.replica.Select(&posts, query, args...); err != nil {
-		return nil, cursor, errors.Wrap(err, "unable to get posts batch for legal hold")
+// revisionsFilterClause builds the additional SQL conditions needed by GetPostRevisionsBatch to
+// restrict the revisions to those of posts that matched the filter in any of their versions: the
+// post itself, or the previous versions Mattermost keeps of it, which point to it by OriginalId.
+func revisionsFilterClause(filter *model.LegalHoldFilter) (string, []any) {
+	return filterClause(filter, true)
+}
+
+// filterClause builds the SQL conditions restricting posts to those matching the filter. If
+// anyVersion is true, the terms and hashtags of the filter are matched against every version of
+// each post rather than the post itself.
+func filterClause(filter *model.LegalHoldFilter, anyVersion bool) (string, []any) {
+	if filter.IsEmpty() {
+		return "", nil
 	}
 
-	if len(posts) < limit {
-		cursor.Completed = true
-	} else {
-		cursor.LastPostCreateAt = posts[len(posts)-1].PostCreateAt
-		cursor.LastPostID = posts[len(posts)-1].PostID
+	var conditions []string
+	var args []any
+
+	table := "Posts"
+	if anyVersion {
+		table = "Versions"
 	}
 
-	cursor.BatchNumber++
+	var matchConditions []string
+	for _, term := range filter.Terms {
+		matchConditions = append(matchConditions, "LOWER("+table+".Message) LIKE ?")
+		args = append(args, "%"+strings.ToLower(sanitizeSearchTerm(term))+"%")
+	}
 
-	return posts, cursor, nil
+	for _, hashtag := range filter.Hashtags {
+		// Hashtags are stored space separated, so pad them to only match whole hashtags.
+		matchConditions = append(matchConditions, "CONCAT(' ', LOWER("+table+".Hashtags), ' ') LIKE ?")
+		args = append(args, "% "+strings.ToLower(sanitizeSearchTerm(hashtag))+" %")
+	}
+
+	if len(matchConditions) > 0 {
+		matchClause := "(" + strings.Join(matchConditions, " OR ") + ")"
+		if anyVersion {
+			// The current version of a post is the one without an OriginalId.
+			matchClause = `EXISTS (
+				SELECT 1 FROM Posts AS Versions
+				WHERE
+					(
+						Versions.ID = COALESCE(NULLIF(Posts.OriginalId, ''), Posts.ID)
+						OR Versions.OriginalId = COALESCE(NULLIF(Posts.OriginalId, ''), Posts.ID)
+					)
+					AND ` + matchClause + `
+			)`
+		}
+		conditions = append(conditions, matchClause)
+	}
+
+	if len(filter.IncludeChannelTypes) > 0 {
+		conditions = append(conditions, "Channels.Type IN ("+sq.Placeholders(len(filter.IncludeChannelTypes))+")")
+		for _, channelType := range filter.IncludeChannelTypes {
+			args = append(args, string(channelType))
+		}
+	}
+
+	if len(filter.ExcludeChannelTypes) > 0 {
+		conditions = append(conditions, "Channels.Type NOT IN ("+sq.Placeholders(len(filter.ExcludeChannelTypes))+")")
+		for _, channelType := range filter.ExcludeChannelTypes {
+			args = append(args, string(channelType))
+		}
+	}
+
+	return "AND " + strings.Join(conditions, " AND "), args
 }
 
 // GetChannelIDsForUserDuring gets the channel IDs for all channels that the user indicated by userID is
@@ -148,8 +349,73 @@ func (ss SQLStore) GetChannelIDsForUserDuring(userID string, startTime int64, en
 	return channelIDs, nil
 }
 
+// GetChannelIDsForTeamsDuring gets the channel IDs for all channels belonging to the teams
+// indicated by teamIDs that existed at some point during the time period from (and including) the
+// startTime up until (but not including) the endTime.
+func (ss SQLStore) GetChannelIDsForTeamsDuring(teamIDs []string, startTime int64, endTime int64) ([]string, error) {
+	if len(teamIDs) == 0 {
+		return []string{}, nil
+	}
+
+	query := ss.replicaBuilder.
+		Select("Channels.Id").
+		From("Channels").
+		Where(sq.Eq{"Channels.TeamId": teamIDs}).
+		Where(sq.Lt{"Channels.CreateAt": endTime}).
+		Where(sq.Or{sq.Eq{"Channels.DeleteAt": 0}, sq.GtOrEq{"Channels.DeleteAt": startTime}})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return []string{}, errors.Wrap(err, "unable to get sql for GetChannelIDsForTeamsDuring")
+	}
+
+	var channelIDs []string
+	if err = ss.replica.Select(&channelIDs, sql, args...); err != nil {
+		return []string{}, errors.Wrap(err, "unable to run query for GetChannelIDsForTeamsDuring")
+	}
+
+	return channelIDs, nil
+}
+
+// GetChannelMembersDuring gets the ChannelMemberHistory records for all users that were members
+// of the channels indicated by channelIDs at some point during the time period from (and including)
+// the startTime up until (but not including) the endTime.
+func (ss SQLStore) GetChannelMembersDuring(channelIDs []string, startTime int64, endTime int64) ([]model.ChannelMemberHistoryEntry, error) {
+	if len(channelIDs) == 0 {
+		return []model.ChannelMemberHistoryEntry{}, nil
+	}
+
+	query := ss.replicaBuilder.
+		Select(
+			"cmh.ChannelId AS ChannelID",
+			"cmh.UserId AS UserID",
+			"Users.Username AS Username",
+			"Users.Email AS Email",
+			"cmh.JoinTime AS JoinTime",
+			"cmh.LeaveTime AS LeaveTime",
+		).
+		From("ChannelMemberHistory AS cmh").
+		Join("Users ON Users.Id = cmh.UserId").
+		Where(sq.Eq{"cmh.ChannelId": channelIDs}).
+		Where(sq.Lt{"cmh.JoinTime": endTime}).
+		Where(sq.Or{sq.Eq{"cmh.LeaveTime": nil}, sq.GtOrEq{"cmh.LeaveTime": startTime}}).
+		OrderBy("cmh.ChannelId", "cmh.JoinTime")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return []model.ChannelMemberHistoryEntry{}, errors.Wrap(err, "unable to get sql for GetChannelMembersDuring")
+	}
+
+	var entries []model.ChannelMemberHistoryEntry
+	if err = ss.replica.Select(&entries, sql, args...); err != nil {
+		return []model.ChannelMemberHistoryEntry{}, errors.Wrap(err, "unable to run query for GetChannelMembersDuring")
+	}
+
+	return entries, nil
+}
+
 // GetFileInfosByIDs gets the file infos corresponding to the provided ids.
-func (ss SQLStore) GetFileInfosByIDs(ids []string) ([]model.FileInfo, error) {
+func (ss SQLStore) GetFileInfosByIDs(ctx context.Context, ids []string) ([]model.FileInfo, error) {
 	query := ss.replicaBuilder.
 		Select(
 			"FileInfo.ID",
@@ -168,7 +434,7 @@ func (ss SQLStore) GetFileInfosByIDs(ids []string) ([]model.FileInfo, error) {
 	}
 
 	var fileInfos []model.FileInfo
-	err = ss.replica.Select(&fileInfos, sql, args...)
+	err = ss.replica.SelectContext(ctx, &fileInfos, sql, args...)
 	if err != nil {
 		return []model.FileInfo{}, errors.Wrap(err, "unable to run query for GetFileInfosByIDs")
 	}
@@ -176,6 +442,89 @@ func (ss SQLStore) GetFileInfosByIDs(ids []string) ([]model.FileInfo, error) {
 	return fileInfos, nil
 }
 
+// GetReactionsForPosts gets the reactions, including removed ones, to the posts with the provided
+// ids.
+func (ss SQLStore) GetReactionsForPosts(ctx context.Context, postIDs []string) ([]model.LegalHoldReaction, error) {
+	query := ss.replicaBuilder.
+		Select(
+			"Reactions.PostId AS PostID",
+			"Reactions.UserId AS UserID",
+			"Users.Username AS UserUsername",
+			"Users.Email AS UserEmail",
+			"Reactions.EmojiName AS EmojiName",
+			"Reactions.CreateAt AS CreateAt",
+			"Reactions.UpdateAt AS UpdateAt",
+			"Reactions.DeleteAt AS DeleteAt",
+		).
+		From("Reactions").
+		Join("Users ON Users.Id = Reactions.UserId").
+		Where(sq.Eq{"Reactions.PostId": postIDs}).
+		OrderBy("Reactions.PostId", "Reactions.CreateAt", "Reactions.UserId", "Reactions.EmojiName")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get sql for GetReactionsForPosts")
+	}
+
+	var reactions []model.LegalHoldReaction
+	if err = ss.replica.SelectContext(ctx, &reactions, sql, args...); err != nil {
+		return nil, errors.Wrap(err, "unable to run query for GetReactionsForPosts")
+	}
+
+	return reactions, nil
+}
+
+// HasPostAcknowledgements returns true if the database supports post acknowledgements, which
+// were introduced in Mattermost Server v7.7.
+func (ss SQLStore) HasPostAcknowledgements(ctx context.Context) (bool, error) {
+	query := `
+		SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_name = 'postacknowledgements'
+	`
+	if ss.src.DriverName() == mattermostModel.DatabaseDriverMysql {
+		query = `
+		SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_name = 'PostAcknowledgements'
+	`
+	}
+
+	var count int
+	if err := ss.replica.GetContext(ctx, &count, query); err != nil {
+		return false, errors.Wrap(err, "unable to check for post acknowledgements")
+	}
+
+	return count > 0, nil
+}
+
+// GetAcknowledgementsForPosts gets the acknowledgements of the posts with the provided ids. It
+// must only be called if HasPostAcknowledgements returns true.
+func (ss SQLStore) GetAcknowledgementsForPosts(ctx context.Context, postIDs []string) ([]model.LegalHoldAcknowledgement, error) {
+	query := ss.replicaBuilder.
+		Select(
+			"PostAcknowledgements.PostId AS PostID",
+			"PostAcknowledgements.UserId AS UserID",
+			"Users.Username AS UserUsername",
+			"Users.Email AS UserEmail",
+			"PostAcknowledgements.AcknowledgedAt AS AcknowledgedAt",
+		).
+		From("PostAcknowledgements").
+		Join("Users ON Users.Id = PostAcknowledgements.UserId").
+		Where(sq.Eq{"PostAcknowledgements.PostId": postIDs}).
+		OrderBy("PostAcknowledgements.PostId", "PostAcknowledgements.AcknowledgedAt", "PostAcknowledgements.UserId")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get sql for GetAcknowledgementsForPosts")
+	}
+
+	var acknowledgements []model.LegalHoldAcknowledgement
+	if err = ss.replica.SelectContext(ctx, &acknowledgements, sql, args...); err != nil {
+		return nil, errors.Wrap(err, "unable to run query for GetAcknowledgementsForPosts")
+	}
+
+	return acknowledgements, nil
+}
+
 // GetChannelMetadataForIDs receives a list of channelIDs and returns the ChannelMetadata for each
 // of the identified channels. ChannelMetadata is all the additional data that is needed to populate
 // the Legal Hold index file with information about the channel.
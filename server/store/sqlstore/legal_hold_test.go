@@ -1,6 +1,7 @@
 package sqlstore
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -33,7 +34,7 @@ func TestSQLStore_GetPostsBatch(t *testing.T) {
 	cursor := model.NewLegalHoldCursor(mattermostModel.GetMillis() - 1000000)
 
 	var legalHold []model.LegalHoldPost
-	legalHold, _, err = th.Store.GetPostsBatch(channel.Id, mattermostModel.GetMillis(), cursor, 1000)
+	legalHold, _, err = th.Store.GetPostsBatch(context.Background(), channel.Id, mattermostModel.GetMillis(), nil, cursor, 1000)
 	require.NoError(t, err)
 	for _, legalHoldItem := range legalHold {
 		t.Log(legalHoldItem)
@@ -54,7 +55,7 @@ func TestSQLStore_GetPostsBatch(t *testing.T) {
 
 	cursor = model.NewLegalHoldCursor(mattermostModel.GetMillis() - 1000000)
 
-	legalHold, _, err = th.Store.GetPostsBatch(directChannel.Id, mattermostModel.GetMillis(), cursor, 1000)
+	legalHold, _, err = th.Store.GetPostsBatch(context.Background(), directChannel.Id, mattermostModel.GetMillis(), nil, cursor, 1000)
 	require.NoError(t, err)
 	for _, legalHoldItem := range legalHold {
 		t.Log(legalHoldItem)
@@ -206,3 +207,317 @@ func TestLegalHold_GetChannelIDsForUserDuring_DeletedChannels(t *testing.T) {
 	// Should include both the deleted channel and the existing channel
 	require.ElementsMatch(t, channelIDs, []string{privateChannel.Id, existingChannel.Id})
 }
+
+func TestSQLStore_LegalHold_GetChannelIDsForTeamsDuring(t *testing.T) {
+	th := SetupHelper(t).SetupBasic(t)
+	defer th.TearDown(t)
+
+	start := mattermostModel.GetMillis()
+	end := start + 10000000
+
+	privateChannel, err := th.CreateChannel("team-private", th.User1.Id, th.Team1.Id, mattermostModel.ChannelTypePrivate)
+	require.NoError(t, err)
+	otherTeamChannel, err := th.CreateChannel("other-team", th.User1.Id, th.Team2.Id, mattermostModel.ChannelTypeOpen)
+	require.NoError(t, err)
+
+	channelIDs, err := th.Store.GetChannelIDsForTeamsDuring([]string{th.Team1.Id}, start-100000, end)
+	require.NoError(t, err)
+	require.ElementsMatch(t, channelIDs, []string{th.Channel1.Id, th.Channel2.Id, privateChannel.Id})
+
+	channelIDs, err = th.Store.GetChannelIDsForTeamsDuring([]string{th.Team1.Id, th.Team2.Id}, start-100000, end)
+	require.NoError(t, err)
+	require.Contains(t, channelIDs, otherTeamChannel.Id)
+
+	// Channels created after the window are not included.
+	channelIDs, err = th.Store.GetChannelIDsForTeamsDuring([]string{th.Team1.Id}, 0, 1)
+	require.NoError(t, err)
+	require.Empty(t, channelIDs)
+}
+
+func TestSQLStore_LegalHold_GetChannelMembersDuring(t *testing.T) {
+	th := SetupHelper(t).SetupBasic(t)
+	defer th.TearDown(t)
+
+	timeReference := mattermostModel.GetMillis()
+	start := timeReference + 1000000
+	end := start + 10000
+
+	// User1 joins before the window and never leaves.
+	require.NoError(t, th.mmStore.ChannelMemberHistory().LogJoinEvent(th.User1.Id, th.Channel1.Id, start-1000))
+	// User2 joins and leaves during the window.
+	require.NoError(t, th.mmStore.ChannelMemberHistory().LogJoinEvent(th.User2.Id, th.Channel1.Id, start+1000))
+	require.NoError(t, th.mmStore.ChannelMemberHistory().LogLeaveEvent(th.User2.Id, th.Channel1.Id, start+2000))
+	// User2 joins the other channel after the window.
+	require.NoError(t, th.mmStore.ChannelMemberHistory().LogJoinEvent(th.User2.Id, th.Channel2.Id, end+1000))
+
+	members, err := th.Store.GetChannelMembersDuring([]string{th.Channel1.Id, th.Channel2.Id}, start, end)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+
+	require.Equal(t, th.Channel1.Id, members[0].ChannelID)
+	require.Equal(t, th.User1.Id, members[0].UserID)
+	require.Equal(t, th.User1.Username, members[0].Username)
+	require.Nil(t, members[0].LeaveTime)
+
+	require.Equal(t, th.User2.Id, members[1].UserID)
+	require.NotNil(t, members[1].LeaveTime)
+	require.Equal(t, start+2000, *members[1].LeaveTime)
+}
+
+func TestSQLStore_GetPostsBatch_Filter(t *testing.T) {
+	th := SetupHelper(t).SetupBasic(t)
+	defer th.TearDown(t)
+
+	channel, err := th.CreateOpenChannel("filter-test", th.User1.Id, th.Team1.Id)
+	require.NoError(t, err)
+
+	_, err = th.CreatePosts(5, th.User1.Id, channel.Id)
+	require.NoError(t, err)
+
+	for _, message := range []string{"About Project Falcon", "nothing to see #falcon here", "#falconry is a hobby"} {
+		_, err = th.mmStore.Post().Save(&mattermostModel.Post{
+			UserId:    th.User1.Id,
+			ChannelId: channel.Id,
+			Message:   message,
+		})
+		require.NoError(t, err)
+	}
+
+	startTime := mattermostModel.GetMillis() - 1000000
+
+	filter := &model.LegalHoldFilter{Terms: []string{"project falcon"}}
+	posts, _, err := th.Store.GetPostsBatch(context.Background(), channel.Id, mattermostModel.GetMillis(), filter, model.NewLegalHoldCursor(startTime), 1000)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	require.Equal(t, "About Project Falcon", posts[0].PostMessage)
+
+	filter = &model.LegalHoldFilter{Hashtags: []string{"#falcon"}}
+	posts, _, err = th.Store.GetPostsBatch(context.Background(), channel.Id, mattermostModel.GetMillis(), filter, model.NewLegalHoldCursor(startTime), 1000)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	require.Equal(t, "nothing to see #falcon here", posts[0].PostMessage)
+
+	filter = &model.LegalHoldFilter{ExcludeChannelTypes: []mattermostModel.ChannelType{mattermostModel.ChannelTypeOpen}}
+	posts, _, err = th.Store.GetPostsBatch(context.Background(), channel.Id, mattermostModel.GetMillis(), filter, model.NewLegalHoldCursor(startTime), 1000)
+	require.NoError(t, err)
+	require.Empty(t, posts)
+}
+
+func TestSQLStore_GetPostRevisionsBatch(t *testing.T) {
+	th := SetupHelper(t).SetupBasic(t)
+	defer th.TearDown(t)
+
+	channel, err := th.CreateOpenChannel("revisions-test", th.User1.Id, th.Team1.Id)
+	require.NoError(t, err)
+
+	// Posts created before the window.
+	createAt := mattermostModel.GetMillis() - 100000
+	var posts []*mattermostModel.Post
+	for _, message := range []string{"original message", "deleted message", "untouched message"} {
+		var post *mattermostModel.Post
+		post, err = th.mmStore.Post().Save(&mattermostModel.Post{
+			UserId:    th.User1.Id,
+			ChannelId: channel.Id,
+			Message:   message,
+			CreateAt:  createAt,
+		})
+		require.NoError(t, err)
+		posts = append(posts, post)
+	}
+
+	startTime := mattermostModel.GetMillis()
+
+	edited := posts[0].Clone()
+	edited.Message = "edited message"
+	edited.EditAt = mattermostModel.GetMillis()
+	_, err = th.mmStore.Post().Update(edited, posts[0])
+	require.NoError(t, err)
+
+	err = th.mmStore.Post().Delete(posts[1].Id, mattermostModel.GetMillis(), th.User1.Id)
+	require.NoError(t, err)
+
+	// A post created within the window is exported with the posts of the window, not as a revision.
+	_, err = th.CreatePosts(1, th.User1.Id, channel.Id)
+	require.NoError(t, err)
+
+	revisions, cursor, err := th.Store.GetPostRevisionsBatch(context.Background(), channel.Id, startTime, mattermostModel.GetMillis()+1, nil, model.NewLegalHoldRevisionCursor(startTime), 1000)
+	require.NoError(t, err)
+	require.True(t, cursor.Completed)
+	require.Len(t, revisions, 3)
+
+	byType := make(map[string]model.LegalHoldPostRevision)
+	for _, revision := range revisions {
+		byType[revision.RevisionType] = revision
+	}
+
+	require.Equal(t, posts[0].Id, byType[model.PostRevisionTypePrevious].RevisionOf)
+	require.Equal(t, "original message", byType[model.PostRevisionTypePrevious].PostMessage)
+	require.Equal(t, posts[0].Id, byType[model.PostRevisionTypeEdited].RevisionOf)
+	require.Equal(t, "edited message", byType[model.PostRevisionTypeEdited].PostMessage)
+	require.NotZero(t, byType[model.PostRevisionTypeEdited].PostEditAt)
+	require.Equal(t, posts[1].Id, byType[model.PostRevisionTypeDeleted].RevisionOf)
+}
+
+func TestSQLStore_GetPostRevisionsBatch_Filter(t *testing.T) {
+	th := SetupHelper(t).SetupBasic(t)
+	defer th.TearDown(t)
+
+	channel, err := th.CreateOpenChannel("revisions-filter-test", th.User1.Id, th.Team1.Id)
+	require.NoError(t, err)
+
+	// Posts created before the window.
+	createAt := mattermostModel.GetMillis() - 100000
+	var posts []*mattermostModel.Post
+	for _, message := range []string{"about project falcon", "nothing to see", "unrelated"} {
+		var post *mattermostModel.Post
+		post, err = th.mmStore.Post().Save(&mattermostModel.Post{
+			UserId:    th.User1.Id,
+			ChannelId: channel.Id,
+			Message:   message,
+			CreateAt:  createAt,
+		})
+		require.NoError(t, err)
+		posts = append(posts, post)
+	}
+
+	startTime := mattermostModel.GetMillis()
+
+	// The first post is edited to no longer match, the second to match, and the third is edited
+	// without ever matching.
+	for i, message := range []string{"about something else", "now about project falcon", "still unrelated"} {
+		edited := posts[i].Clone()
+		edited.Message = message
+		edited.EditAt = mattermostModel.GetMillis()
+		_, err = th.mmStore.Post().Update(edited, posts[i])
+		require.NoError(t, err)
+	}
+
+	filter := &model.LegalHoldFilter{Terms: []string{"project falcon"}}
+	revisions, _, err := th.Store.GetPostRevisionsBatch(context.Background(), channel.Id, startTime, mattermostModel.GetMillis()+1, filter, model.NewLegalHoldRevisionCursor(startTime), 1000)
+	require.NoError(t, err)
+
+	// Every version of the posts that matched at any point is returned.
+	messages := make([]string, 0, len(revisions))
+	for _, revision := range revisions {
+		messages = append(messages, revision.PostMessage)
+	}
+	require.ElementsMatch(t, []string{
+		"about project falcon",
+		"about something else",
+		"nothing to see",
+		"now about project falcon",
+	}, messages)
+}
+
+func TestSQLStore_CountPostsAndFiles(t *testing.T) {
+	th := SetupHelper(t).SetupBasic(t)
+	defer th.TearDown(t)
+
+	channels, err := th.CreateChannels(2, "count-test", th.User1.Id, th.Team1.Id)
+	require.NoError(t, err)
+
+	startTime := mattermostModel.GetMillis() - 1000
+
+	posts, err := th.CreatePosts(3, th.User1.Id, channels[0].Id)
+	require.NoError(t, err)
+	_, err = th.CreatePosts(2, th.User1.Id, channels[1].Id)
+	require.NoError(t, err)
+
+	for _, post := range posts[:2] {
+		_, err = th.mmStore.FileInfo().Save(&mattermostModel.FileInfo{
+			Id:        mattermostModel.NewId(),
+			CreatorId: th.User1.Id,
+			PostId:    post.Id,
+			Name:      "count_test.txt",
+			Path:      "data/count_test.txt",
+		})
+		require.NoError(t, err)
+	}
+
+	endTime := mattermostModel.GetMillis() + 1
+
+	postCount, fileCount, err := th.Store.CountPostsAndFiles(context.Background(), []string{channels[0].Id, channels[1].Id}, startTime, endTime, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), postCount)
+	require.Equal(t, int64(2), fileCount)
+
+	postCount, fileCount, err = th.Store.CountPostsAndFiles(context.Background(), []string{channels[0].Id}, startTime, endTime, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), postCount)
+	require.Equal(t, int64(2), fileCount)
+
+	// Only posts matching the filter are counted.
+	filter := &model.LegalHoldFilter{Terms: []string{"test post 0 of 3"}}
+	postCount, fileCount, err = th.Store.CountPostsAndFiles(context.Background(), []string{channels[0].Id, channels[1].Id}, startTime, endTime, filter)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), postCount)
+	require.Equal(t, int64(1), fileCount)
+
+	// Posts created before the start time are not counted.
+	postCount, fileCount, err = th.Store.CountPostsAndFiles(context.Background(), []string{channels[0].Id}, endTime, endTime+1000, nil)
+	require.NoError(t, err)
+	require.Zero(t, postCount)
+	require.Zero(t, fileCount)
+}
+
+func TestSQLStore_GetReactionsForPosts(t *testing.T) {
+	th := SetupHelper(t).SetupBasic(t)
+	defer th.TearDown(t)
+
+	channel, err := th.CreateOpenChannel("reactions-test", th.User1.Id, th.Team1.Id)
+	require.NoError(t, err)
+
+	posts, err := th.CreatePosts(3, th.User1.Id, channel.Id)
+	require.NoError(t, err)
+
+	_, err = th.CreateReactions(posts[:2], th.User2.Id)
+	require.NoError(t, err)
+
+	reactions, err := th.Store.GetReactionsForPosts(context.Background(), []string{posts[0].Id, posts[2].Id})
+	require.NoError(t, err)
+	require.Len(t, reactions, 1)
+	require.Equal(t, posts[0].Id, reactions[0].PostID)
+	require.Equal(t, th.User2.Id, reactions[0].UserID)
+	require.Equal(t, th.User2.Username, reactions[0].UserUsername)
+	require.Equal(t, "shrug", reactions[0].EmojiName)
+	require.NotZero(t, reactions[0].CreateAt)
+
+	hasAcknowledgements, err := th.Store.HasPostAcknowledgements(context.Background())
+	require.NoError(t, err)
+	if hasAcknowledgements {
+		acknowledgements, err := th.Store.GetAcknowledgementsForPosts(context.Background(), []string{posts[0].Id})
+		require.NoError(t, err)
+		require.Empty(t, acknowledgements)
+	}
+}
+
+func TestPostsFilterClause(t *testing.T) {
+	clause, args := postsFilterClause(nil)
+	require.Empty(t, clause)
+	require.Empty(t, args)
+
+	clause, args = postsFilterClause(&model.LegalHoldFilter{
+		Terms:               []string{"100%"},
+		Hashtags:            []string{"#falcon"},
+		IncludeChannelTypes: []mattermostModel.ChannelType{mattermostModel.ChannelTypePrivate, mattermostModel.ChannelTypeDirect},
+	})
+	require.Equal(t, "AND (LOWER(Posts.Message) LIKE ? OR CONCAT(' ', LOWER(Posts.Hashtags), ' ') LIKE ?) AND Channels.Type IN (?,?)", clause)
+	require.Equal(t, []any{"%100\\%%", "% #falcon %", "P", "D"}, args)
+}
+
+func TestRevisionsFilterClause(t *testing.T) {
+	clause, args := revisionsFilterClause(nil)
+	require.Empty(t, clause)
+	require.Empty(t, args)
+
+	// Channel types apply to the revision, and terms to any version of its post.
+	clause, args = revisionsFilterClause(&model.LegalHoldFilter{
+		Terms:               []string{"falcon"},
+		ExcludeChannelTypes: []mattermostModel.ChannelType{mattermostModel.ChannelTypeOpen},
+	})
+	require.Contains(t, clause, "EXISTS (")
+	require.Contains(t, clause, "(LOWER(Versions.Message) LIKE ?)")
+	require.NotContains(t, clause, "LOWER(Posts.Message)")
+	require.Contains(t, clause, "AND Channels.Type NOT IN (?)")
+	require.Equal(t, []any{"%falcon%", "O"}, args)
+}
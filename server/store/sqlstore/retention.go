@@ -0,0 +1,82 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-legal-hold/server/model"
+)
+
+type retentionPolicyAssignment struct {
+	PolicyID string
+	TargetID string
+}
+
+// GetRetentionPolicies gets the granular data retention policies of the server, together with the
+// teams and channels assigned to them.
+func (ss SQLStore) GetRetentionPolicies(ctx context.Context) ([]model.RetentionPolicy, error) {
+	query := ss.replicaBuilder.
+		Select(
+			"RetentionPolicies.Id AS ID",
+			"RetentionPolicies.DisplayName AS DisplayName",
+			"RetentionPolicies.PostDuration AS PostDurationDays",
+		).
+		From("RetentionPolicies").
+		OrderBy("RetentionPolicies.Id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get sql for GetRetentionPolicies")
+	}
+
+	var policies []model.RetentionPolicy
+	if err = ss.replica.SelectContext(ctx, &policies, sql, args...); err != nil {
+		return nil, errors.Wrap(err, "unable to run query for GetRetentionPolicies")
+	}
+
+	teams, err := ss.getRetentionPolicyAssignments(ctx, "RetentionPoliciesTeams", "TeamId")
+	if err != nil {
+		return nil, err
+	}
+
+	channels, err := ss.getRetentionPolicyAssignments(ctx, "RetentionPoliciesChannels", "ChannelId")
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range policies {
+		policies[i].TeamIDs = teams[policies[i].ID]
+		policies[i].ChannelIDs = channels[policies[i].ID]
+	}
+
+	return policies, nil
+}
+
+// getRetentionPolicyAssignments gets the IDs of the teams or channels assigned to each retention
+// policy, keyed by policy ID.
+func (ss SQLStore) getRetentionPolicyAssignments(ctx context.Context, table, targetColumn string) (map[string][]string, error) {
+	query := ss.replicaBuilder.
+		Select(
+			table+".PolicyId AS PolicyID",
+			table+"."+targetColumn+" AS TargetID",
+		).
+		From(table)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get sql for %s", table)
+	}
+
+	var assignments []retentionPolicyAssignment
+	if err = ss.replica.SelectContext(ctx, &assignments, sql, args...); err != nil {
+		return nil, errors.Wrapf(err, "unable to run query for %s", table)
+	}
+
+	byPolicy := make(map[string][]string)
+	for _, assignment := range assignments {
+		byPolicy[assignment.PolicyID] = append(byPolicy[assignment.PolicyID], assignment.TargetID)
+	}
+
+	return byPolicy, nil
+}
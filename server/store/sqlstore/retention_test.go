@@ -0,0 +1,43 @@
+package sqlstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLStore_GetRetentionPolicies(t *testing.T) {
+	th := SetupHelper(t).SetupBasic(t)
+	defer th.TearDown(t)
+
+	ctx := context.Background()
+
+	policies, err := th.Store.GetRetentionPolicies(ctx)
+	require.NoError(t, err)
+	require.Empty(t, policies)
+
+	channels, err := th.CreateChannels(2, "retention-test", th.User1.Id, th.Team1.Id)
+	require.NoError(t, err)
+
+	postDuration := int64(30)
+	saved, err := th.mmStore.RetentionPolicy().Save(&model.RetentionPolicyWithTeamAndChannelIDs{
+		RetentionPolicy: model.RetentionPolicy{
+			DisplayName:      "Retention Test",
+			PostDurationDays: &postDuration,
+		},
+		TeamIDs:    []string{th.Team2.Id},
+		ChannelIDs: []string{channels[0].Id, channels[1].Id},
+	})
+	require.NoError(t, err)
+
+	policies, err = th.Store.GetRetentionPolicies(ctx)
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	require.Equal(t, saved.ID, policies[0].ID)
+	require.Equal(t, "Retention Test", policies[0].DisplayName)
+	require.Equal(t, postDuration, policies[0].PostDurationDays)
+	require.Equal(t, []string{th.Team2.Id}, policies[0].TeamIDs)
+	require.ElementsMatch(t, []string{channels[0].Id, channels[1].Id}, policies[0].ChannelIDs)
+}
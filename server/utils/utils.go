@@ -1,5 +1,11 @@
 package utils
 
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
 // DeduplicateStringSlice removes duplicate entries from a slice of strings.
 func DeduplicateStringSlice(slice []string) []string {
 	keys := make(map[string]bool)
@@ -15,6 +21,26 @@ func DeduplicateStringSlice(slice []string) []string {
 	return result
 }
 
+// UnorderedEqualSet compares two slices of IDs or other values regardless of order.
+func UnorderedEqualSet[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	setA := make(map[T]bool, len(a))
+	for _, v := range a {
+		setA[v] = true
+	}
+
+	for _, v := range b {
+		if !setA[v] {
+			return false
+		}
+	}
+
+	return true
+}
+
 // Max returns the larger of two int64 values.
 func Max(a, b int64) int64 {
 	if a > b {
@@ -30,3 +56,21 @@ func Min(a, b int64) int64 {
 	}
 	return b
 }
+
+// GetIPAddress returns the IP address of the client of the request, taken from the first of the
+// trusted proxy headers that is set, or from the remote address of the request if none is.
+func GetIPAddress(r *http.Request, trustedProxyIPHeader []string) string {
+	for _, proxyHeader := range trustedProxyIPHeader {
+		address, _, _ := strings.Cut(r.Header.Get(proxyHeader), ",")
+		if address = strings.TrimSpace(address); address != "" {
+			return address
+		}
+	}
+
+	address, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return address
+}
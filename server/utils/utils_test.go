@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,6 +14,29 @@ func TestUtils_DeduplicateStringSlice(t *testing.T) {
 	assert.Equal(t, result, DeduplicateStringSlice(data))
 }
 
+func TestUtils_UnorderedEqualSet(t *testing.T) {
+	// Test with identical slices
+	assert.True(t, UnorderedEqualSet([]string{"a", "b", "c"}, []string{"a", "b", "c"}))
+
+	// Test with different order
+	assert.True(t, UnorderedEqualSet([]string{"a", "b", "c"}, []string{"c", "a", "b"}))
+
+	// Test with different lengths
+	assert.False(t, UnorderedEqualSet([]string{"a", "b"}, []string{"a", "b", "c"}))
+
+	// Test with different elements
+	assert.False(t, UnorderedEqualSet([]string{"a", "b", "c"}, []string{"a", "b", "d"}))
+
+	// Test with empty slices
+	assert.True(t, UnorderedEqualSet([]string{}, []string{}))
+
+	// Test with one empty slice
+	assert.False(t, UnorderedEqualSet([]string{"a"}, []string{}))
+
+	// Test with duplicates
+	assert.True(t, UnorderedEqualSet([]string{"a", "a", "b"}, []string{"b", "a", "a"}))
+}
+
 func TestUtils_Max(t *testing.T) {
 	assert.Equal(t, int64(2), Max(1, 2))
 	assert.Equal(t, int64(2), Max(2, 1))
@@ -22,3 +46,15 @@ func TestUtils_Min(t *testing.T) {
 	assert.Equal(t, int64(1), Min(1, 2))
 	assert.Equal(t, int64(1), Min(2, 1))
 }
+
+func TestUtils_GetIPAddress(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "192.168.0.1, 10.0.0.2")
+
+	assert.Equal(t, "10.0.0.1", GetIPAddress(r, nil))
+	assert.Equal(t, "192.168.0.1", GetIPAddress(r, []string{"X-Real-IP", "X-Forwarded-For"}))
+
+	r.RemoteAddr = "10.0.0.1"
+	assert.Equal(t, "10.0.0.1", GetIPAddress(r, []string{"X-Real-IP"}))
+}
@@ -27,6 +27,11 @@ class APIClient {
         return this.doWithBody(url, 'post', {});
     };
 
+    restoreLegalHold = (id: string) => {
+        const url = `${this.url}/legalholds/${id}/restore`;
+        return this.doWithBody(url, 'post', {});
+    };
+
     updateLegalHold = (id: string, data: UpdateLegalHold) => {
         const url = `${this.url}/legalholds/${id}`;
         return this.doWithBody(url, 'put', data);
@@ -37,6 +42,11 @@ class APIClient {
         return this.doWithBody(url, 'post', {});
     };
 
+    cancelLegalHold = (id: string) => {
+        const url = `${this.url}/legalholds/${id}/cancel`;
+        return this.doWithBody(url, 'post', {});
+    };
+
     testAmazonS3Connection = () => {
         const url = `${this.url}/test_amazon_s3_connection`;
         return this.doWithBody(url, 'post', {}) as Promise<{message: string}>;
@@ -52,6 +62,26 @@ class APIClient {
         return this.doGet(url);
     };
 
+    getPreservationStatus = () => {
+        const url = `${this.url}/preservation`;
+        return this.doGet(url);
+    };
+
+    checkPreservation = () => {
+        const url = `${this.url}/preservation/check`;
+        return this.doWithBody(url, 'post', {});
+    };
+
+    getHealth = () => {
+        const url = `${this.url}/health`;
+        return this.doGet(url);
+    };
+
+    getSigningKey = () => {
+        const url = `${this.url}/signing_key`;
+        return this.doGet(url);
+    };
+
     private doGet = async (url: string, headers = {}) => {
         const options = {
             method: 'get',
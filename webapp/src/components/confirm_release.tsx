@@ -45,7 +45,7 @@ const ConfirmRelease = (props: ConfirmReleaseProps) => {
                 <React.Fragment>
                     {'Are you sure you want to release the legal hold '}
                     <strong>{'"'}{lh.display_name}{'"'}</strong>
-                    {'? All data associated with it will immediately be deleted and cannot be recovered.'}
+                    {'? It will stop collecting data immediately, and all data associated with it will be deleted once the release grace period ends. Until then, the legal hold can be restored.'}
                 </React.Fragment>
             );
         }
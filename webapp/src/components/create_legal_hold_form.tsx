@@ -8,6 +8,7 @@ import {CreateLegalHold} from '@/types';
 import {GenericModal} from '@/components/mattermost-webapp/generic_modal/generic_modal';
 import Input from '@/components/mattermost-webapp/input/input';
 import {isValidDate} from '@/utils/date_validation';
+import {defaultExecutionLength, executionLengthOptions} from '@/utils/execution_length';
 
 import './create_legal_hold_form.scss';
 
@@ -27,6 +28,7 @@ const CreateLegalHoldForm = (props: CreateLegalHoldFormProps) => {
     const [startsAtInvalid, setStartsAtInvalid] = useState(false);
     const [endsAtInvalid, setEndsAtInvalid] = useState(false);
     const [includePublicChannels, setIncludePublicChannels] = useState(false);
+    const [executionLength, setExecutionLength] = useState(defaultExecutionLength);
     const [serverError, setServerError] = useState('');
 
     const displayNameChanged = (e: React.ChangeEvent<HTMLInputElement>) => {
@@ -57,6 +59,10 @@ const CreateLegalHoldForm = (props: CreateLegalHoldFormProps) => {
         setIncludePublicChannels(e.target.checked);
     };
 
+    const executionLengthChanged = (e: React.ChangeEvent<HTMLSelectElement>) => {
+        setExecutionLength(Number(e.target.value));
+    };
+
     const resetForm = () => {
         setDisplayName('');
         setStartsAt('');
@@ -65,6 +71,7 @@ const CreateLegalHoldForm = (props: CreateLegalHoldFormProps) => {
         setGroups([]);
         setSaving(false);
         setIncludePublicChannels(false);
+        setExecutionLength(defaultExecutionLength);
         setServerError('');
         setStartsAtInvalid(false);
         setEndsAtInvalid(false);
@@ -83,6 +90,7 @@ const CreateLegalHoldForm = (props: CreateLegalHoldFormProps) => {
             starts_at: (new Date(startsAt)).getTime(),
             display_name: displayName,
             include_public_channels: includePublicChannels,
+            execution_length: executionLength,
             name: slugify(displayName),
         };
 
@@ -241,6 +249,24 @@ const CreateLegalHoldForm = (props: CreateLegalHoldFormProps) => {
                             hasError={endsAtInvalid}
                         />
                     </div>
+                    <div>
+                        <label htmlFor={'legal-hold-execution-length'}>{'Collect data'}</label>
+                        <select
+                            id='legal-hold-execution-length'
+                            className='form-control'
+                            value={executionLength}
+                            onChange={executionLengthChanged}
+                        >
+                            {executionLengthOptions.map((option) => (
+                                <option
+                                    key={option.value}
+                                    value={option.value}
+                                >
+                                    {option.label}
+                                </option>
+                            ))}
+                        </select>
+                    </div>
                 </div>
             </div>
         </GenericModal>
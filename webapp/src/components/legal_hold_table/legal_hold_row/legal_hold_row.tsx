@@ -21,6 +21,7 @@ interface LegalHoldRowProps {
     users: UserProfile[];
     groups: Group[];
     releaseLegalHold: Function;
+    restoreLegalHold: (id: string) => Promise<void>;
     showUpdateModal: Function;
     showSecretModal: Function;
     runLegalHold: (id: string) => Promise<void>;
@@ -28,9 +29,15 @@ interface LegalHoldRowProps {
 }
 
 const getLastRunDisplay = (lh: LegalHold) => {
+    if (lh.status === 'released') {
+        return 'Released ' + new Date(lh.released_at || 0).toLocaleString();
+    }
     if (lh.status === 'executing') {
         return 'Running now...';
     }
+    if (lh.status === 'failed') {
+        return 'Failed ' + new Date(lh.last_failed_at || 0).toLocaleString();
+    }
     if (!lh.last_execution_ended_at || lh.last_execution_ended_at === 0) {
         return 'Never';
     }
@@ -46,6 +53,7 @@ const LegalHoldRow = (props: LegalHoldRowProps) => {
     const startsAt = (new Date(lh.starts_at)).toLocaleDateString();
     const endsAt = lh.ends_at === 0 ? 'Never' : (new Date(lh.ends_at)).toLocaleDateString();
     const isExecuting = lh.status === 'executing';
+    const isReleased = lh.status === 'released';
 
     const release = () => {
         props.releaseLegalHold(lh);
@@ -65,6 +73,7 @@ const LegalHoldRow = (props: LegalHoldRowProps) => {
             <div data-testid={`groups-${lh.id}`}>{props.groups.length} {'groups'}</div>
             <div
                 data-testid={`last-run-${lh.id}`}
+                title={lh.status === 'failed' ? lh.last_error : undefined}
             >
                 {getLastRunDisplay(lh)}
             </div>
@@ -204,7 +213,7 @@ const LegalHoldRow = (props: LegalHoldRowProps) => {
                         href='#'
                         onClick={(e) => {
                             e.preventDefault();
-                            if (isExecuting) {
+                            if (isExecuting || isReleased) {
                                 return;
                             }
                             setShowRunConfirmModal(true);
@@ -212,8 +221,8 @@ const LegalHoldRow = (props: LegalHoldRowProps) => {
                         style={{
                             marginRight: '20px',
                             height: '24px',
-                            opacity: isExecuting ? '0.5' : '1',
-                            cursor: isExecuting ? 'not-allowed' : 'pointer',
+                            opacity: isExecuting || isReleased ? '0.5' : '1',
+                            cursor: isExecuting || isReleased ? 'not-allowed' : 'pointer',
                         }}
                     >
                         <span
@@ -225,24 +234,40 @@ const LegalHoldRow = (props: LegalHoldRowProps) => {
                         </span>
                     </a>
                 </OverlayTrigger>
-                <a
-                    data-testid={`release-${lh.id}`}
-                    role='button'
-                    aria-label={`${lh.display_name} release button`}
-                    href='#'
-                    onClick={(e) => {
-                        if (isExecuting) {
+                {isReleased ? (
+                    <a
+                        data-testid={`restore-${lh.id}`}
+                        role='button'
+                        aria-label={`${lh.display_name} restore button`}
+                        href='#'
+                        onClick={(e) => {
                             e.preventDefault();
-                            return;
-                        }
-                        release();
-                    }}
-                    className={'btn btn-danger'}
-                    style={{
-                        opacity: isExecuting ? '0.5' : '1',
-                        cursor: isExecuting ? 'not-allowed' : 'pointer',
-                    }}
-                >{'Release'}</a>
+                            props.restoreLegalHold(lh.id).catch(() => {
+                                props.refresh();
+                            });
+                        }}
+                        className={'btn btn-tertiary'}
+                    >{'Restore'}</a>
+                ) : (
+                    <a
+                        data-testid={`release-${lh.id}`}
+                        role='button'
+                        aria-label={`${lh.display_name} release button`}
+                        href='#'
+                        onClick={(e) => {
+                            if (isExecuting) {
+                                e.preventDefault();
+                                return;
+                            }
+                            release();
+                        }}
+                        className={'btn btn-danger'}
+                        style={{
+                            opacity: isExecuting ? '0.5' : '1',
+                            cursor: isExecuting ? 'not-allowed' : 'pointer',
+                        }}
+                    >{'Release'}</a>
+                )}
             </div>
             <RunConfirmationModal
                 show={showRunConfirmModal}
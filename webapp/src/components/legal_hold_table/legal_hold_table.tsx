@@ -10,6 +10,7 @@ interface LegalHoldTableProps {
         getMissingGroupsByIds: Function,
     },
     releaseLegalHold: Function,
+    restoreLegalHold: (id: string) => Promise<void>;
     showUpdateModal: Function,
     showSecretModal: Function,
     runLegalHold: (id: string) => Promise<void>;
@@ -94,6 +95,7 @@ const LegalHoldTable = (props: LegalHoldTableProps) => {
                             legalHold={legalHold}
                             key={'legalhold_' + legalHold.id}
                             releaseLegalHold={props.releaseLegalHold}
+                            restoreLegalHold={props.restoreLegalHold}
                             showUpdateModal={props.showUpdateModal}
                             showSecretModal={props.showSecretModal}
                             runLegalHold={(id: string) => props.runLegalHold(id)}
@@ -60,6 +60,17 @@ const LegalHoldsSetting = () => {
         }
     };
 
+    const restoreLegalHold = async (id: string) => {
+        try {
+            const response = await Client.restoreLegalHold(id);
+            setLegalHoldsFetched(false);
+            return response;
+        } catch (error) {
+            console.log(error); //eslint-disable-line no-console
+            throw error;
+        }
+    };
+
     const updateLegalHold = async (data: UpdateLegalHold) => {
         try {
             const response = await Client.updateLegalHold(data.id, data);
@@ -214,6 +225,7 @@ const LegalHoldsSetting = () => {
                     <LegalHoldTable
                         legalHolds={legalHolds}
                         releaseLegalHold={doShowReleaseModal}
+                        restoreLegalHold={restoreLegalHold}
                         showUpdateModal={doShowUpdateModal}
                         showSecretModal={doShowSecretModal}
                         runLegalHold={doRunLegalHold}
@@ -10,6 +10,7 @@ import UsersInput from '@/components/users_input';
 import GroupsInput from '@/components/groups_input';
 import {LegalHold, UpdateLegalHold} from '@/types';
 import {isValidDate} from '@/utils/date_validation';
+import {defaultExecutionLength, executionLengthOptions} from '@/utils/execution_length';
 
 import '../create_legal_hold_form.scss';
 
@@ -31,6 +32,7 @@ const UpdateLegalHoldForm = (props: UpdateLegalHoldFormProps) => {
     const [endsAt, setEndsAt] = useState('');
     const [saving, setSaving] = useState(false);
     const [includePublicChannels, setIncludePublicChannels] = useState(false);
+    const [executionLength, setExecutionLength] = useState(defaultExecutionLength);
     const [serverError, setServerError] = useState('');
     const [endsAtInvalid, setEndsAtInvalid] = useState(false);
 
@@ -52,6 +54,10 @@ const UpdateLegalHoldForm = (props: UpdateLegalHoldFormProps) => {
         setIncludePublicChannels(e.target.checked);
     };
 
+    const executionLengthChanged = (e: React.ChangeEvent<HTMLSelectElement>) => {
+        setExecutionLength(Number(e.target.value));
+    };
+
     const resetForm = () => {
         setId('');
         setDisplayName('');
@@ -60,6 +66,7 @@ const UpdateLegalHoldForm = (props: UpdateLegalHoldFormProps) => {
         setGroups([]);
         setServerError('');
         setIncludePublicChannels(false);
+        setExecutionLength(defaultExecutionLength);
         setSaving(false);
         setEndsAtInvalid(false);
     };
@@ -76,6 +83,7 @@ const UpdateLegalHoldForm = (props: UpdateLegalHoldFormProps) => {
             setUsers(props.users);
             setGroups(props.groups);
             setIncludePublicChannels(props.legalHold.include_public_channels);
+            setExecutionLength(props.legalHold.execution_length || defaultExecutionLength);
 
             if (props.legalHold.starts_at) {
                 const startsAtString = dayjs(props.legalHold.starts_at).format('YYYY-MM-DD');
@@ -103,8 +111,11 @@ const UpdateLegalHoldForm = (props: UpdateLegalHoldFormProps) => {
             id: props.legalHold.id,
             user_ids: users.map((user) => user.id),
             group_ids: groups.map((group) => group.id),
+            channel_ids: props.legalHold.channel_ids,
+            team_ids: props.legalHold.team_ids,
             ends_at: (new Date(endsAt)).getTime(),
             include_public_channels: includePublicChannels,
+            execution_length: executionLength,
             display_name: displayName,
         };
 
@@ -261,6 +272,24 @@ const UpdateLegalHoldForm = (props: UpdateLegalHoldFormProps) => {
                             hasError={endsAtInvalid}
                         />
                     </div>
+                    <div>
+                        <label htmlFor={'legal-hold-execution-length'}>{'Collect data'}</label>
+                        <select
+                            id='legal-hold-execution-length'
+                            className='form-control'
+                            value={executionLength}
+                            onChange={executionLengthChanged}
+                        >
+                            {executionLengthOptions.map((option) => (
+                                <option
+                                    key={option.value}
+                                    value={option.value}
+                                >
+                                    {option.label}
+                                </option>
+                            ))}
+                        </select>
+                    </div>
                 </div>
             </div>
         </GenericModal>
@@ -6,11 +6,19 @@ export interface LegalHold {
     ends_at: number;
     user_ids: string[];
     group_ids: string[];
+    channel_ids?: string[];
+    team_ids?: string[];
     include_public_channels: boolean;
     secret: string;
     last_execution_ended_at: number;
+    execution_length: number;
     has_messages: boolean;
-    status: 'idle' | 'executing';
+    status: 'idle' | 'executing' | 'released' | 'failed';
+    released_at?: number;
+    released_by?: string;
+    consecutive_failures?: number;
+    last_error?: string;
+    last_failed_at?: number;
 }
 
 export interface CreateLegalHold {
@@ -20,6 +28,9 @@ export interface CreateLegalHold {
     ends_at: number;
     user_ids: Array<string>;
     group_ids?: Array<string>;
+    channel_ids?: Array<string>;
+    team_ids?: Array<string>;
+    execution_length?: number;
 }
 
 export interface UpdateLegalHold {
@@ -28,4 +39,7 @@ export interface UpdateLegalHold {
     ends_at: number;
     user_ids: Array<string>;
     group_ids?: Array<string>;
+    channel_ids?: Array<string>;
+    team_ids?: Array<string>;
+    execution_length?: number;
 }
@@ -0,0 +1,13 @@
+const hour = 60 * 60 * 1000;
+
+export const defaultExecutionLength = 24 * hour;
+
+// executionLengthOptions are the lengths of the executions of a legal hold, and so how often it
+// collects data, that can be chosen for it.
+export const executionLengthOptions = [
+    {value: hour, label: 'Every hour'},
+    {value: 6 * hour, label: 'Every 6 hours'},
+    {value: 12 * hour, label: 'Every 12 hours'},
+    {value: defaultExecutionLength, label: 'Every day'},
+    {value: 7 * defaultExecutionLength, label: 'Every week'},
+];